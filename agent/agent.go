@@ -0,0 +1,143 @@
+// Package agent pushes the goroutines of the running program to roumon.
+//
+// Programs which cannot expose a pprof endpoint import the agent and push their goroutines to a roumon started
+// with -grpc-listen. The agent dials out, so the program does not have to open a port.
+//
+//	a, err := agent.Start(agent.Options{Addr: "roumon.example.com:7777", Token: os.Getenv("ROUMON_TOKEN")})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer a.Stop()
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/becheran/roumon/roumongrpc"
+	"github.com/becheran/roumon/roumonhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// DefaultInterval is the time between two pushes if Options.Interval is not set
+const DefaultInterval = 5 * time.Second
+
+// Options of the agent
+type Options struct {
+	Addr     string        // Address of roumon, for example roumon.example.com:7777
+	Source   string        // Name of the program instance in roumon. Defaults to the host name and the process ID
+	Token    string        // Bearer token which roumon expects. Optional
+	Interval time.Duration // Time between two pushes. Defaults to DefaultInterval
+	TLS      *tls.Config   // Transport security. The connection is not encrypted if nil
+}
+
+// Agent pushes the goroutines periodically until it is stopped
+type Agent struct {
+	opts     Options
+	conn     *grpc.ClientConn
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+	stopErr  error // Result of the first Stop
+}
+
+// Start connects to roumon and pushes the goroutines every interval. Connection errors are logged and the agent
+// reconnects with the next push.
+func Start(opts Options) (*Agent, error) {
+	if len(opts.Addr) == 0 {
+		return nil, errors.New("agent: address of roumon is missing")
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if len(opts.Source) == 0 {
+		host, err := os.Hostname()
+		if err != nil {
+			host = "unknown"
+		}
+		opts.Source = fmt.Sprintf("%s/%d", host, os.Getpid())
+	}
+
+	transport := insecure.NewCredentials()
+	if opts.TLS != nil {
+		transport = credentials.NewTLS(opts.TLS)
+	}
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(transport)}
+	if len(opts.Token) > 0 {
+		dialOpts = append(dialOpts, roumongrpc.Token(opts.Token))
+	}
+	conn, err := grpc.NewClient(opts.Addr, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("agent: failed to connect to %s. Err: %s", opts.Addr, err.Error())
+	}
+
+	a := &Agent{
+		opts:    opts,
+		conn:    conn,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go a.run()
+	return a, nil
+}
+
+// Stop closes the stream which removes the program from roumon. Further calls return the result of the first one.
+func (a *Agent) Stop() error {
+	a.stopOnce.Do(func() {
+		close(a.stop)
+		<-a.stopped
+		a.stopErr = a.conn.Close()
+	})
+	return a.stopErr
+}
+
+func (a *Agent) run() {
+	defer close(a.stopped)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ticker := time.NewTicker(a.opts.Interval)
+	defer ticker.Stop()
+	var stream *roumongrpc.SnapshotSender
+	for {
+		if stream == nil {
+			var err error
+			stream, err = roumongrpc.NewSnapshotClient(a.conn).StreamSnapshots(ctx)
+			if err != nil {
+				log.Printf("agent: failed to open stream to %s. Err: %s", a.opts.Addr, err.Error())
+			}
+		}
+		if stream != nil {
+			if err := a.push(stream); err != nil {
+				log.Printf("agent: failed to push goroutines to %s. Err: %s", a.opts.Addr, err.Error())
+				stream = nil
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-a.stop:
+			if stream != nil {
+				if _, err := stream.CloseAndRecv(); err != nil {
+					log.Printf("agent: failed to close stream to %s. Err: %s", a.opts.Addr, err.Error())
+				}
+			}
+			return
+		}
+	}
+}
+
+func (a *Agent) push(stream *roumongrpc.SnapshotSender) error {
+	dump, err := roumonhttp.Collect()
+	if err != nil {
+		return err
+	}
+	return stream.Send(&roumongrpc.Snapshot{Source: a.opts.Source, Dump: dump})
+}
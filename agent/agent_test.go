@@ -0,0 +1,62 @@
+package agent_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/agent"
+	"github.com/becheran/roumon/internal/collector"
+	"github.com/becheran/roumon/internal/model"
+	"github.com/becheran/roumon/roumongrpc"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+func TestAgentPushes(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	routines := make(chan model.Snapshot)
+	status := make(chan model.FetchStatus)
+	srv := grpc.NewServer(roumongrpc.TokenAuth("secret"))
+	roumongrpc.RegisterSnapshotServer(srv, collector.New(routines, status))
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	defer srv.Stop()
+
+	a, err := agent.Start(agent.Options{
+		Addr:     listener.Addr().String(),
+		Source:   "app",
+		Token:    "secret",
+		Interval: 10 * time.Millisecond,
+	})
+	assert.Nil(t, err)
+
+	snapshot := <-routines
+	assert.Equal(t, "app", snapshot.Target)
+	assert.NotEmpty(t, snapshot.Goroutines)
+	assert.Equal(t, model.Connected, (<-status).State)
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- a.Stop()
+	}()
+	for {
+		select {
+		case <-routines:
+		case s := <-status:
+			if s.Gone {
+				assert.Equal(t, "app", s.Target)
+				assert.Nil(t, <-stopped)
+				assert.Nil(t, a.Stop(), "a second stop returns the first result")
+				return
+			}
+		}
+	}
+}
+
+func TestStartWithoutAddr(t *testing.T) {
+	_, err := agent.Start(agent.Options{})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/becheran/roumon/internal/bundle"
+	"github.com/becheran/roumon/internal/config"
+)
+
+// bundleExt is the extension of a bundle
+const bundleExt = ".tar.gz"
+
+// createBundle packs a recording with the config and an HTML report into one archive for a support ticket
+func createBundle(args []string) {
+	flags := flag.NewFlagSet("roumon bundle", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: roumon bundle [flags] <recording directory>")
+		flags.PrintDefaults()
+	}
+	out := flags.String("out", "", "Path of the bundle. Defaults to the recording directory with .tar.gz appended")
+	configFile := flags.String("config", config.DefaultPath(), "Path to the config file which is packed. Empty leaves the config out")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	dir := flags.Arg(0)
+	if len(*out) == 0 {
+		*out = filepath.Clean(dir) + bundleExt
+	}
+
+	f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	manifest, err := bundle.Write(f, dir, *configFile, buildVersion(), time.Now())
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		_ = os.Remove(*out)
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	snapshots := 0
+	for _, target := range manifest.Targets {
+		snapshots += target.Snapshots
+	}
+	fmt.Printf("Bundled %d snapshots to %s\n", snapshots, *out)
+}
+
+// importBundle unpacks a bundle and lists its targets
+func importBundle(args []string) {
+	flags := flag.NewFlagSet("roumon import", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: roumon import <bundle> [directory]")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	dir := strings.TrimSuffix(flags.Arg(0), bundleExt)
+	if flags.NArg() == 2 {
+		dir = flags.Arg(1)
+	}
+	manifest, err := extractBundle(flags.Arg(0), dir)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	fmt.Printf("Bundle of %s created %s\n", manifest.Roumon, manifest.Created.Format(time.DateTime))
+	for _, target := range manifest.Targets {
+		fmt.Printf("  %s: %d snapshots from %s to %s, %d goroutines\n", target.Name, target.Snapshots,
+			target.First.Format(time.DateTime), target.Last.Format(time.DateTime), target.Goroutines)
+	}
+	fmt.Printf("Replay with: roumon replay %s\n", filepath.Join(dir, bundle.SessionDir))
+	fmt.Printf("Report: %s\n", filepath.Join(dir, bundle.ReportName))
+}
+
+func extractBundle(path, dir string) (bundle.Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return bundle.Manifest{}, err
+	}
+	defer func() { _ = f.Close() }()
+	return bundle.Extract(f, dir)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/becheran/roumon/internal/dump"
+	"github.com/becheran/roumon/internal/model"
+)
+
+// diffFormats are the formats of roumon diff
+var diffFormats = []string{"text", "json", "markdown"}
+
+// diff compares the latest snapshots of two saved dumps or JSON exports, for example before and after a deploy,
+// and prints the goroutines which were added, removed or changed their status or wait time and the stacks whose
+// number of goroutines changed
+func diff(args []string) {
+	flags := flag.NewFlagSet("roumon diff", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: roumon diff [flags] before after")
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", "text", "Format of the diff: text, json or markdown")
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if !slices.Contains(diffFormats, *format) {
+		fmt.Printf("unknown format %q, expected text, json or markdown\n", *format)
+		os.Exit(2)
+	}
+	var routines [2][]model.Goroutine
+	for i, path := range flags.Args() {
+		snapshots, err := dump.LoadFile(path)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+		// The files are named differently, so the targets are cleared to match the goroutines of both
+		latest := snapshots[len(snapshots)-1]
+		latest.SetTarget("")
+		routines[i] = latest.Goroutines
+	}
+
+	result := model.CompareSnapshots(routines[0], routines[1])
+	var err error
+	switch *format {
+	case "json":
+		err = model.WriteDiffJSON(os.Stdout, result)
+	case "markdown":
+		err = model.WriteDiffMarkdown(os.Stdout, result)
+	default:
+		err = model.WriteDiffText(os.Stdout, result)
+	}
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+}
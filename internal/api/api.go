@@ -0,0 +1,203 @@
+// Package api serves the snapshots which a running roumon received as JSON over HTTP, so scripts and dashboards
+// query the live state without a second profiler fetching from the targets.
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// maxHistory is the number of points of the history kept of every target. Older points are dropped.
+const maxHistory = 1000
+
+// Point of the history of a target
+type Point struct {
+	Target     string         `json:"target"`
+	Time       time.Time      `json:"time"`
+	Goroutines int            `json:"goroutines"`
+	Statuses   map[string]int `json:"statuses"` // Goroutines by status
+}
+
+// Group of the goroutines of the latest snapshot of a target with identical stacks
+type Group struct {
+	Target         string  `json:"target"`
+	Fingerprint    string  `json:"fingerprint"` // Same as in the metrics of Prometheus and StatsD
+	Count          int     `json:"count"`
+	Status         string  `json:"status"`
+	Func           string  `json:"func"` // First function outside of the standard library
+	MaxWaitMinutes int64   `json:"maxWaitMinutes"`
+	IDs            []int64 `json:"ids"`
+	Stack          []Frame `json:"stack"`
+	CreatedBy      *Frame  `json:"createdBy,omitempty"`
+}
+
+// Frame of a stack like in the JSON export
+type Frame struct {
+	Func string `json:"func"`
+	File string `json:"file"`
+	Line int32  `json:"line"`
+}
+
+// Server keeps the latest snapshot and the history of every target and serves them at /snapshot, /groups and
+// /history. The target query parameter selects a single target. Safe for concurrent use.
+type Server struct {
+	mu       sync.Mutex
+	targets  []string // Targets in order of their first snapshot
+	latest   map[string]model.Snapshot
+	history  map[string][]Point
+	handlers *http.ServeMux
+}
+
+// NewServer creates a server without snapshots
+func NewServer() *Server {
+	s := &Server{
+		latest:   make(map[string]model.Snapshot),
+		history:  make(map[string][]Point),
+		handlers: http.NewServeMux(),
+	}
+	s.handlers.HandleFunc("/snapshot", s.serveSnapshot)
+	s.handlers.HandleFunc("/groups", s.serveGroups)
+	s.handlers.HandleFunc("/history", s.serveHistory)
+	return s
+}
+
+// Update keeps the snapshot as latest snapshot of its target and adds it to the history
+func (s *Server) Update(snapshot model.Snapshot) {
+	point := Point{Target: snapshot.Target, Time: snapshot.Time, Goroutines: len(snapshot.Goroutines),
+		Statuses: make(map[string]int)}
+	for _, g := range snapshot.Goroutines {
+		point.Statuses[g.Status]++
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.latest[snapshot.Target]; !ok {
+		s.targets = append(s.targets, snapshot.Target)
+	}
+	s.latest[snapshot.Target] = snapshot
+	history := append(s.history[snapshot.Target], point)
+	if len(history) > maxHistory {
+		history = history[len(history)-maxHistory:]
+	}
+	s.history[snapshot.Target] = history
+}
+
+// Tee updates the server with every snapshot of in before it is passed on to out. Returns once in is closed.
+func (s *Server) Tee(in <-chan model.Snapshot, out chan<- model.Snapshot) {
+	for snapshot := range in {
+		s.Update(snapshot)
+		out <- snapshot
+	}
+}
+
+// ServeHTTP serves the endpoints. Only GET is allowed.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.handlers.ServeHTTP(w, r)
+}
+
+// selected returns the latest snapshots of the target of the query or of all targets. Returns false if the target
+// is unknown.
+func (s *Server) selected(r *http.Request) ([]model.Snapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if target := r.URL.Query().Get("target"); len(target) > 0 {
+		snapshot, ok := s.latest[target]
+		return []model.Snapshot{snapshot}, ok
+	}
+	snapshots := make([]model.Snapshot, len(s.targets))
+	for i, target := range s.targets {
+		snapshots[i] = s.latest[target]
+	}
+	return snapshots, true
+}
+
+// serveSnapshot writes the latest snapshots in the JSON format of roumon top -output=json
+func (s *Server) serveSnapshot(w http.ResponseWriter, r *http.Request) {
+	snapshots, ok := s.selected(r)
+	if !ok {
+		http.Error(w, "unknown target", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := model.WriteSnapshotsJSON(w, snapshots); err != nil {
+		log.Printf("Failed to write snapshot: %s", err.Error())
+	}
+}
+
+// serveGroups writes the groups of identical stacks of the latest snapshots, largest groups of a target first
+func (s *Server) serveGroups(w http.ResponseWriter, r *http.Request) {
+	snapshots, ok := s.selected(r)
+	if !ok {
+		http.Error(w, "unknown target", http.StatusNotFound)
+		return
+	}
+	groups := []Group{}
+	for _, snapshot := range snapshots {
+		for _, g := range model.GroupByStack(snapshot.Goroutines) {
+			groups = append(groups, newGroup(snapshot.Target, g))
+		}
+	}
+	writeJSON(w, groups)
+}
+
+// serveHistory writes the goroutine counts of the snapshots of the target of the query or of all targets, ordered
+// by target and time
+func (s *Server) serveHistory(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	points := []Point{}
+	target := r.URL.Query().Get("target")
+	_, known := s.history[target]
+	for _, t := range s.targets {
+		if len(target) == 0 || t == target {
+			points = append(points, s.history[t]...)
+		}
+	}
+	s.mu.Unlock()
+	if len(target) > 0 && !known {
+		http.Error(w, "unknown target", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, points)
+}
+
+func newGroup(target string, g model.StackGroup) Group {
+	first := g.Goroutines[0]
+	group := Group{
+		Target:      target,
+		Fingerprint: g.Fingerprint(),
+		Count:       len(g.Goroutines),
+		Status:      first.Status,
+		Func:        g.Func(),
+		IDs:         make([]int64, len(g.Goroutines)),
+		Stack:       make([]Frame, len(first.StackTrace)),
+	}
+	for i, routine := range g.Goroutines {
+		group.IDs[i] = routine.ID
+		group.MaxWaitMinutes = max(group.MaxWaitMinutes, routine.WaitSinceMin)
+	}
+	for i, frame := range first.StackTrace {
+		group.Stack[i] = Frame{Func: frame.FuncName, File: frame.File, Line: frame.Line}
+	}
+	if first.CratedBy != nil {
+		group.CreatedBy = &Frame{Func: first.CratedBy.FuncName, File: first.CratedBy.File, Line: first.CratedBy.Line}
+	}
+	return group
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		log.Printf("Failed to write response: %s", err.Error())
+	}
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func get(t *testing.T, s *Server, url string, v any) int {
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+	if recorder.Code == http.StatusOK {
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), v))
+	}
+	return recorder.Code
+}
+
+func TestServer(t *testing.T) {
+	serve := []model.StackFrame{{FuncName: "main.serve(0x1)", File: "/src/main.go", Line: 20}}
+	creator := &model.StackFrame{FuncName: "main.main", File: "/src/main.go", Line: 8}
+	start := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	s := NewServer()
+	s.Update(model.Snapshot{Target: "b", Time: start, Goroutines: []model.Goroutine{{ID: 1, Status: "running"}}})
+	s.Update(model.Snapshot{Target: "a", Time: start, Goroutines: []model.Goroutine{
+		{ID: 1, Status: "running"},
+	}})
+	s.Update(model.Snapshot{Target: "a", Time: start.Add(time.Second), Goroutines: []model.Goroutine{
+		{ID: 1, Status: "running"},
+		{ID: 7, Status: "select", WaitSinceMin: 3, StackTrace: serve, CratedBy: creator},
+		{ID: 9, Status: "select", WaitSinceMin: 12, StackTrace: serve, CratedBy: creator},
+	}})
+
+	var snapshot struct {
+		Version   int
+		Snapshots []struct {
+			Target     string
+			Goroutines []struct{ ID int64 }
+		}
+	}
+	assert.Equal(t, http.StatusOK, get(t, s, "/snapshot", &snapshot))
+	assert.Equal(t, model.SnapshotVersion, snapshot.Version)
+	assert.Len(t, snapshot.Snapshots, 2)
+	assert.Equal(t, "b", snapshot.Snapshots[0].Target, "targets in the order of their first snapshot")
+	assert.Len(t, snapshot.Snapshots[1].Goroutines, 3, "the latest snapshot")
+
+	var groups []Group
+	assert.Equal(t, http.StatusOK, get(t, s, "/groups?target=a", &groups))
+	assert.Len(t, groups, 2)
+	assert.Equal(t, Group{Target: "a", Fingerprint: groups[0].Fingerprint, Count: 2, Status: "select",
+		Func: "main.serve", MaxWaitMinutes: 12, IDs: []int64{7, 9}, Stack: []Frame{{Func: "main.serve(0x1)",
+			File: "/src/main.go", Line: 20}}, CreatedBy: &Frame{Func: "main.main", File: "/src/main.go", Line: 8}}, groups[0])
+	assert.Len(t, groups[0].Fingerprint, 16)
+
+	var history []Point
+	assert.Equal(t, http.StatusOK, get(t, s, "/history", &history))
+	assert.Len(t, history, 3)
+	assert.Equal(t, "b", history[0].Target)
+	assert.Equal(t, http.StatusOK, get(t, s, "/history?target=a", &history))
+	assert.Len(t, history, 2)
+	assert.Equal(t, 3, history[1].Goroutines)
+	assert.Equal(t, map[string]int{"running": 1, "select": 2}, history[1].Statuses)
+	assert.True(t, history[1].Time.Equal(start.Add(time.Second)))
+
+	for _, url := range []string{"/snapshot?target=c", "/groups?target=c", "/history?target=c"} {
+		assert.Equal(t, http.StatusNotFound, get(t, s, url, nil), url)
+	}
+	assert.Equal(t, http.StatusNotFound, get(t, s, "/", nil))
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/snapshot", strings.NewReader("{}")))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
+
+func TestEmptyServer(t *testing.T) {
+	s := NewServer()
+	var groups []Group
+	assert.Equal(t, http.StatusOK, get(t, s, "/groups", &groups))
+	assert.NotNil(t, groups, "an empty array instead of null")
+	var history []Point
+	assert.Equal(t, http.StatusOK, get(t, s, "/history", &history))
+	assert.NotNil(t, history)
+}
+
+func TestHistoryLimit(t *testing.T) {
+	s := NewServer()
+	start := time.Now()
+	for i := 0; i < maxHistory+5; i++ {
+		s.Update(model.Snapshot{Target: "a", Time: start.Add(time.Duration(i) * time.Second)})
+	}
+	assert.Len(t, s.history["a"], maxHistory)
+	assert.True(t, s.history["a"][0].Time.Equal(start.Add(5*time.Second)), "the oldest points are dropped")
+}
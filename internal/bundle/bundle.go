@@ -0,0 +1,236 @@
+// Package bundle packs a recorded session into a single gzip compressed tar archive, which is attached to a support
+// ticket, and unpacks it on the other side. Next to the recording the archive holds the config, a manifest with the
+// targets and an HTML report of their latest snapshots.
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/becheran/roumon/internal/dump"
+	"github.com/becheran/roumon/internal/report"
+)
+
+// Version of the layout of a bundle. Bundles of newer versions are rejected.
+const Version = 1
+
+// Names of the entries of a bundle
+const (
+	ManifestName = "manifest.json"
+	ConfigName   = "config.json"
+	ReportName   = "report.html"
+	SessionDir   = "session" // Recording with one subdirectory per target, which roumon replay plays back
+)
+
+// Manifest describes the recording of a bundle
+type Manifest struct {
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	Roumon  string    `json:"roumon"` // Version of roumon which created the bundle
+	Targets []Target  `json:"targets"`
+}
+
+// Target of the recording
+type Target struct {
+	Name       string    `json:"name"`
+	Snapshots  int       `json:"snapshots"`
+	First      time.Time `json:"first"`
+	Last       time.Time `json:"last"`
+	Goroutines int       `json:"goroutines"` // Goroutines of the last snapshot
+}
+
+// Write packs the recording in dir, the config file and the manifest and report of the recording. A missing config
+// file is left out, as is a config path which is empty. The modification times of the recorded files, which are the
+// times of the snapshots, are kept.
+func Write(w io.Writer, dir, configPath, version string, now time.Time) (Manifest, error) {
+	snapshots, err := dump.LoadSession(dir)
+	if err != nil {
+		return Manifest{}, err
+	}
+	if len(snapshots) == 0 {
+		return Manifest{}, fmt.Errorf("no snapshots recorded in %s", dir)
+	}
+	manifest := Manifest{Version: Version, Created: now, Roumon: version}
+	index := make(map[string]int)
+	for _, s := range snapshots {
+		i, ok := index[s.Target]
+		if !ok {
+			i = len(manifest.Targets)
+			index[s.Target] = i
+			manifest.Targets = append(manifest.Targets, Target{Name: s.Target, First: s.Time})
+		}
+		t := &manifest.Targets[i]
+		t.Snapshots++
+		t.Last = s.Time
+		t.Goroutines = len(s.Goroutines)
+	}
+
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+	if err := writeFile(archive, ManifestName, append(content, '\n'), now); err != nil {
+		return manifest, err
+	}
+	if len(configPath) > 0 {
+		content, err := os.ReadFile(configPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return manifest, fmt.Errorf("failed to read config. Err: %s", err.Error())
+		}
+		if err == nil {
+			if err := writeFile(archive, ConfigName, content, now); err != nil {
+				return manifest, err
+			}
+		}
+	}
+	var html strings.Builder
+	if err := report.Write(&html, snapshots, now); err != nil {
+		return manifest, err
+	}
+	if err := writeFile(archive, ReportName, []byte(html.String()), now); err != nil {
+		return manifest, err
+	}
+	err = filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(entry.Name(), ".") && file != dir {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		return copyFile(archive, path.Join(SessionDir, filepath.ToSlash(rel)), file)
+	})
+	if err != nil {
+		return manifest, fmt.Errorf("failed to pack recording. Err: %s", err.Error())
+	}
+	if err := archive.Close(); err != nil {
+		return manifest, err
+	}
+	return manifest, gz.Close()
+}
+
+func writeFile(archive *tar.Writer, name string, content []byte, modTime time.Time) error {
+	header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), ModTime: modTime, Format: tar.FormatPAX}
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := archive.Write(content)
+	return err
+}
+
+// copyFile adds the file with its modification time. PAX headers keep the fraction of a second.
+func copyFile(archive *tar.Writer, name, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{Name: name, Mode: 0644, Size: info.Size(), ModTime: info.ModTime(), Format: tar.FormatPAX}
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(archive, f)
+	return err
+}
+
+// Extract unpacks the bundle into dir and returns its manifest. Only regular files are unpacked and entries which
+// would land outside of dir are rejected. The recording is found in the session subdirectory of dir.
+func Extract(r io.Reader, dir string) (Manifest, error) {
+	var manifest Manifest
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return manifest, fmt.Errorf("not a gzip compressed bundle. Err: %s", err.Error())
+	}
+	archive := tar.NewReader(gz)
+	found := false
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return manifest, fmt.Errorf("failed to read bundle. Err: %s", err.Error())
+		}
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+		if header.Typeflag != tar.TypeReg {
+			return manifest, fmt.Errorf("bundle entry %s is not a regular file", header.Name)
+		}
+		name := path.Clean(header.Name)
+		if !fs.ValidPath(name) {
+			return manifest, fmt.Errorf("bundle entry %s is outside of the bundle", header.Name)
+		}
+		if name == ManifestName {
+			content, err := io.ReadAll(archive)
+			if err != nil {
+				return manifest, err
+			}
+			if err := json.Unmarshal(content, &manifest); err != nil {
+				return manifest, fmt.Errorf("failed to parse manifest. Err: %s", err.Error())
+			}
+			if manifest.Version > Version {
+				return manifest, fmt.Errorf("bundle version %d is newer than the supported version %d. Update roumon",
+					manifest.Version, Version)
+			}
+			found = true
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return manifest, err
+			}
+			if err := os.WriteFile(filepath.Join(dir, ManifestName), content, 0644); err != nil {
+				return manifest, err
+			}
+			continue
+		}
+		if err := extractFile(archive, filepath.Join(dir, filepath.FromSlash(name)), header.ModTime); err != nil {
+			return manifest, fmt.Errorf("failed to unpack %s. Err: %s", name, err.Error())
+		}
+	}
+	if !found {
+		return manifest, fmt.Errorf("not a roumon bundle, %s is missing", ManifestName)
+	}
+	return manifest, nil
+}
+
+func extractFile(archive io.Reader, file string, modTime time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, archive)
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(file, modTime, modTime)
+}
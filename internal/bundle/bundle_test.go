@@ -0,0 +1,99 @@
+package bundle_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/bundle"
+	"github.com/becheran/roumon/internal/dump"
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteAndExtract(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "session")
+	recorder, err := dump.NewRecorder(dir)
+	assert.Nil(t, err)
+	start := time.Date(2024, 1, 2, 15, 4, 5, 250*int(time.Millisecond), time.Local)
+	routines := []model.Goroutine{
+		{ID: 1, Status: "running", StackTrace: []model.StackFrame{{FuncName: "main.main()", File: "/src/main.go", Line: 5}}},
+		{ID: 2, Status: "select", StackTrace: []model.StackFrame{{FuncName: "main.serve()", File: "/src/main.go", Line: 12}}},
+	}
+	for i, target := range []string{"localhost:6060", "localhost:6061", "localhost:6060"} {
+		_, err := recorder.Save(model.Snapshot{Target: target, Time: start.Add(time.Duration(i) * time.Second),
+			Goroutines: routines[:i%2+1]})
+		assert.Nil(t, err)
+	}
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	assert.Nil(t, os.WriteFile(configPath, []byte(`{"theme":"light"}`), 0600))
+
+	var archive bytes.Buffer
+	manifest, err := bundle.Write(&archive, dir, configPath, "v1.2.3", start)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1.2.3", manifest.Roumon)
+	assert.Equal(t, []bundle.Target{
+		{Name: "localhost_6060", Snapshots: 2, First: start, Last: start.Add(2 * time.Second), Goroutines: 1},
+		{Name: "localhost_6061", Snapshots: 1, First: start.Add(time.Second), Last: start.Add(time.Second), Goroutines: 2},
+	}, manifest.Targets)
+
+	out := filepath.Join(t.TempDir(), "incident")
+	extracted, err := bundle.Extract(bytes.NewReader(archive.Bytes()), out)
+	assert.Nil(t, err)
+	assert.Len(t, extracted.Targets, 2)
+	assert.True(t, extracted.Created.Equal(start))
+	for _, name := range []string{bundle.ManifestName, bundle.ConfigName, bundle.ReportName} {
+		assert.FileExists(t, filepath.Join(out, name))
+	}
+	snapshots, err := dump.LoadSession(filepath.Join(out, bundle.SessionDir))
+	assert.Nil(t, err)
+	assert.Len(t, snapshots, 3)
+	assert.True(t, snapshots[0].Time.Equal(start), "the time of a snapshot is kept")
+	assert.Equal(t, "localhost_6061", snapshots[1].Target)
+
+	_, err = bundle.Extract(bytes.NewReader(archive.Bytes()), out)
+	assert.Error(t, err, "existing files are not overwritten")
+}
+
+func TestWriteWithoutConfig(t *testing.T) {
+	dir := t.TempDir()
+	_, err := bundle.Write(&bytes.Buffer{}, dir, "", "dev", time.Now())
+	assert.Error(t, err, "nothing recorded")
+
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "dump.txt"), []byte("goroutine 1 [running]:\nmain.main()\n\t/src/main.go:5 +0x1d\n"), 0644))
+	var archive bytes.Buffer
+	manifest, err := bundle.Write(&archive, dir, filepath.Join(dir, "missing.json"), "dev", time.Now())
+	assert.Nil(t, err)
+	assert.Len(t, manifest.Targets, 1)
+	out := t.TempDir()
+	_, err = bundle.Extract(&archive, out)
+	assert.Nil(t, err)
+	assert.NoFileExists(t, filepath.Join(out, bundle.ConfigName))
+	assert.FileExists(t, filepath.Join(out, bundle.SessionDir, "dump.txt"))
+}
+
+func TestExtractRejects(t *testing.T) {
+	archive := func(name string, content string) *bytes.Buffer {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		w := tar.NewWriter(gz)
+		assert.Nil(t, w.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}))
+		_, err := w.Write([]byte(content))
+		assert.Nil(t, err)
+		assert.Nil(t, w.Close())
+		assert.Nil(t, gz.Close())
+		return &buf
+	}
+	_, err := bundle.Extract(archive("../escape.txt", "x"), t.TempDir())
+	assert.ErrorContains(t, err, "outside of the bundle")
+	_, err = bundle.Extract(archive("session/dump.txt", "x"), t.TempDir())
+	assert.ErrorContains(t, err, "not a roumon bundle")
+	_, err = bundle.Extract(archive(bundle.ManifestName, `{"version":99}`), t.TempDir())
+	assert.ErrorContains(t, err, "newer than the supported version")
+	_, err = bundle.Extract(bytes.NewReader([]byte("plain text")), t.TempDir())
+	assert.ErrorContains(t, err, "not a gzip compressed bundle")
+}
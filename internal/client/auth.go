@@ -0,0 +1,44 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// validateAuth checks that at most one authentication method is configured
+func validateAuth(opts Options) error {
+	methods := 0
+	for _, option := range []string{opts.BasicAuth, opts.BearerToken, opts.TokenFile} {
+		if len(option) > 0 {
+			methods++
+		}
+	}
+	if methods > 1 {
+		return fmt.Errorf("only one of basic auth, bearer token and token file can be set")
+	}
+	if len(opts.BasicAuth) > 0 && !strings.Contains(opts.BasicAuth, ":") {
+		return fmt.Errorf("basic auth must be of the form user:password")
+	}
+	return nil
+}
+
+// authorize adds the configured credentials to the request. The token file is read for every request because
+// tokens are rotated by some platforms.
+func authorize(req *http.Request, opts Options) error {
+	switch {
+	case len(opts.BasicAuth) > 0:
+		user, password, _ := strings.Cut(opts.BasicAuth, ":")
+		req.SetBasicAuth(user, password)
+	case len(opts.BearerToken) > 0:
+		req.Header.Set("Authorization", "Bearer "+opts.BearerToken)
+	case len(opts.TokenFile) > 0:
+		token, err := os.ReadFile(opts.TokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read token file. Err: %s", err.Error())
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	return nil
+}
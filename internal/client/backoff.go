@@ -0,0 +1,34 @@
+package client
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// maxBackoffInterval is the longest interval the client backs off to unless the configured interval is longer
+const maxBackoffInterval = time.Minute
+
+// adaptInterval returns the interval until the next fetch. The interval is doubled if the last fetch took longer
+// than the interval, and shrinks back to the configured interval once the target is fast again.
+func adaptInterval(configured, current, took time.Duration) time.Duration {
+	limit := max(configured, maxBackoffInterval)
+	switch {
+	case took > current:
+		return min(limit, 2*current)
+	case took < current/2:
+		return max(configured, current/2)
+	}
+	return max(configured, current)
+}
+
+// retryDelay returns the time until the next attempt after the given number of consecutive failures. The delay
+// grows exponentially and is randomized by up to half so that targets behind the same proxy do not retry in sync.
+func retryDelay(configured time.Duration, failures int) time.Duration {
+	limit := max(configured, maxBackoffInterval)
+	delay := configured
+	for i := 1; i < failures && delay < limit; i++ {
+		delay *= 2
+	}
+	delay = min(limit, delay)
+	return delay/2 + rand.N(delay/2+1)
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptInterval(t *testing.T) {
+	// Healthy target
+	assert.Equal(t, time.Second, adaptInterval(time.Second, time.Second, 10*time.Millisecond))
+	assert.Equal(t, time.Second, adaptInterval(time.Second, time.Second, 800*time.Millisecond))
+	// Slow target
+	assert.Equal(t, 2*time.Second, adaptInterval(time.Second, time.Second, 1500*time.Millisecond))
+	assert.Equal(t, 4*time.Second, adaptInterval(time.Second, 2*time.Second, 3*time.Second))
+	assert.Equal(t, time.Minute, adaptInterval(time.Second, time.Minute, 2*time.Minute))
+	assert.Equal(t, 2*time.Minute, adaptInterval(2*time.Minute, 2*time.Minute, 3*time.Minute))
+	// Recover
+	assert.Equal(t, 2*time.Second, adaptInterval(time.Second, 4*time.Second, 10*time.Millisecond))
+	assert.Equal(t, time.Second, adaptInterval(time.Second, 1500*time.Millisecond, 10*time.Millisecond))
+	// Configured interval changed
+	assert.Equal(t, 5*time.Second, adaptInterval(5*time.Second, time.Second, 10*time.Millisecond))
+}
+
+func TestRetryDelay(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		delay := retryDelay(time.Second, 1)
+		assert.GreaterOrEqual(t, delay, 500*time.Millisecond)
+		assert.LessOrEqual(t, delay, time.Second)
+
+		delay = retryDelay(time.Second, 4)
+		assert.GreaterOrEqual(t, delay, 4*time.Second)
+		assert.LessOrEqual(t, delay, 8*time.Second)
+
+		delay = retryDelay(time.Second, 100)
+		assert.GreaterOrEqual(t, delay, 30*time.Second)
+		assert.LessOrEqual(t, delay, time.Minute)
+
+		delay = retryDelay(2*time.Minute, 3)
+		assert.LessOrEqual(t, delay, 2*time.Minute)
+	}
+}
@@ -1,53 +1,507 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"mime"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/becheran/roumon/internal/model"
+	"github.com/becheran/roumon/roumonhttp"
 )
 
 // Client for pprof events
 type Client struct {
-	c      *http.Client
-	server string
+	c        *http.Client
+	opts     Options
+	headers  http.Header
+	target   Target
+	server   string
+	endpoint string
+	detected bool // The endpoint was detected and a fetch succeeded. Detected again until then
+	useJSON  bool
+	interval atomic.Int64
+	limiter  *limiter
+	wakeup   chan struct{}
+	retry    chan struct{}
+	refresh  chan struct{}
+	name     string          // Name of the target shown to the user
+	failFast bool            // Terminate if the first fetch fails
+	ctx      context.Context // Canceled by Stop. Cancels requests in flight
+	cancel   context.CancelFunc
+	done     chan struct{} // Closed when Run returns
+}
+
+// Options configure the connection to the target
+type Options struct {
+	CACert     string // Path to a PEM encoded CA certificate used to verify the server
+	ClientCert string // Path to a PEM encoded client certificate
+	ClientKey  string // Path to the PEM encoded key of the client certificate
+	Insecure   bool   // Skip verification of the server certificate
+
+	BasicAuth   string // Credentials of the form user:password
+	BearerToken string // Token sent as bearer authorization
+	TokenFile   string // File which contains the bearer token
+
+	Headers []string // Additional request headers of the form "Name: value"
+
+	Proxy string // URL of a HTTP or SOCKS5 proxy. Defaults to the proxy environment variables
+
+	SSH           string // SSH server user@host[:port] to connect to the target from
+	SSHKey        string // Private key for the SSH server. Defaults to the SSH agent and the keys in ~/.ssh
+	SSHKnownHosts string // Known hosts file to verify the SSH server. Defaults to ~/.ssh/known_hosts
+
+	Profiles       []string      // Names of the profiles fetched alongside the goroutines. See Profiles
+	Interval       time.Duration // Time between two fetches. Defaults to one second
+	FetchTimeout   time.Duration // Timeout of a single request including reading the response. Zero disables it
+	Expvar         bool          // Fetch the expvars from /debug/vars alongside the goroutines
+	MaxRetries     int           // Consecutive failed fetches until the client gives up. Zero retries forever
+	HealthInterval time.Duration // Time between two health probes of the target. Zero disables the probes
+
+	RateLimit     float64 // Maximum requests per second to the same host. Zero is unlimited
+	MaxConcurrent int     // Maximum requests in flight to all targets of a pool. Zero is unlimited
 }
 
 // NewClient creates a new client listening for pprof events
 func NewClient(ip string, port int) *Client {
-	server := fmt.Sprintf("http://%s:%d/debug/pprof/goroutine?debug=2", ip, port)
-	log.Printf("Attach to server %s\n", server)
-	c := &http.Client{}
-	return &Client{
-		c:      c,
-		server: server,
+	client, err := New(net.JoinHostPort(ip, strconv.Itoa(port)), Options{})
+	if err != nil {
+		log.Fatalf("Failed to create client: %s", err.Error())
 	}
+	return client
 }
 
-// Run starts the client and listen for incoming routine changes
-func (client *Client) Run(terminate chan<- error, routineUpdate chan<- []model.Goroutine) {
-	ticker := time.NewTicker(time.Second * 1)
-	defer ticker.Stop()
+// New creates a new client listening for pprof events of the target. See ParseTarget for the supported formats.
+func New(target string, opts Options) (*Client, error) {
+	t, err := ParseTarget(target)
+	if err != nil {
+		return nil, err
+	}
 
+	if err := validateAuth(opts); err != nil {
+		return nil, err
+	}
+	if err := validateProfiles(opts.Profiles); err != nil {
+		return nil, err
+	}
+	headers, err := parseHeaders(opts.Headers)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig, err = tlsConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	transport.Proxy, err = proxyFunc(opts)
+	if err != nil {
+		return nil, err
+	}
+	dialContext := (&net.Dialer{}).DialContext
+	bastion, err := tunnel(opts)
+	if err != nil {
+		return nil, err
+	}
+	if bastion != nil {
+		dialContext = bastion.DialContext
+		transport.DialContext = dialContext
+	}
+	if len(t.Socket) > 0 {
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialContext(ctx, "unix", t.Socket)
+		}
+	}
+
+	server := t.goroutineURL()
+	log.Printf("Attach to server %s\n", t)
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &Client{
+		ctx:      ctx,
+		cancel:   cancel,
+		c:        &http.Client{Transport: transport},
+		opts:     opts,
+		headers:  headers,
+		target:   t,
+		server:   server,
+		endpoint: t.endpointURL(),
+		limiter:  newLimiter(opts),
+		wakeup:   make(chan struct{}, 1),
+		retry:    make(chan struct{}, 1),
+		refresh:  make(chan struct{}, 1),
+		name:     t.String(),
+		failFast: true,
+		done:     make(chan struct{}),
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	client.interval.Store(int64(interval))
+	return client, nil
+}
+
+// Stop the client. Run returns without sending further updates.
+func (client *Client) Stop() {
+	client.cancel()
+}
+
+// Interval returns the time between two fetches
+func (client *Client) Interval() time.Duration {
+	return time.Duration(client.interval.Load())
+}
+
+// SetInterval changes the time between two fetches. The next fetch starts immediately.
+func (client *Client) SetInterval(interval time.Duration) {
+	client.interval.Store(int64(interval))
+	log.Printf("Set fetch interval to %s", interval)
+	select {
+	case client.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// Retry fetching immediately. Resumes polling if the client gave up.
+func (client *Client) Retry() {
+	select {
+	case client.retry <- struct{}{}:
+	default:
+	}
+}
+
+// Refresh fetches immediately instead of waiting for the end of the interval. Failed fetches keep their backoff
+// and a client which gave up keeps waiting for a retry.
+func (client *Client) Refresh() {
+	select {
+	case client.refresh <- struct{}{}:
+	default:
+	}
+}
+
+// Run starts the client and listen for incoming routine changes. The status channel is optional.
+// Failed fetches are retried with an exponential backoff. The client gives up after MaxRetries consecutive
+// failures and fails if it never connected to the target.
+func (client *Client) Run(terminate chan<- error, routineUpdate chan<- model.Snapshot, statusUpdate chan<- model.FetchStatus) {
+	defer close(client.done)
+	if statusUpdate != nil && client.opts.HealthInterval > 0 {
+		ctx, cancel := context.WithCancel(client.ctx)
+		probed := make(chan struct{})
+		go func() {
+			client.probe(ctx, statusUpdate)
+			close(probed)
+		}()
+		defer func() {
+			cancel()
+			<-probed
+		}()
+	}
+	connected := false
+	failures := 0
+	interval := client.Interval()
 	for {
-		resp, err := client.c.Get(client.server)
-		if err != nil {
-			terminate <- fmt.Errorf("failed to list go routines. Err: %s", err.Error())
+		start := time.Now()
+		if !client.detected {
+			client.useJSON = client.detect()
+		}
+
+		var snapshot model.Snapshot
+		var err error
+		if client.useJSON {
+			snapshot, err = client.fetchJSON()
+		} else {
+			snapshot, err = client.fetchDump()
+		}
+		took := time.Since(start)
+
+		if client.ctx.Err() != nil {
+			// Stopped while fetching
 			return
 		}
 
-		goroutines, err := model.ParseStackFrame(resp.Body)
+		state := model.Connected
+		timeout := errors.Is(err, context.DeadlineExceeded)
+		var wait time.Duration
 		if err != nil {
-			log.Printf("Error while parsing stack: %s", err.Error())
+			if timeout {
+				err = fmt.Errorf("fetch timed out after %s", client.opts.FetchTimeout)
+			} else {
+				err = fmt.Errorf("failed to list go routines. Err: %s", err.Error())
+			}
+			failures++
+			log.Printf("%s: %s (failure %d)", client.name, err.Error(), failures)
+			state = model.Retrying
+			if client.opts.MaxRetries > 0 && failures > client.opts.MaxRetries {
+				state = model.GaveUp
+				if !connected && client.failFast {
+					terminate <- fmt.Errorf("%s: %s", client.name, err.Error())
+					return
+				}
+				log.Printf("%s: Gave up after %d failures", client.name, failures)
+			}
+			wait = retryDelay(client.Interval(), failures)
 		} else {
-			routineUpdate <- goroutines
+			connected = true
+			client.detected = true
+			failures = 0
+			snapshot.Profiles = client.fetchProfiles()
+			if client.opts.Expvar {
+				snapshot.Vars, err = client.fetchVars()
+				if err != nil {
+					log.Printf("%s: Failed to fetch expvars: %s", client.name, err.Error())
+					err = nil
+				}
+			}
+			snapshot.SetTarget(client.name)
+			select {
+			case routineUpdate <- snapshot:
+			case <-client.ctx.Done():
+				return
+			}
+
+			next := adaptInterval(client.Interval(), interval, took)
+			if next != interval {
+				log.Printf("Adapt fetch interval from %s to %s", interval, next)
+				interval = next
+			}
+			wait = interval - took
+		}
+
+		if statusUpdate != nil {
+			status := model.FetchStatus{
+				Target:   client.name,
+				Time:     start,
+				State:    state,
+				Timeout:  timeout,
+				Failures: failures,
+				Interval: wait,
+				Latency:  took,
+				Err:      err,
+			}
+			select {
+			case statusUpdate <- status:
+			case <-client.ctx.Done():
+				return
+			}
 		}
 
+		timer := time.NewTimer(wait)
+		refresh := client.refresh
+		if state == model.GaveUp {
+			// Wait for a retry
+			timer.Stop()
+			refresh = nil
+		}
+		select {
+		case <-timer.C:
+		case <-refresh:
+		case <-client.wakeup:
+			interval = client.Interval()
+		case <-client.retry:
+			failures = 0
+		case <-client.ctx.Done():
+			timer.Stop()
+			return
+		}
+		timer.Stop()
+	}
+}
+
+// newRequest creates a GET request with the configured headers and authorization
+func (client *Client) newRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range client.headers {
+		if key == "Host" {
+			// Go ignores the host header field. See: https://pkg.go.dev/net/http#Request
+			req.Host = values[len(values)-1]
+			continue
+		}
+		req.Header[key] = values
+	}
+	if len(req.Header.Get("Accept-Encoding")) == 0 {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	if err := authorize(req, client.opts); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (client *Client) get(url string) (*http.Response, error) {
+	req, err := client.newRequest(client.ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	release, err := client.limiter.acquire(client.ctx, client.target.host())
+	if err != nil {
+		return nil, err
+	}
+	cancel := context.CancelFunc(func() {})
+	if client.opts.FetchTimeout > 0 {
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(client.ctx, client.opts.FetchTimeout)
+		req = req.WithContext(ctx)
+	}
+	resp, err := client.c.Do(req)
+	if err != nil {
+		cancel()
+		release()
+		return nil, err
+	}
+	// The request is in flight until its body is read
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel, release: release}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err = fmt.Errorf("unexpected response status %s", resp.Status)
+	} else {
+		err = decodeBody(resp)
+	}
+	if err != nil {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Printf("Error while closing response body: %s", errClose.Error())
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// cancelBody releases the context of the request and its slot of the limiter once the body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel  context.CancelFunc
+	release func()
+	once    sync.Once
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.cancel()
+		b.release()
+	})
+	return err
+}
+
+func parseHeaders(raw []string) (http.Header, error) {
+	headers := http.Header{}
+	for _, header := range raw {
+		key, value, found := strings.Cut(header, ":")
+		key = strings.TrimSpace(key)
+		if !found || len(key) == 0 || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("header must be of the form \"Name: value\", but got: %s", header)
+		}
+		headers.Add(key, strings.TrimSpace(value))
+	}
+	return headers, nil
+}
+
+// detect returns true if the target serves the roumon endpoint
+func (client *Client) detect() bool {
+	resp, err := client.get(client.endpoint)
+	if err != nil {
+		return false
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Error while closing response body: %s", err.Error())
+		}
+	}()
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	found := resp.StatusCode == http.StatusOK && mediaType == roumonhttp.ContentType
+	if found {
+		log.Printf("Use roumon endpoint %s", client.endpoint)
+	}
+	return found
+}
+
+func (client *Client) fetchDump() (snapshot model.Snapshot, err error) {
+	resp, err := client.get(client.server)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Error while closing response body: %s", err.Error())
+		}
+	}()
+
+	result, errParse := model.ParseDump(resp.Body)
+	if errParse != nil {
+		// The dump is incomplete
+		err = fmt.Errorf("failed to read dump. Err: %w", errParse)
+		return
+	}
+	if result.Skipped > 0 {
+		log.Printf("Skipped %d malformed goroutines", result.Skipped)
+	}
+	snapshot = model.Snapshot{
+		Time:       time.Now(),
+		Goroutines: result.Goroutines,
+		Skipped:    result.Skipped,
+	}
+	return
+}
+
+func (client *Client) fetchJSON() (snapshot model.Snapshot, err error) {
+	resp, err := client.get(client.endpoint)
+	if err != nil {
+		return
+	}
+	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			log.Printf("Error while closing response body: %s", err.Error())
 		}
-		<-ticker.C
+	}()
+
+	var dump roumonhttp.Dump
+	if err = json.NewDecoder(resp.Body).Decode(&dump); err != nil {
+		err = fmt.Errorf("failed to decode roumon endpoint response. Err: %w", err)
+		return
+	}
+	snapshot = FromDump(dump)
+	return
+}
+
+// FromDump converts the response of the roumon endpoint to a snapshot
+func FromDump(dump roumonhttp.Dump) model.Snapshot {
+	snapshot := model.Snapshot{
+		Time:       dump.Time,
+		Goroutines: make([]model.Goroutine, len(dump.Goroutines)),
+		Build:      dump.Build,
 	}
+	for i, g := range dump.Goroutines {
+		routine := model.Goroutine{
+			ID:             g.ID,
+			Status:         g.Status,
+			WaitSinceMin:   g.WaitMinutes,
+			LockedToThread: g.LockedToThread,
+			Labels:         g.Labels,
+			StackTrace:     make([]model.StackFrame, len(g.Stack)),
+		}
+		for idx, f := range g.Stack {
+			routine.StackTrace[idx] = fromFrame(f)
+		}
+		if g.CreatedBy != nil {
+			createdBy := fromFrame(*g.CreatedBy)
+			routine.CratedBy = &createdBy
+		}
+		snapshot.Goroutines[i] = routine
+	}
+	return snapshot
+}
+
+func fromFrame(f roumonhttp.Frame) model.StackFrame {
+	return model.NewStackFrame(f.Func, f.File, f.Line, f.Offset)
 }
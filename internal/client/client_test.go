@@ -1,35 +1,820 @@
 package client_test
 
 import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"expvar"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/http/pprof"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/becheran/roumon/internal/client"
 	"github.com/becheran/roumon/internal/model"
+	"github.com/becheran/roumon/roumonhttp"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
 )
 
 func TestEmptyResponse(t *testing.T) {
 	const testport = 6062
 
 	// test server
+	listener, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", testport))
+	assert.Nil(t, err)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/goroutine", func(w http.ResponseWriter, r *http.Request) {})
 	go func() {
-		err := http.ListenAndServe(fmt.Sprintf("localhost:%d", testport), nil)
+		err := http.Serve(listener, mux)
 		assert.Nil(t, err)
 	}()
 
 	testClient := client.NewClient("localhost", testport)
 
 	done := make(chan error)
-	routines := make(chan []model.Goroutine)
+	routines := make(chan model.Snapshot)
 
-	go testClient.Run(done, routines)
+	go testClient.Run(done, routines, nil)
 	select {
 	case r := <-routines:
-		assert.Empty(t, r)
+		assert.Empty(t, r.Goroutines)
 	case <-done:
 		log.Fatal("Failed")
 	}
 }
+
+func TestRoumonEndpoint(t *testing.T) {
+	server := httptest.NewServer(roumonhttp.Handler())
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	assert.Nil(t, err)
+	port, err := strconv.Atoi(serverURL.Port())
+	assert.Nil(t, err)
+
+	testClient := client.NewClient(serverURL.Hostname(), port)
+
+	done := make(chan error)
+	routines := make(chan model.Snapshot)
+
+	go testClient.Run(done, routines, nil)
+	select {
+	case r := <-routines:
+		assert.NotEmpty(t, r.Goroutines)
+		assert.NotNil(t, r.Build)
+	case err := <-done:
+		log.Fatal(err)
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "app.sock")
+	listener, err := net.Listen("unix", socket)
+	assert.Nil(t, err)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/custom/debug/pprof/goroutine", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "2", r.URL.Query().Get("debug"))
+		fmt.Fprint(w, "goroutine 1 [running]:\nmain.main()\n\t/home/user/main.go:10 +0x1d\n")
+	})
+	go func() {
+		_ = http.Serve(listener, mux)
+	}()
+	defer listener.Close()
+
+	testClient, err := client.New("unix://"+socket+":/custom/debug/pprof/goroutine", client.Options{})
+	assert.Nil(t, err)
+
+	done := make(chan error)
+	routines := make(chan model.Snapshot)
+
+	go testClient.Run(done, routines, nil)
+	select {
+	case r := <-routines:
+		assert.Len(t, r.Goroutines, 1)
+	case err := <-done:
+		log.Fatal(err)
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	target, err := client.ParseTarget("localhost:6060")
+	assert.Nil(t, err)
+	assert.Equal(t, "http://localhost:6060", target.Base)
+	assert.Equal(t, "/debug/pprof/goroutine", target.Path)
+	assert.Empty(t, target.Socket)
+
+	target, err = client.ParseTarget("https://example.com/admin/debug/pprof/goroutine")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com", target.Base)
+	assert.Equal(t, "/admin", target.Prefix)
+
+	target, err = client.ParseTarget("unix:///var/run/app.sock")
+	assert.Nil(t, err)
+	assert.Equal(t, "/var/run/app.sock", target.Socket)
+	assert.Equal(t, "/debug/pprof/goroutine", target.Path)
+
+	target, err = client.ParseTarget("unix:///var/run/app.sock:/debug/pprof/goroutine")
+	assert.Nil(t, err)
+	assert.Equal(t, "/var/run/app.sock", target.Socket)
+	assert.Equal(t, "/debug/pprof/goroutine", target.Path)
+
+	_, err = client.ParseTarget("unix://")
+	assert.NotNil(t, err)
+	_, err = client.ParseTarget("ftp://example.com")
+	assert.NotNil(t, err)
+}
+
+func TestTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "goroutine 1 [running]:\nmain.main()\n\t/home/user/main.go:10 +0x1d\n")
+	}))
+	defer server.Close()
+
+	caCert := filepath.Join(t.TempDir(), "ca.pem")
+	err := os.WriteFile(caCert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600)
+	assert.Nil(t, err)
+
+	for _, opts := range []client.Options{{Insecure: true}, {CACert: caCert}} {
+		testClient, err := client.New(server.URL, opts)
+		assert.Nil(t, err)
+
+		done := make(chan error)
+		routines := make(chan model.Snapshot)
+		go testClient.Run(done, routines, nil)
+		select {
+		case r := <-routines:
+			assert.Len(t, r.Goroutines, 1)
+		case err := <-done:
+			log.Fatal(err)
+		}
+	}
+
+	testClient, err := client.New(server.URL, client.Options{Interval: time.Millisecond, MaxRetries: 1})
+	assert.Nil(t, err)
+	done := make(chan error)
+	go testClient.Run(done, make(chan model.Snapshot), nil)
+	assert.NotNil(t, <-done)
+
+	_, err = client.New(server.URL, client.Options{ClientCert: caCert})
+	assert.NotNil(t, err)
+	_, err = client.New(server.URL, client.Options{CACert: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.NotNil(t, err)
+}
+
+func TestAuth(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	assert.Nil(t, os.WriteFile(tokenFile, []byte("file-token\n"), 0600))
+
+	tests := []struct {
+		opts     client.Options
+		expected string
+	}{
+		{client.Options{BasicAuth: "user:pass"}, "Basic dXNlcjpwYXNz"},
+		{client.Options{BearerToken: "secret"}, "Bearer secret"},
+		{client.Options{TokenFile: tokenFile}, "Bearer file-token"},
+	}
+	for _, test := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, test.expected, r.Header.Get("Authorization"))
+		}))
+
+		testClient, err := client.New(server.URL, test.opts)
+		assert.Nil(t, err)
+		done := make(chan error)
+		routines := make(chan model.Snapshot)
+		go testClient.Run(done, routines, nil)
+		select {
+		case <-routines:
+		case err := <-done:
+			log.Fatal(err)
+		}
+		server.Close()
+	}
+
+	_, err := client.New("localhost:6060", client.Options{BasicAuth: "user"})
+	assert.NotNil(t, err)
+	_, err = client.New("localhost:6060", client.Options{BasicAuth: "user:pass", BearerToken: "secret"})
+	assert.NotNil(t, err)
+}
+
+func TestHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "foo", r.Header.Get("X-Org"))
+		assert.Equal(t, []string{"a", "b"}, r.Header.Values("X-Multi"))
+		assert.Equal(t, "app.internal", r.Host)
+	}))
+	defer server.Close()
+
+	testClient, err := client.New(server.URL, client.Options{Headers: []string{"X-Org: foo", "X-Multi: a", "X-Multi:b", "Host: app.internal"}})
+	assert.Nil(t, err)
+	done := make(chan error)
+	routines := make(chan model.Snapshot)
+	go testClient.Run(done, routines, nil)
+	select {
+	case <-routines:
+	case err := <-done:
+		log.Fatal(err)
+	}
+
+	_, err = client.New(server.URL, client.Options{Headers: []string{"X-Org foo"}})
+	assert.NotNil(t, err)
+	_, err = client.New(server.URL, client.Options{Headers: []string{": foo"}})
+	assert.NotNil(t, err)
+}
+
+func TestProxy(t *testing.T) {
+	proxied := make(chan string, 1)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case proxied <- r.URL.String():
+		default:
+		}
+		fmt.Fprint(w, "goroutine 1 [running]:\nmain.main()\n\t/home/user/main.go:10 +0x1d\n")
+	}))
+	defer proxy.Close()
+
+	testClient, err := client.New("http://pprof.internal:6060", client.Options{Proxy: proxy.URL})
+	assert.Nil(t, err)
+	done := make(chan error)
+	routines := make(chan model.Snapshot)
+	go testClient.Run(done, routines, nil)
+	select {
+	case r := <-routines:
+		assert.Len(t, r.Goroutines, 1)
+	case err := <-done:
+		log.Fatal(err)
+	}
+	assert.Equal(t, "http://pprof.internal:6060/debug/roumon", <-proxied)
+
+	_, err = client.New("localhost:6060", client.Options{Proxy: "ftp://proxy:21"})
+	assert.NotNil(t, err)
+	_, err = client.New("localhost:6060", client.Options{Proxy: "socks5://"})
+	assert.NotNil(t, err)
+	_, err = client.New("localhost:6060", client.Options{Proxy: "socks5://jump:1080"})
+	assert.Nil(t, err)
+}
+
+func TestSetInterval(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	testClient, err := client.New(server.URL, client.Options{Interval: time.Hour})
+	assert.Nil(t, err)
+	assert.Equal(t, time.Hour, testClient.Interval())
+
+	done := make(chan error)
+	routines := make(chan model.Snapshot)
+	go testClient.Run(done, routines, nil)
+	<-routines
+
+	testClient.SetInterval(2 * time.Hour)
+	assert.Equal(t, 2*time.Hour, testClient.Interval())
+	select {
+	case <-routines:
+	case <-time.After(5 * time.Second):
+		t.Fatal("interval change did not trigger a fetch")
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	testClient, err := client.New(server.URL, client.Options{Interval: time.Hour})
+	assert.Nil(t, err)
+
+	done := make(chan error)
+	routines := make(chan model.Snapshot)
+	go testClient.Run(done, routines, nil)
+	<-routines
+
+	testClient.Refresh()
+	select {
+	case <-routines:
+	case <-time.After(5 * time.Second):
+		t.Fatal("refresh did not trigger a fetch")
+	}
+	assert.Equal(t, time.Hour, testClient.Interval())
+}
+
+func TestPool(t *testing.T) {
+	var servers []string
+	for i := 0; i < 2; i++ {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "goroutine 1 [running]:\nmain.main()\n\t/home/user/main.go:10 +0x1d\n")
+		}))
+		defer server.Close()
+		servers = append(servers, server.URL)
+	}
+
+	pool, err := client.NewPool(servers, client.Options{Interval: time.Hour})
+	assert.Nil(t, err)
+	assert.Equal(t, time.Hour, pool.Interval())
+
+	done := make(chan error)
+	routines := make(chan model.Snapshot)
+	go pool.Run(done, routines, nil)
+
+	seen := map[string]bool{}
+	for len(seen) < 2 {
+		select {
+		case r := <-routines:
+			assert.Len(t, r.Goroutines, 1)
+			assert.Equal(t, r.Target, r.Goroutines[0].Target)
+			seen[r.Target] = true
+		case err := <-done:
+			log.Fatal(err)
+		}
+	}
+	assert.True(t, seen[servers[0]])
+	assert.True(t, seen[servers[1]])
+
+	_, err = client.NewPool([]string{servers[0], "ftp://invalid"}, client.Options{})
+	assert.NotNil(t, err)
+}
+
+func TestPoolSync(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "goroutine 1 [running]:\nmain.main()\n\t/home/user/main.go:10 +0x1d\n")
+	}))
+	defer server.Close()
+
+	pool, err := client.NewPool([]string{server.URL}, client.Options{Interval: time.Hour})
+	assert.Nil(t, err)
+
+	done := make(chan error)
+	routines := make(chan model.Snapshot)
+	status := make(chan model.FetchStatus)
+	go pool.Run(done, routines, status)
+	pool.Sync(map[string]string{"discovered": server.URL})
+
+	seen := map[string]bool{}
+	for len(seen) < 2 {
+		select {
+		case r := <-routines:
+			seen[r.Target] = true
+		case <-status:
+		case err := <-done:
+			log.Fatal(err)
+		}
+	}
+	assert.True(t, seen[server.URL])
+	assert.True(t, seen["discovered"])
+
+	pool.Sync(map[string]string{})
+	for {
+		select {
+		case <-routines:
+		case s := <-status:
+			if s.Gone {
+				assert.Equal(t, "discovered", s.Target)
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("removed target was not reported as gone")
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/debug/pprof/goroutine" {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		if requests <= 2 {
+			http.Error(w, "upstream unavailable", http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, "goroutine 1 [running]:\nmain.main()\n\t/home/user/main.go:10 +0x1d\n")
+	}))
+	defer server.Close()
+
+	testClient, err := client.New(server.URL, client.Options{Interval: time.Millisecond, MaxRetries: 5})
+	assert.Nil(t, err)
+	done := make(chan error)
+	routines := make(chan model.Snapshot)
+	status := make(chan model.FetchStatus)
+	go testClient.Run(done, routines, status)
+
+	for _, failures := range []int{1, 2} {
+		s := <-status
+		assert.Equal(t, model.Retrying, s.State)
+		assert.Equal(t, failures, s.Failures)
+		assert.NotNil(t, s.Err)
+	}
+	select {
+	case r := <-routines:
+		assert.Len(t, r.Goroutines, 1)
+	case err := <-done:
+		log.Fatal(err)
+	}
+	s := <-status
+	assert.Equal(t, model.Connected, s.State)
+	assert.Equal(t, 0, s.Failures)
+	testClient.Stop()
+}
+
+func TestGiveUp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream unavailable", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	// A target which was never reachable terminates roumon
+	testClient, err := client.New(server.URL, client.Options{Interval: time.Millisecond, MaxRetries: 2})
+	assert.Nil(t, err)
+	done := make(chan error)
+	go testClient.Run(done, make(chan model.Snapshot), nil)
+	assert.NotNil(t, <-done)
+
+	// Discovered targets give up and wait for a retry
+	pool, err := client.NewPool(nil, client.Options{Interval: time.Millisecond, MaxRetries: 1})
+	assert.Nil(t, err)
+	status := make(chan model.FetchStatus)
+	go pool.Run(done, make(chan model.Snapshot), status)
+	pool.Sync(map[string]string{"discovered": server.URL})
+	assert.Equal(t, model.Retrying, (<-status).State)
+	assert.Equal(t, model.GaveUp, (<-status).State)
+
+	pool.Retry()
+	s := <-status
+	assert.Equal(t, model.Retrying, s.State)
+	assert.Equal(t, 1, s.Failures)
+	pool.Sync(map[string]string{})
+}
+
+func TestSSH(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	dir := t.TempDir()
+
+	// The user key
+	_, userKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	userPem, err := ssh.MarshalPrivateKey(userKey, "")
+	assert.Nil(t, err)
+	keyFile := filepath.Join(dir, "id_ed25519")
+	assert.Nil(t, os.WriteFile(keyFile, pem.EncodeToMemory(userPem), 0600))
+	userSigner, err := ssh.NewSignerFromKey(userKey)
+	assert.Nil(t, err)
+
+	// SSH server which forwards TCP connections
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	hostSigner, err := ssh.NewSignerFromKey(hostKey)
+	assert.Nil(t, err)
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "ops" && bytes.Equal(key.Marshal(), userSigner.PublicKey().Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown key")
+		},
+	}
+	config.AddHostKey(hostSigner)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	go serveSSH(listener, config)
+
+	knownHosts := filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(listener.Addr().String())}, hostSigner.PublicKey())
+	assert.Nil(t, os.WriteFile(knownHosts, []byte(line+"\n"), 0600))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "goroutine 1 [running]:\nmain.main()\n\t/home/user/main.go:10 +0x1d\n")
+	}))
+	defer server.Close()
+
+	opts := client.Options{SSH: "ops@" + listener.Addr().String(), SSHKey: keyFile, SSHKnownHosts: knownHosts}
+	testClient, err := client.New(server.URL, opts)
+	assert.Nil(t, err)
+	done := make(chan error)
+	routines := make(chan model.Snapshot)
+	go testClient.Run(done, routines, nil)
+	select {
+	case r := <-routines:
+		assert.Len(t, r.Goroutines, 1)
+	case err := <-done:
+		log.Fatal(err)
+	}
+	testClient.Stop()
+
+	_, err = client.New(server.URL, client.Options{SSH: "ops@other:22", SSHKey: filepath.Join(dir, "missing")})
+	assert.NotNil(t, err)
+}
+
+func serveSSH(listener net.Listener, config *ssh.ServerConfig) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			_, chans, reqs, err := ssh.NewServerConn(conn, config)
+			if err != nil {
+				return
+			}
+			go ssh.DiscardRequests(reqs)
+			for newChannel := range chans {
+				var forward struct {
+					DestAddr string
+					DestPort uint32
+					OrigAddr string
+					OrigPort uint32
+				}
+				if newChannel.ChannelType() != "direct-tcpip" || ssh.Unmarshal(newChannel.ExtraData(), &forward) != nil {
+					_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported")
+					continue
+				}
+				target, err := net.Dial("tcp", net.JoinHostPort(forward.DestAddr, strconv.Itoa(int(forward.DestPort))))
+				if err != nil {
+					_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+					continue
+				}
+				channel, requests, err := newChannel.Accept()
+				if err != nil {
+					continue
+				}
+				go ssh.DiscardRequests(requests)
+				go func() {
+					_, _ = io.Copy(channel, target)
+					_ = channel.CloseWrite()
+				}()
+				go func() {
+					_, _ = io.Copy(target, channel)
+					_ = target.Close()
+				}()
+			}
+		}()
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/pprof/goroutine", pprof.Handler("goroutine"))
+	mux.Handle("/debug/pprof/heap", pprof.Handler("heap"))
+	mux.Handle("/debug/pprof/mutex", pprof.Handler("mutex"))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	testClient, err := client.New(server.URL, client.Options{Profiles: []string{"heap", "mutex", "block"}})
+	assert.Nil(t, err)
+	done := make(chan error)
+	routines := make(chan model.Snapshot)
+	go testClient.Run(done, routines, nil)
+	select {
+	case r := <-routines:
+		assert.NotEmpty(t, r.Goroutines)
+		// The block profile is not served
+		assert.Len(t, r.Profiles, 2)
+		assert.Equal(t, "heap", r.Profiles[0].Name)
+		assert.Contains(t, r.Profiles[0].SampleTypes, model.SampleType{Type: "inuse_space", Unit: "bytes"})
+		assert.Equal(t, "mutex", r.Profiles[1].Name)
+	case err := <-done:
+		log.Fatal(err)
+	}
+	testClient.Stop()
+
+	_, err = client.New(server.URL, client.Options{Profiles: []string{"cpu"}})
+	assert.NotNil(t, err)
+}
+
+func TestExpvar(t *testing.T) {
+	expvar.NewInt("requests").Set(42)
+	expvar.NewMap("cache").Add("hits", 7)
+	mux := http.NewServeMux()
+	mux.Handle("/debug/pprof/goroutine", pprof.Handler("goroutine"))
+	mux.Handle("/debug/vars", expvar.Handler())
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	testClient, err := client.New(server.URL, client.Options{Expvar: true})
+	assert.Nil(t, err)
+	done := make(chan error)
+	routines := make(chan model.Snapshot)
+	go testClient.Run(done, routines, nil)
+	select {
+	case r := <-routines:
+		assert.NotEmpty(t, r.Goroutines)
+		assert.Equal(t, 42.0, r.Vars["requests"])
+		assert.Equal(t, 7.0, r.Vars["cache.hits"])
+		assert.Greater(t, r.Vars["memstats.HeapInuse"], 0.0)
+		// Arrays and strings are skipped
+		assert.NotContains(t, r.Vars, "memstats.PauseNs")
+		assert.NotContains(t, r.Vars, "cmdline")
+	case err := <-done:
+		log.Fatal(err)
+	}
+	testClient.Stop()
+}
+
+func TestCompressedResponse(t *testing.T) {
+	const dump = "goroutine 1 [running]:\nmain.main()\n\t/home/user/main.go:10 +0x1d\n"
+	encoders := map[string]func(io.Writer) io.WriteCloser{
+		"gzip":    func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"deflate": func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+		"raw deflate": func(w io.Writer) io.WriteCloser {
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		},
+	}
+	for name, encoder := range encoders {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "gzip, deflate", r.Header.Get("Accept-Encoding"))
+			w.Header().Set("Content-Encoding", strings.TrimPrefix(name, "raw "))
+			enc := encoder(w)
+			fmt.Fprint(enc, dump)
+			assert.Nil(t, enc.Close())
+		}))
+
+		testClient, err := client.New(server.URL, client.Options{})
+		assert.Nil(t, err)
+		done := make(chan error)
+		routines := make(chan model.Snapshot)
+		go testClient.Run(done, routines, nil)
+		select {
+		case r := <-routines:
+			assert.Len(t, r.Goroutines, 1, name)
+		case err := <-done:
+			log.Fatal(err)
+		}
+		testClient.Stop()
+		server.Close()
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	testClient, err := client.New(server.URL, client.Options{FetchTimeout: 20 * time.Millisecond, Interval: time.Hour})
+	assert.Nil(t, err)
+	done := make(chan error)
+	status := make(chan model.FetchStatus)
+	go testClient.Run(done, make(chan model.Snapshot), status)
+	s := <-status
+	assert.Equal(t, model.Retrying, s.State)
+	assert.True(t, s.Timeout)
+	assert.Contains(t, s.Err.Error(), "timed out")
+}
+
+func TestStopCancelsFetch(t *testing.T) {
+	started := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/debug/pprof/goroutine" {
+			http.NotFound(w, r)
+			return
+		}
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	testClient, err := client.New(server.URL, client.Options{})
+	assert.Nil(t, err)
+	returned := make(chan struct{})
+	go func() {
+		testClient.Run(make(chan error), make(chan model.Snapshot), make(chan model.FetchStatus))
+		close(returned)
+	}()
+	<-started
+	testClient.Stop()
+	select {
+	case <-returned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stop did not cancel the fetch")
+	}
+}
+
+func TestHealthProbe(t *testing.T) {
+	var down atomic.Bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	mux.HandleFunc("/debug/pprof/goroutine", func(w http.ResponseWriter, r *http.Request) {})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	testClient, err := client.New(server.URL, client.Options{Interval: time.Hour, HealthInterval: 10 * time.Millisecond})
+	assert.Nil(t, err)
+	done := make(chan error)
+	routines := make(chan model.Snapshot, 1)
+	status := make(chan model.FetchStatus)
+	go testClient.Run(done, routines, status)
+	defer testClient.Stop()
+
+	probe := func() model.FetchStatus {
+		for s := range status {
+			if s.Probe {
+				return s
+			}
+		}
+		return model.FetchStatus{}
+	}
+	up := probe()
+	assert.Nil(t, up.Err)
+	assert.Equal(t, up.Time, up.Since)
+	assert.Equal(t, up.Since, probe().Since)
+
+	down.Store(true)
+	for s := probe(); s.Err == nil; s = probe() {
+	}
+	failed := probe()
+	assert.NotNil(t, failed.Err)
+	assert.True(t, failed.Since.After(up.Since))
+	assert.True(t, failed.Time.After(failed.Since))
+}
+
+func TestTruncatedDump(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/debug/pprof/goroutine" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "goroutine 1 [running]:\nmain.main()\n\t/home/user/main.go:10 +0x1d\n\n")
+		if requests.Add(1) == 1 {
+			// A line which exceeds the limit of the parser cuts the dump off
+			fmt.Fprintf(w, "goroutine 2 [running]:\n%s\n", strings.Repeat("x", 5*1024*1024))
+		}
+	}))
+	defer server.Close()
+
+	testClient, err := client.New(server.URL, client.Options{Interval: time.Millisecond})
+	assert.Nil(t, err)
+	done := make(chan error)
+	routines := make(chan model.Snapshot)
+	status := make(chan model.FetchStatus)
+	go testClient.Run(done, routines, status)
+	defer testClient.Stop()
+
+	s := <-status
+	assert.Equal(t, model.Retrying, s.State)
+	assert.Contains(t, s.Err.Error(), "failed to read dump")
+	select {
+	case r := <-routines:
+		assert.Len(t, r.Goroutines, 1)
+	case err := <-done:
+		log.Fatal(err)
+	}
+}
+
+func TestDetectAfterFailure(t *testing.T) {
+	var up atomic.Bool
+	handler := roumonhttp.Handler()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			http.Error(w, "starting", http.StatusServiceUnavailable)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	testClient, err := client.New(server.URL, client.Options{Interval: time.Millisecond})
+	assert.Nil(t, err)
+	done := make(chan error)
+	routines := make(chan model.Snapshot, 1)
+	status := make(chan model.FetchStatus)
+	go testClient.Run(done, routines, status)
+	defer testClient.Stop()
+
+	assert.Equal(t, model.Retrying, (<-status).State)
+	up.Store(true)
+	for s := range status {
+		if s.State == model.Connected {
+			break
+		}
+	}
+	// Only the roumon endpoint reports the build
+	assert.NotNil(t, (<-routines).Build)
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// acceptEncoding is sent with every request. Large dumps of busy services compress about ten times.
+const acceptEncoding = "gzip, deflate"
+
+// decodedBody closes the decoder and the underlying body
+type decodedBody struct {
+	io.Reader
+	decoder io.Closer
+	body    io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	errDecoder := b.decoder.Close()
+	if err := b.body.Close(); err != nil {
+		return err
+	}
+	return errDecoder
+}
+
+// decodeBody replaces the body of the response with the decompressed body
+func decodeBody(resp *http.Response) error {
+	var decoder io.ReadCloser
+	switch encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+		return nil
+	case "gzip", "x-gzip":
+		reader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return fmt.Errorf("invalid gzip response. Err: %s", err.Error())
+		}
+		decoder = reader
+	case "deflate":
+		// Deflate should be zlib wrapped, but some servers send raw deflate data
+		buffered := bufio.NewReader(resp.Body)
+		header, err := buffered.Peek(2)
+		if err == nil && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 && header[0]&0x0f == 8 {
+			decoder, err = zlib.NewReader(buffered)
+			if err != nil {
+				return fmt.Errorf("invalid deflate response. Err: %s", err.Error())
+			}
+		} else {
+			decoder = flate.NewReader(buffered)
+		}
+	default:
+		return fmt.Errorf("unsupported content encoding %s", encoding)
+	}
+	resp.Body = &decodedBody{Reader: decoder, decoder: decoder, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	return nil
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// fetchVars fetches the numeric expvars of the target
+func (client *Client) fetchVars() (map[string]float64, error) {
+	resp, err := client.get(client.target.varsURL())
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Error while closing response body: %s", err.Error())
+		}
+	}()
+	var raw map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode expvars. Err: %s", err.Error())
+	}
+	vars := make(map[string]float64)
+	flattenVars("", raw, vars)
+	return vars, nil
+}
+
+// flattenVars adds all numbers and booleans of the objects to vars. Arrays, like the GC pauses, are skipped.
+func flattenVars(prefix string, raw map[string]interface{}, vars map[string]float64) {
+	for key, value := range raw {
+		name := prefix + key
+		switch v := value.(type) {
+		case float64:
+			vars[name] = v
+		case bool:
+			if v {
+				vars[name] = 1
+			} else {
+				vars[name] = 0
+			}
+		case map[string]interface{}:
+			flattenVars(name+".", v, vars)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// probe checks every HealthInterval if the target is up. The probes are not rate limited and are independent of
+// the fetches of the profiles, so a slow or rarely polled target is still shown as up or down quickly.
+func (client *Client) probe(ctx context.Context, statusUpdate chan<- model.FetchStatus) {
+	ticker := time.NewTicker(client.opts.HealthInterval)
+	defer ticker.Stop()
+	var since time.Time
+	up := false
+	for {
+		start := time.Now()
+		err := client.ping(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if since.IsZero() || up != (err == nil) {
+			up = err == nil
+			since = start
+			if !up {
+				log.Printf("%s: Health probe failed: %s", client.name, err.Error())
+			}
+		}
+		status := model.FetchStatus{
+			Target:  client.name,
+			Time:    start,
+			Latency: time.Since(start),
+			Err:     err,
+			Probe:   true,
+			Since:   since,
+		}
+		select {
+		case statusUpdate <- status:
+		case <-ctx.Done():
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// ping fetches the pprof index of the target which is cheap to serve
+func (client *Client) ping(ctx context.Context) error {
+	timeout := client.opts.HealthInterval
+	if client.opts.FetchTimeout > 0 {
+		timeout = min(timeout, client.opts.FetchTimeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := client.newRequest(ctx, client.target.indexURL())
+	if err != nil {
+		return err
+	}
+	resp, err := client.c.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if err := resp.Body.Close(); err != nil {
+		log.Printf("Error while closing response body: %s", err.Error())
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// Pool fetches the snapshots of multiple targets concurrently. Every target has its own client.
+// Targets can be added and removed while the pool is running.
+type Pool struct {
+	opts     Options
+	limiter  *limiter // Shared by all clients
+	mu       sync.Mutex
+	clients  map[string]*Client // Clients by name of their target
+	static   map[string]bool    // Targets passed to NewPool which are never removed by Sync
+	interval time.Duration
+	running  bool
+	ctx      context.Context // Canceled by Stop. Ends the updates of removed targets which are not received
+	cancel   context.CancelFunc
+
+	terminate     chan<- error
+	routineUpdate chan<- model.Snapshot
+	statusUpdate  chan<- model.FetchStatus
+}
+
+// NewPool creates a client for each target. All clients share the same options.
+func NewPool(targets []string, opts Options) (*Pool, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := &Pool{
+		ctx:      ctx,
+		cancel:   cancel,
+		opts:     opts,
+		limiter:  newLimiter(opts),
+		clients:  make(map[string]*Client, len(targets)),
+		static:   make(map[string]bool, len(targets)),
+		interval: opts.Interval,
+	}
+	if pool.interval <= 0 {
+		pool.interval = time.Second
+	}
+	for _, target := range targets {
+		c, err := New(target, pool.clientOptions())
+		if err != nil {
+			return nil, err
+		}
+		c.limiter = pool.limiter
+		pool.clients[c.name] = c
+		pool.static[c.name] = true
+	}
+	return pool, nil
+}
+
+func (pool *Pool) clientOptions() Options {
+	opts := pool.opts
+	opts.Interval = pool.interval
+	return opts
+}
+
+// Interval returns the time between two fetches
+func (pool *Pool) Interval() time.Duration {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	return pool.interval
+}
+
+// SetInterval changes the time between two fetches of all clients
+func (pool *Pool) SetInterval(interval time.Duration) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	pool.interval = interval
+	for _, c := range pool.clients {
+		c.SetInterval(interval)
+	}
+}
+
+// Retry all targets immediately. Resumes polling of targets which gave up.
+func (pool *Pool) Retry() {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	for _, c := range pool.clients {
+		c.Retry()
+	}
+}
+
+// Refresh fetches the snapshots of all targets immediately
+func (pool *Pool) Refresh() {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	for _, c := range pool.clients {
+		c.Refresh()
+	}
+}
+
+// Stop all clients and cancel their requests in flight
+func (pool *Pool) Stop() {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	pool.cancel()
+	for _, c := range pool.clients {
+		c.Stop()
+	}
+}
+
+// Run starts all clients. Clients added later with Sync are started right away. See Client.Run.
+func (pool *Pool) Run(terminate chan<- error, routineUpdate chan<- model.Snapshot, statusUpdate chan<- model.FetchStatus) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	pool.terminate = terminate
+	pool.routineUpdate = routineUpdate
+	pool.statusUpdate = statusUpdate
+	pool.running = true
+	for _, c := range pool.clients {
+		go c.Run(terminate, routineUpdate, statusUpdate)
+	}
+}
+
+// Sync the discovered targets with the given addresses by name. Clients of discovered targets which are not part
+// of the map anymore are stopped. Discovered targets do not terminate roumon if they are not reachable. Targets
+// passed to NewPool are kept.
+func (pool *Pool) Sync(targets map[string]string) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	for name, c := range pool.clients {
+		if pool.static[name] {
+			continue
+		}
+		if address, ok := targets[name]; ok && address == c.target.Raw {
+			continue
+		}
+		log.Printf("Remove target %s", name)
+		c.Stop()
+		delete(pool.clients, name)
+		if pool.running && pool.statusUpdate != nil {
+			go func(name string, c *Client) {
+				// All updates of the client are received before it is reported as gone
+				<-c.done
+				select {
+				case pool.statusUpdate <- model.FetchStatus{Target: name, Time: time.Now(), Gone: true}:
+				case <-pool.ctx.Done():
+				}
+			}(name, c)
+		}
+	}
+
+	for name, address := range targets {
+		if _, ok := pool.clients[name]; ok {
+			continue
+		}
+		c, err := New(address, pool.clientOptions())
+		if err != nil {
+			log.Printf("Failed to add target %s: %s", name, err.Error())
+			continue
+		}
+		log.Printf("Add target %s (%s)", name, address)
+		c.name = name
+		c.failFast = false
+		c.limiter = pool.limiter
+		pool.clients[name] = c
+		if pool.running {
+			go c.Run(pool.terminate, pool.routineUpdate, pool.statusUpdate)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/google/pprof/profile"
+)
+
+// Profiles which can be fetched alongside the goroutines
+var Profiles = []string{"heap", "block", "mutex"}
+
+func validateProfiles(names []string) error {
+	for _, name := range names {
+		known := false
+		for _, p := range Profiles {
+			known = known || p == name
+		}
+		if !known {
+			return fmt.Errorf("unknown profile %s. Must be one of %s", name, strings.Join(Profiles, ", "))
+		}
+	}
+	return nil
+}
+
+// fetchProfiles fetches all configured profiles. Profiles which fail are logged and skipped.
+func (client *Client) fetchProfiles() []model.Profile {
+	var profiles []model.Profile
+	for _, name := range client.opts.Profiles {
+		p, err := client.fetchProfile(name)
+		if err != nil {
+			log.Printf("%s: Failed to fetch %s profile: %s", client.name, name, err.Error())
+			continue
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles
+}
+
+func (client *Client) fetchProfile(name string) (model.Profile, error) {
+	resp, err := client.get(client.target.profileURL(name))
+	if err != nil {
+		return model.Profile{}, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Error while closing response body: %s", err.Error())
+		}
+	}()
+	p, err := profile.Parse(resp.Body)
+	if err != nil {
+		return model.Profile{}, fmt.Errorf("failed to parse profile. Err: %s", err.Error())
+	}
+	return fromProfile(name, p), nil
+}
+
+func fromProfile(name string, p *profile.Profile) model.Profile {
+	result := model.Profile{
+		Name:        name,
+		SampleTypes: make([]model.SampleType, len(p.SampleType)),
+		Samples:     make([]model.Sample, len(p.Sample)),
+	}
+	for i, t := range p.SampleType {
+		result.SampleTypes[i] = model.SampleType{Type: t.Type, Unit: t.Unit}
+	}
+	for i, s := range p.Sample {
+		sample := model.Sample{Values: s.Value}
+		for _, location := range s.Location {
+			// The last line is the caller into which the other lines were inlined
+			for idx, line := range location.Line {
+				if line.Function == nil {
+					continue
+				}
+				frame := model.NewStackFrame(line.Function.Name, line.Function.Filename, int32(line.Line), nil)
+				frame.Inlined = idx < len(location.Line)-1
+				sample.Stack = append(sample.Stack, frame)
+			}
+		}
+		result.Samples[i] = sample
+	}
+	return result
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// proxyFunc returns the proxy function of the transport. Without an explicit proxy the HTTP_PROXY, HTTPS_PROXY
+// and NO_PROXY environment variables are used.
+func proxyFunc(opts Options) (func(*http.Request) (*url.URL, error), error) {
+	if len(opts.Proxy) == 0 {
+		return http.ProxyFromEnvironment, nil
+	}
+	proxy, err := url.Parse(opts.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %s. Err: %s", opts.Proxy, err.Error())
+	}
+	switch proxy.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("proxy scheme must be http, https, socks5 or socks5h, but got: %s", opts.Proxy)
+	}
+	if len(proxy.Host) == 0 {
+		return nil, fmt.Errorf("proxy host missing: %s", opts.Proxy)
+	}
+	return http.ProxyURL(proxy), nil
+}
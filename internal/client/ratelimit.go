@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// limiter spaces the requests to the same host and caps the number of requests in flight. It is shared by all
+// clients of a pool.
+type limiter struct {
+	spacing time.Duration // Minimum time between two requests to the same host. Zero is unlimited
+	slots   chan struct{} // Semaphore of the requests in flight. Nil is unlimited
+	mu      sync.Mutex
+	next    map[string]time.Time // Earliest start of the next request by host
+}
+
+func newLimiter(opts Options) *limiter {
+	l := &limiter{next: make(map[string]time.Time)}
+	if opts.RateLimit > 0 {
+		l.spacing = time.Duration(float64(time.Second) / opts.RateLimit)
+	}
+	if opts.MaxConcurrent > 0 {
+		l.slots = make(chan struct{}, opts.MaxConcurrent)
+	}
+	return l
+}
+
+// acquire waits until a request to the host may start. The returned function has to be called once the request
+// finished.
+func (l *limiter) acquire(ctx context.Context, host string) (release func(), err error) {
+	release = func() {}
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+			release = func() { <-l.slots }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if l.spacing <= 0 {
+		return release, nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	start := now
+	if next := l.next[host]; next.After(now) {
+		start = next
+	}
+	l.next[host] = start.Add(l.spacing)
+	l.mu.Unlock()
+	if !start.After(now) {
+		return release, nil
+	}
+	timer := time.NewTimer(start.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return release, nil
+	case <-ctx.Done():
+		release()
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterSpacesRequestsByHost(t *testing.T) {
+	l := newLimiter(Options{RateLimit: 20})
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := l.acquire(context.Background(), "a:6060")
+		assert.Nil(t, err)
+		release()
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	// Other hosts are not delayed
+	start = time.Now()
+	release, err := l.acquire(context.Background(), "b:6060")
+	assert.Nil(t, err)
+	release()
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestLimiterCapsConcurrentRequests(t *testing.T) {
+	l := newLimiter(Options{MaxConcurrent: 1})
+	release, err := l.acquire(context.Background(), "a:6060")
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(ctx, "b:6060")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	release()
+	release, err = l.acquire(context.Background(), "b:6060")
+	assert.Nil(t, err)
+	release()
+}
+
+func TestBodyHoldsSlot(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "goroutine 1 [running]:\nmain.main()\n\t/home/user/main.go:10 +0x1d\n")
+	}))
+	defer server.Close()
+
+	c, err := New(server.URL, Options{MaxConcurrent: 1})
+	assert.Nil(t, err)
+	resp, err := c.get(server.URL)
+	assert.Nil(t, err)
+	assert.Len(t, c.limiter.slots, 1, "the slot is held while the body is read")
+
+	assert.Nil(t, resp.Body.Close())
+	assert.Len(t, c.limiter.slots, 0)
+	assert.Nil(t, resp.Body.Close())
+	assert.Len(t, c.limiter.slots, 0, "closing twice releases once")
+}
@@ -0,0 +1,193 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// defaultSSHKeys are tried if no key is configured
+var defaultSSHKeys = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+var (
+	tunnelsMu sync.Mutex
+	tunnels   = make(map[string]*sshTunnel) // Tunnels by destination. Shared by all clients
+)
+
+// sshTunnel dials the targets from a SSH server. The connection is established on first use and after it broke.
+type sshTunnel struct {
+	dest   string // host:port of the SSH server
+	config *ssh.ClientConfig
+
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+// tunnel returns the tunnel to the SSH server of the options. Returns nil if no SSH server is set.
+func tunnel(opts Options) (*sshTunnel, error) {
+	if len(opts.SSH) == 0 {
+		return nil, nil
+	}
+	username, dest := parseSSHDestination(opts.SSH)
+	key := username + "@" + dest
+	tunnelsMu.Lock()
+	defer tunnelsMu.Unlock()
+	if t, ok := tunnels[key]; ok {
+		return t, nil
+	}
+
+	auth, err := sshAuth(opts.SSHKey)
+	if err != nil {
+		return nil, err
+	}
+	knownHosts := opts.SSHKnownHosts
+	if len(knownHosts) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find known hosts. Err: %s", err.Error())
+		}
+		knownHosts = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	hostKeyCallback, err := knownhosts.New(knownHosts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read known hosts. Err: %s", err.Error())
+	}
+
+	t := &sshTunnel{
+		dest: dest,
+		config: &ssh.ClientConfig{
+			User:            username,
+			Auth:            auth,
+			HostKeyCallback: hostKeyCallback,
+		},
+	}
+	tunnels[key] = t
+	return t, nil
+}
+
+// parseSSHDestination splits user@host[:port]. Defaults to the current user and port 22.
+func parseSSHDestination(raw string) (username, dest string) {
+	username, dest, found := strings.Cut(raw, "@")
+	if !found {
+		dest = raw
+		username = ""
+		if current, err := user.Current(); err == nil {
+			username = current.Username
+		}
+	}
+	if _, _, err := net.SplitHostPort(dest); err != nil {
+		dest = net.JoinHostPort(dest, "22")
+	}
+	return username, dest
+}
+
+// sshAuth uses the SSH agent and the key file. Without key file the default keys of the user are used.
+func sshAuth(keyFile string) ([]ssh.AuthMethod, error) {
+	var signers []ssh.Signer
+	if socket := os.Getenv("SSH_AUTH_SOCK"); len(socket) > 0 {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			agentSigners, err := agent.NewClient(conn).Signers()
+			if err != nil {
+				log.Printf("Failed to get keys of SSH agent: %s", err.Error())
+			}
+			signers = append(signers, agentSigners...)
+		} else {
+			log.Printf("Failed to connect to SSH agent: %s", err.Error())
+		}
+	}
+
+	if len(keyFile) > 0 {
+		signer, err := readSSHKey(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, signer)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		for _, name := range defaultSSHKeys {
+			signer, err := readSSHKey(filepath.Join(home, ".ssh", name))
+			if err != nil {
+				if !os.IsNotExist(err) {
+					log.Printf("Skip SSH key %s: %s", name, err.Error())
+				}
+				continue
+			}
+			signers = append(signers, signer)
+		}
+	}
+
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("no SSH key found. Start a SSH agent or set the key file")
+	}
+	return []ssh.AuthMethod{ssh.PublicKeys(signers...)}, nil
+}
+
+func readSSHKey(path string) (ssh.Signer, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(pem)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH key %s. Err: %s", path, err.Error())
+	}
+	return signer, nil
+}
+
+// DialContext connects to the address from the SSH server
+func (t *sshTunnel) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, err := t.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := client.DialContext(ctx, network, addr)
+	if err != nil {
+		// The SSH connection might be broken. Reconnect with the next dial.
+		t.reset(client)
+		return nil, fmt.Errorf("failed to dial %s via SSH server %s. Err: %s", addr, t.dest, err.Error())
+	}
+	return conn, nil
+}
+
+func (t *sshTunnel) connect(ctx context.Context) (*ssh.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client != nil {
+		return t.client, nil
+	}
+
+	log.Printf("Connect to SSH server %s", t.dest)
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", t.dest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SSH server %s. Err: %s", t.dest, err.Error())
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, t.dest, t.config)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("SSH handshake with %s failed. Err: %s", t.dest, err.Error())
+	}
+	t.client = ssh.NewClient(c, chans, reqs)
+	return t.client, nil
+}
+
+func (t *sshTunnel) reset(client *ssh.Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client != client {
+		return
+	}
+	if err := client.Close(); err != nil {
+		log.Printf("Error while closing SSH connection: %s", err.Error())
+	}
+	t.client = nil
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/becheran/roumon/roumonhttp"
+)
+
+const goroutinePath = "/debug/pprof/goroutine"
+
+// Target describes where the goroutine profile is fetched from
+type Target struct {
+	Raw    string // Target as specified by the user
+	Socket string // Path of the unix domain socket. Empty for TCP targets
+	Base   string // Scheme and host of the server, for example http://localhost:6060
+	Prefix string // Path prefix in front of /debug/pprof
+	Path   string // Path of the goroutine profile
+}
+
+// ParseTarget parses a target of the forms
+//
+//	host:port
+//	http://host:port[/prefix/debug/pprof/goroutine]
+//	https://host:port[/prefix/debug/pprof/goroutine]
+//	unix:///path/to/socket[:/prefix/debug/pprof/goroutine]
+func ParseTarget(target string) (Target, error) {
+	if strings.HasPrefix(target, "unix://") {
+		socket := target[len("unix://"):]
+		path := goroutinePath
+		if sep := strings.Index(socket, ":/"); sep >= 0 {
+			path = socket[sep+1:]
+			socket = socket[:sep]
+		}
+		if len(socket) == 0 {
+			return Target{}, fmt.Errorf("missing socket path in target %s", target)
+		}
+		return newTarget(target, socket, "http://unix", path), nil
+	}
+
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return Target{}, fmt.Errorf("invalid target %s. Err: %s", target, err.Error())
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return Target{}, fmt.Errorf("unsupported scheme %s of target %s", u.Scheme, target)
+	}
+	if len(u.Host) == 0 {
+		return Target{}, fmt.Errorf("missing host in target %s", target)
+	}
+	path := u.Path
+	if path == "" || path == "/" {
+		path = goroutinePath
+	}
+	return newTarget(target, "", u.Scheme+"://"+u.Host, path), nil
+}
+
+func newTarget(raw, socket, base, path string) Target {
+	return Target{
+		Raw:    raw,
+		Socket: socket,
+		Base:   base,
+		Prefix: strings.TrimSuffix(path, goroutinePath),
+		Path:   path,
+	}
+}
+
+// String returns the target as specified by the user
+func (t Target) String() string {
+	return t.Raw
+}
+
+// host identifies the server of the target to limit the requests to it
+func (t Target) host() string {
+	if len(t.Socket) > 0 {
+		return t.Socket
+	}
+	return t.Base
+}
+
+func (t Target) goroutineURL() string {
+	return t.Base + t.Path + "?debug=2"
+}
+
+func (t Target) indexURL() string {
+	return t.Base + strings.TrimSuffix(t.Path, "goroutine")
+}
+
+func (t Target) profileURL(name string) string {
+	return t.Base + strings.TrimSuffix(t.Path, "goroutine") + name
+}
+
+func (t Target) varsURL() string {
+	return t.Base + t.Prefix + "/debug/vars"
+}
+
+func (t Target) endpointURL() string {
+	return t.Base + t.Prefix + roumonhttp.Path
+}
@@ -0,0 +1,110 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"time"
+
+	"github.com/becheran/roumon/internal/client"
+	"github.com/becheran/roumon/internal/model"
+	"github.com/becheran/roumon/roumongrpc"
+	"google.golang.org/grpc/peer"
+)
+
+// Collector receives the snapshots which are pushed by the monitored apps. Every app is shown as a target
+// until it closes its stream.
+type Collector struct {
+	routineUpdate    chan<- model.Snapshot
+	statusUpdate     chan<- model.FetchStatus
+	keepDisconnected bool
+	ctx              context.Context // Canceled by Stop. Ends the updates which are not received anymore
+	cancel           context.CancelFunc
+}
+
+// New creates a collector which forwards the received snapshots. The status channel is optional.
+func New(routineUpdate chan<- model.Snapshot, statusUpdate chan<- model.FetchStatus) *Collector {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Collector{
+		routineUpdate: routineUpdate,
+		statusUpdate:  statusUpdate,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+}
+
+// Stop the streams without waiting for the updates to be received. Call it before the gRPC server is stopped,
+// which waits for the streams to end.
+func (c *Collector) Stop() {
+	c.cancel()
+}
+
+// SetKeepDisconnected keeps the apps which closed their stream as disconnected targets instead of removing them.
+// An app which reconnects with the same source continues its target.
+func (c *Collector) SetKeepDisconnected(keep bool) {
+	c.keepDisconnected = keep
+}
+
+// StreamSnapshots receives the snapshots of one app
+func (c *Collector) StreamSnapshots(stream roumongrpc.SnapshotStream) error {
+	ctx := stream.Context()
+	var source string
+	received := 0
+	defer func() {
+		if len(source) > 0 && c.statusUpdate != nil {
+			log.Printf("Stream of %s closed", source)
+			status := model.FetchStatus{Target: source, Time: time.Now(), Gone: true}
+			if c.keepDisconnected {
+				status = model.FetchStatus{Target: source, Time: time.Now(), State: model.Disconnected}
+			}
+			// The stream is closed already, so only Stop ends a send which is not received
+			select {
+			case c.statusUpdate <- status:
+			case <-c.ctx.Done():
+			}
+		}
+	}()
+
+	for {
+		pushed, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(&roumongrpc.Ack{Received: received})
+		}
+		if err != nil {
+			return err
+		}
+		received++
+
+		if len(source) == 0 {
+			source = pushed.Source
+			if len(source) == 0 {
+				if p, ok := peer.FromContext(ctx); ok {
+					source = p.Addr.String()
+				}
+			}
+			log.Printf("Receive snapshots of %s", source)
+		}
+
+		snapshot := client.FromDump(pushed.Dump)
+		snapshot.SetTarget(source)
+		select {
+		case c.routineUpdate <- snapshot:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.ctx.Done():
+			return c.ctx.Err()
+		}
+		if c.statusUpdate != nil {
+			// The interval is chosen by the app
+			status := model.FetchStatus{Target: source, Time: time.Now(), State: model.Connected}
+			select {
+			case c.statusUpdate <- status:
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-c.ctx.Done():
+				return c.ctx.Err()
+			}
+		}
+	}
+}
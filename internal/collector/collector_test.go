@@ -0,0 +1,120 @@
+package collector_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/collector"
+	"github.com/becheran/roumon/internal/model"
+	"github.com/becheran/roumon/roumongrpc"
+	"github.com/becheran/roumon/roumonhttp"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestCollector(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	routines := make(chan model.Snapshot)
+	status := make(chan model.FetchStatus)
+	srv := grpc.NewServer()
+	roumongrpc.RegisterSnapshotServer(srv, collector.New(routines, status))
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nil(t, err)
+	defer conn.Close()
+	stream, err := roumongrpc.NewSnapshotClient(conn).StreamSnapshots(context.Background())
+	assert.Nil(t, err)
+	dump, err := roumonhttp.Collect()
+	assert.Nil(t, err)
+	assert.Nil(t, stream.Send(&roumongrpc.Snapshot{Dump: dump}))
+
+	snapshot := <-routines
+	assert.NotEmpty(t, snapshot.Goroutines)
+	assert.Contains(t, snapshot.Target, "127.0.0.1:")
+	assert.Equal(t, snapshot.Target, snapshot.Goroutines[0].Target)
+	assert.Equal(t, model.Connected, (<-status).State)
+
+	go func() {
+		_, _ = stream.CloseAndRecv()
+	}()
+	gone := <-status
+	assert.True(t, gone.Gone)
+	assert.Equal(t, snapshot.Target, gone.Target)
+}
+
+func TestCollectorKeepsDisconnected(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	routines := make(chan model.Snapshot)
+	status := make(chan model.FetchStatus)
+	c := collector.New(routines, status)
+	c.SetKeepDisconnected(true)
+	srv := grpc.NewServer()
+	roumongrpc.RegisterSnapshotServer(srv, c)
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nil(t, err)
+	defer conn.Close()
+	stream, err := roumongrpc.NewSnapshotClient(conn).StreamSnapshots(context.Background())
+	assert.Nil(t, err)
+	assert.Nil(t, stream.Send(&roumongrpc.Snapshot{Source: "app"}))
+	<-routines
+	assert.Equal(t, model.Connected, (<-status).State)
+
+	go func() {
+		_, _ = stream.CloseAndRecv()
+	}()
+	disconnected := <-status
+	assert.False(t, disconnected.Gone)
+	assert.Equal(t, model.Disconnected, disconnected.State)
+	assert.Equal(t, "app", disconnected.Target)
+}
+
+func TestCollectorStop(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	routines := make(chan model.Snapshot)
+	status := make(chan model.FetchStatus)
+	c := collector.New(routines, status)
+	srv := grpc.NewServer()
+	roumongrpc.RegisterSnapshotServer(srv, c)
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nil(t, err)
+	defer conn.Close()
+	stream, err := roumongrpc.NewSnapshotClient(conn).StreamSnapshots(context.Background())
+	assert.Nil(t, err)
+	assert.Nil(t, stream.Send(&roumongrpc.Snapshot{Source: "app"}))
+	<-routines
+	assert.Equal(t, model.Connected, (<-status).State)
+
+	// Nobody receives the gone status of the closed stream
+	closed := make(chan error)
+	go func() {
+		_, err := stream.CloseAndRecv()
+		closed <- err
+	}()
+	c.Stop()
+	select {
+	case err := <-closed:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not end after Stop")
+	}
+}
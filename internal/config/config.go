@@ -0,0 +1,102 @@
+// Package config loads the settings of roumon which are kept across restarts
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Config of roumon. All fields are optional.
+type Config struct {
+	Keymap    string            `json:"keymap,omitempty"`    // Preset of the keybindings: default, vim or emacs
+	Keys      map[string]string `json:"keys,omitempty"`      // Key sequence by action. Overrides the keys of the preset
+	Theme     string            `json:"theme,omitempty"`     // Built-in theme: dark, light or high-contrast
+	Colors    map[string]string `json:"colors,omitempty"`    // Color by role. Overrides the colors of the theme
+	TrueColor bool              `json:"truecolor,omitempty"` // Draw 24 bit colors
+	NoColor   bool              `json:"nocolor,omitempty"`   // Draw the default colors of the terminal only
+	Linear    bool              `json:"linear,omitempty"`    // One pane at a time without borders and symbols for screen readers
+	Editor    string            `json:"editor,omitempty"`    // Command which opens a frame with the placeholders {file} and {line}
+	Paths     map[string]string `json:"paths,omitempty"`     // Local path by build path prefix
+	Hide      []string          `json:"hide,omitempty"`      // Packages whose frames are hidden along with the standard library
+	Bookmarks []Bookmark        `json:"bookmarks,omitempty"` // Filters recalled with the keys 1 to 9. Saved when a bookmark is saved
+	Filters   []Filter          `json:"filters,omitempty"`   // Named filters which are picked in the UI or with -filter. Not changed by the UI
+	Columns   string            `json:"columns,omitempty"`   // Columns of the list in the format of -columns. Saved when a column is toggled
+	Layout    Layout            `json:"layout"`              // Arrangement of the panes. Saved when the panes are moved
+}
+
+// Bookmark of a filter and a sort of the list
+type Bookmark struct {
+	Name   string `json:"name"`
+	Filter string `json:"filter,omitempty"` // Filter text. Empty lists all goroutines
+	Status string `json:"status,omitempty"` // Status counters which filter the list, for example select,chan receive or -IO wait
+	Sort   string `json:"sort,omitempty"`   // Sort such as -wait. Empty keeps the sort of the list
+}
+
+// Filter is a named filter expression, which a team shares in the config file
+type Filter struct {
+	Name   string `json:"name"`
+	Filter string `json:"filter"` // Filter text such as pkg:github.com/myorg/ !wait<1m
+}
+
+// Layout of the list and the details
+type Layout struct {
+	Vertical bool    `json:"vertical,omitempty"` // List above the details instead of left of them
+	List     float64 `json:"list,omitempty"`     // Share of the list in the space of list and details. 0 is the default
+}
+
+// DefaultPath returns the path of the config file in the user config directory, for example
+// ~/.config/roumon/config.json. Empty if there is no user config directory.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "roumon", "config.json")
+}
+
+// Load reads the config file. A missing file is an empty config.
+func Load(path string) (Config, error) {
+	var cfg Config
+	if len(path) == 0 {
+		return cfg, nil
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read config. Err: %s", err.Error())
+	}
+	if err := json.Unmarshal(content, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse config %s. Err: %s", path, err.Error())
+	}
+	return cfg, nil
+}
+
+// Update loads the config file, applies the update and writes the file. Settings which were overridden by flags
+// are not written.
+func Update(path string, update func(cfg *Config)) error {
+	if len(path) == 0 {
+		return errors.New("no config file")
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		return err
+	}
+	update(&cfg)
+	content, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory. Err: %s", err.Error())
+	}
+	if err := os.WriteFile(path, append(content, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write config. Err: %s", err.Error())
+	}
+	return nil
+}
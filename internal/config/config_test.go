@@ -0,0 +1,84 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/becheran/roumon/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{"keymap": "vim", "keys": {"quit": "<C-q>"}, "columns": "id,func:40"}`), 0600))
+
+	cfg, err := config.Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "vim", cfg.Keymap)
+	assert.Equal(t, "<C-q>", cfg.Keys["quit"])
+	assert.Equal(t, "id,func:40", cfg.Columns)
+}
+
+func TestLoadTheme(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{"theme": "light", "colors": {"ok": "#00af00"}, "truecolor": true}`), 0600))
+
+	cfg, err := config.Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "light", cfg.Theme)
+	assert.Equal(t, "#00af00", cfg.Colors["ok"])
+	assert.True(t, cfg.TrueColor)
+}
+
+func TestLoadEditor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{"editor": "code --goto {file}:{line}", "paths": {"/build": "/home/me"}}`), 0600))
+
+	cfg, err := config.Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "code --goto {file}:{line}", cfg.Editor)
+	assert.Equal(t, "/home/me", cfg.Paths["/build"])
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	cfg, err := config.Load(filepath.Join(t.TempDir(), "config.json"))
+	assert.Nil(t, err)
+	assert.Equal(t, config.Config{}, cfg)
+}
+
+func TestLoadInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{"keymap": `), 0600))
+	_, err := config.Load(path)
+	assert.NotNil(t, err)
+}
+
+func TestUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "roumon", "config.json")
+	assert.Nil(t, config.Update(path, func(cfg *config.Config) { cfg.Keymap = "vim" }))
+	assert.Nil(t, config.Update(path, func(cfg *config.Config) {
+		cfg.Layout = config.Layout{Vertical: true, List: 0.3}
+	}))
+	assert.Nil(t, config.Update(path, func(cfg *config.Config) { cfg.Columns = "id,func:40" }))
+
+	cfg, err := config.Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "vim", cfg.Keymap)
+	assert.Equal(t, config.Layout{Vertical: true, List: 0.3}, cfg.Layout)
+	assert.Equal(t, "id,func:40", cfg.Columns)
+}
+
+func TestLoadFilters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{"filters": [{"name": "own code", "filter": "pkg:github.com/myorg/"}]}`), 0600))
+
+	cfg, err := config.Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []config.Filter{{Name: "own code", Filter: "pkg:github.com/myorg/"}}, cfg.Filters)
+
+	assert.Nil(t, config.Update(path, func(cfg *config.Config) { cfg.Keymap = "vim" }))
+	cfg, err = config.Load(path)
+	assert.Nil(t, err)
+	assert.Len(t, cfg.Filters, 1, "updates keep the filters")
+}
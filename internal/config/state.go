@@ -0,0 +1,67 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// State of the list at the end of a session, which is restored at the next start. Unlike the config it is written
+// by roumon only.
+type State struct {
+	Filter  string `json:"filter,omitempty"`  // Filter text. Empty lists all goroutines
+	Status  string `json:"status,omitempty"`  // Status counters which filter the list, for example select or -IO wait
+	Sort    string `json:"sort,omitempty"`    // Sort such as -wait
+	View    string `json:"view,omitempty"`    // stacks, creators or diff. Empty lists all goroutines
+	Compact bool   `json:"compact,omitempty"` // One dense line for every goroutine
+}
+
+// DefaultStatePath returns the path of the state file in the user cache directory, for example
+// ~/.cache/roumon/state.json. Empty if there is no user cache directory.
+func DefaultStatePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "roumon", "state.json")
+}
+
+// LoadState reads the state file. A missing file is an empty state.
+func LoadState(path string) (State, error) {
+	var state State
+	if len(path) == 0 {
+		return state, nil
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read state. Err: %s", err.Error())
+	}
+	if err := json.Unmarshal(content, &state); err != nil {
+		return state, fmt.Errorf("failed to parse state %s. Err: %s", path, err.Error())
+	}
+	return state, nil
+}
+
+// SaveState writes the state file
+func SaveState(path string, state State) error {
+	if len(path) == 0 {
+		return errors.New("no state file")
+	}
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory. Err: %s", err.Error())
+	}
+	if err := os.WriteFile(path, append(content, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write state. Err: %s", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package config_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/becheran/roumon/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "roumon", "state.json")
+	state, err := config.LoadState(path)
+	assert.Nil(t, err)
+	assert.Equal(t, config.State{}, state, "a missing file is an empty state")
+
+	saved := config.State{Filter: "pgxpool", Status: "select", Sort: "-wait", View: "stacks", Compact: true}
+	assert.Nil(t, config.SaveState(path, saved))
+	state, err = config.LoadState(path)
+	assert.Nil(t, err)
+	assert.Equal(t, saved, state)
+}
@@ -0,0 +1,99 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultConsulAddr is the address of the local consul agent
+const defaultConsulAddr = "http://127.0.0.1:8500"
+
+// Consul discovers the healthy instances of a service registered in consul
+type Consul struct {
+	Addr    string // Address of the consul agent. Defaults to CONSUL_HTTP_ADDR or the local agent
+	Token   string // ACL token. Defaults to CONSUL_HTTP_TOKEN
+	Service string // Name of the service
+
+	Client *http.Client // Defaults to http.DefaultClient
+}
+
+type consulEntry struct {
+	Node struct {
+		Node    string `json:"Node"`
+		Address string `json:"Address"`
+	} `json:"Node"`
+	Service struct {
+		ID      string `json:"ID"`
+		Address string `json:"Address"`
+		Port    int    `json:"Port"`
+	} `json:"Service"`
+}
+
+// Discover returns all instances of the service which pass their health checks
+func (c *Consul) Discover(ctx context.Context) (map[string]string, error) {
+	addr := c.Addr
+	if len(addr) == 0 {
+		addr = os.Getenv("CONSUL_HTTP_ADDR")
+	}
+	if len(addr) == 0 {
+		addr = defaultConsulAddr
+	}
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	token := c.Token
+	if len(token) == 0 {
+		token = os.Getenv("CONSUL_HTTP_TOKEN")
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	endpoint := strings.TrimSuffix(addr, "/") + "/v1/health/service/" + url.PathEscape(c.Service) + "?passing=true"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(token) > 0 {
+		req.Header.Set("X-Consul-Token", token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("consul request failed. Err: %s", err.Error())
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Error while closing response body: %s", err.Error())
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("consul request failed with status %s", resp.Status)
+	}
+
+	var entries []consulEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to parse consul response. Err: %s", err.Error())
+	}
+	targets := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		host := entry.Service.Address
+		if len(host) == 0 {
+			host = entry.Node.Address
+		}
+		name := entry.Service.ID
+		if len(name) == 0 {
+			name = entry.Node.Node
+		}
+		targets[name] = net.JoinHostPort(host, strconv.Itoa(entry.Service.Port))
+	}
+	return targets, nil
+}
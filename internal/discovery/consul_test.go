@@ -0,0 +1,42 @@
+package discovery
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsul(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/health/service/api", r.URL.Path)
+		assert.Equal(t, "true", r.URL.Query().Get("passing"))
+		assert.Equal(t, "secret", r.Header.Get("X-Consul-Token"))
+		_, _ = w.Write([]byte(`[
+			{"Node": {"Node": "node-1", "Address": "10.0.0.1"}, "Service": {"ID": "api-1", "Address": "", "Port": 6060}},
+			{"Node": {"Node": "node-2", "Address": "10.0.0.2"}, "Service": {"ID": "api-2", "Address": "10.0.1.2", "Port": 7070}}
+		]`))
+	}))
+	defer server.Close()
+
+	consul := Consul{Addr: server.URL, Token: "secret", Service: "api"}
+	targets, err := consul.Discover(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"api-1": "10.0.0.1:6060",
+		"api-2": "10.0.1.2:7070",
+	}, targets)
+}
+
+func TestConsulError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "ACL not found", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	consul := Consul{Addr: server.URL, Service: "api"}
+	_, err := consul.Discover(context.Background())
+	assert.NotNil(t, err)
+}
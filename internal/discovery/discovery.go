@@ -0,0 +1,54 @@
+package discovery
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Discoverer finds the targets which shall be monitored
+type Discoverer interface {
+	// Discover returns the addresses of all current targets by their name. See client.ParseTarget for the
+	// supported address formats.
+	Discover(ctx context.Context) (map[string]string, error)
+}
+
+// Run calls the discoverer periodically and passes the targets to sync until the context is canceled.
+// The targets are kept if the discovery fails.
+func Run(ctx context.Context, d Discoverer, interval time.Duration, sync func(targets map[string]string)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		targets, err := d.Discover(ctx)
+		if err != nil {
+			log.Printf("Discovery failed: %s", err.Error())
+		} else {
+			sync(targets)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Multi combines the targets of multiple discoverers. It fails if one of them fails so that no targets are
+// removed by accident.
+type Multi []Discoverer
+
+// Discover returns the targets of all discoverers
+func (m Multi) Discover(ctx context.Context) (map[string]string, error) {
+	targets := make(map[string]string)
+	for _, d := range m {
+		found, err := d.Discover(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for name, address := range found {
+			targets[name] = address
+		}
+	}
+	return targets, nil
+}
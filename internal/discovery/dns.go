@@ -0,0 +1,38 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// DNS discovers targets by a SRV record, for example _pprof._tcp.myservice.example.com
+type DNS struct {
+	Name string // Name of the SRV record
+
+	Resolver *net.Resolver // Defaults to net.DefaultResolver
+}
+
+// Discover returns one target per SRV entry
+func (d *DNS) Discover(ctx context.Context) (map[string]string, error) {
+	resolver := d.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	_, records, err := resolver.LookupSRV(ctx, "", "", d.Name)
+	if err != nil {
+		return nil, fmt.Errorf("SRV lookup of %s failed. Err: %s", d.Name, err.Error())
+	}
+	return srvTargets(records), nil
+}
+
+func srvTargets(records []*net.SRV) map[string]string {
+	targets := make(map[string]string, len(records))
+	for _, record := range records {
+		address := net.JoinHostPort(strings.TrimSuffix(record.Target, "."), strconv.Itoa(int(record.Port)))
+		targets[address] = address
+	}
+	return targets
+}
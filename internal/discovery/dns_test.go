@@ -0,0 +1,19 @@
+package discovery
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSRVTargets(t *testing.T) {
+	targets := srvTargets([]*net.SRV{
+		{Target: "app-1.example.com.", Port: 6060},
+		{Target: "app-2.example.com.", Port: 6061},
+	})
+	assert.Equal(t, map[string]string{
+		"app-1.example.com:6060": "app-1.example.com:6060",
+		"app-2.example.com:6061": "app-2.example.com:6061",
+	}, targets)
+}
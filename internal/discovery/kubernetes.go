@@ -0,0 +1,206 @@
+package discovery
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// portForwardTimeout is the time kubectl has to establish a port forward
+const portForwardTimeout = 10 * time.Second
+
+var forwardingLine = regexp.MustCompile(`^Forwarding from 127\.0\.0\.1:(\d+) -> \d+`)
+
+// Kubernetes discovers pods by label selector. The pprof port of every running pod is forwarded to a local port
+// with kubectl, which uses the same credentials as the user.
+type Kubernetes struct {
+	Kubectl   string // Path of the kubectl binary. Defaults to kubectl
+	Context   string // Kube context. Defaults to the current context
+	Namespace string // Defaults to the namespace of the context
+	Selector  string // Label selector, for example app=myservice
+	Port      int    // pprof port of the pods
+
+	mu       sync.Mutex
+	forwards map[string]*portForward // Port forwards by pod name
+}
+
+type portForward struct {
+	cmd       *exec.Cmd
+	localPort int
+	exited    chan struct{}
+}
+
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Name              string  `json:"name"`
+			Namespace         string  `json:"namespace"`
+			DeletionTimestamp *string `json:"deletionTimestamp"`
+		} `json:"metadata"`
+		Status struct {
+			Phase string `json:"phase"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// Discover lists all running pods and keeps a port forward open for each of them
+func (k *Kubernetes) Discover(ctx context.Context) (map[string]string, error) {
+	out, err := exec.CommandContext(ctx, k.kubectl(), k.args("get", "pods", "-o", "json", "-l", k.Selector)...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("kubectl get pods failed: %s", string(exitErr.Stderr))
+		}
+		return nil, fmt.Errorf("kubectl get pods failed. Err: %s", err.Error())
+	}
+	pods, err := parsePods(out)
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.forwards == nil {
+		k.forwards = make(map[string]*portForward)
+	}
+
+	running := make(map[string]bool, len(pods))
+	for _, pod := range pods {
+		running[pod] = true
+	}
+	for pod, forward := range k.forwards {
+		select {
+		case <-forward.exited:
+			log.Printf("Port forward to pod %s exited", pod)
+			delete(k.forwards, pod)
+			continue
+		default:
+		}
+		if !running[pod] {
+			forward.stop()
+			delete(k.forwards, pod)
+		}
+	}
+
+	targets := make(map[string]string, len(pods))
+	for _, pod := range pods {
+		forward, ok := k.forwards[pod]
+		if !ok {
+			forward, err = k.forward(pod)
+			if err != nil {
+				log.Printf("Failed to forward port of pod %s: %s", pod, err.Error())
+				continue
+			}
+			k.forwards[pod] = forward
+		}
+		targets[pod] = fmt.Sprintf("127.0.0.1:%d", forward.localPort)
+	}
+	return targets, nil
+}
+
+// Close stops all port forwards
+func (k *Kubernetes) Close() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for pod, forward := range k.forwards {
+		forward.stop()
+		delete(k.forwards, pod)
+	}
+}
+
+func (k *Kubernetes) kubectl() string {
+	if len(k.Kubectl) > 0 {
+		return k.Kubectl
+	}
+	return "kubectl"
+}
+
+func (k *Kubernetes) args(args ...string) []string {
+	if len(k.Context) > 0 {
+		args = append(args, "--context", k.Context)
+	}
+	if len(k.Namespace) > 0 {
+		args = append(args, "--namespace", k.Namespace)
+	}
+	return args
+}
+
+// forward starts kubectl port-forward to a random local port and waits until the port is known
+func (k *Kubernetes) forward(pod string) (*portForward, error) {
+	cmd := exec.Command(k.kubectl(), k.args("port-forward", "pod/"+pod, fmt.Sprintf(":%d", k.Port))...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	forward := &portForward{cmd: cmd, exited: make(chan struct{})}
+	ports := make(chan int, 1)
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			if port, ok := parseForwardingLine(scanner.Text()); ok {
+				select {
+				case ports <- port:
+				default:
+				}
+			}
+		}
+		if err := cmd.Wait(); err != nil {
+			log.Printf("kubectl port-forward to pod %s stopped: %s", pod, err.Error())
+		}
+		close(forward.exited)
+	}()
+
+	select {
+	case forward.localPort = <-ports:
+		return forward, nil
+	case <-forward.exited:
+		return nil, fmt.Errorf("kubectl port-forward exited")
+	case <-time.After(portForwardTimeout):
+		forward.stop()
+		return nil, fmt.Errorf("kubectl port-forward did not report a local port within %s", portForwardTimeout)
+	}
+}
+
+func (forward *portForward) stop() {
+	if forward.cmd.Process != nil {
+		if err := forward.cmd.Process.Kill(); err != nil {
+			log.Printf("Failed to stop port forward: %s", err.Error())
+		}
+	}
+}
+
+// parsePods returns the names of all running pods which are not terminating
+func parsePods(out []byte) ([]string, error) {
+	var list podList
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse pod list. Err: %s", err.Error())
+	}
+	var pods []string
+	for _, item := range list.Items {
+		if item.Status.Phase != "Running" || item.Metadata.DeletionTimestamp != nil {
+			continue
+		}
+		pods = append(pods, item.Metadata.Name)
+	}
+	return pods, nil
+}
+
+// parseForwardingLine returns the local port of a line like "Forwarding from 127.0.0.1:41235 -> 6060"
+func parseForwardingLine(line string) (int, bool) {
+	match := forwardingLine.FindStringSubmatch(line)
+	if match == nil {
+		return 0, false
+	}
+	port, err := strconv.Atoi(match[1])
+	return port, err == nil
+}
@@ -0,0 +1,32 @@
+package discovery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePods(t *testing.T) {
+	out := []byte(`{"items": [
+		{"metadata": {"name": "app-1", "namespace": "default"}, "status": {"phase": "Running"}},
+		{"metadata": {"name": "app-2", "namespace": "default"}, "status": {"phase": "Pending"}},
+		{"metadata": {"name": "app-3", "namespace": "default", "deletionTimestamp": "2024-01-01T00:00:00Z"}, "status": {"phase": "Running"}}
+	]}`)
+	pods, err := parsePods(out)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"app-1"}, pods)
+
+	_, err = parsePods([]byte("error: no context"))
+	assert.NotNil(t, err)
+}
+
+func TestParseForwardingLine(t *testing.T) {
+	port, ok := parseForwardingLine("Forwarding from 127.0.0.1:41235 -> 6060")
+	assert.True(t, ok)
+	assert.Equal(t, 41235, port)
+
+	_, ok = parseForwardingLine("Forwarding from [::1]:41235 -> 6060")
+	assert.False(t, ok)
+	_, ok = parseForwardingLine("Handling connection for 41235")
+	assert.False(t, ok)
+}
@@ -0,0 +1,106 @@
+package dump
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// Attach sends SIGQUIT to the local go process and reads the dump which the runtime writes to stderr. The runtime
+// exits the process after the dump. Stderr of the process has to be a file or the systemd journal.
+func Attach(pid int, timeout time.Duration) ([]model.Snapshot, error) {
+	fd := fmt.Sprintf("/proc/%d/fd/2", pid)
+	stderr, err := os.Readlink(fd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stderr of process %d. Err: %s", pid, err.Error())
+	}
+
+	var read func() ([]byte, error)
+	if strings.HasPrefix(stderr, "socket:") {
+		// Services started by systemd log to the journal
+		since := time.Now().Unix()
+		read = func() ([]byte, error) {
+			out, err := exec.Command("journalctl", fmt.Sprintf("_PID=%d", pid), fmt.Sprintf("--since=@%d", since),
+				"--output=cat", "--no-pager").Output()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the journal. Err: %s", err.Error())
+			}
+			return out, nil
+		}
+	} else {
+		f, err := os.Open(fd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open stderr of process %d. Err: %s", pid, err.Error())
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				log.Printf("Error while closing stderr of process %d: %s", pid, err.Error())
+			}
+		}()
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("stderr of process %d is %s. Redirect it to a file or the journal to capture the dump", pid, stderr)
+		}
+		offset := info.Size()
+		read = func() ([]byte, error) {
+			return io.ReadAll(io.NewSectionReader(f, offset, 1<<62))
+		}
+	}
+
+	if err := syscall.Kill(pid, syscall.SIGQUIT); err != nil {
+		return nil, fmt.Errorf("failed to send SIGQUIT to process %d. Err: %s", pid, err.Error())
+	}
+	deadline := time.Now().Add(timeout)
+	for !exited(pid) {
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("process %d did not exit within %s after SIGQUIT. It might handle the signal itself", pid, timeout)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	out, err := read()
+	if err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("pid %d", pid)
+	var snapshots []model.Snapshot
+	err = model.ParseDumps(bytes.NewReader(out), func(result model.ParseResult) {
+		snapshot := model.Snapshot{
+			Time:       time.Now(),
+			Goroutines: result.Goroutines,
+			Skipped:    result.Skipped,
+			Source:     stderr,
+		}
+		snapshot.SetTarget(name)
+		snapshots = append(snapshots, snapshot)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dump of process %d. Err: %s", pid, err.Error())
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("no goroutine dump found in %s", stderr)
+	}
+	return snapshots, nil
+}
+
+// exited checks if the process is gone or a zombie which waits for its parent
+func exited(pid int) bool {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return true
+	}
+	// The state follows the command name in parentheses
+	fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+	return len(fields) == 0 || fields[0] == "Z" || fields[0] == "X"
+}
@@ -0,0 +1,97 @@
+package dump
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// The test binary runs as the process which is attached to if the variable is set
+const attachHelperEnv = "ROUMON_ATTACH_HELPER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(attachHelperEnv) == "1" {
+		attachHelper()
+	}
+	os.Exit(m.Run())
+}
+
+// attachHelper blocks three goroutines and waits to be attached to
+func attachHelper() {
+	blocked := make(chan struct{})
+	for range 3 {
+		go func() { <-blocked }()
+	}
+	fmt.Println("ready")
+	time.Sleep(time.Minute)
+	os.Exit(0)
+}
+
+// startHelper starts the helper process with the stderr and waits until its goroutines are blocked
+func startHelper(t *testing.T, stderr *os.File) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), attachHelperEnv+"=1")
+	cmd.Stderr = stderr
+	stdout, err := cmd.StdoutPipe()
+	assert.Nil(t, err)
+	assert.Nil(t, cmd.Start())
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, "ready\n", line)
+	return cmd
+}
+
+func TestAttach(t *testing.T) {
+	stderr, err := os.Create(filepath.Join(t.TempDir(), "stderr.log"))
+	assert.Nil(t, err)
+	defer stderr.Close()
+	// Output of the process before the signal is not part of the dump
+	_, err = stderr.WriteString("goroutine 99 [running]:\nmain.old()\n\t/src/old.go:1 +0x1\n\n")
+	assert.Nil(t, err)
+	cmd := startHelper(t, stderr)
+	pid := cmd.Process.Pid
+	assert.False(t, exited(pid))
+
+	snapshots, err := Attach(pid, 5*time.Second)
+	assert.Nil(t, err)
+	assert.True(t, exited(pid), "a terminated process which was not waited for is a zombie")
+	assert.Len(t, snapshots, 1)
+	assert.Equal(t, fmt.Sprintf("pid %d", pid), snapshots[0].Target)
+	assert.Equal(t, stderr.Name(), snapshots[0].Source)
+	helpers := 0
+	for _, g := range snapshots[0].Goroutines {
+		assert.NotEqual(t, int64(99), g.ID)
+		if model.StackContains(g.StackTrace, "attachHelper.func") {
+			helpers++
+		}
+	}
+	assert.Equal(t, 3, helpers)
+
+	var exitErr *exec.ExitError
+	assert.True(t, errors.As(cmd.Wait(), &exitErr), "the runtime exits the process after the dump")
+	assert.True(t, exited(pid))
+}
+
+func TestAttachPipe(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	assert.Nil(t, err)
+	defer reader.Close()
+	cmd := startHelper(t, writer)
+	assert.Nil(t, writer.Close())
+
+	_, err = Attach(cmd.Process.Pid, time.Second)
+	assert.ErrorContains(t, err, "Redirect it to a file or the journal")
+	assert.False(t, exited(cmd.Process.Pid), "the process is not signaled if its dump cannot be read")
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package dump
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// Attach is only supported on linux
+func Attach(pid int, timeout time.Duration) ([]model.Snapshot, error) {
+	return nil, fmt.Errorf("attaching to process %d is not supported on %s", pid, runtime.GOOS)
+}
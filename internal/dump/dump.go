@@ -0,0 +1,146 @@
+package dump
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// Read parses the consecutive dumps of the reader and sends one snapshot per dump. A dump is sent once the next
+// dump starts or the reader is closed.
+func Read(reader io.Reader, name string, routineUpdate chan<- model.Snapshot) error {
+	return model.ParseDumps(reader, func(result model.ParseResult) {
+		snapshot := model.Snapshot{
+			Time:       time.Now(),
+			Goroutines: result.Goroutines,
+			Skipped:    result.Skipped,
+		}
+		snapshot.SetTarget(name)
+		routineUpdate <- snapshot
+	})
+}
+
+// LoadFile reads all dumps of the file. The snapshots are named after the file and get its modification time.
+// Snapshots of a JSON export keep their target and time.
+func LoadFile(path string) ([]model.Snapshot, error) {
+	return load(path, []string{path})
+}
+
+// LoadDir reads the dumps of all files in the directory. The files are ordered by name or by modification time.
+func LoadDir(dir string, byModTime bool) ([]model.Snapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dump directory. Err: %s", err.Error())
+	}
+	type file struct {
+		path    string
+		modTime time.Time
+	}
+	var files []file
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file{path: filepath.Join(dir, entry.Name()), modTime: info.ModTime()})
+	}
+	if byModTime {
+		sort.SliceStable(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	}
+
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.path
+	}
+	return load(dir, paths)
+}
+
+// load reads the dumps of the files in order. All snapshots are named after the given name.
+func load(name string, paths []string) ([]model.Snapshot, error) {
+	var snapshots []model.Snapshot
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open dump. Err: %s", err.Error())
+		}
+		info, err := f.Stat()
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		count := len(snapshots)
+		if strings.HasSuffix(path, ".json") {
+			snapshots, err = loadJSON(f, name, info.ModTime(), snapshots)
+		} else {
+			err = parseFile(f, func(result model.ParseResult) {
+				snapshot := model.Snapshot{
+					Time:       info.ModTime(),
+					Goroutines: result.Goroutines,
+					Skipped:    result.Skipped,
+					Source:     path,
+				}
+				snapshot.SetTarget(name)
+				snapshots = append(snapshots, snapshot)
+			})
+		}
+		if errClose := f.Close(); errClose != nil {
+			log.Printf("Error while closing dump: %s", errClose.Error())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dump %s. Err: %s", path, err.Error())
+		}
+		if len(snapshots) == count {
+			log.Printf("No goroutines found in %s", path)
+		}
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("no goroutine dump found in %s", name)
+	}
+	return snapshots, nil
+}
+
+// parseFile parses the dumps of the file. Files ending with .gz, such as recorded snapshots, are decompressed.
+func parseFile(f *os.File, emit func(result model.ParseResult)) error {
+	var reader io.Reader = f
+	if strings.HasSuffix(f.Name(), ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		reader = gz
+	}
+	return model.ParseDumps(reader, emit)
+}
+
+// loadJSON appends the snapshots of a JSON export. Snapshots keep their target and time. The goroutines of a
+// list export are named after the given name and get the modification time.
+func loadJSON(f *os.File, name string, modTime time.Time, snapshots []model.Snapshot) ([]model.Snapshot, error) {
+	loaded, err := model.ReadJSON(f)
+	if err != nil {
+		return snapshots, err
+	}
+	for _, snapshot := range loaded {
+		if len(snapshot.Target) == 0 {
+			snapshot.SetTarget(name)
+		}
+		if snapshot.Time.IsZero() {
+			snapshot.Time = modTime
+		}
+		if len(snapshot.Source) == 0 {
+			snapshot.Source = f.Name()
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
@@ -0,0 +1,121 @@
+package dump_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/dump"
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const twoDumps = `goroutine 1 [running]:
+main.main()
+	/home/user/main.go:10 +0x1d
+
+goroutine 1 [sleep]:
+time.Sleep(0x3b9aca00)
+	/usr/local/go/src/runtime/time.go:195 +0x135
+main.main()
+	/home/user/main.go:12 +0x2a
+
+goroutine 6 [chan receive]:
+main.worker()
+	/home/user/main.go:20 +0x1d
+`
+
+func TestRead(t *testing.T) {
+	routines := make(chan model.Snapshot, 2)
+	assert.Nil(t, dump.Read(strings.NewReader(twoDumps), "stdin", routines))
+	close(routines)
+
+	var snapshots []model.Snapshot
+	for snapshot := range routines {
+		snapshots = append(snapshots, snapshot)
+	}
+	assert.Len(t, snapshots, 2)
+	assert.Len(t, snapshots[0].Goroutines, 1)
+	assert.Len(t, snapshots[1].Goroutines, 2)
+	assert.Equal(t, "stdin", snapshots[1].Target)
+	assert.Equal(t, "sleep", snapshots[1].Goroutines[0].Status)
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "02.txt"), []byte(twoDumps), 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "01.txt"), []byte(twoDumps[:strings.Index(twoDumps, "\n\n")]), 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, ".hidden"), []byte(twoDumps), 0600))
+	old := time.Now().Add(-time.Hour)
+	assert.Nil(t, os.Chtimes(filepath.Join(dir, "02.txt"), old, old))
+
+	snapshots, err := dump.LoadDir(dir, false)
+	assert.Nil(t, err)
+	assert.Len(t, snapshots, 3)
+	assert.Equal(t, filepath.Join(dir, "01.txt"), snapshots[0].Source)
+	assert.Equal(t, dir, snapshots[0].Target)
+	assert.Equal(t, filepath.Join(dir, "02.txt"), snapshots[2].Source)
+
+	snapshots, err = dump.LoadDir(dir, true)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "02.txt"), snapshots[0].Source)
+	assert.True(t, snapshots[0].Time.Equal(old))
+
+	_, err = dump.LoadDir(t.TempDir(), false)
+	assert.NotNil(t, err)
+	_, err = dump.LoadFile(filepath.Join(dir, "missing.txt"))
+	assert.NotNil(t, err)
+}
+
+func TestLoadJSON(t *testing.T) {
+	routines, err := model.ParseStackFrame(strings.NewReader(twoDumps[:strings.Index(twoDumps, "\n\n")]))
+	assert.Nil(t, err)
+	var buf bytes.Buffer
+	assert.Nil(t, model.WriteJSON(&buf, routines))
+	path := filepath.Join(t.TempDir(), "list.json")
+	assert.Nil(t, os.WriteFile(path, buf.Bytes(), 0600))
+
+	snapshots, err := dump.LoadFile(path)
+	assert.Nil(t, err)
+	assert.Len(t, snapshots, 1)
+	assert.Equal(t, path, snapshots[0].Target)
+	assert.Equal(t, path, snapshots[0].Source)
+	assert.False(t, snapshots[0].Time.IsZero())
+	assert.Equal(t, routines[0].ID, snapshots[0].Goroutines[0].ID)
+}
+
+func TestFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	first := twoDumps[:strings.Index(twoDumps, "\n\n")+2]
+	assert.Nil(t, os.WriteFile(path, []byte("starting app\n"+first), 0600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	routines := make(chan model.Snapshot)
+	go func() {
+		assert.Nil(t, dump.Follow(ctx, filepath.Join(filepath.Dir(path), "*.log"), 10*time.Millisecond, routines))
+	}()
+
+	snapshot := <-routines
+	assert.Equal(t, path, snapshot.Target)
+	assert.Len(t, snapshot.Goroutines, 1)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	assert.Nil(t, err)
+	_, err = f.WriteString("SIGQUIT: quit\n\n" + twoDumps[len(first):])
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+
+	snapshot = <-routines
+	assert.Len(t, snapshot.Goroutines, 2)
+	assert.Equal(t, "sleep", snapshot.Goroutines[0].Status)
+
+	// Truncated files are read from the start
+	assert.Nil(t, os.WriteFile(path, []byte(first), 0600))
+	snapshot = <-routines
+	assert.Len(t, snapshot.Goroutines, 1)
+}
@@ -0,0 +1,125 @@
+package dump
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// followed file
+type followed struct {
+	info    os.FileInfo
+	offset  int64
+	changed bool         // File changed during the last poll
+	pending bytes.Buffer // Appended data which is parsed once the file did not change for one interval
+}
+
+// Follow watches all files matching the glob pattern and sends the dumps which are appended to them. Files are
+// polled every interval and the appended data is parsed once the file did not change for one interval, so that
+// dumps which are still being written are not cut. Truncated or replaced files are read from the start.
+func Follow(ctx context.Context, pattern string, interval time.Duration, routineUpdate chan<- model.Snapshot) error {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid follow pattern %s. Err: %s", pattern, err.Error())
+	}
+	files := make(map[string]*followed)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			f, ok := files[path]
+			if !ok {
+				log.Printf("Follow %s", path)
+				f = &followed{}
+				files[path] = f
+			}
+			if err := f.poll(path); err != nil {
+				log.Printf("Failed to follow %s: %s", path, err.Error())
+				continue
+			}
+			f.emit(ctx, path, routineUpdate)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll reads the data appended since the last poll
+func (f *followed) poll(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if f.info != nil && (!os.SameFile(f.info, info) || info.Size() < f.offset) {
+		log.Printf("%s was truncated or replaced", path)
+		f.offset = 0
+		f.pending.Reset()
+	}
+	f.info = info
+	f.changed = info.Size() != f.offset
+	if !f.changed {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Error while closing %s: %s", path, err.Error())
+		}
+	}()
+	if _, err := file.Seek(f.offset, io.SeekStart); err != nil {
+		return err
+	}
+	read, err := io.CopyN(&f.pending, file, info.Size()-f.offset)
+	f.offset += read
+	if err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// emit parses the pending data once the file is quiet
+func (f *followed) emit(ctx context.Context, path string, routineUpdate chan<- model.Snapshot) {
+	if f.changed || f.pending.Len() == 0 {
+		return
+	}
+	var snapshots []model.Snapshot
+	err := model.ParseDumps(&f.pending, func(result model.ParseResult) {
+		snapshot := model.Snapshot{
+			Time:       f.info.ModTime(),
+			Goroutines: result.Goroutines,
+			Skipped:    result.Skipped,
+			Source:     path,
+		}
+		snapshot.SetTarget(path)
+		snapshots = append(snapshots, snapshot)
+	})
+	f.pending.Reset()
+	if err != nil {
+		log.Printf("Failed to parse dump of %s: %s", path, err.Error())
+	}
+	for _, snapshot := range snapshots {
+		select {
+		case routineUpdate <- snapshot:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
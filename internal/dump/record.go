@@ -0,0 +1,99 @@
+package dump
+
+import (
+	"compress/gzip"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// unsafeName matches the characters of a target which are replaced in the name of its directory
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Recorder saves snapshots as gzip compressed goroutine dumps, which are replayed with LoadDir. Every target gets
+// a directory below the directory of the recorder and every snapshot a file named after its time, for example
+// localhost_6060/20240102-150405.000.txt.gz. The modification time of the file is the time of the snapshot.
+type Recorder struct {
+	dir string
+}
+
+// NewRecorder creates the directory of the recording if it does not exist yet
+func NewRecorder(dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory. Err: %s", err.Error())
+	}
+	return &Recorder{dir: dir}, nil
+}
+
+// Save writes the snapshot to a new file and returns its path. Labels, build info and profiles are not part of a
+// goroutine dump and are lost.
+func (r *Recorder) Save(snapshot model.Snapshot) (string, error) {
+	dir := filepath.Join(r.dir, unsafeName.ReplaceAllString(snapshot.Target, "_"))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, snapshot.Time.Format("20060102-150405.000")+".txt.gz")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	writer := gzip.NewWriter(file)
+	_, err = writer.Write([]byte(model.Dump(snapshot.Goroutines)))
+	if errClose := writer.Close(); err == nil {
+		err = errClose
+	}
+	if errClose := file.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return "", err
+	}
+	return path, os.Chtimes(path, snapshot.Time, snapshot.Time)
+}
+
+// Tee saves every snapshot of in before it is passed on to out. Snapshots which fail to save are logged and passed
+// on anyway. Returns once in is closed.
+func (r *Recorder) Tee(in <-chan model.Snapshot, out chan<- model.Snapshot) {
+	for snapshot := range in {
+		if _, err := r.Save(snapshot); err != nil {
+			log.Printf("%s: Failed to record snapshot: %s", snapshot.Target, err.Error())
+		}
+		out <- snapshot
+	}
+}
+
+// LoadSession reads the snapshots of a recording of the Recorder in the order of their time. Every subdirectory of
+// the recording holds the snapshots of the target it is named after. A directory without subdirectories is read as
+// one target like with LoadDir.
+func LoadSession(dir string) ([]model.Snapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording. Err: %s", err.Error())
+	}
+	var snapshots []model.Snapshot
+	targets := 0
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name()[0] == '.' {
+			continue
+		}
+		targets++
+		loaded, err := LoadDir(filepath.Join(dir, entry.Name()), false)
+		if err != nil {
+			return nil, err
+		}
+		for i := range loaded {
+			loaded[i].SetTarget(entry.Name())
+		}
+		snapshots = append(snapshots, loaded...)
+	}
+	if targets == 0 {
+		return LoadDir(dir, false)
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].Time.Before(snapshots[j].Time) })
+	return snapshots, nil
+}
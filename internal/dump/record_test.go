@@ -0,0 +1,42 @@
+package dump_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/dump"
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "session")
+	recorder, err := dump.NewRecorder(dir)
+	assert.Nil(t, err)
+
+	routines, err := model.ParseStackFrame(strings.NewReader(twoDumps[strings.Index(twoDumps, "\n\n"):]))
+	assert.Nil(t, err)
+	first := time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local)
+	in := make(chan model.Snapshot, 2)
+	out := make(chan model.Snapshot, 2)
+	in <- model.Snapshot{Target: "localhost:6060", Time: first.Add(time.Second), Goroutines: routines}
+	in <- model.Snapshot{Target: "localhost:6060", Time: first, Goroutines: routines[:1]}
+	close(in)
+	recorder.Tee(in, out)
+	assert.Len(t, out, 2, "snapshots are passed on")
+
+	path, err := recorder.Save(model.Snapshot{Target: "localhost:6060", Time: first, Goroutines: routines})
+	assert.Error(t, err, "an existing file is not overwritten")
+	assert.Empty(t, path)
+
+	snapshots, err := dump.LoadDir(filepath.Join(dir, "localhost_6060"), false)
+	assert.Nil(t, err)
+	assert.Len(t, snapshots, 2)
+	assert.Equal(t, filepath.Join(dir, "localhost_6060", "20240102-150405.000.txt.gz"), snapshots[0].Source)
+	assert.True(t, snapshots[0].Time.Equal(first))
+	assert.Len(t, snapshots[0].Goroutines, 1)
+	assert.Len(t, snapshots[1].Goroutines, len(routines))
+	assert.Equal(t, "main.worker()", snapshots[1].Goroutines[len(routines)-1].StackTrace[0].FuncName)
+}
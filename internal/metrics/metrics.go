@@ -0,0 +1,126 @@
+// Package metrics serves the goroutine counts of the latest snapshot of every target in the text format of
+// Prometheus, so that alerting rules catch leaks of long-running programs.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// maxGroups is the number of the largest stack groups of every target which are exposed. Smaller groups are left
+// out to keep the number of series low.
+const maxGroups = 50
+
+// contentType of the text format of Prometheus
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Exporter keeps the latest snapshot of every target and serves its metrics. Safe for concurrent use.
+type Exporter struct {
+	mu        sync.Mutex
+	snapshots map[string]model.Snapshot // Latest snapshot by target
+}
+
+// NewExporter creates an exporter without snapshots
+func NewExporter() *Exporter {
+	return &Exporter{snapshots: make(map[string]model.Snapshot)}
+}
+
+// Update replaces the snapshot of the target of the snapshot
+func (e *Exporter) Update(snapshot model.Snapshot) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.snapshots[snapshot.Target] = snapshot
+}
+
+// Tee updates the exporter with every snapshot of in before it is passed on to out. Returns once in is closed.
+func (e *Exporter) Tee(in <-chan model.Snapshot, out chan<- model.Snapshot) {
+	for snapshot := range in {
+		e.Update(snapshot)
+		out <- snapshot
+	}
+}
+
+// ServeHTTP writes the metrics of the latest snapshots
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	if err := e.Write(w); err != nil {
+		log.Printf("Failed to write metrics: %s", err.Error())
+	}
+}
+
+// Write writes the metrics of the latest snapshots in the text format of Prometheus: roumon_goroutines with the
+// goroutines of every target and status, roumon_stack_group and roumon_stack_group_max_wait_minutes with the
+// goroutines and the longest wait of the largest groups of identical stacks and
+// roumon_snapshot_timestamp_seconds with the time of the latest snapshot, which tells if a target stopped sending.
+// The fingerprint of a group stays the same as long as the stack, the status and the creator do not change.
+func (e *Exporter) Write(w io.Writer) error {
+	e.mu.Lock()
+	snapshots := make([]model.Snapshot, 0, len(e.snapshots))
+	for _, snapshot := range e.snapshots {
+		snapshots = append(snapshots, snapshot)
+	}
+	e.mu.Unlock()
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Target < snapshots[j].Target })
+
+	var text strings.Builder
+	writeHeader(&text, "roumon_goroutines", "Goroutines of the latest snapshot by status.")
+	for _, snapshot := range snapshots {
+		counts := make(map[string]int)
+		for _, g := range snapshot.Goroutines {
+			counts[g.Status]++
+		}
+		statuses := make([]string, 0, len(counts))
+		for status := range counts {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+		for _, status := range statuses {
+			fmt.Fprintf(&text, "roumon_goroutines{target=%s,status=%s} %d\n", label(snapshot.Target), label(status),
+				counts[status])
+		}
+	}
+
+	var groups, waits strings.Builder
+	for _, snapshot := range snapshots {
+		all := model.GroupByStack(snapshot.Goroutines)
+		for _, group := range all[:min(len(all), maxGroups)] {
+			labels := fmt.Sprintf("target=%s,fingerprint=%s,top_func=%s,status=%s", label(snapshot.Target),
+				label(group.Fingerprint()), label(group.Func()), label(group.Goroutines[0].Status))
+			maxWait := int64(0)
+			for _, g := range group.Goroutines {
+				maxWait = max(maxWait, g.WaitSinceMin)
+			}
+			fmt.Fprintf(&groups, "roumon_stack_group{%s} %d\n", labels, len(group.Goroutines))
+			fmt.Fprintf(&waits, "roumon_stack_group_max_wait_minutes{%s} %d\n", labels, maxWait)
+		}
+	}
+	writeHeader(&text, "roumon_stack_group", "Goroutines of the latest snapshot with identical stacks.")
+	text.WriteString(groups.String())
+	writeHeader(&text, "roumon_stack_group_max_wait_minutes", "Longest wait of the goroutines with identical stacks.")
+	text.WriteString(waits.String())
+
+	writeHeader(&text, "roumon_snapshot_timestamp_seconds", "Time of the latest snapshot.")
+	for _, snapshot := range snapshots {
+		fmt.Fprintf(&text, "roumon_snapshot_timestamp_seconds{target=%s} %.3f\n", label(snapshot.Target),
+			float64(snapshot.Time.UnixMilli())/1000)
+	}
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+// writeHeader writes the help and the type of the gauge
+func writeHeader(text *strings.Builder, name, help string) {
+	fmt.Fprintf(text, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+}
+
+// label returns the value as quoted label value of the text format
+func label(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
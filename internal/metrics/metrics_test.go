@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	serve := []model.StackFrame{{FuncName: "main.serve(0x1)", File: "/src/main.go", Line: 20}}
+	e := NewExporter()
+	e.Update(model.Snapshot{Target: "b", Time: time.UnixMilli(1700000000500), Goroutines: []model.Goroutine{
+		{ID: 1, Status: "running", StackTrace: []model.StackFrame{{FuncName: "main.main()"}}},
+	}})
+	e.Update(model.Snapshot{Target: `a"1`, Time: time.UnixMilli(1700000000000), Goroutines: []model.Goroutine{
+		{ID: 1, Status: "select", WaitSinceMin: 3, StackTrace: serve},
+		{ID: 2, Status: "select", WaitSinceMin: 12, StackTrace: serve},
+		{ID: 3, Status: "chan receive", StackTrace: serve},
+	}})
+
+	var text strings.Builder
+	assert.Nil(t, e.Write(&text))
+	lines := strings.Split(text.String(), "\n")
+	assert.Equal(t, []string{
+		"# HELP roumon_goroutines Goroutines of the latest snapshot by status.",
+		"# TYPE roumon_goroutines gauge",
+		`roumon_goroutines{target="a\"1",status="chan receive"} 1`,
+		`roumon_goroutines{target="a\"1",status="select"} 2`,
+		`roumon_goroutines{target="b",status="running"} 1`,
+	}, lines[:5])
+	select2 := model.StackGroup{Key: model.Goroutine{Status: "select", StackTrace: serve}.StackKey()}.Fingerprint()
+	assert.Contains(t, lines, `roumon_stack_group{target="a\"1",fingerprint="`+select2+`",top_func="main.serve",status="select"} 2`)
+	assert.Contains(t, lines, `roumon_stack_group_max_wait_minutes{target="a\"1",fingerprint="`+select2+`",top_func="main.serve",status="select"} 12`)
+	assert.Contains(t, lines, `roumon_snapshot_timestamp_seconds{target="b"} 1700000000.500`)
+
+	// A newer snapshot replaces the one of the target
+	e.Update(model.Snapshot{Target: "b", Time: time.UnixMilli(1700000001000)})
+	text.Reset()
+	assert.Nil(t, e.Write(&text))
+	assert.NotContains(t, text.String(), `status="running"`)
+	assert.Contains(t, text.String(), `roumon_snapshot_timestamp_seconds{target="b"} 1700000001.000`)
+}
+
+func TestServeHTTP(t *testing.T) {
+	e := NewExporter()
+	e.Update(model.Snapshot{Target: "a", Goroutines: []model.Goroutine{{ID: 1, Status: "running"}}})
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, contentType, recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), `roumon_goroutines{target="a",status="running"} 1`)
+}
@@ -0,0 +1,195 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// GoroutineChange is a goroutine which changed its status or its wait time between two snapshots
+type GoroutineChange struct {
+	Before Goroutine
+	After  Goroutine
+}
+
+// StackChange is a stack whose number of goroutines changed between two snapshots
+type StackChange struct {
+	Status string
+	Func   string
+	Before int
+	After  int
+}
+
+// SnapshotDiff lists what changed between an older and a newer snapshot. Goroutines are matched by their
+// fingerprint, which does not match across restarts. Stacks are matched by their stack key, so they also show
+// the change of a deploy.
+type SnapshotDiff struct {
+	Added     []Goroutine
+	Removed   []Goroutine
+	Changed   []GoroutineChange
+	Unchanged int
+	Stacks    []StackChange // Largest change first
+}
+
+// CompareSnapshots compares the goroutines of the older with the ones of the newer snapshot
+func CompareSnapshots(older, newer []Goroutine) SnapshotDiff {
+	var diff SnapshotDiff
+	before := make(map[string]Goroutine, len(older))
+	for _, g := range older {
+		before[g.Fingerprint()] = g
+	}
+	for _, entry := range Diff(older, newer) {
+		switch entry.Change {
+		case Added:
+			diff.Added = append(diff.Added, entry.Goroutine)
+		case Removed:
+			diff.Removed = append(diff.Removed, entry.Goroutine)
+		default:
+			old := before[entry.Fingerprint()]
+			if old.Status != entry.Status || old.WaitSinceMin != entry.WaitSinceMin {
+				diff.Changed = append(diff.Changed, GoroutineChange{Before: old, After: entry.Goroutine})
+			} else {
+				diff.Unchanged++
+			}
+		}
+	}
+
+	stacks := make(map[string]*StackChange)
+	var keys []string
+	count := func(routines []Goroutine, after bool) {
+		for _, group := range GroupByStack(routines) {
+			stack, ok := stacks[group.Key]
+			if !ok {
+				stack = &StackChange{Status: group.Goroutines[0].Status, Func: group.Func()}
+				stacks[group.Key] = stack
+				keys = append(keys, group.Key)
+			}
+			if after {
+				stack.After = len(group.Goroutines)
+			} else {
+				stack.Before = len(group.Goroutines)
+			}
+		}
+	}
+	count(older, false)
+	count(newer, true)
+	for _, key := range keys {
+		if stack := stacks[key]; stack.Before != stack.After {
+			diff.Stacks = append(diff.Stacks, *stack)
+		}
+	}
+	slices.SortStableFunc(diff.Stacks, func(a, b StackChange) int { return abs(b.After-b.Before) - abs(a.After-a.Before) })
+	return diff
+}
+
+func abs(n int) int {
+	return max(n, -n)
+}
+
+// describe returns the status, wait and top function of the goroutine, for example select 5m main.serve
+func describe(g Goroutine) string {
+	text := g.Status
+	if g.WaitSinceMin > 0 {
+		text += fmt.Sprintf(" %dm", g.WaitSinceMin)
+	}
+	return text + " " + g.TopFunc()
+}
+
+// WriteDiffText writes the diff as text aligned with spaces: the stacks whose number of goroutines changed, the
+// goroutines which were added or removed and the ones which changed their status or wait time
+func WriteDiffText(w io.Writer, diff SnapshotDiff) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "%d added, %d removed, %d changed, %d unchanged goroutines\n", len(diff.Added),
+		len(diff.Removed), len(diff.Changed), diff.Unchanged)
+	if len(diff.Stacks) > 0 {
+		fmt.Fprintln(table, "\nBEFORE\tAFTER\tDELTA\tSTATUS\tFUNCTION")
+	}
+	for _, stack := range diff.Stacks {
+		fmt.Fprintf(table, "%d\t%d\t%+d\t%s\t%s\n", stack.Before, stack.After, stack.After-stack.Before, stack.Status,
+			stack.Func)
+	}
+	if len(diff.Added)+len(diff.Removed)+len(diff.Changed) > 0 {
+		fmt.Fprintln(table, "\n\tID\tBEFORE\tAFTER")
+	}
+	for _, g := range diff.Added {
+		fmt.Fprintf(table, "+\t%d\t-\t%s\n", g.ID, describe(g))
+	}
+	for _, g := range diff.Removed {
+		fmt.Fprintf(table, "-\t%d\t%s\t-\n", g.ID, describe(g))
+	}
+	for _, change := range diff.Changed {
+		fmt.Fprintf(table, "~\t%d\t%s\t%s\n", change.After.ID, describe(change.Before), describe(change.After))
+	}
+	return table.Flush()
+}
+
+// exportedDiff is the JSON format of a diff
+type exportedDiff struct {
+	Added     []exportedGoroutine `json:"added"`
+	Removed   []exportedGoroutine `json:"removed"`
+	Changed   []exportedChange    `json:"changed"`
+	Unchanged int                 `json:"unchanged"`
+	Stacks    []exportedStack     `json:"stacks"`
+}
+
+type exportedChange struct {
+	Before exportedGoroutine `json:"before"`
+	After  exportedGoroutine `json:"after"`
+}
+
+type exportedStack struct {
+	Status string `json:"status"`
+	Func   string `json:"func"`
+	Before int    `json:"before"`
+	After  int    `json:"after"`
+}
+
+// WriteDiffJSON writes the diff as JSON object. Goroutines have the format of WriteJSON.
+func WriteDiffJSON(w io.Writer, diff SnapshotDiff) error {
+	exported := exportedDiff{
+		Added:     exportGoroutines(diff.Added),
+		Removed:   exportGoroutines(diff.Removed),
+		Changed:   make([]exportedChange, len(diff.Changed)),
+		Unchanged: diff.Unchanged,
+		Stacks:    make([]exportedStack, len(diff.Stacks)),
+	}
+	for i, change := range diff.Changed {
+		pair := exportGoroutines([]Goroutine{change.Before, change.After})
+		exported.Changed[i] = exportedChange{Before: pair[0], After: pair[1]}
+	}
+	for i, stack := range diff.Stacks {
+		exported.Stacks[i] = exportedStack{Status: stack.Status, Func: stack.Func, Before: stack.Before, After: stack.After}
+	}
+	return writeIndented(w, exported)
+}
+
+// WriteDiffMarkdown writes the diff as Markdown tables for an issue or a deploy review
+func WriteDiffMarkdown(w io.Writer, diff SnapshotDiff) error {
+	var text strings.Builder
+	fmt.Fprintf(&text, "## Goroutine diff\n\n%d added, %d removed, %d changed, %d unchanged goroutines\n",
+		len(diff.Added), len(diff.Removed), len(diff.Changed), diff.Unchanged)
+	if len(diff.Stacks) > 0 {
+		text.WriteString("\n### Stacks\n\n| Before | After | Delta | Status | Function |\n| ---: | ---: | ---: | --- | --- |\n")
+	}
+	for _, stack := range diff.Stacks {
+		fmt.Fprintf(&text, "| %d | %d | %+d | %s | `%s` |\n", stack.Before, stack.After, stack.After-stack.Before,
+			markdownCell(stack.Status), markdownCell(stack.Func))
+	}
+	if len(diff.Added)+len(diff.Removed)+len(diff.Changed) > 0 {
+		text.WriteString("\n### Goroutines\n\n| | ID | Before | After |\n| --- | ---: | --- | --- |\n")
+	}
+	for _, g := range diff.Added {
+		fmt.Fprintf(&text, "| + | %d | | %s |\n", g.ID, markdownCell(describe(g)))
+	}
+	for _, g := range diff.Removed {
+		fmt.Fprintf(&text, "| - | %d | %s | |\n", g.ID, markdownCell(describe(g)))
+	}
+	for _, change := range diff.Changed {
+		fmt.Fprintf(&text, "| ~ | %d | %s | %s |\n", change.After.ID, markdownCell(describe(change.Before)),
+			markdownCell(describe(change.After)))
+	}
+	_, err := io.WriteString(w, text.String())
+	return err
+}
@@ -0,0 +1,89 @@
+package model_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func compareFixture() ([]model.Goroutine, []model.Goroutine) {
+	serve := []model.StackFrame{{FuncName: "main.serve(0x1)", File: "/src/main.go", Line: 20}}
+	work := []model.StackFrame{{FuncName: "main.work()", File: "/src/main.go", Line: 30}}
+	older := []model.Goroutine{
+		{ID: 1, Status: "select", StackTrace: serve},
+		{ID: 2, Status: "select", WaitSinceMin: 1, StackTrace: serve},
+		{ID: 3, Status: "running", StackTrace: work},
+		{ID: 4, Status: "select", StackTrace: serve},
+	}
+	newer := []model.Goroutine{
+		{ID: 1, Status: "select", StackTrace: serve},
+		{ID: 2, Status: "select", WaitSinceMin: 5, StackTrace: serve},
+		{ID: 3, Status: "chan receive", StackTrace: work},
+		{ID: 5, Status: "chan receive", StackTrace: work},
+		{ID: 6, Status: "chan receive", StackTrace: work},
+	}
+	return older, newer
+}
+
+func TestCompareSnapshots(t *testing.T) {
+	older, newer := compareFixture()
+	diff := model.CompareSnapshots(older, newer)
+
+	assert.Equal(t, []model.Goroutine{newer[3], newer[4]}, diff.Added)
+	assert.Equal(t, []model.Goroutine{older[3]}, diff.Removed)
+	assert.Equal(t, []model.GoroutineChange{
+		{Before: older[1], After: newer[1]},
+		{Before: older[2], After: newer[2]},
+	}, diff.Changed)
+	assert.Equal(t, 1, diff.Unchanged)
+	assert.Equal(t, []model.StackChange{
+		{Status: "chan receive", Func: "main.work", Before: 0, After: 3},
+		{Status: "select", Func: "main.serve", Before: 3, After: 2},
+		{Status: "running", Func: "main.work", Before: 1, After: 0},
+	}, diff.Stacks)
+
+	diff = model.CompareSnapshots(newer, newer)
+	assert.Empty(t, diff.Added)
+	assert.Empty(t, diff.Changed)
+	assert.Empty(t, diff.Stacks)
+	assert.Equal(t, len(newer), diff.Unchanged)
+}
+
+func TestWriteDiff(t *testing.T) {
+	diff := model.CompareSnapshots(compareFixture())
+
+	var buf bytes.Buffer
+	assert.Nil(t, model.WriteDiffText(&buf, diff))
+	lines := strings.Split(buf.String(), "\n")
+	assert.Equal(t, "2 added, 1 removed, 2 changed, 1 unchanged goroutines", lines[0])
+	assert.Equal(t, []string{"0", "3", "+3", "chan", "receive", "main.work"}, strings.Fields(lines[3]))
+	assert.Contains(t, buf.String(), "select 1m main.serve")
+	assert.Equal(t, []string{"~", "3", "running", "main.work", "chan", "receive", "main.work"},
+		strings.Fields(lines[len(lines)-2]))
+
+	buf.Reset()
+	assert.Nil(t, model.WriteDiffJSON(&buf, diff))
+	var exported struct {
+		Added     []map[string]any
+		Removed   []map[string]any
+		Changed   []map[string]map[string]any
+		Unchanged int
+		Stacks    []map[string]any
+	}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &exported))
+	assert.Len(t, exported.Added, 2)
+	assert.Equal(t, float64(4), exported.Removed[0]["id"])
+	assert.Equal(t, float64(5), exported.Changed[0]["after"]["waitMinutes"])
+	assert.Equal(t, "main.work", exported.Stacks[0]["func"])
+	assert.Equal(t, float64(3), exported.Stacks[0]["after"])
+
+	buf.Reset()
+	assert.Nil(t, model.WriteDiffMarkdown(&buf, diff))
+	assert.Contains(t, buf.String(), "| 0 | 3 | +3 | chan receive | `main.work` |\n")
+	assert.Contains(t, buf.String(), "| - | 4 | select main.serve | |\n")
+	assert.Contains(t, buf.String(), "| ~ | 2 | select 1m main.serve | select 5m main.serve |\n")
+}
@@ -0,0 +1,66 @@
+package model
+
+import "fmt"
+
+// Change of a goroutine between two snapshots
+type Change int
+
+const (
+	// Persisting goroutines exist in both snapshots
+	Persisting Change = iota
+	// Added goroutines only exist in the newer snapshot
+	Added
+	// Removed goroutines only exist in the older snapshot
+	Removed
+)
+
+var changeNames = map[Change]string{
+	Persisting: "persisting",
+	Added:      "added",
+	Removed:    "removed",
+}
+
+func (c Change) String() string {
+	return changeNames[c]
+}
+
+// DiffEntry is a goroutine of a diff. Persisting goroutines are taken from the newer snapshot.
+type DiffEntry struct {
+	Goroutine
+	Change Change
+}
+
+// Fingerprint identifies a goroutine across snapshots. The ID is unique within a process. The target and the
+// creator tell apart goroutines with the same ID of different or restarted processes.
+func (g Goroutine) Fingerprint() string {
+	createdBy := ""
+	if g.CratedBy != nil {
+		createdBy = fmt.Sprintf("%s:%s:%d", g.CratedBy.Func(), g.CratedBy.File, g.CratedBy.Line)
+	}
+	return fmt.Sprintf("%s/%d/%s", g.Target, g.ID, createdBy)
+}
+
+// Diff matches the goroutines of the older and the newer snapshot by fingerprint. The removed goroutines come
+// first in the order of the older snapshot, followed by all goroutines of the newer snapshot.
+func Diff(older, newer []Goroutine) []DiffEntry {
+	inNewer := make(map[string]bool, len(newer))
+	for _, g := range newer {
+		inNewer[g.Fingerprint()] = true
+	}
+	inOlder := make(map[string]bool, len(older))
+	var entries []DiffEntry
+	for _, g := range older {
+		inOlder[g.Fingerprint()] = true
+		if !inNewer[g.Fingerprint()] {
+			entries = append(entries, DiffEntry{Goroutine: g, Change: Removed})
+		}
+	}
+	for _, g := range newer {
+		change := Added
+		if inOlder[g.Fingerprint()] {
+			change = Persisting
+		}
+		entries = append(entries, DiffEntry{Goroutine: g, Change: change})
+	}
+	return entries
+}
@@ -0,0 +1,40 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	creator := &model.StackFrame{FuncName: "main.main", File: "/src/main.go", Line: 10}
+	older := []model.Goroutine{
+		{ID: 1, Status: "running"},
+		{ID: 2, Status: "select", CratedBy: creator},
+		{ID: 3, Status: "IO wait"},
+	}
+	newer := []model.Goroutine{
+		{ID: 1, Status: "select"},
+		{ID: 3, Status: "IO wait", CratedBy: creator},
+		{ID: 4, Status: "running"},
+	}
+
+	entries := model.Diff(older, newer)
+	assert.Len(t, entries, 5)
+	assert.Equal(t, model.DiffEntry{Goroutine: older[1], Change: model.Removed}, entries[0])
+	// Same ID of a different creator
+	assert.Equal(t, model.DiffEntry{Goroutine: older[2], Change: model.Removed}, entries[1])
+	assert.Equal(t, model.DiffEntry{Goroutine: newer[0], Change: model.Persisting}, entries[2])
+	assert.Equal(t, model.DiffEntry{Goroutine: newer[1], Change: model.Added}, entries[3])
+	assert.Equal(t, model.DiffEntry{Goroutine: newer[2], Change: model.Added}, entries[4])
+	assert.Equal(t, "removed", model.Removed.String())
+}
+
+func TestFingerprintDependsOnTarget(t *testing.T) {
+	a := model.Goroutine{ID: 1, Target: "a"}
+	b := model.Goroutine{ID: 1, Target: "b"}
+	assert.NotEqual(t, a.Fingerprint(), b.Fingerprint())
+	a.Status = "select"
+	assert.Equal(t, a.Fingerprint(), model.Goroutine{ID: 1, Target: "a"}.Fingerprint())
+}
@@ -0,0 +1,133 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strings"
+)
+
+// dotMain is the node of the goroutines without creator, which created the first spawn sites
+const dotMain = "main"
+
+// dotNode is a spawn site of the creation graph
+type dotNode struct {
+	id    string
+	site  *StackFrame // Nil for the main goroutine
+	count int         // Live goroutines created at the site
+}
+
+// dotEdge connects the site of a creator with the site where it created goroutines
+type dotEdge struct {
+	from, to string
+	count    int // Live goroutines created by goroutines of the from site at the to site
+}
+
+// siteKey identifies a spawn site
+func siteKey(site StackFrame) string {
+	return fmt.Sprintf("%s:%s:%d", site.Func(), site.File, site.Line)
+}
+
+// createdByMain tells if the first known creator of the goroutine is a goroutine without creator, like the main
+// goroutine. The creators are followed like in creatorSites.
+func createdByMain(g Goroutine, byID map[string]Goroutine, depth int) bool {
+	if g.CratedBy == nil {
+		return true
+	}
+	if creator, ok := byID[goroutineKey(g.Target, g.CreatorID)]; ok && g.CreatorID != 0 && depth < maxCreatorDepth {
+		return createdByMain(creator, byID, depth+1)
+	}
+	return len(g.Ancestors) > 0 && g.Ancestors[len(g.Ancestors)-1].CreatedBy == nil
+}
+
+// WriteDOT writes the creation graph of the goroutines in the DOT language of Graphviz, for example for
+// dot -Tsvg. Nodes are the spawn sites with the number of live goroutines created there. An edge leads from the
+// site which created a goroutine to the site where that goroutine created others and is weighted with their
+// number. Creators are found like in CreatorTree. Dashed edges lead through sites of creators which exited.
+func WriteDOT(w io.Writer, routines []Goroutine) error {
+	byID := make(map[string]Goroutine, len(routines))
+	for _, g := range routines {
+		byID[goroutineKey(g.Target, g.ID)] = g
+	}
+	var nodes []*dotNode
+	nodeByKey := make(map[string]*dotNode)
+	node := func(site *StackFrame) *dotNode {
+		key := dotMain
+		if site != nil {
+			key = siteKey(*site)
+		}
+		n, ok := nodeByKey[key]
+		if !ok {
+			n = &dotNode{id: fmt.Sprintf("n%d", len(nodes)), site: site}
+			nodeByKey[key] = n
+			nodes = append(nodes, n)
+		}
+		return n
+	}
+	var edges []*dotEdge
+	edgeByKey := make(map[[2]string]*dotEdge)
+	edge := func(from, to *dotNode) *dotEdge {
+		key := [2]string{from.id, to.id}
+		e, ok := edgeByKey[key]
+		if !ok {
+			e = &dotEdge{from: from.id, to: to.id}
+			edgeByKey[key] = e
+			edges = append(edges, e)
+		}
+		return e
+	}
+
+	for _, g := range routines {
+		sites := creatorSites(g, byID, 0)
+		if len(sites) == 0 {
+			continue
+		}
+		var parent *dotNode
+		if createdByMain(g, byID, 0) {
+			parent = node(nil)
+		}
+		for i := range sites {
+			n := node(&sites[i])
+			if parent != nil {
+				e := edge(parent, n)
+				if i == len(sites)-1 {
+					e.count++
+				}
+			}
+			parent = n
+		}
+		parent.count++
+	}
+
+	var dot strings.Builder
+	dot.WriteString("digraph goroutines {\n\trankdir=LR;\n\tnode [shape=box, fontname=\"monospace\"];\n")
+	for _, n := range nodes {
+		label := dotMain
+		if n.site != nil {
+			label = fmt.Sprintf("%s\\n%s:%d", dotEscape(n.site.Func()), dotEscape(path.Base(n.site.File)), n.site.Line)
+		}
+		if n.count == 1 {
+			label += "\\n1 goroutine"
+		} else if n.count > 1 {
+			label += fmt.Sprintf("\\n%d goroutines", n.count)
+		}
+		fmt.Fprintf(&dot, "\t%s [label=\"%s\"];\n", n.id, label)
+	}
+	for _, e := range edges {
+		if e.count == 0 {
+			fmt.Fprintf(&dot, "\t%s -> %s [style=dashed];\n", e.from, e.to)
+			continue
+		}
+		fmt.Fprintf(&dot, "\t%s -> %s [label=\"%d\", weight=%d, penwidth=%.1f];\n", e.from, e.to, e.count, e.count,
+			1+math.Log2(float64(e.count)))
+	}
+	dot.WriteString("}\n")
+	_, err := io.WriteString(w, dot.String())
+	return err
+}
+
+// dotEscape escapes the text for a quoted string of DOT
+func dotEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text)
+}
@@ -0,0 +1,43 @@
+package model_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteDOT(t *testing.T) {
+	routines, err := model.ParseStackFrame(strings.NewReader(trace_ancestors))
+	assert.Nil(t, err)
+	var buf bytes.Buffer
+	assert.Nil(t, model.WriteDOT(&buf, routines))
+	dot := buf.String()
+
+	assert.True(t, strings.HasPrefix(dot, "digraph goroutines {\n"))
+	assert.True(t, strings.HasSuffix(dot, "}\n"))
+	assert.Contains(t, dot, "\tn0 [label=\"main\"];\n")
+	assert.Contains(t, dot, "\tn1 [label=\"main.main\\nmain.go:19\\n1 goroutine\"];\n")
+	assert.Contains(t, dot, "\tn0 -> n1 [label=\"1\", weight=1, penwidth=1.0];\n")
+	assert.Contains(t, dot, "\tn1 -> n2 [label=\"1\", weight=1, penwidth=1.0];\n")
+	// Goroutine 8 exited after creating goroutine 9, so its site created no live goroutine
+	assert.Contains(t, dot, "\tn3 [label=\"main.main\\nmain.go:20\"];\n")
+	assert.Contains(t, dot, "\tn0 -> n3 [style=dashed];\n")
+	assert.Contains(t, dot, "\tn3 -> n4 [label=\"1\", weight=1, penwidth=1.0];\n")
+}
+
+func TestWriteDOTCounts(t *testing.T) {
+	site := &model.StackFrame{FuncName: `main.serve("x")`, File: "/src/main.go", Line: 20}
+	var routines []model.Goroutine
+	for id := int64(2); id < 6; id++ {
+		routines = append(routines, model.Goroutine{ID: id, CratedBy: site, CreatorID: 1})
+	}
+	routines = append(routines, model.Goroutine{ID: 1})
+
+	var buf bytes.Buffer
+	assert.Nil(t, model.WriteDOT(&buf, routines))
+	assert.Contains(t, buf.String(), "\tn1 [label=\"main.serve\\nmain.go:20\\n4 goroutines\"];\n")
+	assert.Contains(t, buf.String(), "\tn0 -> n1 [label=\"4\", weight=4, penwidth=3.0];\n")
+}
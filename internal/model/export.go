@@ -0,0 +1,301 @@
+package model
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// SnapshotVersion is the version of the JSON format of WriteSnapshotsJSON. It changes if a field changes its
+// meaning or is removed.
+const SnapshotVersion = 1
+
+// exportedGoroutine is the JSON format of a goroutine
+type exportedGoroutine struct {
+	Target      string             `json:"target,omitempty"`
+	ID          int64              `json:"id"`
+	Status      string             `json:"status"`
+	WaitMinutes int64              `json:"waitMinutes,omitempty"`
+	Locked      bool               `json:"lockedToThread,omitempty"`
+	Labels      map[string]string  `json:"labels,omitempty"`
+	Stack       []exportedFrame    `json:"stack"`
+	CreatedBy   *exportedFrame     `json:"createdBy,omitempty"`
+	CreatorID   int64              `json:"creatorId,omitempty"`
+	Ancestors   []exportedAncestor `json:"ancestors,omitempty"`
+}
+
+type exportedFrame struct {
+	Func   string `json:"func"`
+	File   string `json:"file"`
+	Line   int32  `json:"line"`
+	Offset *int   `json:"offset,omitempty"`
+}
+
+type exportedAncestor struct {
+	ID        int64          `json:"id"`
+	CreatedBy *exportedFrame `json:"createdBy,omitempty"`
+}
+
+// exportedSnapshots is the JSON format of WriteSnapshotsJSON
+type exportedSnapshots struct {
+	Version   int                `json:"version"`
+	Snapshots []exportedSnapshot `json:"snapshots"`
+}
+
+type exportedSnapshot struct {
+	Target     string              `json:"target"`
+	Time       time.Time           `json:"time"`
+	Source     string              `json:"source,omitempty"`
+	Skipped    int                 `json:"skipped,omitempty"`
+	Build      *debug.BuildInfo    `json:"build,omitempty"`
+	Vars       map[string]float64  `json:"vars,omitempty"`
+	Goroutines []exportedGoroutine `json:"goroutines"`
+}
+
+func exportFrame(frame StackFrame) exportedFrame {
+	return exportedFrame{Func: frame.FuncName, File: frame.File, Line: frame.Line, Offset: frame.Position}
+}
+
+// exportFramePtr exports the frame if it is set
+func exportFramePtr(frame *StackFrame) *exportedFrame {
+	if frame == nil {
+		return nil
+	}
+	exported := exportFrame(*frame)
+	return &exported
+}
+
+func exportGoroutines(routines []Goroutine) []exportedGoroutine {
+	exported := make([]exportedGoroutine, len(routines))
+	for i, g := range routines {
+		e := exportedGoroutine{
+			Target:      g.Target,
+			ID:          g.ID,
+			Status:      g.Status,
+			WaitMinutes: g.WaitSinceMin,
+			Locked:      g.LockedToThread,
+			Labels:      g.Labels,
+			Stack:       make([]exportedFrame, len(g.StackTrace)),
+			CreatorID:   g.CreatorID,
+		}
+		for j, frame := range g.StackTrace {
+			e.Stack[j] = exportFrame(frame)
+		}
+		e.CreatedBy = exportFramePtr(g.CratedBy)
+		for _, ancestor := range g.Ancestors {
+			e.Ancestors = append(e.Ancestors, exportedAncestor{ID: ancestor.ID, CreatedBy: exportFramePtr(ancestor.CreatedBy)})
+		}
+		exported[i] = e
+	}
+	return exported
+}
+
+func writeIndented(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// WriteJSON writes the goroutines as a JSON array. Every goroutine lists its frames with function, file and line.
+func WriteJSON(w io.Writer, routines []Goroutine) error {
+	return writeIndented(w, exportGoroutines(routines))
+}
+
+// WriteSnapshotsJSON writes the snapshots with their time, target, build info and all parsed goroutines as one JSON
+// object for other tools. The format is described in doc/snapshot.md.
+func WriteSnapshotsJSON(w io.Writer, snapshots []Snapshot) error {
+	exported := exportedSnapshots{Version: SnapshotVersion, Snapshots: make([]exportedSnapshot, len(snapshots))}
+	for i, s := range snapshots {
+		exported.Snapshots[i] = exportedSnapshot{
+			Target:     s.Target,
+			Time:       s.Time,
+			Source:     s.Source,
+			Skipped:    s.Skipped,
+			Build:      s.Build,
+			Vars:       s.Vars,
+			Goroutines: exportGoroutines(s.Goroutines),
+		}
+	}
+	return writeIndented(w, exported)
+}
+
+// importFrame is the inverse of exportFrame
+func importFrame(frame exportedFrame) StackFrame {
+	return NewStackFrame(frame.Func, frame.File, frame.Line, frame.Offset)
+}
+
+func importFramePtr(frame *exportedFrame) *StackFrame {
+	if frame == nil {
+		return nil
+	}
+	imported := importFrame(*frame)
+	return &imported
+}
+
+func importGoroutines(exported []exportedGoroutine) []Goroutine {
+	routines := make([]Goroutine, len(exported))
+	for i, e := range exported {
+		g := Goroutine{
+			Target:         e.Target,
+			ID:             e.ID,
+			Status:         e.Status,
+			WaitSinceMin:   e.WaitMinutes,
+			LockedToThread: e.Locked,
+			Labels:         e.Labels,
+			StackTrace:     make([]StackFrame, len(e.Stack)),
+			CratedBy:       importFramePtr(e.CreatedBy),
+			CreatorID:      e.CreatorID,
+		}
+		for j, frame := range e.Stack {
+			g.StackTrace[j] = importFrame(frame)
+		}
+		for _, ancestor := range e.Ancestors {
+			g.Ancestors = append(g.Ancestors, Ancestor{ID: ancestor.ID, CreatedBy: importFramePtr(ancestor.CreatedBy)})
+		}
+		routines[i] = g
+	}
+	return routines
+}
+
+// ReadJSON reads the goroutines of WriteJSON or the snapshots of WriteSnapshotsJSON. The goroutines of WriteJSON
+// are returned as one snapshot without target and time.
+func ReadJSON(reader io.Reader) ([]Snapshot, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var exported []exportedGoroutine
+		if err := json.Unmarshal(data, &exported); err != nil {
+			return nil, err
+		}
+		return []Snapshot{{Goroutines: importGoroutines(exported)}}, nil
+	}
+	var exported exportedSnapshots
+	if err := json.Unmarshal(data, &exported); err != nil {
+		return nil, err
+	}
+	if exported.Version > SnapshotVersion {
+		return nil, fmt.Errorf("snapshot version %d is newer than the supported version %d", exported.Version,
+			SnapshotVersion)
+	}
+	snapshots := make([]Snapshot, len(exported.Snapshots))
+	for i, s := range exported.Snapshots {
+		snapshots[i] = Snapshot{
+			Target:     s.Target,
+			Time:       s.Time,
+			Source:     s.Source,
+			Skipped:    s.Skipped,
+			Build:      s.Build,
+			Vars:       s.Vars,
+			Goroutines: importGoroutines(s.Goroutines),
+		}
+	}
+	return snapshots, nil
+}
+
+// csvHeader names the columns of WriteCSV
+var csvHeader = []string{"target", "id", "status", "wait_minutes", "function", "location", "created_by", "depth", "labels"}
+
+// WriteCSV writes one row for every goroutine with its top frame and its creator. Labels are joined as key=value
+// pairs separated by semicolons.
+func WriteCSV(w io.Writer, routines []Goroutine) error {
+	return writeCSV(w, routines, true)
+}
+
+// WriteCSVRows writes the rows of WriteCSV without the header, to append the goroutines of a later snapshot
+func WriteCSVRows(w io.Writer, routines []Goroutine) error {
+	return writeCSV(w, routines, false)
+}
+
+func writeCSV(w io.Writer, routines []Goroutine, header bool) error {
+	writer := csv.NewWriter(w)
+	if header {
+		if err := writer.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	for _, g := range routines {
+		function, location, creator := "", "", ""
+		if len(g.StackTrace) > 0 {
+			function = g.StackTrace[0].Func()
+			location = g.StackTrace[0].File + ":" + strconv.Itoa(int(g.StackTrace[0].Line))
+		}
+		if g.CratedBy != nil {
+			creator = g.CratedBy.Func()
+		}
+		labels := make([]string, 0, len(g.Labels))
+		for key, value := range g.Labels {
+			labels = append(labels, key+"="+value)
+		}
+		sort.Strings(labels)
+		row := []string{g.Target, strconv.FormatInt(g.ID, 10), g.Status, strconv.FormatInt(g.WaitSinceMin, 10),
+			function, location, creator, strconv.Itoa(len(g.StackTrace)), strings.Join(labels, ";")}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteTable writes the goroutines grouped by their stacks as a table aligned with spaces, largest groups first.
+// Every row shows the number of goroutines, their status, the range of their wait times, the function where they
+// wait and their creator. A positive limit cuts the table after as many groups.
+func WriteTable(w io.Writer, routines []Goroutine, limit int, header bool) error {
+	groups := GroupByStack(routines)
+	if limit > 0 && len(groups) > limit {
+		groups = groups[:limit]
+	}
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if header {
+		fmt.Fprintln(table, "COUNT\tSTATUS\tWAIT\tFUNCTION\tCREATED BY")
+	}
+	for _, group := range groups {
+		g := group.Goroutines[0]
+		creator := "-"
+		if g.CratedBy != nil {
+			creator = g.CratedBy.Func()
+		}
+		fmt.Fprintf(table, "%d\t%s\t%s\t%s\t%s\n", len(group.Goroutines), g.Status, group.WaitRange(), group.Func(), creator)
+	}
+	return table.Flush()
+}
+
+// WriteFolded writes one line for every distinct stack with the number of goroutines in it, in the folded format
+// of FlameGraph and inferno, for example main.main;main.serve;net.(*conn).Read 120. Frames are listed outermost
+// first and the lines are sorted, so flamegraph.pl or inferno-flamegraph render them as they are. Goroutines
+// without frames are left out.
+func WriteFolded(w io.Writer, routines []Goroutine) error {
+	counts := make(map[string]int)
+	for _, g := range routines {
+		if len(g.StackTrace) == 0 {
+			continue
+		}
+		frames := make([]string, len(g.StackTrace))
+		for i, frame := range g.StackTrace {
+			frames[len(frames)-1-i] = strings.ReplaceAll(frame.Func(), ";", ":")
+		}
+		counts[strings.Join(frames, ";")]++
+	}
+	stacks := make([]string, 0, len(counts))
+	for stack := range counts {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+	for _, stack := range stacks {
+		if _, err := fmt.Fprintf(w, "%s %d\n", stack, counts[stack]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package model_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteJSON(t *testing.T) {
+	routines, err := model.ParseStackFrame(strings.NewReader(trace_1))
+	assert.Nil(t, err)
+	var buf bytes.Buffer
+	assert.Nil(t, model.WriteJSON(&buf, routines))
+
+	var exported []map[string]any
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &exported))
+	assert.Len(t, exported, len(routines))
+	assert.Equal(t, float64(3), exported[2]["id"])
+	assert.Equal(t, "select", exported[2]["status"])
+	stack := exported[2]["stack"].([]any)
+	assert.Equal(t, "/home/user/dev/TestService/code/testapp/internal/mylib/testStore.go",
+		stack[0].(map[string]any)["file"])
+	assert.Equal(t, float64(411), exported[2]["createdBy"].(map[string]any)["line"])
+}
+
+func TestWriteCSV(t *testing.T) {
+	routines, err := model.ParseStackFrame(strings.NewReader(trace_1))
+	assert.Nil(t, err)
+	routines[2].Target = "localhost:6060"
+	routines[2].Labels = map[string]string{"tenant": "a", "handler": "b"}
+	var buf bytes.Buffer
+	assert.Nil(t, model.WriteCSV(&buf, routines))
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	assert.Nil(t, err)
+	assert.Len(t, rows, len(routines)+1)
+	assert.Equal(t, "target", rows[0][0])
+	assert.Equal(t, []string{"localhost:6060", "3", "select", "0",
+		"company/foo/bar/SecureTest/internal/mylib.(*filetestStore).createWatcher.func1",
+		"/home/user/dev/TestService/code/testapp/internal/mylib/testStore.go:485",
+		"company/foo/bar/SecureTest/internal/mylib.(*filetestStore).createWatcher", "1", "handler=b;tenant=a"}, rows[3])
+
+	buf.Reset()
+	assert.Nil(t, model.WriteCSVRows(&buf, routines))
+	rows, err = csv.NewReader(&buf).ReadAll()
+	assert.Nil(t, err)
+	assert.Len(t, rows, len(routines))
+	assert.Equal(t, "localhost:6060", rows[2][0])
+}
+
+func TestWriteTable(t *testing.T) {
+	routines, err := model.ParseStackFrame(strings.NewReader(trace_1))
+	assert.Nil(t, err)
+	routines = append(routines, routines[1], routines[1])
+	routines[len(routines)-1].WaitSinceMin = 3
+	var buf bytes.Buffer
+	assert.Nil(t, model.WriteTable(&buf, routines, 2, true))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, []string{"COUNT", "STATUS", "WAIT", "FUNCTION", "CREATED", "BY"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"3", "chan", "receive", "3-16m", "main.main", "-"}, strings.Fields(lines[1]))
+
+	buf.Reset()
+	assert.Nil(t, model.WriteTable(&buf, routines, 0, false))
+	assert.Equal(t, 4, strings.Count(buf.String(), "\n"))
+}
+
+func TestWriteSnapshotsJSON(t *testing.T) {
+	routines, err := model.ParseStackFrame(strings.NewReader(trace_1))
+	assert.Nil(t, err)
+	snapshot := model.Snapshot{Time: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC), Goroutines: routines, Skipped: 2}
+	snapshot.SetTarget("localhost:6060")
+	var buf bytes.Buffer
+	assert.Nil(t, model.WriteSnapshotsJSON(&buf, []model.Snapshot{snapshot}))
+
+	var exported struct {
+		Version   int
+		Snapshots []map[string]any
+	}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &exported))
+	assert.Equal(t, model.SnapshotVersion, exported.Version)
+	assert.Len(t, exported.Snapshots, 1)
+	assert.Equal(t, "localhost:6060", exported.Snapshots[0]["target"])
+	assert.Equal(t, "2024-01-02T15:04:05Z", exported.Snapshots[0]["time"])
+	assert.Equal(t, float64(2), exported.Snapshots[0]["skipped"])
+	assert.NotContains(t, exported.Snapshots[0], "build")
+	goroutines := exported.Snapshots[0]["goroutines"].([]any)
+	assert.Len(t, goroutines, len(routines))
+	frame := goroutines[2].(map[string]any)["stack"].([]any)[0].(map[string]any)
+	assert.Equal(t, float64(485), frame["line"])
+	assert.Contains(t, frame, "offset")
+}
+
+func TestReadJSON(t *testing.T) {
+	routines, err := model.ParseStackFrame(strings.NewReader(trace_1))
+	assert.Nil(t, err)
+	snapshot := model.Snapshot{Time: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC), Goroutines: routines, Skipped: 2}
+	snapshot.SetTarget("localhost:6060")
+	var buf bytes.Buffer
+	assert.Nil(t, model.WriteSnapshotsJSON(&buf, []model.Snapshot{snapshot}))
+
+	snapshots, err := model.ReadJSON(&buf)
+	assert.Nil(t, err)
+	assert.Len(t, snapshots, 1)
+	assert.Equal(t, "localhost:6060", snapshots[0].Target)
+	assert.True(t, snapshot.Time.Equal(snapshots[0].Time))
+	assert.Equal(t, 2, snapshots[0].Skipped)
+	assert.Equal(t, model.Dump(routines), model.Dump(snapshots[0].Goroutines))
+
+	buf.Reset()
+	assert.Nil(t, model.WriteJSON(&buf, routines))
+	snapshots, err = model.ReadJSON(&buf)
+	assert.Nil(t, err)
+	assert.Len(t, snapshots, 1)
+	assert.True(t, snapshots[0].Time.IsZero())
+	assert.Equal(t, model.Dump(routines), model.Dump(snapshots[0].Goroutines))
+
+	_, err = model.ReadJSON(strings.NewReader(`{"version": 99, "snapshots": []}`))
+	assert.ErrorContains(t, err, "version 99")
+	_, err = model.ReadJSON(strings.NewReader("goroutine 1 [running]:"))
+	assert.Error(t, err)
+}
+
+func TestWriteFolded(t *testing.T) {
+	serve := model.StackFrame{FuncName: "main.serve(0x1)"}
+	routines := []model.Goroutine{
+		{ID: 1, StackTrace: []model.StackFrame{{FuncName: "net.(*conn).Read(0x2)"}, serve}},
+		{ID: 2, StackTrace: []model.StackFrame{serve}},
+		{ID: 3, StackTrace: []model.StackFrame{{FuncName: "net.(*conn).Read(0x3)"}, serve}},
+	}
+	var buf bytes.Buffer
+	assert.Nil(t, model.WriteFolded(&buf, routines))
+	assert.Equal(t, "main.serve 1\nmain.serve;net.(*conn).Read 2\n", buf.String())
+}
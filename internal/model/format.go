@@ -0,0 +1,54 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dump formats the goroutines like the goroutine dump of the Go runtime, so that the text can be parsed again
+func Dump(routines []Goroutine) string {
+	dumps := make([]string, len(routines))
+	for i, g := range routines {
+		dumps[i] = g.Dump()
+	}
+	return strings.Join(dumps, "\n")
+}
+
+// Dump formats the goroutine like the Go runtime. Arguments are kept, the frames of ancestors are lost.
+func (g Goroutine) Dump() string {
+	var text strings.Builder
+	state := g.Status
+	if g.WaitSinceMin > 0 {
+		state += fmt.Sprintf(", %d minutes", g.WaitSinceMin)
+	}
+	if g.LockedToThread {
+		state += ", locked to thread"
+	}
+	fmt.Fprintf(&text, "goroutine %d [%s]:\n", g.ID, state)
+	for _, frame := range g.StackTrace {
+		fmt.Fprintf(&text, "%s\n\t%s\n", frame.FuncName, frame.Pos())
+	}
+	if g.CratedBy != nil {
+		creator := ""
+		if g.CreatorID != 0 {
+			creator = fmt.Sprintf(" in goroutine %d", g.CreatorID)
+		}
+		fmt.Fprintf(&text, "created by %s%s\n\t%s\n", g.CratedBy.FuncName, creator, g.CratedBy.Pos())
+	}
+	for _, ancestor := range g.Ancestors {
+		fmt.Fprintf(&text, "[originating from goroutine %d]:\n", ancestor.ID)
+		if ancestor.CreatedBy != nil {
+			fmt.Fprintf(&text, "created by %s\n\t%s\n", ancestor.CreatedBy.FuncName, ancestor.CreatedBy.Pos())
+		}
+	}
+	return text.String()
+}
+
+// Pos formats the file, line and offset of the frame like the Go runtime, for example
+// /usr/local/go/src/net/http/server.go:2969 +0x970
+func (s StackFrame) Pos() string {
+	if s.Position == nil {
+		return fmt.Sprintf("%s:%d", s.File, s.Line)
+	}
+	return fmt.Sprintf("%s:%d +0x%x", s.File, s.Line, *s.Position)
+}
@@ -0,0 +1,31 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDumpRoundTrip(t *testing.T) {
+	for _, trace := range []string{trace_1, trace_2, trace_ancestors} {
+		routines, err := model.ParseStackFrame(strings.NewReader(trace))
+		assert.Nil(t, err)
+		parsed, err := model.ParseStackFrame(strings.NewReader(model.Dump(routines)))
+		assert.Nil(t, err)
+		assert.Equal(t, routines, parsed)
+	}
+}
+
+func TestDump(t *testing.T) {
+	routines, err := model.ParseStackFrame(strings.NewReader(trace_1))
+	assert.Nil(t, err)
+	assert.Equal(t, `goroutine 3 [select]:
+company/foo/bar/SecureTest/internal/mylib.(*filetestStore).createWatcher.func1(0xc0001b0320)
+	/home/user/dev/TestService/code/testapp/internal/mylib/testStore.go:485 +0x1be
+created by company/foo/bar/SecureTest/internal/mylib.(*filetestStore).createWatcher
+	/home/user/dev/TestService/code/testapp/internal/mylib/testStore.go:411 +0x159
+`, routines[2].Dump())
+	assert.True(t, strings.HasPrefix(routines[1].Dump(), "goroutine 1 [chan receive, 16 minutes]:\n"))
+}
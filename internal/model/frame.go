@@ -0,0 +1,72 @@
+package model
+
+import "strings"
+
+// FuncParts of a fully qualified function name like net/http.(*conn).serve
+type FuncParts struct {
+	Package  string // Import path, for example net/http
+	Receiver string // Receiver type of a method, for example (*conn). Empty for functions
+	Name     string // Function or method name including closure suffixes, for example serve.func1
+	Args     string // Argument list as printed by the runtime. Empty if unknown
+}
+
+// Parts splits the function name of the frame. Value receivers cannot be told apart from functions with nested
+// closures for sure. The first name is taken as receiver type unless the second one is a closure.
+func (s StackFrame) Parts() FuncParts {
+	name := s.Func()
+	parts := FuncParts{Args: s.FuncName[len(name):]}
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		parts.Name = name
+		return parts
+	}
+	parts.Package = name[:slash+1+dot]
+	rest := name[slash+2+dot:]
+
+	if strings.HasPrefix(rest, "(") {
+		if end := strings.Index(rest, ")."); end > 0 {
+			parts.Receiver = rest[:end+1]
+			parts.Name = rest[end+2:]
+			return parts
+		}
+	}
+	if first, second, ok := strings.Cut(rest, "."); ok && !isClosure(second) {
+		parts.Receiver = first
+		parts.Name = second
+		return parts
+	}
+	parts.Name = rest
+	return parts
+}
+
+// InPackage returns true if the function of the frame belongs to the package or to a package below it. A trailing
+// slash matches only the packages below, for example github.com/myorg/ for all packages of the organization.
+func (s StackFrame) InPackage(pkg string) bool {
+	own := s.Parts().Package
+	if strings.HasSuffix(pkg, "/") {
+		return strings.HasPrefix(own, pkg)
+	}
+	return own == pkg || strings.HasPrefix(own, pkg+"/")
+}
+
+// isClosure returns true for the names the compiler gives anonymous functions, like func1 or gowrap2
+func isClosure(name string) bool {
+	for _, prefix := range []string{"func", "gowrap", "deferwrap"} {
+		if suffix, ok := strings.CutPrefix(name, prefix); ok && len(suffix) > 0 && suffix[0] >= '0' && suffix[0] <= '9' {
+			return true
+		}
+	}
+	return false
+}
+
+// Stdlib returns true if the frame belongs to the runtime or the standard library. The first element of the
+// import path of other packages contains a dot, except for the main package.
+func (s StackFrame) Stdlib() bool {
+	pkg := s.Parts().Package
+	if len(pkg) == 0 || pkg == "main" {
+		return false
+	}
+	first, _, _ := strings.Cut(pkg, "/")
+	return !strings.Contains(first, ".")
+}
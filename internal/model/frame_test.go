@@ -0,0 +1,40 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrameParts(t *testing.T) {
+	parts := func(name string) model.FuncParts { return model.StackFrame{FuncName: name}.Parts() }
+
+	assert.Equal(t, model.FuncParts{Package: "net/http", Receiver: "(*conn)", Name: "serve", Args: "(0xc0001a2000, {0x7a1b40, 0xc00007e0c0})"},
+		parts("net/http.(*conn).serve(0xc0001a2000, {0x7a1b40, 0xc00007e0c0})"))
+	assert.Equal(t, model.FuncParts{Package: "main", Name: "main", Args: "()"}, parts("main.main()"))
+	assert.Equal(t, model.FuncParts{Package: "main", Name: "worker.func1", Args: "()"}, parts("main.worker.func1()"))
+	// Dots in the last element of the import path are escaped
+	assert.Equal(t, model.FuncParts{Package: "gopkg.in/yaml%2ev3", Receiver: "Server", Name: "Run.func2", Args: "(...)"},
+		parts("gopkg.in/yaml%2ev3.Server.Run.func2(...)"))
+	assert.Equal(t, model.FuncParts{Package: "example.com/q", Receiver: "(*Queue[...])", Name: "Pop"},
+		parts("example.com/q.(*Queue[...]).Pop"))
+	assert.Equal(t, model.FuncParts{Name: "goexit"}, parts("goexit"))
+}
+
+func TestFrameStdlib(t *testing.T) {
+	assert.True(t, model.StackFrame{FuncName: "runtime.gopark(0x0)"}.Stdlib())
+	assert.True(t, model.StackFrame{FuncName: "internal/poll.(*FD).Read(0xc0)"}.Stdlib())
+	assert.False(t, model.StackFrame{FuncName: "main.main()"}.Stdlib())
+	assert.False(t, model.StackFrame{FuncName: "github.com/becheran/roumon/internal/ui.(*UI).Run()"}.Stdlib())
+}
+
+func TestFrameInPackage(t *testing.T) {
+	frame := model.StackFrame{FuncName: "github.com/myorg/api/handler.(*Server).Get(0xc0)"}
+	assert.True(t, frame.InPackage("github.com/myorg/api/handler"))
+	assert.True(t, frame.InPackage("github.com/myorg/api"))
+	assert.True(t, frame.InPackage("github.com/myorg/"))
+	assert.False(t, frame.InPackage("github.com/myorg/api/handl"))
+	assert.False(t, frame.InPackage("github.com/my"))
+	assert.False(t, frame.InPackage("github.com/myorg/api/handler/"), "a trailing slash matches the packages below only")
+}
@@ -0,0 +1,85 @@
+package model
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// StackGroup contains the goroutines with identical stacks
+type StackGroup struct {
+	Key        string // Stack key of all goroutines of the group
+	Goroutines []Goroutine
+}
+
+// StackKey identifies goroutines which wait with the same status in the same stack. The status, the function,
+// file and line of every frame and the creator are compared. IDs, wait times and arguments are ignored.
+func (g Goroutine) StackKey() string {
+	var key strings.Builder
+	key.WriteString(g.Status)
+	for _, frame := range g.StackTrace {
+		fmt.Fprintf(&key, "|%s:%s:%d", frame.Func(), frame.File, frame.Line)
+	}
+	if g.CratedBy != nil {
+		fmt.Fprintf(&key, "|created by %s:%s:%d", g.CratedBy.Func(), g.CratedBy.File, g.CratedBy.Line)
+	}
+	return key.String()
+}
+
+// GroupByStack groups the goroutines with identical stacks. The largest groups come first. Groups of the same size
+// and the goroutines within a group keep their order.
+func GroupByStack(routines []Goroutine) []StackGroup {
+	var groups []StackGroup
+	index := make(map[string]int)
+	for _, g := range routines {
+		key := g.StackKey()
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, StackGroup{Key: key})
+		}
+		groups[i].Goroutines = append(groups[i].Goroutines, g)
+	}
+	slices.SortStableFunc(groups, func(a, b StackGroup) int {
+		return len(b.Goroutines) - len(a.Goroutines)
+	})
+	return groups
+}
+
+// Fingerprint shortens the stack key of the group to 16 hex digits, which stay the same as long as the stack, the
+// status and the creator of the group do not change
+func (s StackGroup) Fingerprint() string {
+	hash := sha1.Sum([]byte(s.Key))
+	return hex.EncodeToString(hash[:])[:16]
+}
+
+// WaitRange returns the range of the wait times of the goroutines, for example 3-16m, or - if none of them waits
+func (s StackGroup) WaitRange() string {
+	minWait, maxWait := s.Goroutines[0].WaitSinceMin, s.Goroutines[0].WaitSinceMin
+	for _, g := range s.Goroutines {
+		minWait = min(minWait, g.WaitSinceMin)
+		maxWait = max(maxWait, g.WaitSinceMin)
+	}
+	switch {
+	case minWait != maxWait:
+		return fmt.Sprintf("%d-%dm", minWait, maxWait)
+	case maxWait > 0:
+		return fmt.Sprintf("%dm", maxWait)
+	}
+	return "-"
+}
+
+// Func returns the function of the first frame outside of the runtime and the standard library, which is where
+// the goroutines of the group wait. Falls back to the function on top of the stack.
+func (s StackGroup) Func() string {
+	g := s.Goroutines[0]
+	for _, frame := range g.StackTrace {
+		if !frame.Stdlib() {
+			return frame.Func()
+		}
+	}
+	return g.TopFunc()
+}
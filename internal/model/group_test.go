@@ -0,0 +1,42 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupByStack(t *testing.T) {
+	get := []model.StackFrame{
+		{FuncName: "runtime.gopark(0x0?)", File: "/usr/local/go/src/runtime/proc.go", Line: 398},
+		{FuncName: "github.com/redis/redis.(*Pool).Get(0xc000010000)", File: "/go/pkg/redis/pool.go", Line: 120},
+	}
+	other := []model.StackFrame{{FuncName: "main.worker()", File: "/src/main.go", Line: 22}}
+	routines := []model.Goroutine{
+		{ID: 1, Status: "select", StackTrace: other},
+		{ID: 2, Status: "chan receive", StackTrace: get, WaitSinceMin: 3},
+		{ID: 3, Status: "chan receive", StackTrace: get},
+		{ID: 4, Status: "running", StackTrace: other},
+		{ID: 5, Status: "select", StackTrace: other},
+	}
+
+	groups := model.GroupByStack(routines)
+	assert.Len(t, groups, 3)
+	assert.Equal(t, []model.Goroutine{routines[0], routines[4]}, groups[0].Goroutines)
+	assert.Equal(t, []model.Goroutine{routines[1], routines[2]}, groups[1].Goroutines)
+	assert.Equal(t, []model.Goroutine{routines[3]}, groups[2].Goroutines)
+	assert.Equal(t, "github.com/redis/redis.(*Pool).Get", groups[1].Func())
+	assert.Equal(t, "main.worker", groups[0].Func())
+	assert.Len(t, groups[0].Fingerprint(), 16)
+	assert.NotEqual(t, groups[0].Fingerprint(), groups[2].Fingerprint(), "the status is part of the fingerprint")
+	assert.Equal(t, groups[1].Fingerprint(), model.GroupByStack(routines[1:3])[0].Fingerprint())
+}
+
+func TestStackKeyIgnoresArguments(t *testing.T) {
+	a := model.Goroutine{ID: 1, Status: "select", StackTrace: []model.StackFrame{{FuncName: "main.f(0x1)", File: "/src/main.go", Line: 3}}}
+	b := model.Goroutine{ID: 2, Status: "select", StackTrace: []model.StackFrame{{FuncName: "main.f(0x2)", File: "/src/main.go", Line: 3}}}
+	assert.Equal(t, a.StackKey(), b.StackKey())
+	b.CratedBy = &model.StackFrame{FuncName: "main.main", File: "/src/main.go", Line: 10}
+	assert.NotEqual(t, a.StackKey(), b.StackKey())
+}
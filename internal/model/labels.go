@@ -0,0 +1,112 @@
+package model
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// LabelGroup is one entry of a goroutine profile in debug=1 format. All goroutines of a group share the same
+// stack and labels. See: https://pkg.go.dev/runtime/pprof#Do
+type LabelGroup struct {
+	Count  int
+	Labels map[string]string
+	Funcs  []string // Function names of the stack without arguments
+}
+
+// ParseLabelGroups reads a goroutine profile in debug=1 format and returns all groups which have labels set
+func ParseLabelGroups(reader io.Reader) (groups []LabelGroup, err error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+
+	var current *LabelGroup
+	flush := func() {
+		if current != nil && len(current.Labels) > 0 {
+			groups = append(groups, *current)
+		}
+		current = nil
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "# labels: "):
+			if current == nil {
+				continue
+			}
+			if errParse := json.Unmarshal([]byte(line[10:]), &current.Labels); errParse != nil {
+				err = fmt.Errorf("failed to parse labels %s. Err: %s", line, errParse.Error())
+				return
+			}
+		case strings.HasPrefix(line, "#\t"):
+			if current == nil {
+				continue
+			}
+			// For example: #	0x46d6a4	runtime/pprof.writeRuntimeProfile+0xb4	/usr/local/go/src/runtime/pprof/pprof.go:734
+			fields := strings.Split(line, "\t")
+			if len(fields) < 3 {
+				continue
+			}
+			name := fields[2]
+			if sep := strings.LastIndex(name, "+"); sep > 0 {
+				name = name[:sep]
+			}
+			current.Funcs = append(current.Funcs, name)
+		case strings.Contains(line, " @ "):
+			flush()
+			count, errParse := strconv.Atoi(line[:strings.Index(line, " ")])
+			if errParse != nil {
+				continue
+			}
+			current = &LabelGroup{Count: count}
+		}
+	}
+	flush()
+
+	err = scanner.Err()
+	return
+}
+
+// AssignLabels sets the labels of the goroutines which have the same stack as one of the groups. Goroutines
+// are matched by the function names of their stacks ignoring runtime functions. Goroutine dumps and label
+// groups are not taken atomically, so the result is a best effort.
+func AssignLabels(routines []Goroutine, groups []LabelGroup) {
+	remaining := make(map[string][]LabelGroup, len(groups))
+	for _, g := range groups {
+		key := labelKey(g.Funcs)
+		remaining[key] = append(remaining[key], g)
+	}
+
+	for i := range routines {
+		funcs := make([]string, len(routines[i].StackTrace))
+		for idx, frame := range routines[i].StackTrace {
+			funcs[idx] = frame.Func()
+		}
+		key := labelKey(funcs)
+		candidates := remaining[key]
+		if len(candidates) == 0 {
+			continue
+		}
+		routines[i].Labels = candidates[0].Labels
+		candidates[0].Count--
+		if candidates[0].Count <= 0 {
+			candidates = candidates[1:]
+		}
+		remaining[key] = candidates
+	}
+}
+
+func labelKey(funcs []string) string {
+	var key strings.Builder
+	for _, f := range funcs {
+		if strings.HasPrefix(f, "runtime.") || strings.HasPrefix(f, "internal/runtime/") {
+			continue
+		}
+		key.WriteString(f)
+		key.WriteByte('\n')
+	}
+	return key.String()
+}
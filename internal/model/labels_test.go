@@ -0,0 +1,57 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var labelProfile = `goroutine profile: total 3
+2 @ 0x43c0d6 0x46d6a5 0x46d6a6
+# labels: {"tenant":"acme", "worker":"1"}
+#	0x46d6a4	main.worker+0xb4	/home/user/main.go:30
+#	0x46d6a5	main.main.func1+0x2e	/home/user/main.go:12
+
+1 @ 0x43c0d6 0x46d6a5
+#	0x46d6a4	main.main+0xb4	/home/user/main.go:10
+`
+
+func TestParseLabelGroups(t *testing.T) {
+	groups, err := model.ParseLabelGroups(strings.NewReader(labelProfile))
+	assert.Nil(t, err)
+	assert.Len(t, groups, 1)
+	assert.Equal(t, 2, groups[0].Count)
+	assert.Equal(t, map[string]string{"tenant": "acme", "worker": "1"}, groups[0].Labels)
+	assert.Equal(t, []string{"main.worker", "main.main.func1"}, groups[0].Funcs)
+}
+
+func TestAssignLabels(t *testing.T) {
+	worker := []model.StackFrame{
+		{FuncName: "runtime.gopark(0x0)"},
+		{FuncName: "main.worker(0xc0001b0320)"},
+		{FuncName: "main.main.func1()"},
+	}
+	routines := []model.Goroutine{
+		{ID: 1, StackTrace: []model.StackFrame{{FuncName: "main.main()"}}},
+		{ID: 2, StackTrace: worker},
+		{ID: 3, StackTrace: worker},
+		{ID: 4, StackTrace: worker},
+	}
+	model.AssignLabels(routines, []model.LabelGroup{{
+		Count:  2,
+		Labels: map[string]string{"tenant": "acme"},
+		Funcs:  []string{"main.worker", "main.main.func1"},
+	}})
+	assert.Nil(t, routines[0].Labels)
+	assert.Equal(t, "acme", routines[1].Labels["tenant"])
+	assert.Equal(t, "acme", routines[2].Labels["tenant"])
+	assert.Nil(t, routines[3].Labels)
+}
+
+func TestStackFrameFunc(t *testing.T) {
+	assert.Equal(t, "net/http.(*conn).serve", model.StackFrame{FuncName: "net/http.(*conn).serve(0xc000fe5f40, 0xe54aa0)"}.Func())
+	assert.Equal(t, "main.main", model.StackFrame{FuncName: "main.main()"}.Func())
+	assert.Equal(t, "net/http.(*Server).Serve", model.StackFrame{FuncName: "net/http.(*Server).Serve"}.Func())
+}
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// markdownStacks is the number of the largest stacks which the Markdown summary shows
+	markdownStacks = 10
+	// markdownFrames is the number of frames of every stack which the Markdown summary shows
+	markdownFrames = 8
+	// markdownWaiters is the number of the longest waiting goroutines which the Markdown summary lists
+	markdownWaiters = 10
+)
+
+// WriteMarkdown writes a summary of the goroutines for an issue or an incident document: the goroutine count of
+// every status, the largest stacks with their top frames and the goroutines which wait the longest.
+func WriteMarkdown(w io.Writer, routines []Goroutine) error {
+	var text strings.Builder
+	fmt.Fprintf(&text, "## Goroutines\n\n%d goroutines in total\n\n| Status | Goroutines |\n| --- | ---: |\n", len(routines))
+	for _, count := range CountStatuses(routines) {
+		fmt.Fprintf(&text, "| %s | %d |\n", markdownCell(count.Status), count.Count)
+	}
+
+	groups := GroupByStack(routines)
+	if len(groups) > 0 {
+		fmt.Fprintf(&text, "\n## Top stacks\n")
+	}
+	for i, group := range groups[:min(len(groups), markdownStacks)] {
+		g := group.Goroutines[0]
+		fmt.Fprintf(&text, "\n### %d. %d × %s in `%s`\n\nWait %s", i+1, len(group.Goroutines), g.Status, group.Func(),
+			group.WaitRange())
+		if g.CratedBy != nil {
+			fmt.Fprintf(&text, ", created by `%s`", g.CratedBy.Func())
+		}
+		text.WriteString("\n\n```\n")
+		for _, frame := range g.StackTrace[:min(len(g.StackTrace), markdownFrames)] {
+			fmt.Fprintf(&text, "%s\n\t%s\n", frame.FuncName, frame.Pos())
+		}
+		if more := len(g.StackTrace) - markdownFrames; more > 0 {
+			fmt.Fprintf(&text, "... %d more frames\n", more)
+		}
+		text.WriteString("```\n")
+	}
+
+	waiting := waiters(routines)
+	if len(waiting) > 0 {
+		text.WriteString("\n## Longest waiters\n\n| ID | Status | Wait | Function |\n| ---: | --- | ---: | --- |\n")
+	}
+	for _, g := range waiting[:min(len(waiting), markdownWaiters)] {
+		fmt.Fprintf(&text, "| %d | %s | %dm | `%s` |\n", g.ID, markdownCell(g.Status), g.WaitSinceMin,
+			markdownCell(g.TopFunc()))
+	}
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+// markdownCell escapes the pipes of the text, which would end the cell of a table
+func markdownCell(text string) string {
+	return strings.ReplaceAll(text, "|", `\|`)
+}
@@ -0,0 +1,42 @@
+package model_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	routines, err := model.ParseStackFrame(strings.NewReader(trace_1))
+	assert.Nil(t, err)
+	routines = append(routines, routines[1])
+	routines[len(routines)-1].ID = 9
+	routines[len(routines)-1].WaitSinceMin = 30
+	var buf bytes.Buffer
+	assert.Nil(t, model.WriteMarkdown(&buf, routines))
+	text := buf.String()
+
+	assert.Contains(t, text, "5 goroutines in total")
+	assert.Contains(t, text, "| chan receive | 2 |")
+	assert.Contains(t, text, "### 1. 2 × chan receive in `main.main`")
+	assert.Contains(t, text, "Wait 16-30m")
+	assert.Contains(t, text, "```\ncompany/foo/bar/SecureTest/internal/Testservice.(*TestService).Start(0xc0001adc70)\n\t")
+	assert.Contains(t, text, "## Longest waiters")
+	assert.Less(t, strings.Index(text, "| 9 | chan receive | 30m |"), strings.Index(text, "| 1 | chan receive | 16m |"))
+}
+
+func TestWriteMarkdownFrames(t *testing.T) {
+	stack := make([]model.StackFrame, 12)
+	for i := range stack {
+		stack[i] = model.StackFrame{FuncName: "main.f()", File: "main.go", Line: int32(i)}
+	}
+	var buf bytes.Buffer
+	assert.Nil(t, model.WriteMarkdown(&buf, []model.Goroutine{{ID: 1, Status: "a|b", StackTrace: stack}}))
+	assert.Equal(t, 8, strings.Count(buf.String(), "main.f()"))
+	assert.Contains(t, buf.String(), "... 4 more frames")
+	assert.Contains(t, buf.String(), `| a\|b | 1 |`)
+	assert.NotContains(t, buf.String(), "Longest waiters", "nothing waits")
+}
@@ -5,8 +5,18 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"regexp"
+	"runtime/debug"
 	"strconv"
 	"strings"
+	"time"
+)
+
+const (
+	// maxLineLength of a single line in a goroutine dump
+	maxLineLength = 4 * 1024 * 1024
+	// elidedFrames is printed by the runtime instead of frames of very deep stacks
+	elidedFrames = "...additional frames elided..."
 )
 
 // Goroutine info from pprof API. See: https://github.com/DataDog/go-profiler-notes/blob/main/goroutine.md
@@ -19,7 +29,79 @@ type Goroutine struct {
 	WaitSinceMin   int64
 	StackTrace     []StackFrame
 	CratedBy       *StackFrame // Only one frame long. Nill if not set
+	CreatorID      int64       // ID of the goroutine which created this one. Zero if unknown, which is the case before Go 1.21
+	Ancestors      []Ancestor  // Creator first. Only listed if the program runs with GODEBUG=tracebackancestors=N
 	LockedToThread bool
+	Labels         map[string]string // Profiler labels. Nil if unknown
+	Target         string            // Target the goroutine was fetched from
+}
+
+// Ancestor of a goroutine. The ancestor may have exited already.
+type Ancestor struct {
+	ID        int64
+	CreatedBy *StackFrame // Nil if not set
+}
+
+// Snapshot of all goroutines of a target at one point in time
+type Snapshot struct {
+	Target     string
+	Time       time.Time
+	Goroutines []Goroutine
+	Skipped    int                // Number of goroutines which could not be parsed
+	Build      *debug.BuildInfo   // Build info of the target. Nil if unknown
+	Source     string             // File the snapshot was read from. Empty if fetched
+	Profiles   []Profile          // Heap, block or mutex profiles fetched alongside the goroutines
+	Vars       map[string]float64 // Numeric expvars by name. Nested values are joined with a dot, e.g. memstats.NumGC
+}
+
+// SetTarget sets the target of the snapshot and all of its goroutines
+func (s *Snapshot) SetTarget(target string) {
+	s.Target = target
+	for i := range s.Goroutines {
+		s.Goroutines[i].Target = target
+	}
+}
+
+// Merge returns the goroutines of all snapshots in the order of the snapshots
+func Merge(snapshots []Snapshot) []Goroutine {
+	count := 0
+	for _, s := range snapshots {
+		count += len(s.Goroutines)
+	}
+	routines := make([]Goroutine, 0, count)
+	for _, s := range snapshots {
+		routines = append(routines, s.Goroutines...)
+	}
+	return routines
+}
+
+// ConnState is the state of the connection to a target
+type ConnState int
+
+const (
+	// Connected if the last fetch succeeded
+	Connected ConnState = iota
+	// Retrying after the last fetch failed
+	Retrying
+	// GaveUp after too many failed fetches. The target is not polled until a retry is requested.
+	GaveUp
+	// Disconnected program which pushed its snapshots. Its last snapshot is kept until it reconnects.
+	Disconnected
+)
+
+// FetchStatus describes the result of the last attempt to fetch a snapshot from a target
+type FetchStatus struct {
+	Target   string
+	Time     time.Time
+	State    ConnState
+	Failures int           // Number of consecutive failed fetches
+	Interval time.Duration // Effective interval until the next fetch
+	Latency  time.Duration // Duration of the last fetch
+	Err      error         // Error of the last fetch. Nil if successful
+	Timeout  bool          // The last fetch timed out
+	Gone     bool          // Target is not monitored anymore
+	Probe    bool          // Result of a health probe instead of a fetch. Err is nil if the target is up
+	Since    time.Time     // Time the target went up or down. Only set for probes
 }
 
 // StackContains returns true if string is included on one of the elements of the stack slice
@@ -32,6 +114,50 @@ func StackContains(sf []StackFrame, subString string) bool {
 	return false
 }
 
+// StackMatches returns true if the function or the position of one of the frames matches the regular expression
+func StackMatches(sf []StackFrame, re *regexp.Regexp) bool {
+	for _, s := range sf {
+		if s.Matches(re) {
+			return true
+		}
+	}
+	return false
+}
+
+// FrameScope selects the frames of a goroutine whose functions a filter matches
+type FrameScope int
+
+const (
+	// AnyFrame matches the functions of all frames of the stack
+	AnyFrame FrameScope = iota
+	// TopFrame matches the function which the goroutine runs or waits in
+	TopFrame
+	// CreatorFrame matches the function with the go statement which created the goroutine
+	CreatorFrame
+)
+
+// FuncMatches returns true if the function of one of the frames of the scope matches the regular expression. The
+// arguments of the functions are not matched.
+func (g Goroutine) FuncMatches(re *regexp.Regexp, scope FrameScope) bool {
+	switch scope {
+	case TopFrame:
+		return len(g.StackTrace) > 0 && re.MatchString(g.StackTrace[0].Func())
+	case CreatorFrame:
+		return g.CratedBy != nil && re.MatchString(g.CratedBy.Func())
+	}
+	for _, s := range g.StackTrace {
+		if re.MatchString(s.Func()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches returns true if the function or the position file:line +0xoffset matches the regular expression
+func (s StackFrame) Matches(re *regexp.Regexp) bool {
+	return re.MatchString(s.FuncName) || re.MatchString(s.Pos())
+}
+
 // StackFrame contains the info for one stack frame
 // See: https://dev.to/mcaci/reading-stack-traces-in-go-3ah5
 type StackFrame struct {
@@ -39,13 +165,47 @@ type StackFrame struct {
 	File     string
 	Line     int32
 	Position *int // Relative stack position. Not mandatory
+
+	Inlined       bool // Function was inlined by the compiler. Arguments are not available
+	Autogenerated bool // Wrapper function generated by the compiler which has no source file
+}
+
+// NewStackFrame creates a frame and detects inlined and autogenerated functions
+func NewStackFrame(funcName, file string, line int32, pos *int) StackFrame {
+	return StackFrame{
+		FuncName:      funcName,
+		File:          file,
+		Line:          line,
+		Position:      pos,
+		Inlined:       strings.HasSuffix(funcName, "(...)"),
+		Autogenerated: file == "<autogenerated>",
+	}
+}
+
+// Func returns the function name without the argument list
+func (s StackFrame) Func() string {
+	if strings.HasSuffix(s.FuncName, ")") {
+		if argsStart := strings.LastIndex(s.FuncName, "("); argsStart > 0 {
+			return s.FuncName[:argsStart]
+		}
+	}
+	return s.FuncName
 }
 
 func (s StackFrame) String() string {
-	return fmt.Sprintf("%s\n   file://%s#%d +0x%x", s.FuncName, s.File, s.Line, s.Position)
+	pos := ""
+	if s.Position != nil {
+		pos = fmt.Sprintf(" +0x%x", *s.Position)
+	}
+	if s.Autogenerated {
+		return fmt.Sprintf("%s\n   %s:%d%s", s.FuncName, s.File, s.Line, pos)
+	}
+	return fmt.Sprintf("%s\n   file://%s#%d%s", s.FuncName, s.File, s.Line, pos)
 }
 
+// ParseStackPos parses the position line of a stack frame.
 // For example /usr/local/go/src/net/http/server.go:2969 +0x970
+// The offset is missing for inlined frames. Additional fields like fp=0xc000 sp=0xc000 pc=0x44 are ignored.
 func ParseStackPos(text string) (fileName string, line int32, pos *int, err error) {
 	text = strings.TrimSpace(text)
 
@@ -55,26 +215,38 @@ func ParseStackPos(text string) (fileName string, line int32, pos *int, err erro
 	}
 
 	fileLineSep := strings.LastIndex(text, ":")
+	if fileLineSep < 0 {
+		err = fmt.Errorf("expected file and line separated by ':', but got: %s", text)
+		return
+	}
 
 	fileName = text[:fileLineSep]
 
-	linePosSep := strings.LastIndex(text, " ")
-	var lineStr string
-	if fileLineSep+1 >= linePosSep {
-		// Cannot parse stack pos for text. Keep default of nill
-		lineStr = text[fileLineSep+1:]
-	} else {
-		posInt64, errParse := strconv.ParseInt(text[linePosSep+4:], 16, 64)
-		if errParse != nil {
-			err = fmt.Errorf("could parse stack pos %s to line int. Error: %s", text, errParse.Error())
+	fields := strings.Fields(text[fileLineSep+1:])
+	if len(fields) == 0 {
+		err = fmt.Errorf("missing line number in %s", text)
+		return
+	}
+
+	for _, field := range fields[1:] {
+		switch {
+		case strings.HasPrefix(field, "+0x"):
+			posInt64, errParse := strconv.ParseInt(field[3:], 16, 64)
+			if errParse != nil {
+				err = fmt.Errorf("could parse stack pos %s to line int. Error: %s", text, errParse.Error())
+				return
+			}
+			posInt := int(posInt64)
+			pos = &posInt
+		case strings.Contains(field, "="):
+			// Register values printed with GOTRACEBACK=system
+		default:
+			err = fmt.Errorf("unexpected field %s in stack pos %s", field, text)
 			return
 		}
-		posInt := int(posInt64)
-		pos = &posInt
-		lineStr = text[fileLineSep+1 : linePosSep]
 	}
 
-	lineInt, errParse := strconv.ParseInt(lineStr, 10, 32)
+	lineInt, errParse := strconv.ParseInt(fields[0], 10, 32)
 	if errParse != nil {
 		err = fmt.Errorf("could parse line %s to line int. Err: %s", text, errParse.Error())
 		return
@@ -94,7 +266,23 @@ func ParseHeader(header string) (routine Goroutine, err error) {
 		err = fmt.Errorf("expected goroutine header, but got: %s", header[0:10])
 		return
 	}
+	if !strings.HasSuffix(header, "]:") {
+		err = fmt.Errorf("expected goroutine header to end with \"]:\", but got: %s", header)
+		return
+	}
 	separator := strings.Index(header[10:], " ")
+	stateStart := strings.Index(header, " [")
+	if separator <= 0 || stateStart < 10+separator {
+		err = fmt.Errorf("expected goroutine ID followed by state, but got: %s", header)
+		return
+	}
+	// Fields like gp=0xc000002380 m=0 are printed after the ID if the program crashes or receives SIGQUIT
+	for _, field := range strings.Fields(header[10+separator : stateStart]) {
+		if !strings.Contains(field, "=") {
+			err = fmt.Errorf("expected goroutine ID followed by state, but got: %s", header)
+			return
+		}
+	}
 
 	id, parseErr := strconv.ParseInt(header[10:10+separator], 10, 64)
 	if parseErr != nil {
@@ -103,7 +291,7 @@ func ParseHeader(header string) (routine Goroutine, err error) {
 	}
 
 	// Remove []:
-	fullState := header[12+separator : len(header)-2]
+	fullState := header[stateStart+2 : len(header)-2]
 	firstComma := strings.Index(fullState, ",")
 	var status string
 	lockedToThread := false
@@ -143,65 +331,142 @@ func ParseHeader(header string) (routine Goroutine, err error) {
 	return
 }
 
+// ParseResult contains all goroutines of a dump and the number of goroutine entries which had to be skipped
+type ParseResult struct {
+	Goroutines []Goroutine
+	Skipped    int
+}
+
 // ParseStackFrame reads full file and return all goroutines as slice
 func ParseStackFrame(reader io.Reader) (routines []Goroutine, err error) {
+	result, err := ParseDump(reader)
+	return result.Goroutines, err
+}
+
+// ParseDump reads full file and returns all goroutines which could be parsed.
+// A malformed goroutine entry is skipped and does not affect the following ones. The parser
+// resynchronizes on the next blank line or goroutine header.
+func ParseDump(reader io.Reader) (result ParseResult, err error) {
+	err = parseDumps(reader, false, func(r ParseResult) {
+		result = r
+	})
+	return
+}
+
+// ParseDumps parses consecutive dumps of the reader, for example the output of repeated SIGQUITs. A new dump
+// starts when a goroutine ID repeats. emit is called once per dump.
+func ParseDumps(reader io.Reader, emit func(result ParseResult)) error {
+	return parseDumps(reader, true, emit)
+}
+
+func parseDumps(reader io.Reader, split bool, emit func(result ParseResult)) error {
 	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+
+	var result ParseResult
+	seen := make(map[int64]bool)
+	block := make([]string, 0, 32)
+	flush := func() {
+		if len(block) == 0 {
+			return
+		}
+		header := block[0]
+		routine, parseErr := parseGoroutine(block)
+		block = block[:0]
+		if parseErr != nil {
+			log.Printf("Skip goroutine %q. Err: %s", header, parseErr.Error())
+			result.Skipped++
+			return
+		}
+		if split && seen[routine.ID] {
+			emit(result)
+			result = ParseResult{}
+			seen = make(map[int64]bool)
+		}
+		seen[routine.ID] = true
+		result.Goroutines = append(result.Goroutines, routine)
+	}
+
 	for scanner.Scan() {
 		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "goroutine "):
+			flush()
+			block = append(block, line)
+		case len(strings.TrimSpace(line)) == 0:
+			flush()
+		case len(block) > 0:
+			block = append(block, line)
+		default:
+			log.Printf("Ignore line outside of goroutine: %q", line)
+		}
+	}
+	flush()
+	if !split || len(result.Goroutines) > 0 || result.Skipped > 0 {
+		emit(result)
+	}
+	return scanner.Err()
+}
 
-		routine, err := ParseHeader(line)
-		if err != nil {
-			log.Printf("Failed to parse routine header. Err: %s", err.Error())
-			continue
+// parseGoroutine parses the header and stack of a single goroutine. Panics are recovered and returned as error.
+func parseGoroutine(lines []string) (routine Goroutine, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("recovered from panic: %v", r)
 		}
+	}()
 
-		routine.StackTrace = make([]StackFrame, 0, 8)
-		for scanner.Scan() {
-			traceLine := scanner.Text()
+	routine, err = ParseHeader(lines[0])
+	if err != nil {
+		return
+	}
 
-			if len(traceLine) == 0 {
-				break
+	routine.StackTrace = make([]StackFrame, 0, 8)
+	for i := 1; i < len(lines); i++ {
+		traceLine := lines[i]
+		if strings.TrimSpace(traceLine) == elidedFrames {
+			continue
+		}
+		// The frames of an ancestor are skipped. Only its creator is kept.
+		if idText, ok := strings.CutPrefix(traceLine, "[originating from goroutine "); ok {
+			id, parseErr := strconv.ParseInt(strings.TrimSuffix(idText, "]:"), 10, 64)
+			if parseErr != nil {
+				err = fmt.Errorf("failed to parse ancestor %q. Err: %s", traceLine, parseErr.Error())
+				return
 			}
+			routine.Ancestors = append(routine.Ancestors, Ancestor{ID: id})
+			continue
+		}
+		if i+1 >= len(lines) {
+			err = fmt.Errorf("unexpected end of goroutine after %q", traceLine)
+			return
+		}
+		i++
+		file, line, pos, parseErr := ParseStackPos(lines[i])
+		if parseErr != nil {
+			err = fmt.Errorf("failed to parse stack. Err: %s", parseErr.Error())
+			return
+		}
 
-			if strings.HasPrefix(traceLine, "created by ") {
-				if !scanner.Scan() {
-					log.Println("Unexpected end of file")
-					continue
-				}
-
-				file, line, pos, err := ParseStackPos(scanner.Text())
-				if err != nil {
-					log.Printf("Failed to parse created by stack. Err: %s", err.Error())
-					continue
-				}
-				routine.CratedBy = &StackFrame{
-					FuncName: traceLine[11:],
-					File:     file,
-					Line:     line,
-					Position: pos,
-				}
-			} else {
-				if !scanner.Scan() {
-					log.Println("Unexpected end of file")
-					continue
-				}
-				file, line, pos, err := ParseStackPos(scanner.Text())
-				if err != nil {
-					log.Printf("Failed to parse stack. Err: %s", err.Error())
-					continue
-				}
-				frame := StackFrame{
-					FuncName: traceLine,
-					File:     file,
-					Line:     line,
-					Position: pos,
+		switch {
+		case strings.HasPrefix(traceLine, "created by "):
+			// Since Go 1.21 the creator is followed by its ID, for example created by main.main in goroutine 1
+			funcName, creator, found := strings.Cut(traceLine[11:], " in goroutine ")
+			createdBy := NewStackFrame(funcName, file, line, pos)
+			if len(routine.Ancestors) > 0 {
+				routine.Ancestors[len(routine.Ancestors)-1].CreatedBy = &createdBy
+				continue
+			}
+			routine.CratedBy = &createdBy
+			if found {
+				if routine.CreatorID, err = strconv.ParseInt(creator, 10, 64); err != nil {
+					err = fmt.Errorf("failed to parse creator %q. Err: %s", traceLine, err.Error())
+					return
 				}
-				routine.StackTrace = append(routine.StackTrace, frame)
 			}
+		case len(routine.Ancestors) == 0:
+			routine.StackTrace = append(routine.StackTrace, NewStackFrame(traceLine, file, line, pos))
 		}
-		routines = append(routines, routine)
 	}
-
-	err = scanner.Err()
 	return
 }
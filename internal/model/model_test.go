@@ -1,6 +1,7 @@
 package model_test
 
 import (
+	"regexp"
 	"strings"
 	"testing"
 
@@ -155,6 +156,29 @@ func TestStackContains(t *testing.T) {
 	assert.False(t, model.StackContains(sf, "12"))
 }
 
+func TestStackMatches(t *testing.T) {
+	sf := []model.StackFrame{{FuncName: "main.(*Server).serve(0xc000010000)", File: "/src/server.go", Line: 42}}
+	assert.True(t, model.StackMatches(sf, regexp.MustCompile(`\(\*Server\)\.serve`)))
+	assert.True(t, model.StackMatches(sf, regexp.MustCompile(`server\.go:4\d`)))
+	assert.False(t, model.StackMatches(sf, regexp.MustCompile(`client\.go`)))
+}
+
+func TestFuncMatches(t *testing.T) {
+	g := model.Goroutine{
+		StackTrace: []model.StackFrame{
+			{FuncName: "sync.runtime_SemacquireMutex(0xc0000a0004, 0x0, 0x1)", File: "/go/src/runtime/sema.go", Line: 77},
+			{FuncName: "main.(*Cache).Get(0xc0000a0000)", File: "/src/cache.go", Line: 12},
+		},
+		CratedBy: &model.StackFrame{FuncName: "main.serve", File: "/src/main.go", Line: 30},
+	}
+	assert.True(t, g.FuncMatches(regexp.MustCompile(`Cache\)\.Get$`), model.AnyFrame))
+	assert.False(t, g.FuncMatches(regexp.MustCompile(`cache\.go`), model.AnyFrame))
+	assert.True(t, g.FuncMatches(regexp.MustCompile(`Semacquire`), model.TopFrame))
+	assert.False(t, g.FuncMatches(regexp.MustCompile(`Cache`), model.TopFrame))
+	assert.True(t, g.FuncMatches(regexp.MustCompile(`^main\.serve$`), model.CreatorFrame))
+	assert.False(t, model.Goroutine{}.FuncMatches(regexp.MustCompile(`.`), model.CreatorFrame))
+}
+
 func Test_ParseStackPos_Valid(t *testing.T) {
 	fileName, line, pos, err := model.ParseStackPos("C:/Program Files/Go/src/runtime/syscall_windows.go:356 +0xf2")
 	assert.Nil(t, err)
@@ -170,6 +194,8 @@ func Test_ParseHeader_Invalid(t *testing.T) {
 	assert.NotNil(t, err)
 	_, err = model.ParseHeader("goroutine0fd")
 	assert.NotNil(t, err)
+	_, err = model.ParseHeader("goroutine 1 main [running]:")
+	assert.NotNil(t, err)
 }
 
 func Test_ParseHeader_Valid(t *testing.T) {
@@ -192,6 +218,12 @@ func Test_ParseHeader_Valid(t *testing.T) {
 	assert.Equal(t, "chan receive", result.Status)
 	assert.Equal(t, int64(16), result.WaitSinceMin)
 	assert.Equal(t, true, result.LockedToThread)
+
+	result, err = model.ParseHeader("goroutine 7 gp=0xc000007a40 m=nil [IO wait, 2 minutes]:")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), result.ID)
+	assert.Equal(t, "IO wait", result.Status)
+	assert.Equal(t, int64(2), result.WaitSinceMin)
 }
 
 func Benchmark_ParseTrace(b *testing.B) {
@@ -211,3 +243,122 @@ func Benchmark_ParseHeader(b *testing.B) {
 		model.ParseHeader("goroutine 268 [runnable, locked to thread]:")
 	}
 }
+
+var trace_malformed = `goroutine 1 [running]:
+main.main()
+	/home/user/main.go:10 +0x1d
+
+goroutine 2 [select]:
+main.broken()
+	/home/user/main.go:not-a-line +0x1d
+main.caller()
+	/home/user/main.go:20 +0x2e
+goroutine 3 [chan receive, 2 minutes]:
+main.worker()
+	/home/user/main.go:30 +0x3f
+created by main.main
+	/home/user/main.go:11 +0x4a
+
+goroutine 4 [
+main.main()
+	/home/user/main.go:10 +0x1d
+
+goroutine 5 [IO wait]:
+main.truncated()
+
+goroutine 6 [runnable]:
+main.deep()
+	/home/user/main.go:40 +0x5b
+...additional frames elided...
+created by main.main
+	/home/user/main.go:12 +0x6c
+`
+
+func TestParseDumpSkipsMalformed(t *testing.T) {
+	result, err := model.ParseDump(strings.NewReader(trace_malformed))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, result.Skipped)
+	assert.Len(t, result.Goroutines, 3)
+
+	assert.Equal(t, int64(1), result.Goroutines[0].ID)
+	assert.Len(t, result.Goroutines[0].StackTrace, 1)
+
+	r3 := result.Goroutines[1]
+	assert.Equal(t, int64(3), r3.ID)
+	assert.Equal(t, "chan receive", r3.Status)
+	assert.Equal(t, int64(2), r3.WaitSinceMin)
+	assert.Equal(t, "main.main", r3.CratedBy.FuncName)
+
+	r6 := result.Goroutines[2]
+	assert.Equal(t, int64(6), r6.ID)
+	assert.Len(t, r6.StackTrace, 1)
+	assert.Equal(t, int32(12), r6.CratedBy.Line)
+}
+
+func TestParseDumpIgnoresNoise(t *testing.T) {
+	result, err := model.ParseDump(strings.NewReader("SIGQUIT: quit\nPC=0x46b0c1 m=0 sigcode=0\n\n" + trace_2))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, result.Skipped)
+	assert.Len(t, result.Goroutines, 1)
+}
+
+func TestParseDumps(t *testing.T) {
+	dumps := "SIGQUIT: quit\n\n" + trace_2 + "\n\n" + trace_inlined + "\n\nSIGQUIT: quit\n\n" + trace_2 + "\n"
+	var results []model.ParseResult
+	err := model.ParseDumps(strings.NewReader(dumps), func(result model.ParseResult) {
+		results = append(results, result)
+	})
+	assert.Nil(t, err)
+	assert.Len(t, results, 2)
+	assert.Len(t, results[0].Goroutines, 2)
+	assert.Len(t, results[1].Goroutines, 1)
+
+	results = nil
+	err = model.ParseDumps(strings.NewReader(""), func(result model.ParseResult) {
+		results = append(results, result)
+	})
+	assert.Nil(t, err)
+	assert.Empty(t, results)
+}
+
+var trace_inlined = `goroutine 7 [select]:
+main.(*worker).loop(...)
+	/home/user/worker.go:52
+main.(*worker).run(0xc0000a2000)
+	/home/user/worker.go:40 +0x1d fp=0xc000051fd0 sp=0xc000051f98 pc=0x4a3b1d
+main.(*worker).run-fm()
+	<autogenerated>:1 +0x25
+created by main.start
+	<autogenerated>:1`
+
+func TestParseInlinedAndAutogenerated(t *testing.T) {
+	result, err := model.ParseDump(strings.NewReader(trace_inlined))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, result.Skipped)
+	assert.Len(t, result.Goroutines, 1)
+
+	r := result.Goroutines[0]
+	assert.Len(t, r.StackTrace, 3)
+	assert.True(t, r.StackTrace[0].Inlined)
+	assert.Nil(t, r.StackTrace[0].Position)
+	assert.Equal(t, int32(52), r.StackTrace[0].Line)
+	assert.False(t, r.StackTrace[1].Inlined)
+	assert.Equal(t, 0x1d, *r.StackTrace[1].Position)
+	assert.True(t, r.StackTrace[2].Autogenerated)
+	assert.True(t, r.CratedBy.Autogenerated)
+	assert.Equal(t, int32(1), r.CratedBy.Line)
+	assert.Equal(t, "main.start", r.CratedBy.FuncName)
+}
+
+func TestMergeSnapshots(t *testing.T) {
+	a := model.Snapshot{Goroutines: []model.Goroutine{{ID: 1}, {ID: 2}}}
+	a.SetTarget("a:6060")
+	b := model.Snapshot{Goroutines: []model.Goroutine{{ID: 1}}}
+	b.SetTarget("b:6060")
+
+	routines := model.Merge([]model.Snapshot{a, b})
+	assert.Len(t, routines, 3)
+	assert.Equal(t, "a:6060", routines[0].Target)
+	assert.Equal(t, "a:6060", routines[1].Target)
+	assert.Equal(t, "b:6060", routines[2].Target)
+}
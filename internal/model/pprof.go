@@ -0,0 +1,62 @@
+package model
+
+import (
+	"io"
+
+	"github.com/google/pprof/profile"
+)
+
+// frameKey identifies the location of a frame in a pprof profile
+type frameKey struct {
+	function string
+	file     string
+	line     int32
+}
+
+// toPprof converts the goroutines into a goroutine profile of pprof. Every goroutine is one sample with a count of
+// one, so pprof sums up the goroutines of a stack. Profiler labels become labels of the sample.
+func toPprof(routines []Goroutine) *profile.Profile {
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "goroutine", Unit: "count"}},
+		PeriodType: &profile.ValueType{Type: "goroutine", Unit: "count"},
+		Period:     1,
+	}
+	functions := make(map[frameKey]*profile.Function)
+	locations := make(map[frameKey]*profile.Location)
+	location := func(frame StackFrame) *profile.Location {
+		key := frameKey{function: frame.Func(), file: frame.File, line: frame.Line}
+		if l, ok := locations[key]; ok {
+			return l
+		}
+		fnKey := frameKey{function: key.function, file: key.file}
+		fn, ok := functions[fnKey]
+		if !ok {
+			fn = &profile.Function{ID: uint64(len(p.Function) + 1), Name: key.function, SystemName: key.function, Filename: key.file}
+			functions[fnKey] = fn
+			p.Function = append(p.Function, fn)
+		}
+		l := &profile.Location{ID: uint64(len(p.Location) + 1), Line: []profile.Line{{Function: fn, Line: int64(key.line)}}}
+		locations[key] = l
+		p.Location = append(p.Location, l)
+		return l
+	}
+	for _, g := range routines {
+		sample := &profile.Sample{Value: []int64{1}}
+		for _, frame := range g.StackTrace {
+			sample.Location = append(sample.Location, location(frame))
+		}
+		if len(g.Labels) > 0 {
+			sample.Label = make(map[string][]string, len(g.Labels))
+			for key, value := range g.Labels {
+				sample.Label[key] = []string{value}
+			}
+		}
+		p.Sample = append(p.Sample, sample)
+	}
+	return p
+}
+
+// WritePprof writes the goroutines as gzip compressed pprof profile, which go tool pprof and speedscope open
+func WritePprof(w io.Writer, routines []Goroutine) error {
+	return toPprof(routines).Write(w)
+}
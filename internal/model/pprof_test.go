@@ -0,0 +1,31 @@
+package model_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWritePprof(t *testing.T) {
+	routines, err := model.ParseStackFrame(strings.NewReader(trace_1))
+	assert.Nil(t, err)
+	routines = append(routines, routines[1])
+	routines[2].Labels = map[string]string{"tenant": "acme"}
+	var buf bytes.Buffer
+	assert.Nil(t, model.WritePprof(&buf, routines))
+
+	p, err := profile.Parse(&buf)
+	assert.Nil(t, err)
+	assert.Nil(t, p.CheckValid())
+	assert.Equal(t, "goroutine", p.SampleType[0].Type)
+	assert.Len(t, p.Sample, len(routines))
+	assert.Equal(t, p.Sample[1].Location, p.Sample[len(routines)-1].Location, "locations of equal frames are shared")
+	top := p.Sample[2].Location[0].Line[0]
+	assert.Equal(t, routines[2].StackTrace[0].Func(), top.Function.Name)
+	assert.Equal(t, int64(485), top.Line)
+	assert.Equal(t, []string{"acme"}, p.Sample[2].Label["tenant"])
+}
@@ -0,0 +1,40 @@
+package model
+
+// Profile is a heap, block or mutex profile of a target
+type Profile struct {
+	Name        string       // Name of the profile, for example heap
+	SampleTypes []SampleType // Type of each value of the samples
+	Samples     []Sample
+}
+
+// SampleType describes one value of the samples, for example inuse_space in bytes
+type SampleType struct {
+	Type string
+	Unit string
+}
+
+// Sample of a profile. The values match the sample types of the profile.
+type Sample struct {
+	Values []int64
+	Stack  []StackFrame // Innermost frame first
+}
+
+// Total returns the sum of the values of the sample type. Returns 0 if the profile has no such sample type.
+func (p Profile) Total(sampleType string) int64 {
+	idx := -1
+	for i, t := range p.SampleTypes {
+		if t.Type == sampleType {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return 0
+	}
+	var total int64
+	for _, s := range p.Samples {
+		if idx < len(s.Values) {
+			total += s.Values[idx]
+		}
+	}
+	return total
+}
@@ -0,0 +1,21 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfileTotal(t *testing.T) {
+	p := model.Profile{
+		SampleTypes: []model.SampleType{{Type: "contentions", Unit: "count"}, {Type: "delay", Unit: "nanoseconds"}},
+		Samples: []model.Sample{
+			{Values: []int64{2, 100}},
+			{Values: []int64{3, 50}},
+		},
+	}
+	assert.Equal(t, int64(5), p.Total("contentions"))
+	assert.Equal(t, int64(150), p.Total("delay"))
+	assert.Equal(t, int64(0), p.Total("inuse_space"))
+}
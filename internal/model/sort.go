@@ -0,0 +1,85 @@
+package model
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// SortKey is the column the goroutines are sorted by
+type SortKey int
+
+const (
+	// SortNone keeps the order of the dump
+	SortNone SortKey = iota
+	// SortByID sorts by goroutine ID
+	SortByID
+	// SortByStatus sorts by status and wait reason
+	SortByStatus
+	// SortByWait sorts by the minutes the goroutine is blocked
+	SortByWait
+	// SortByFunction sorts by the function on top of the stack
+	SortByFunction
+	// SortByDepth sorts by the number of stack frames
+	SortByDepth
+)
+
+// SortKeys contains all keys in the order they are cycled through
+var SortKeys = []SortKey{SortNone, SortByID, SortByStatus, SortByWait, SortByFunction, SortByDepth}
+
+var sortKeyNames = map[SortKey]string{
+	SortNone:       "none",
+	SortByID:       "id",
+	SortByStatus:   "status",
+	SortByWait:     "wait",
+	SortByFunction: "function",
+	SortByDepth:    "depth",
+}
+
+func (k SortKey) String() string {
+	return sortKeyNames[k]
+}
+
+// ParseSortKey returns the key of the name, for example wait
+func ParseSortKey(name string) (SortKey, error) {
+	for _, key := range SortKeys {
+		if sortKeyNames[key] == name {
+			return key, nil
+		}
+	}
+	return SortNone, fmt.Errorf("unknown sort %s. Expected none, id, status, wait, function or depth", name)
+}
+
+// Sort the goroutines by the key. Goroutines with equal keys keep their order.
+func Sort(routines []Goroutine, key SortKey, descending bool) {
+	if key == SortNone {
+		return
+	}
+	slices.SortStableFunc(routines, func(a, b Goroutine) int {
+		var c int
+		switch key {
+		case SortByID:
+			c = cmp.Compare(a.ID, b.ID)
+		case SortByStatus:
+			c = cmp.Compare(a.Status, b.Status)
+		case SortByWait:
+			c = cmp.Compare(a.WaitSinceMin, b.WaitSinceMin)
+		case SortByFunction:
+			c = cmp.Compare(a.TopFunc(), b.TopFunc())
+		case SortByDepth:
+			c = cmp.Compare(len(a.StackTrace), len(b.StackTrace))
+		}
+		if descending {
+			return -c
+		}
+		return c
+	})
+}
+
+// TopFunc returns the function on top of the stack without its arguments. Empty if the stack is unknown.
+func (g Goroutine) TopFunc() string {
+	if len(g.StackTrace) == 0 {
+		return ""
+	}
+	return g.StackTrace[0].Func()
+}
@@ -0,0 +1,49 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func ids(routines []model.Goroutine) []int64 {
+	result := make([]int64, len(routines))
+	for i, g := range routines {
+		result[i] = g.ID
+	}
+	return result
+}
+
+func TestSort(t *testing.T) {
+	frame := func(name string) model.StackFrame { return model.StackFrame{FuncName: name + "(0x1)"} }
+	routines := []model.Goroutine{
+		{ID: 3, Status: "select", WaitSinceMin: 5, StackTrace: []model.StackFrame{frame("b.B")}},
+		{ID: 1, Status: "running", StackTrace: []model.StackFrame{frame("c.C"), frame("a.A")}},
+		{ID: 2, Status: "IO wait", WaitSinceMin: 12, StackTrace: []model.StackFrame{frame("a.A"), frame("b.B"), frame("c.C")}},
+	}
+
+	model.Sort(routines, model.SortNone, true)
+	assert.Equal(t, []int64{3, 1, 2}, ids(routines))
+	model.Sort(routines, model.SortByID, false)
+	assert.Equal(t, []int64{1, 2, 3}, ids(routines))
+	model.Sort(routines, model.SortByID, true)
+	assert.Equal(t, []int64{3, 2, 1}, ids(routines))
+	model.Sort(routines, model.SortByStatus, false)
+	assert.Equal(t, []int64{2, 1, 3}, ids(routines))
+	model.Sort(routines, model.SortByWait, true)
+	assert.Equal(t, []int64{2, 3, 1}, ids(routines))
+	model.Sort(routines, model.SortByFunction, false)
+	assert.Equal(t, []int64{2, 3, 1}, ids(routines))
+	model.Sort(routines, model.SortByDepth, false)
+	assert.Equal(t, []int64{3, 1, 2}, ids(routines))
+	assert.Equal(t, "wait", model.SortByWait.String())
+}
+
+func TestParseSortKey(t *testing.T) {
+	key, err := model.ParseSortKey("wait")
+	assert.NoError(t, err)
+	assert.Equal(t, model.SortByWait, key)
+	_, err = model.ParseSortKey("age")
+	assert.Error(t, err)
+}
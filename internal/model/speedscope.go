@@ -0,0 +1,101 @@
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// speedscopeSchema is the URL of the JSON schema of the speedscope file format
+const speedscopeSchema = "https://www.speedscope.app/file-format-schema.json"
+
+// speedscopeFile is the file format of speedscope
+type speedscopeFile struct {
+	Schema             string              `json:"$schema"`
+	Shared             speedscopeShared    `json:"shared"`
+	Profiles           []speedscopeProfile `json:"profiles"`
+	Name               string              `json:"name"`
+	ActiveProfileIndex int                 `json:"activeProfileIndex"`
+	Exporter           string              `json:"exporter"`
+}
+
+type speedscopeShared struct {
+	Frames []speedscopeFrame `json:"frames"`
+}
+
+type speedscopeFrame struct {
+	Name string `json:"name"`
+	File string `json:"file,omitempty"`
+	Line int32  `json:"line,omitempty"`
+}
+
+type speedscopeProfile struct {
+	Type       string  `json:"type"`
+	Name       string  `json:"name"`
+	Unit       string  `json:"unit"`
+	StartValue int     `json:"startValue"`
+	EndValue   int     `json:"endValue"`
+	Samples    [][]int `json:"samples"`
+	Weights    []int   `json:"weights"`
+}
+
+// WriteSpeedscope writes the goroutines in the JSON format of speedscope, whose web UI shows them as flame graph,
+// sandwich and left heavy view. Every target becomes a profile in which every distinct stack is one sample weighted
+// with the number of goroutines in it. Goroutines without frames are left out.
+func WriteSpeedscope(w io.Writer, routines []Goroutine) error {
+	file := speedscopeFile{Schema: speedscopeSchema, Name: "roumon goroutines", Exporter: "roumon"}
+	frames := make(map[frameKey]int)
+	profiles := make(map[string]int)
+	samples := make(map[string]map[string]int) // Index of the sample of a stack by target
+	for _, g := range routines {
+		if len(g.StackTrace) == 0 {
+			continue
+		}
+		p, ok := profiles[g.Target]
+		if !ok {
+			name := g.Target
+			if len(name) == 0 {
+				name = "goroutines"
+			}
+			p = len(file.Profiles)
+			profiles[g.Target] = p
+			samples[g.Target] = make(map[string]int)
+			file.Profiles = append(file.Profiles, speedscopeProfile{Type: "sampled", Name: name, Unit: "none",
+				Samples: [][]int{}, Weights: []int{}})
+		}
+		profile := &file.Profiles[p]
+		profile.EndValue++
+
+		// speedscope lists the frames of a sample outermost first
+		stack := make([]int, len(g.StackTrace))
+		var key strings.Builder
+		for i, frame := range g.StackTrace {
+			fk := frameKey{function: frame.Func(), file: frame.File, line: frame.Line}
+			index, ok := frames[fk]
+			if !ok {
+				index = len(file.Shared.Frames)
+				frames[fk] = index
+				file.Shared.Frames = append(file.Shared.Frames, speedscopeFrame{Name: fk.function, File: fk.file, Line: fk.line})
+			}
+			stack[len(stack)-1-i] = index
+			key.WriteString(strconv.Itoa(index))
+			key.WriteByte(',')
+		}
+		if s, ok := samples[g.Target][key.String()]; ok {
+			profile.Weights[s]++
+			continue
+		}
+		samples[g.Target][key.String()] = len(profile.Samples)
+		profile.Samples = append(profile.Samples, stack)
+		profile.Weights = append(profile.Weights, 1)
+	}
+	if file.Profiles == nil {
+		file.Profiles = []speedscopeProfile{}
+	}
+	if file.Shared.Frames == nil {
+		file.Shared.Frames = []speedscopeFrame{}
+	}
+	// Not indented, since the samples would take one line per frame
+	return json.NewEncoder(w).Encode(file)
+}
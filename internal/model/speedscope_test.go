@@ -0,0 +1,61 @@
+package model_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteSpeedscope(t *testing.T) {
+	main := model.StackFrame{FuncName: "main.main()", File: "/src/main.go", Line: 10}
+	serve := model.StackFrame{FuncName: "main.serve(0x1)", File: "/src/main.go", Line: 20}
+	read := model.StackFrame{FuncName: "net.(*conn).Read(0x2)", File: "/go/src/net/net.go", Line: 30}
+	routines := []model.Goroutine{
+		{ID: 1, Target: "a", StackTrace: []model.StackFrame{read, serve, main}},
+		{ID: 2, Target: "a", StackTrace: []model.StackFrame{serve, main}},
+		{ID: 3, Target: "a", StackTrace: []model.StackFrame{read, serve, main}},
+		{ID: 4, Target: "a"},
+		{ID: 1, Target: "b", StackTrace: []model.StackFrame{main}},
+	}
+	var buf bytes.Buffer
+	assert.Nil(t, model.WriteSpeedscope(&buf, routines))
+
+	var file struct {
+		Schema string `json:"$schema"`
+		Shared struct {
+			Frames []struct {
+				Name string
+				File string
+				Line int
+			}
+		}
+		Profiles []struct {
+			Type     string
+			Name     string
+			EndValue int
+			Samples  [][]int
+			Weights  []int
+		}
+	}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &file))
+	assert.Equal(t, "https://www.speedscope.app/file-format-schema.json", file.Schema)
+	assert.Len(t, file.Shared.Frames, 3)
+	assert.Equal(t, "net.(*conn).Read", file.Shared.Frames[0].Name)
+	assert.Equal(t, "/go/src/net/net.go", file.Shared.Frames[0].File)
+	assert.Equal(t, 30, file.Shared.Frames[0].Line)
+
+	assert.Len(t, file.Profiles, 2)
+	assert.Equal(t, "sampled", file.Profiles[0].Type)
+	assert.Equal(t, "a", file.Profiles[0].Name)
+	assert.Equal(t, [][]int{{2, 1, 0}, {2, 1}}, file.Profiles[0].Samples, "outermost frame first")
+	assert.Equal(t, []int{2, 1}, file.Profiles[0].Weights)
+	assert.Equal(t, 3, file.Profiles[0].EndValue)
+	assert.Equal(t, [][]int{{2}}, file.Profiles[1].Samples)
+
+	buf.Reset()
+	assert.Nil(t, model.WriteSpeedscope(&buf, nil))
+	assert.Contains(t, buf.String(), `"profiles":[]`)
+}
@@ -0,0 +1,111 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// StatusCount is the number of goroutines with a status
+type StatusCount struct {
+	Status string
+	Count  int
+}
+
+// TemplateData is the data which WriteTemplate executes a template with. The fields and methods of Snapshot,
+// Goroutine, StackFrame and StackGroup are available in the template.
+type TemplateData struct {
+	Generated  time.Time
+	Snapshots  []Snapshot  // Latest snapshot of every target
+	Goroutines []Goroutine // Goroutines of all snapshots
+}
+
+// templateFuncs are the functions of a template in addition to the built-in functions of text/template
+var templateFuncs = template.FuncMap{
+	"groups":   GroupByStack,
+	"statuses": CountStatuses,
+	"waiters":  waiters,
+	"head":     head,
+	"json":     toJSON,
+	"join":     strings.Join,
+	"lower":    strings.ToLower,
+	"upper":    strings.ToUpper,
+	"replace":  strings.ReplaceAll,
+	"pad":      pad,
+}
+
+// CountStatuses counts the goroutines of every status, most frequent first. Statuses with the same count are
+// ordered by name.
+func CountStatuses(routines []Goroutine) []StatusCount {
+	counts := make(map[string]int)
+	for _, g := range routines {
+		counts[g.Status]++
+	}
+	statuses := make([]StatusCount, 0, len(counts))
+	for status, count := range counts {
+		statuses = append(statuses, StatusCount{Status: status, Count: count})
+	}
+	slices.SortFunc(statuses, func(a, b StatusCount) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.Status, b.Status)
+	})
+	return statuses
+}
+
+// waiters returns the goroutines which wait, longest first
+func waiters(routines []Goroutine) []Goroutine {
+	waiting := slices.DeleteFunc(slices.Clone(routines), func(g Goroutine) bool { return g.WaitSinceMin == 0 })
+	slices.SortStableFunc(waiting, func(a, b Goroutine) int { return int(b.WaitSinceMin - a.WaitSinceMin) })
+	return waiting
+}
+
+// head returns the first n elements of the slice, or all of them if there are fewer
+func head(n int, list any) (any, error) {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("head of %s, expected a list", v.Kind())
+	}
+	return v.Slice(0, max(0, min(n, v.Len()))).Interface(), nil
+}
+
+func toJSON(v any) (string, error) {
+	content, err := json.Marshal(v)
+	return string(content), err
+}
+
+// pad fills the text with spaces on the right up to the width. Negative widths fill on the left.
+func pad(width int, text string) string {
+	return fmt.Sprintf("%*s", -width, text)
+}
+
+// ParseTemplate reads a text/template file for WriteTemplate. Besides the built-in functions the template calls
+// groups, statuses and waiters with a list of goroutines, head n list, json, join, lower, upper, replace and
+// pad width text. Referring to a field which does not exist is an error, while a missing key of a map, such as a
+// label which a goroutine does not have, is empty.
+func ParseTemplate(path string) (*template.Template, error) {
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs).Option("missingkey=zero").ParseFiles(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template. Err: %s", err.Error())
+	}
+	return tmpl, nil
+}
+
+// WriteTemplate executes the template with the snapshots. See TemplateData for the data of the template.
+func WriteTemplate(w io.Writer, tmpl *template.Template, snapshots []Snapshot, generated time.Time) error {
+	data := TemplateData{Generated: generated, Snapshots: snapshots, Goroutines: Merge(snapshots)}
+	// The output is buffered so that a failing template does not write half of it
+	var text strings.Builder
+	if err := tmpl.Execute(&text, data); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, text.String())
+	return err
+}
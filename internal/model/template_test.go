@@ -0,0 +1,67 @@
+package model_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTemplate(t *testing.T, text string, snapshots []model.Snapshot) (string, error) {
+	path := filepath.Join(t.TempDir(), "report.tmpl")
+	assert.Nil(t, os.WriteFile(path, []byte(text), 0644))
+	tmpl, err := model.ParseTemplate(path)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	err = model.WriteTemplate(&out, tmpl, snapshots, time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC))
+	return out.String(), err
+}
+
+func TestWriteTemplate(t *testing.T) {
+	serve := []model.StackFrame{{FuncName: "main.serve(0x1)", File: "/src/main.go", Line: 20}}
+	snapshots := []model.Snapshot{
+		{Target: "a", Goroutines: []model.Goroutine{
+			{ID: 1, Status: "running", Target: "a", StackTrace: []model.StackFrame{{FuncName: "main.main()"}}},
+			{ID: 2, Status: "select", Target: "a", WaitSinceMin: 3, StackTrace: serve},
+		}},
+		{Target: "b", Goroutines: []model.Goroutine{
+			{ID: 1, Status: "select", Target: "b", WaitSinceMin: 12, StackTrace: serve, Labels: map[string]string{"tenant": "acme"}},
+		}},
+	}
+	out, err := writeTemplate(t, `{{.Generated.Format "2006-01-02"}} {{len .Goroutines}}
+{{range .Snapshots}}{{.Target}}={{len .Goroutines}} {{end}}
+{{range statuses .Goroutines}}{{pad 8 .Status}}|{{.Count}}
+{{end}}{{range head 1 (groups .Goroutines)}}{{len .Goroutines}} {{.Func}} {{.WaitRange}} {{(index .Goroutines 0).TopFunc}}
+{{end}}{{range waiters .Goroutines}}{{.Target}}/{{.ID}} {{upper .Status}} {{json .Labels}}
+{{end}}`, snapshots)
+	assert.Nil(t, err)
+	assert.Equal(t, `2024-01-02 3
+a=2 b=1 
+select  |2
+running |1
+2 main.serve 3-12m main.serve
+b/1 SELECT {"tenant":"acme"}
+a/2 SELECT null
+`, out)
+}
+
+func TestWriteTemplateErrors(t *testing.T) {
+	_, err := writeTemplate(t, "{{.Goroutines", nil)
+	assert.ErrorContains(t, err, "failed to parse template")
+	out, err := writeTemplate(t, "before {{.Missing}}", nil)
+	assert.Error(t, err, "unknown fields are an error")
+	assert.Empty(t, out, "nothing is written if the template fails")
+	out, err = writeTemplate(t, "{{range .Goroutines}}[{{.Labels.tenant}}]{{end}}", []model.Snapshot{{Goroutines: []model.Goroutine{
+		{ID: 1}, {ID: 2, Labels: map[string]string{"tenant": "acme"}},
+	}}})
+	assert.Nil(t, err)
+	assert.Equal(t, "[][acme]", out, "missing labels are empty")
+	_, err = writeTemplate(t, "{{head 1 .Generated}}", nil)
+	assert.ErrorContains(t, err, "expected a list")
+}
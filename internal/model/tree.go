@@ -0,0 +1,93 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+)
+
+// maxCreatorDepth guards against endless chains of creators
+const maxCreatorDepth = 64
+
+// CreatorNode is a spawn site in the tree of creators. The goroutines of the node were created at the site by a
+// goroutine which was created at the site of the parent node.
+type CreatorNode struct {
+	Key        string      // Sites from the root to the node. Unique in the tree
+	Site       *StackFrame // Nil for the goroutines without creator, such as the main goroutine
+	Goroutines []Goroutine // Goroutines created at the site
+	Children   []*CreatorNode
+	Total      int // Goroutines of the node and all of its descendants
+}
+
+// CreatorTree builds the tree of spawn sites. The site of the creating goroutine is found by the creator ID, which
+// is printed since Go 1.21, or by the ancestors if the program runs with GODEBUG=tracebackancestors=N. The
+// ancestors also restore the sites of creators which exited already. Without both, all sites are roots. Larger
+// subtrees come first. Sites of the same size keep the order of their first goroutine.
+func CreatorTree(routines []Goroutine) []*CreatorNode {
+	byID := make(map[string]Goroutine, len(routines))
+	for _, g := range routines {
+		byID[goroutineKey(g.Target, g.ID)] = g
+	}
+	root := &CreatorNode{}
+	for _, g := range routines {
+		node := root
+		for _, site := range creatorSites(g, byID, 0) {
+			node = node.child(&site)
+		}
+		if g.CratedBy == nil {
+			node = node.child(nil)
+		}
+		node.Goroutines = append(node.Goroutines, g)
+	}
+	root.count()
+	return root.Children
+}
+
+// creatorSites returns the sites from the first known creator to the site of the goroutine
+func creatorSites(g Goroutine, byID map[string]Goroutine, depth int) []StackFrame {
+	if g.CratedBy == nil {
+		return nil
+	}
+	var sites []StackFrame
+	if creator, ok := byID[goroutineKey(g.Target, g.CreatorID)]; ok && g.CreatorID != 0 && depth < maxCreatorDepth {
+		sites = creatorSites(creator, byID, depth+1)
+	} else {
+		for i := len(g.Ancestors) - 1; i >= 0; i-- {
+			if site := g.Ancestors[i].CreatedBy; site != nil {
+				sites = append(sites, *site)
+			}
+		}
+	}
+	return append(sites, *g.CratedBy)
+}
+
+func goroutineKey(target string, id int64) string {
+	return fmt.Sprintf("%s/%d", target, id)
+}
+
+// child returns the child of the site and adds it if it does not exist yet
+func (n *CreatorNode) child(site *StackFrame) *CreatorNode {
+	key := n.Key + ">"
+	if site != nil {
+		key += fmt.Sprintf("%s:%s:%d", site.Func(), site.File, site.Line)
+	}
+	for _, c := range n.Children {
+		if c.Key == key {
+			return c
+		}
+	}
+	c := &CreatorNode{Key: key, Site: site}
+	n.Children = append(n.Children, c)
+	return c
+}
+
+// count sums up the goroutines of the subtree and sorts the children by their size
+func (n *CreatorNode) count() int {
+	n.Total = len(n.Goroutines)
+	for _, c := range n.Children {
+		n.Total += c.count()
+	}
+	slices.SortStableFunc(n.Children, func(a, b *CreatorNode) int {
+		return b.Total - a.Total
+	})
+	return n.Total
+}
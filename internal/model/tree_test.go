@@ -0,0 +1,113 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// Dump of a program with GODEBUG=tracebackancestors=5. Goroutine 8 exited after creating goroutine 9.
+var trace_ancestors = `goroutine 1 [running]:
+main.main()
+	/tmp/anc/main.go:23 +0x5d
+
+goroutine 7 [sleep]:
+time.Sleep(0x34630b8a000)
+	/usr/local/go/src/runtime/time.go:368 +0x165
+main.spawner()
+	/tmp/anc/main.go:13 +0x29
+created by main.main in goroutine 1
+	/tmp/anc/main.go:19 +0x1e
+[originating from goroutine 1]:
+main.main(...)
+	/tmp/anc/main.go:20 +0x1e
+
+goroutine 10 [sleep]:
+time.Sleep(0x34630b8a000)
+	/usr/local/go/src/runtime/time.go:368 +0x165
+main.leaf()
+	/tmp/anc/main.go:9 +0x1d
+created by main.spawner in goroutine 7
+	/tmp/anc/main.go:12 +0x1a
+[originating from goroutine 7]:
+main.spawner(...)
+	/tmp/anc/main.go:13 +0x1a
+created by main.main
+	/tmp/anc/main.go:19 +0x1e
+[originating from goroutine 1]:
+main.main(...)
+	/tmp/anc/main.go:20 +0x1e
+
+goroutine 9 [sleep]:
+time.Sleep(0x34630b8a000)
+	/usr/local/go/src/runtime/time.go:368 +0x165
+main.leaf()
+	/tmp/anc/main.go:9 +0x1d
+created by main.shortLived in goroutine 8
+	/tmp/anc/main.go:16 +0x1a
+[originating from goroutine 8]:
+main.shortLived(...)
+	/tmp/anc/main.go:16 +0x1a
+created by main.main
+	/tmp/anc/main.go:20 +0x2a
+[originating from goroutine 1]:
+main.main(...)
+	/tmp/anc/main.go:21 +0x2a
+`
+
+func TestParseCreatorAndAncestors(t *testing.T) {
+	result, err := model.ParseDump(strings.NewReader(trace_ancestors))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, result.Skipped)
+	assert.Len(t, result.Goroutines, 4)
+
+	r9 := result.Goroutines[3]
+	assert.Len(t, r9.StackTrace, 2)
+	assert.Equal(t, "main.shortLived", r9.CratedBy.FuncName)
+	assert.Equal(t, int64(8), r9.CreatorID)
+	assert.Len(t, r9.Ancestors, 2)
+	assert.Equal(t, int64(8), r9.Ancestors[0].ID)
+	assert.Equal(t, "main.main", r9.Ancestors[0].CreatedBy.FuncName)
+	assert.Equal(t, int32(20), r9.Ancestors[0].CreatedBy.Line)
+	assert.Equal(t, int64(1), r9.Ancestors[1].ID)
+	assert.Nil(t, r9.Ancestors[1].CreatedBy)
+}
+
+func TestCreatorTree(t *testing.T) {
+	result, err := model.ParseDump(strings.NewReader(trace_ancestors))
+	assert.Nil(t, err)
+
+	roots := model.CreatorTree(result.Goroutines)
+	assert.Len(t, roots, 3)
+	// Goroutine 7 and its child 10
+	assert.Equal(t, "main.main", roots[0].Site.Func())
+	assert.Equal(t, int32(19), roots[0].Site.Line)
+	assert.Equal(t, 2, roots[0].Total)
+	assert.Len(t, roots[0].Children, 1)
+	assert.Equal(t, int64(10), roots[0].Children[0].Goroutines[0].ID)
+	// Main goroutine comes before the smaller sites which occur later
+	assert.Nil(t, roots[1].Site)
+	assert.Equal(t, int64(1), roots[1].Goroutines[0].ID)
+	// Goroutine 9 of the exited goroutine 8
+	assert.Equal(t, int32(20), roots[2].Site.Line)
+	assert.Empty(t, roots[2].Goroutines)
+	assert.Equal(t, 1, roots[2].Total)
+	assert.Equal(t, int64(9), roots[2].Children[0].Goroutines[0].ID)
+}
+
+func TestCreatorTreeWithoutCreatorIDs(t *testing.T) {
+	creator := &model.StackFrame{FuncName: "main.main", File: "/src/main.go", Line: 10}
+	routines := []model.Goroutine{
+		{ID: 1, Status: "running"},
+		{ID: 2, Status: "select", CratedBy: creator},
+		{ID: 3, Status: "select", CratedBy: creator},
+	}
+	roots := model.CreatorTree(routines)
+	assert.Len(t, roots, 2)
+	assert.Equal(t, 2, roots[0].Total)
+	assert.Len(t, roots[0].Goroutines, 2)
+	assert.Empty(t, roots[0].Children)
+	assert.Equal(t, 1, roots[1].Total)
+}
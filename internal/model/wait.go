@@ -0,0 +1,94 @@
+package model
+
+import "strings"
+
+// WaitClass groups the goroutine states and wait reasons of the runtime.
+// See: https://github.com/golang/go/blob/go1.22.0/src/runtime/runtime2.go#L1072-L1116
+type WaitClass int
+
+const (
+	// WaitClassUser for states which are caused by user code and do not fit any other class, like sleep
+	WaitClassUser WaitClass = iota
+	// WaitClassRunning for goroutines which are running or ready to run
+	WaitClassRunning
+	// WaitClassSyscall for goroutines executing a system call
+	WaitClassSyscall
+	// WaitClassIdleRuntime for goroutines of the runtime itself, like GC workers and the finalizer
+	WaitClassIdleRuntime
+	// WaitClassNetwork for goroutines waiting on the network poller
+	WaitClassNetwork
+	// WaitClassSync for goroutines blocked on primitives of the sync package
+	WaitClassSync
+	// WaitClassChannel for goroutines blocked on channel operations or select
+	WaitClassChannel
+)
+
+// WaitClasses contains all classes in display order
+var WaitClasses = []WaitClass{
+	WaitClassRunning,
+	WaitClassSyscall,
+	WaitClassNetwork,
+	WaitClassChannel,
+	WaitClassSync,
+	WaitClassUser,
+	WaitClassIdleRuntime,
+}
+
+var waitClassNames = map[WaitClass]string{
+	WaitClassUser:        "user",
+	WaitClassRunning:     "running",
+	WaitClassSyscall:     "syscall",
+	WaitClassIdleRuntime: "idle-runtime",
+	WaitClassNetwork:     "network",
+	WaitClassSync:        "sync",
+	WaitClassChannel:     "channel",
+}
+
+func (c WaitClass) String() string {
+	return waitClassNames[c]
+}
+
+var waitReasonClasses = map[string]WaitClass{
+	"idle":                     WaitClassRunning,
+	"running":                  WaitClassRunning,
+	"runnable":                 WaitClassRunning,
+	"preempted":                WaitClassRunning,
+	"copystack":                WaitClassRunning,
+	"syscall":                  WaitClassSyscall,
+	"waiting for cgo callback": WaitClassSyscall,
+	"IO wait":                  WaitClassNetwork,
+	"semacquire":               WaitClassSync,
+	"finalizer wait":           WaitClassIdleRuntime,
+	"cleanup wait":             WaitClassIdleRuntime,
+	"force gc (idle)":          WaitClassIdleRuntime,
+	"garbage collection":       WaitClassIdleRuntime,
+	"garbage collection scan":  WaitClassIdleRuntime,
+	"wait for GC cycle":        WaitClassIdleRuntime,
+	"dumping heap":             WaitClassIdleRuntime,
+	"panicwait":                WaitClassIdleRuntime,
+	"debug call":               WaitClassIdleRuntime,
+	"stopping the world":       WaitClassIdleRuntime,
+	"flushing proc caches":     WaitClassIdleRuntime,
+	"trace reader (blocked)":   WaitClassIdleRuntime,
+	"trace goroutine status":   WaitClassIdleRuntime,
+	"trace proc status":        WaitClassIdleRuntime,
+	"page trace flush":         WaitClassIdleRuntime,
+	"sleep":                    WaitClassUser,
+	"coroutine":                WaitClassUser,
+}
+
+// ClassifyWaitReason maps the status of a goroutine to its class. Unknown states are classified as user.
+func ClassifyWaitReason(status string) WaitClass {
+	if class, ok := waitReasonClasses[status]; ok {
+		return class
+	}
+	switch {
+	case strings.HasPrefix(status, "chan "), strings.HasPrefix(status, "select"):
+		return WaitClassChannel
+	case strings.HasPrefix(status, "sync."):
+		return WaitClassSync
+	case strings.HasPrefix(status, "GC "), strings.HasPrefix(status, "GOMAXPROCS "):
+		return WaitClassIdleRuntime
+	}
+	return WaitClassUser
+}
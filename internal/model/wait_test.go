@@ -0,0 +1,28 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyWaitReason(t *testing.T) {
+	assert.Equal(t, model.WaitClassRunning, model.ClassifyWaitReason("running"))
+	assert.Equal(t, model.WaitClassRunning, model.ClassifyWaitReason("runnable"))
+	assert.Equal(t, model.WaitClassSyscall, model.ClassifyWaitReason("syscall"))
+	assert.Equal(t, model.WaitClassNetwork, model.ClassifyWaitReason("IO wait"))
+	assert.Equal(t, model.WaitClassChannel, model.ClassifyWaitReason("chan receive"))
+	assert.Equal(t, model.WaitClassChannel, model.ClassifyWaitReason("chan send (nil chan)"))
+	assert.Equal(t, model.WaitClassChannel, model.ClassifyWaitReason("select"))
+	assert.Equal(t, model.WaitClassChannel, model.ClassifyWaitReason("select (no cases)"))
+	assert.Equal(t, model.WaitClassSync, model.ClassifyWaitReason("semacquire"))
+	assert.Equal(t, model.WaitClassSync, model.ClassifyWaitReason("sync.Mutex.Lock"))
+	assert.Equal(t, model.WaitClassIdleRuntime, model.ClassifyWaitReason("GC assist wait"))
+	assert.Equal(t, model.WaitClassIdleRuntime, model.ClassifyWaitReason("GC worker (idle)"))
+	assert.Equal(t, model.WaitClassIdleRuntime, model.ClassifyWaitReason("force gc (idle)"))
+	assert.Equal(t, model.WaitClassIdleRuntime, model.ClassifyWaitReason("finalizer wait"))
+	assert.Equal(t, model.WaitClassUser, model.ClassifyWaitReason("sleep"))
+	assert.Equal(t, model.WaitClassUser, model.ClassifyWaitReason("something new"))
+	assert.Equal(t, "idle-runtime", model.WaitClassIdleRuntime.String())
+}
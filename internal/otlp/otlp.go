@@ -0,0 +1,243 @@
+// Package otlp pushes the goroutine counts of every snapshot as OpenTelemetry metrics to an OTLP endpoint. The
+// metrics are sent as JSON over HTTP, which every OpenTelemetry collector accepts, so roumon needs no SDK.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+const (
+	// metricsPath is appended to an endpoint without path
+	metricsPath = "/v1/metrics"
+	// maxFunctions is the number of the top functions with the most goroutines which are pushed per snapshot. The
+	// rest is left out to keep the number of series low.
+	maxFunctions = 20
+	// queueSize is the number of snapshots which wait to be pushed. Further snapshots are dropped.
+	queueSize = 16
+	// unit of all metrics in the notation of UCUM
+	unit = "{goroutine}"
+)
+
+// Exporter pushes the goroutine counts of the snapshots to an OTLP/HTTP endpoint
+type Exporter struct {
+	endpoint string
+	headers  http.Header
+	client   *http.Client
+	version  string
+}
+
+// NewExporter creates an exporter for the endpoint, for example http://collector:4318. An endpoint without path
+// gets /v1/metrics appended like OTEL_EXPORTER_OTLP_ENDPOINT. The headers of the form "Name: value" are added to
+// OTEL_EXPORTER_OTLP_HEADERS, which lists name=value pairs separated by commas. version is the version of roumon,
+// which is pushed as version of the instrumentation scope.
+func NewExporter(endpoint string, headers []string, timeout time.Duration, version string) (*Exporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return nil, fmt.Errorf("OTLP endpoint must be an http or https URL, but got: %s", endpoint)
+	}
+	if len(strings.Trim(u.Path, "/")) == 0 {
+		u.Path = strings.TrimSuffix(u.Path, "/") + metricsPath
+	}
+	e := &Exporter{endpoint: u.String(), headers: http.Header{}, client: &http.Client{Timeout: timeout}, version: version}
+	if env := os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"); len(env) > 0 {
+		for _, pair := range strings.Split(env, ",") {
+			key, value, found := strings.Cut(pair, "=")
+			if !found || len(strings.TrimSpace(key)) == 0 {
+				return nil, fmt.Errorf("OTEL_EXPORTER_OTLP_HEADERS must list name=value pairs, but got: %s", pair)
+			}
+			if value, err = url.QueryUnescape(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("invalid value of header %s in OTEL_EXPORTER_OTLP_HEADERS. Err: %s", key, err.Error())
+			}
+			e.headers.Add(strings.TrimSpace(key), value)
+		}
+	}
+	for _, header := range headers {
+		key, value, found := strings.Cut(header, ":")
+		key = strings.TrimSpace(key)
+		if !found || len(key) == 0 || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("header must be of the form \"Name: value\", but got: %s", header)
+		}
+		e.headers.Add(key, strings.TrimSpace(value))
+	}
+	return e, nil
+}
+
+// Endpoint returns the URL which the metrics are pushed to
+func (e *Exporter) Endpoint() string {
+	return e.endpoint
+}
+
+// Push sends the goroutine counts of the snapshot in one request
+func (e *Exporter) Push(ctx context.Context, snapshot model.Snapshot) error {
+	body, err := json.Marshal(e.metrics(snapshot))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range e.headers {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Error while closing response body: %s", err.Error())
+		}
+	}()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("OTLP endpoint returned %s %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// Tee pushes every snapshot of in in the background after it is passed on to out, so a slow endpoint does not
+// hold back the snapshots. Snapshots which arrive while too many are waiting are dropped. Failed pushes are logged.
+// Returns once in is closed.
+func (e *Exporter) Tee(in <-chan model.Snapshot, out chan<- model.Snapshot) {
+	queue := make(chan model.Snapshot, queueSize)
+	defer close(queue)
+	go func() {
+		for snapshot := range queue {
+			if err := e.Push(context.Background(), snapshot); err != nil {
+				log.Printf("%s: Failed to push metrics: %s", snapshot.Target, err.Error())
+			}
+		}
+	}()
+	for snapshot := range in {
+		out <- snapshot
+		select {
+		case queue <- snapshot:
+		default:
+			log.Printf("%s: Dropped metrics, the OTLP endpoint is too slow", snapshot.Target)
+		}
+	}
+}
+
+// The types below are the JSON encoding of the OTLP metrics protocol. Integers of 64 bits are strings.
+
+type metricsRequest struct {
+	ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+}
+
+type resourceMetrics struct {
+	Resource     resource       `json:"resource"`
+	ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+}
+
+type resource struct {
+	Attributes []attribute `json:"attributes"`
+}
+
+type scopeMetrics struct {
+	Scope   scope    `json:"scope"`
+	Metrics []metric `json:"metrics"`
+}
+
+type scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type metric struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Unit        string `json:"unit"`
+	Gauge       gauge  `json:"gauge"`
+}
+
+type gauge struct {
+	DataPoints []dataPoint `json:"dataPoints"`
+}
+
+type dataPoint struct {
+	Attributes   []attribute `json:"attributes,omitempty"`
+	TimeUnixNano string      `json:"timeUnixNano"`
+	AsInt        string      `json:"asInt"`
+}
+
+type attribute struct {
+	Key   string         `json:"key"`
+	Value attributeValue `json:"value"`
+}
+
+type attributeValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+func stringAttribute(key, value string) attribute {
+	return attribute{Key: key, Value: attributeValue{StringValue: value}}
+}
+
+// metrics converts the snapshot into the gauges roumon.goroutines with the total count,
+// roumon.goroutines.status with the count of every status and roumon.goroutines.function with the count of the
+// top functions with the most goroutines. The target of the snapshot is the resource.
+func (e *Exporter) metrics(snapshot model.Snapshot) metricsRequest {
+	now := strconv.FormatInt(snapshot.Time.UnixNano(), 10)
+	point := func(count int, attributes ...attribute) dataPoint {
+		return dataPoint{Attributes: attributes, TimeUnixNano: now, AsInt: strconv.Itoa(count)}
+	}
+	statuses := make(map[string]int)
+	functions := make(map[string]int)
+	for _, g := range snapshot.Goroutines {
+		statuses[g.Status]++
+		functions[g.TopFunc()]++
+	}
+
+	total := metric{Name: "roumon.goroutines", Description: "Goroutines of the target", Unit: unit}
+	total.Gauge.DataPoints = []dataPoint{point(len(snapshot.Goroutines))}
+	byStatus := metric{Name: "roumon.goroutines.status", Description: "Goroutines of the target by status", Unit: unit}
+	for _, status := range sortedKeys(statuses) {
+		byStatus.Gauge.DataPoints = append(byStatus.Gauge.DataPoints,
+			point(statuses[status], stringAttribute("status", status)))
+	}
+	byFunction := metric{Name: "roumon.goroutines.function",
+		Description: "Goroutines of the target by the function in which they run or wait", Unit: unit}
+	keys := sortedKeys(functions)
+	sort.SliceStable(keys, func(i, j int) bool { return functions[keys[i]] > functions[keys[j]] })
+	for _, function := range keys[:min(len(keys), maxFunctions)] {
+		byFunction.Gauge.DataPoints = append(byFunction.Gauge.DataPoints,
+			point(functions[function], stringAttribute("function", function)))
+	}
+	metrics := []metric{total, byStatus}
+	if len(byFunction.Gauge.DataPoints) > 0 {
+		metrics = append(metrics, byFunction)
+	}
+
+	return metricsRequest{ResourceMetrics: []resourceMetrics{{
+		Resource: resource{Attributes: []attribute{
+			stringAttribute("service.name", "roumon"),
+			stringAttribute("roumon.target", snapshot.Target),
+		}},
+		ScopeMetrics: []scopeMetrics{{Scope: scope{Name: "roumon", Version: e.version}, Metrics: metrics}},
+	}}}
+}
+
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,78 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPush(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "api-key=se%20cret")
+	var received metricsRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/metrics", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "se cret", r.Header.Get("Api-Key"))
+		assert.Equal(t, "acme", r.Header.Get("X-Tenant"))
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer server.Close()
+
+	exporter, err := NewExporter(server.URL, []string{"X-Tenant: acme"}, time.Second, "v1.2.3")
+	assert.Nil(t, err)
+	serve := []model.StackFrame{{FuncName: "main.serve(0x1)"}}
+	snapshot := model.Snapshot{
+		Target: "localhost:6060",
+		Time:   time.Unix(1700000000, 0),
+		Goroutines: []model.Goroutine{
+			{ID: 1, Status: "running", StackTrace: []model.StackFrame{{FuncName: "main.main()"}}},
+			{ID: 2, Status: "select", StackTrace: serve},
+			{ID: 3, Status: "select", StackTrace: serve},
+		},
+	}
+	assert.Nil(t, exporter.Push(context.Background(), snapshot))
+
+	assert.Len(t, received.ResourceMetrics, 1)
+	assert.Contains(t, received.ResourceMetrics[0].Resource.Attributes, stringAttribute("roumon.target", "localhost:6060"))
+	scope := received.ResourceMetrics[0].ScopeMetrics[0]
+	assert.Equal(t, "v1.2.3", scope.Scope.Version)
+	assert.Len(t, scope.Metrics, 3)
+	assert.Equal(t, "roumon.goroutines", scope.Metrics[0].Name)
+	assert.Equal(t, []dataPoint{{TimeUnixNano: "1700000000000000000", AsInt: "3"}}, scope.Metrics[0].Gauge.DataPoints)
+	assert.Equal(t, []dataPoint{
+		{Attributes: []attribute{stringAttribute("status", "running")}, TimeUnixNano: "1700000000000000000", AsInt: "1"},
+		{Attributes: []attribute{stringAttribute("status", "select")}, TimeUnixNano: "1700000000000000000", AsInt: "2"},
+	}, scope.Metrics[1].Gauge.DataPoints)
+	assert.Equal(t, "roumon.goroutines.function", scope.Metrics[2].Name)
+	assert.Equal(t, []attribute{stringAttribute("function", "main.serve")}, scope.Metrics[2].Gauge.DataPoints[0].Attributes)
+	assert.Equal(t, "2", scope.Metrics[2].Gauge.DataPoints[0].AsInt)
+}
+
+func TestPushError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	exporter, err := NewExporter(server.URL+"/otlp/v1/metrics", nil, time.Second, "")
+	assert.Nil(t, err)
+	assert.Equal(t, server.URL+"/otlp/v1/metrics", exporter.Endpoint())
+	err = exporter.Push(context.Background(), model.Snapshot{})
+	assert.ErrorContains(t, err, "401 Unauthorized unauthorized")
+}
+
+func TestNewExporterErrors(t *testing.T) {
+	_, err := NewExporter("localhost:4318", nil, time.Second, "")
+	assert.ErrorContains(t, err, "http or https URL")
+	_, err = NewExporter("http://localhost:4318", []string{"X-Tenant"}, time.Second, "")
+	assert.ErrorContains(t, err, "Name: value")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "api-key")
+	_, err = NewExporter("http://localhost:4318", nil, time.Second, "")
+	assert.ErrorContains(t, err, "OTEL_EXPORTER_OTLP_HEADERS")
+}
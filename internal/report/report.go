@@ -0,0 +1,202 @@
+// Package report writes goroutine snapshots as a standalone HTML page, which is shared with people who do not run
+// roumon themselves.
+package report
+
+import (
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// maxIDs is the number of goroutine IDs which a group lists
+const maxIDs = 50
+
+// page is the data of the report template
+type page struct {
+	Generated time.Time
+	Targets   []target
+}
+
+// target shows the latest snapshot of a target and the goroutine counts of all its snapshots
+type target struct {
+	Name     string
+	Latest   model.Snapshot
+	Statuses []statusCount
+	Groups   []group
+	History  []historyPoint
+}
+
+type statusCount struct {
+	Status string
+	Count  int
+}
+
+type historyPoint struct {
+	Time  time.Time
+	Count int
+}
+
+// group of goroutines with identical stacks
+type group struct {
+	Count     int
+	Status    string
+	Wait      string
+	Func      string
+	CreatedBy string
+	IDs       string
+	Labels    string // Distinct labels of the goroutines as key=value
+	Stack     string
+	Text      string // Lower case text which the filter of the page searches
+}
+
+// Write writes the snapshots as one HTML page with inline styles and scripts. Every target is shown with its latest
+// snapshot, in which the goroutines are grouped by their stacks, largest groups first. A recording with several
+// snapshots of a target additionally lists the goroutine count of every snapshot.
+func Write(w io.Writer, snapshots []model.Snapshot, generated time.Time) error {
+	p := page{Generated: generated}
+	index := make(map[string]int)
+	for _, s := range snapshots {
+		i, ok := index[s.Target]
+		if !ok {
+			i = len(p.Targets)
+			index[s.Target] = i
+			p.Targets = append(p.Targets, target{Name: s.Target})
+		}
+		p.Targets[i].Latest = s
+		p.Targets[i].History = append(p.Targets[i].History, historyPoint{Time: s.Time, Count: len(s.Goroutines)})
+	}
+	for i := range p.Targets {
+		t := &p.Targets[i]
+		if len(t.History) < 2 {
+			t.History = nil
+		}
+		t.Statuses = statusCounts(t.Latest.Goroutines)
+		for _, g := range model.GroupByStack(t.Latest.Goroutines) {
+			t.Groups = append(t.Groups, newGroup(g))
+		}
+	}
+	return pageTemplate.Execute(w, p)
+}
+
+// statusCounts counts the goroutines of every status, most frequent first
+func statusCounts(routines []model.Goroutine) []statusCount {
+	counts := make(map[string]int)
+	for _, g := range routines {
+		counts[g.Status]++
+	}
+	result := make([]statusCount, 0, len(counts))
+	for status, count := range counts {
+		result = append(result, statusCount{Status: status, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Status < result[j].Status
+	})
+	return result
+}
+
+func newGroup(g model.StackGroup) group {
+	first := g.Goroutines[0]
+	result := group{
+		Count:     len(g.Goroutines),
+		Status:    first.Status,
+		Wait:      g.WaitRange(),
+		Func:      g.Func(),
+		CreatedBy: "-",
+		Stack:     strings.TrimSuffix(first.Dump(), "\n"),
+	}
+	if first.CratedBy != nil {
+		result.CreatedBy = first.CratedBy.Func()
+	}
+	ids := make([]string, 0, min(len(g.Goroutines), maxIDs))
+	labels := make(map[string]bool)
+	for i, routine := range g.Goroutines {
+		if i < maxIDs {
+			ids = append(ids, strconv.FormatInt(routine.ID, 10))
+		}
+		for key, value := range routine.Labels {
+			labels[key+"="+value] = true
+		}
+	}
+	result.IDs = strings.Join(ids, ", ")
+	if len(g.Goroutines) > maxIDs {
+		result.IDs += ", …"
+	}
+	sorted := make([]string, 0, len(labels))
+	for label := range labels {
+		sorted = append(sorted, label)
+	}
+	sort.Strings(sorted)
+	result.Labels = strings.Join(sorted, ", ")
+	result.Text = strings.ToLower(result.Status + " " + result.Labels + " " + result.Stack)
+	return result
+}
+
+var pageTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>roumon report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h2 { margin-top: 2em; }
+table { border-collapse: collapse; }
+td, th { padding: 0.2em 0.8em; text-align: left; }
+tr:nth-child(even) { background: #f4f4f4; }
+.num { text-align: right; }
+details { border-top: 1px solid #ddd; padding: 0.3em 0; }
+summary { cursor: pointer; font-family: monospace; }
+pre { background: #f4f4f4; padding: 0.8em; overflow-x: auto; }
+.meta { color: #666; }
+#filter { width: 30em; padding: 0.3em; }
+</style>
+</head>
+<body>
+<h1>roumon report</h1>
+<p class="meta">Generated {{.Generated.Format "2006-01-02 15:04:05 MST"}}</p>
+<p><input id="filter" type="search" placeholder="Filter by status, function, file or label" autofocus> <span id="matches"></span></p>
+{{range .Targets}}
+<h2>{{.Name}}</h2>
+<p class="meta">Snapshot of {{.Latest.Time.Format "2006-01-02 15:04:05 MST"}} with {{len .Latest.Goroutines}} goroutines{{if .Latest.Skipped}}, {{.Latest.Skipped}} could not be parsed{{end}}</p>
+<table>
+<tr><th>Status</th><th class="num">Goroutines</th></tr>
+{{range .Statuses}}<tr><td>{{.Status}}</td><td class="num">{{.Count}}</td></tr>
+{{end}}</table>
+{{if .History}}<h3>Recording</h3>
+<table>
+<tr><th>Time</th><th class="num">Goroutines</th></tr>
+{{range .History}}<tr><td>{{.Time.Format "2006-01-02 15:04:05"}}</td><td class="num">{{.Count}}</td></tr>
+{{end}}</table>
+{{end}}<h3>Stacks</h3>
+{{range .Groups}}<details class="group" data-text="{{.Text}}">
+<summary>{{.Count}} × {{.Status}} in {{.Func}}</summary>
+<p>Wait {{.Wait}}, created by {{.CreatedBy}}</p>
+<p>IDs {{.IDs}}</p>
+{{if .Labels}}<p>Labels {{.Labels}}</p>
+{{end}}
+<pre>{{.Stack}}</pre>
+</details>
+{{end}}{{end}}
+<script>
+const filter = document.getElementById("filter");
+filter.addEventListener("input", () => {
+  const words = filter.value.toLowerCase().split(/\s+/).filter(w => w.length > 0);
+  let matches = 0;
+  for (const group of document.querySelectorAll(".group")) {
+    const match = words.every(w => group.dataset.text.includes(w));
+    group.hidden = !match;
+    matches += match ? 1 : 0;
+  }
+  document.getElementById("matches").textContent = words.length > 0 ? matches + " stacks" : "";
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,54 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	frame := model.StackFrame{FuncName: "main.serve(0x1)", File: "/src/main.go", Line: 12}
+	routines := []model.Goroutine{
+		{ID: 1, Status: "running", StackTrace: []model.StackFrame{{FuncName: "main.main()", File: "/src/main.go", Line: 5}}},
+		{ID: 2, Status: "select", WaitSinceMin: 3, StackTrace: []model.StackFrame{frame}, Labels: map[string]string{"tenant": "<acme>"}},
+		{ID: 3, Status: "select", WaitSinceMin: 16, StackTrace: []model.StackFrame{frame}},
+	}
+	start := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	snapshots := []model.Snapshot{
+		{Target: "app", Time: start, Goroutines: routines[:1]},
+		{Target: "app", Time: start.Add(time.Minute), Goroutines: routines},
+	}
+	var buf bytes.Buffer
+	assert.Nil(t, Write(&buf, snapshots, start))
+	page := buf.String()
+
+	assert.Contains(t, page, "<h2>app</h2>")
+	assert.Contains(t, page, "with 3 goroutines")
+	assert.Contains(t, page, "2 × select in main.serve")
+	assert.Less(t, strings.Index(page, "2 × select"), strings.Index(page, "1 × running"), "largest groups come first")
+	assert.Contains(t, page, "Wait 3-16m")
+	assert.Contains(t, page, "IDs 2, 3")
+	assert.Contains(t, page, "Labels tenant=&lt;acme&gt;", "values are escaped")
+	assert.Contains(t, page, "<td>2024-01-02 15:05:05</td>", "a recording lists every snapshot")
+}
+
+func TestWriteSingleSnapshot(t *testing.T) {
+	snapshots := []model.Snapshot{{Target: "dump.txt", Goroutines: []model.Goroutine{{ID: 1, Status: "running"}}}}
+	var buf bytes.Buffer
+	assert.Nil(t, Write(&buf, snapshots, time.Now()))
+	assert.NotContains(t, buf.String(), "<h3>Recording</h3>")
+}
+
+func TestNewGroupIDs(t *testing.T) {
+	routines := make([]model.Goroutine, maxIDs+1)
+	for i := range routines {
+		routines[i] = model.Goroutine{ID: int64(i + 1), Status: "select"}
+	}
+	g := newGroup(model.GroupByStack(routines)[0])
+	assert.Equal(t, maxIDs+1, g.Count)
+	assert.True(t, strings.HasSuffix(g.IDs, ", 50, …"))
+}
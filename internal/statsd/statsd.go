@@ -0,0 +1,151 @@
+// Package statsd sends the goroutine counts of every snapshot as gauges to a StatsD or DogStatsD agent over UDP.
+package statsd
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+const (
+	// maxGroups is the number of the largest stack groups of every snapshot which are sent
+	maxGroups = 20
+	// maxPacket is the size of a datagram which fits into the MTU of common networks
+	maxPacket = 1432
+)
+
+var (
+	// invalidName matches the characters which are replaced in the parts of plain StatsD metric names
+	invalidName = regexp.MustCompile(`[^A-Za-z0-9_\-]+`)
+	// invalidTag matches the characters which are replaced in DogStatsD tags
+	invalidTag = regexp.MustCompile(`[^A-Za-z0-9_.:\-/]+`)
+)
+
+// Client sends gauges to a StatsD agent. For DogStatsD the target, status, function and fingerprint of a gauge are
+// tags, for plain StatsD they are appended to the metric name, for example
+// roumon.goroutines.status.localhost_6060.select.
+type Client struct {
+	conn   net.Conn
+	prefix string
+	tags   []string // DogStatsD tags of all gauges. Nil for plain StatsD
+}
+
+// NewClient connects to the agent at addr, for example localhost:8125. The prefix is put in front of every metric
+// name, for example roumon. dogStatsD sends the details of a gauge as tags and adds the constant tags of the form
+// key:value to every gauge.
+func NewClient(addr, prefix string, dogStatsD bool, tags []string) (*Client, error) {
+	if !dogStatsD && len(tags) > 0 {
+		return nil, fmt.Errorf("tags need DogStatsD")
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to StatsD at %s. Err: %s", addr, err.Error())
+	}
+	c := &Client{conn: conn, prefix: strings.TrimSuffix(prefix, ".")}
+	if dogStatsD {
+		c.tags = make([]string, 0, len(tags))
+		for _, tag := range tags {
+			c.tags = append(c.tags, tagValue(tag))
+		}
+	}
+	return c, nil
+}
+
+// Close closes the connection to the agent
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Send sends the gauges of the snapshot: goroutines with the count of the target, goroutines.status with the count
+// of every status and stack_group with the count of the largest groups of identical stacks. Lines are packed into
+// as few datagrams as possible.
+func (c *Client) Send(snapshot model.Snapshot) error {
+	var packet strings.Builder
+	for _, line := range c.lines(snapshot) {
+		if packet.Len() > 0 && packet.Len()+1+len(line) > maxPacket {
+			if _, err := c.conn.Write([]byte(packet.String())); err != nil {
+				return err
+			}
+			packet.Reset()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	if packet.Len() == 0 {
+		return nil
+	}
+	_, err := c.conn.Write([]byte(packet.String()))
+	return err
+}
+
+// Tee sends the gauges of every snapshot of in before it is passed on to out. Failed sends are logged. Returns once
+// in is closed.
+func (c *Client) Tee(in <-chan model.Snapshot, out chan<- model.Snapshot) {
+	for snapshot := range in {
+		if err := c.Send(snapshot); err != nil {
+			log.Printf("%s: Failed to send to StatsD: %s", snapshot.Target, err.Error())
+		}
+		out <- snapshot
+	}
+}
+
+// lines returns one gauge per line in the StatsD format, for example roumon.goroutines:120|g
+func (c *Client) lines(snapshot model.Snapshot) []string {
+	var lines []string
+	// details are pairs of tag keys and values
+	gauge := func(name string, value int, details ...string) {
+		if len(c.prefix) > 0 {
+			name = c.prefix + "." + name
+		}
+		if c.tags == nil {
+			parts := []string{name}
+			for i := 1; i < len(details); i += 2 {
+				parts = append(parts, metricPart(details[i]))
+			}
+			lines = append(lines, fmt.Sprintf("%s:%d|g", strings.Join(parts, "."), value))
+			return
+		}
+		tags := append([]string{}, c.tags...)
+		for i := 0; i+1 < len(details); i += 2 {
+			tags = append(tags, details[i]+":"+tagValue(details[i+1]))
+		}
+		lines = append(lines, fmt.Sprintf("%s:%d|g|#%s", name, value, strings.Join(tags, ",")))
+	}
+
+	gauge("goroutines", len(snapshot.Goroutines), "target", snapshot.Target)
+	counts := make(map[string]int)
+	for _, g := range snapshot.Goroutines {
+		counts[g.Status]++
+	}
+	statuses := make([]string, 0, len(counts))
+	for status := range counts {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		gauge("goroutines.status", counts[status], "target", snapshot.Target, "status", status)
+	}
+	groups := model.GroupByStack(snapshot.Goroutines)
+	for _, group := range groups[:min(len(groups), maxGroups)] {
+		gauge("stack_group", len(group.Goroutines), "target", snapshot.Target, "status", group.Goroutines[0].Status,
+			"top_func", group.Func(), "fingerprint", group.Fingerprint())
+	}
+	return lines
+}
+
+// metricPart returns the text as one part of a plain StatsD metric name, in which dots separate the parts
+func metricPart(text string) string {
+	return invalidName.ReplaceAllString(text, "_")
+}
+
+// tagValue replaces the characters which DogStatsD does not allow in tags
+func tagValue(text string) string {
+	return invalidTag.ReplaceAllString(text, "_")
+}
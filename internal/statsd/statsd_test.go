@@ -0,0 +1,93 @@
+package statsd
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func testSnapshot() model.Snapshot {
+	serve := []model.StackFrame{{FuncName: "main.serve(0x1)", File: "/src/main.go", Line: 20}}
+	return model.Snapshot{Target: "localhost:6060", Goroutines: []model.Goroutine{
+		{ID: 1, Status: "running", StackTrace: []model.StackFrame{{FuncName: "main.main()"}}},
+		{ID: 2, Status: "select", StackTrace: serve},
+		{ID: 3, Status: "select", StackTrace: serve},
+	}}
+}
+
+func TestSend(t *testing.T) {
+	agent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer agent.Close()
+
+	c, err := NewClient(agent.LocalAddr().String(), "roumon.", true, []string{"env:prod"})
+	assert.Nil(t, err)
+	defer c.Close()
+	snapshot := testSnapshot()
+	assert.Nil(t, c.Send(snapshot))
+
+	buf := make([]byte, maxPacket)
+	assert.Nil(t, agent.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := agent.ReadFrom(buf)
+	assert.Nil(t, err)
+	lines := strings.Split(string(buf[:n]), "\n")
+	fingerprint := model.GroupByStack(snapshot.Goroutines)[0].Fingerprint()
+	assert.Equal(t, []string{
+		"roumon.goroutines:3|g|#env:prod,target:localhost:6060",
+		"roumon.goroutines.status:1|g|#env:prod,target:localhost:6060,status:running",
+		"roumon.goroutines.status:2|g|#env:prod,target:localhost:6060,status:select",
+		"roumon.stack_group:2|g|#env:prod,target:localhost:6060,status:select,top_func:main.serve,fingerprint:" + fingerprint,
+		"roumon.stack_group:1|g|#env:prod,target:localhost:6060,status:running,top_func:main.main,fingerprint:" +
+			model.GroupByStack(snapshot.Goroutines)[1].Fingerprint(),
+	}, lines)
+}
+
+func TestPlainLines(t *testing.T) {
+	c := &Client{prefix: "roumon"}
+	snapshot := testSnapshot()
+	snapshot.Goroutines[0].Status = "chan receive"
+	lines := c.lines(snapshot)
+	assert.Equal(t, "roumon.goroutines.localhost_6060:3|g", lines[0])
+	assert.Equal(t, "roumon.goroutines.status.localhost_6060.chan_receive:1|g", lines[1])
+	assert.True(t, strings.HasPrefix(lines[3], "roumon.stack_group.localhost_6060.select.main_serve."))
+
+	c.prefix = ""
+	assert.Equal(t, "goroutines.localhost_6060:3|g", c.lines(snapshot)[0])
+}
+
+func TestSendSplitsPackets(t *testing.T) {
+	agent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer agent.Close()
+	c, err := NewClient(agent.LocalAddr().String(), "roumon", false, nil)
+	assert.Nil(t, err)
+	defer c.Close()
+
+	snapshot := model.Snapshot{Target: strings.Repeat("t", 200)}
+	for i := 0; i < 30; i++ {
+		frame := model.StackFrame{FuncName: "main.f", File: "/src/main.go", Line: int32(i)}
+		snapshot.Goroutines = append(snapshot.Goroutines, model.Goroutine{ID: int64(i), Status: "select", StackTrace: []model.StackFrame{frame}})
+	}
+	assert.Nil(t, c.Send(snapshot))
+	assert.Nil(t, agent.SetReadDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, 65536)
+	count := 0
+	for count < len(c.lines(snapshot)) {
+		n, _, err := agent.ReadFrom(buf)
+		assert.Nil(t, err)
+		assert.LessOrEqual(t, n, maxPacket)
+		count += strings.Count(string(buf[:n]), "\n") + 1
+	}
+	assert.Equal(t, 22, count)
+}
+
+func TestNewClientErrors(t *testing.T) {
+	_, err := NewClient("localhost:8125", "roumon", false, []string{"env:prod"})
+	assert.ErrorContains(t, err, "DogStatsD")
+	_, err = NewClient("localhost", "roumon", false, nil)
+	assert.ErrorContains(t, err, "failed to connect")
+}
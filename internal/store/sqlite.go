@@ -0,0 +1,202 @@
+// Package store persists snapshots in an SQLite database for ad-hoc SQL queries after the session.
+package store
+
+import (
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/becheran/roumon/internal/model"
+
+	// Pure Go SQLite driver, which needs neither cgo nor an installed SQLite
+	_ "modernc.org/sqlite"
+)
+
+// timeLayout of the snapshots in the database, which the date and time functions of SQLite understand
+const timeLayout = "2006-01-02T15:04:05.000Z"
+
+// schema of the database. Stacks are stored once and referenced by the goroutines of all snapshots. The view
+// stack_counts counts the goroutines of every stack and status of a snapshot.
+const schema = `CREATE TABLE IF NOT EXISTS snapshots (
+	id INTEGER PRIMARY KEY,
+	target TEXT NOT NULL,
+	time TEXT NOT NULL,
+	goroutines INTEGER NOT NULL,
+	skipped INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS stacks (
+	id TEXT PRIMARY KEY,
+	func TEXT NOT NULL,
+	depth INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS frames (
+	stack_id TEXT NOT NULL REFERENCES stacks(id),
+	depth INTEGER NOT NULL,
+	func TEXT NOT NULL,
+	package TEXT NOT NULL,
+	file TEXT NOT NULL,
+	line INTEGER NOT NULL,
+	PRIMARY KEY (stack_id, depth)
+);
+CREATE TABLE IF NOT EXISTS goroutines (
+	snapshot_id INTEGER NOT NULL REFERENCES snapshots(id),
+	id INTEGER NOT NULL,
+	status TEXT NOT NULL,
+	wait_minutes INTEGER NOT NULL,
+	locked INTEGER NOT NULL,
+	stack_id TEXT NOT NULL REFERENCES stacks(id),
+	created_by TEXT,
+	creator_id INTEGER,
+	PRIMARY KEY (snapshot_id, id)
+);
+CREATE TABLE IF NOT EXISTS labels (
+	snapshot_id INTEGER NOT NULL,
+	goroutine_id INTEGER NOT NULL,
+	key TEXT NOT NULL,
+	value TEXT NOT NULL,
+	PRIMARY KEY (snapshot_id, goroutine_id, key)
+);
+CREATE INDEX IF NOT EXISTS frames_package ON frames(package);
+CREATE VIEW IF NOT EXISTS stack_counts AS
+	SELECT snapshot_id, stack_id, status, count(*) AS goroutines FROM goroutines GROUP BY snapshot_id, stack_id, status;
+`
+
+// errClosed is returned for snapshots which are saved after the database was closed
+var errClosed = errors.New("database is closed")
+
+// SQLite writes snapshots to a database file. Safe for concurrent use.
+type SQLite struct {
+	mu     sync.Mutex
+	db     *sql.DB
+	path   string
+	closed bool
+	stacks map[string]bool // Stacks which were written during the session
+}
+
+// OpenSQLite opens the database file and creates the tables if they do not exist yet. Snapshots are appended to
+// the snapshots of earlier sessions.
+func OpenSQLite(path string) (*SQLite, error) {
+	conn, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s. Err: %s", path, err.Error())
+	}
+	// Snapshots are written one after the other
+	conn.SetMaxOpenConns(1)
+	if _, err := conn.Exec(schema); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to create the tables of %s. Err: %s", path, err.Error())
+	}
+	return &SQLite{db: conn, path: path, stacks: make(map[string]bool)}, nil
+}
+
+// Save writes the snapshot in one transaction
+func (db *SQLite) Save(snapshot model.Snapshot) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return errClosed
+	}
+	tx, err := db.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to write %s. Err: %s", db.path, err.Error())
+	}
+	added, err := writeSnapshot(tx, snapshot, db.stacks)
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		_ = tx.Rollback()
+	}
+	if err != nil {
+		// The stacks of the failed snapshot are written again with the next one
+		for _, id := range added {
+			delete(db.stacks, id)
+		}
+		return fmt.Errorf("failed to write %s. Err: %s", db.path, err.Error())
+	}
+	return nil
+}
+
+// Close the database after all snapshots were written
+func (db *SQLite) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return errClosed
+	}
+	db.closed = true
+	return db.db.Close()
+}
+
+// Tee saves every snapshot of in before it is passed on to out. Snapshots which fail to save are logged and passed
+// on anyway. Returns once in is closed.
+func (db *SQLite) Tee(in <-chan model.Snapshot, out chan<- model.Snapshot) {
+	for snapshot := range in {
+		if err := db.Save(snapshot); err != nil {
+			log.Printf("%s: Failed to store snapshot: %s", snapshot.Target, err.Error())
+		}
+		out <- snapshot
+	}
+}
+
+// stackID identifies the frames of the goroutine independent of its status and arguments
+func stackID(g model.Goroutine) string {
+	hash := sha1.New()
+	for _, frame := range g.StackTrace {
+		fmt.Fprintf(hash, "%s:%s:%d|", frame.Func(), frame.File, frame.Line)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// writeSnapshot inserts the snapshot. Stacks which are written already are skipped and the new ones are added to
+// written and returned.
+func writeSnapshot(tx *sql.Tx, snapshot model.Snapshot, written map[string]bool) (added []string, err error) {
+	result, err := tx.Exec("INSERT INTO snapshots (target, time, goroutines, skipped) VALUES (?, ?, ?, ?)",
+		snapshot.Target, snapshot.Time.UTC().Format(timeLayout), len(snapshot.Goroutines), snapshot.Skipped)
+	if err != nil {
+		return nil, err
+	}
+	snapshotID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range snapshot.Goroutines {
+		id := stackID(g)
+		if !written[id] {
+			written[id] = true
+			added = append(added, id)
+			if _, err := tx.Exec("INSERT OR IGNORE INTO stacks VALUES (?, ?, ?)", id, g.TopFunc(), len(g.StackTrace)); err != nil {
+				return added, err
+			}
+			for depth, frame := range g.StackTrace {
+				_, err := tx.Exec("INSERT OR IGNORE INTO frames VALUES (?, ?, ?, ?, ?, ?)", id, depth, frame.Func(),
+					frame.Parts().Package, frame.File, frame.Line)
+				if err != nil {
+					return added, err
+				}
+			}
+		}
+		var createdBy, creatorID any
+		if g.CratedBy != nil {
+			createdBy = g.CratedBy.Func()
+		}
+		if g.CreatorID != 0 {
+			creatorID = g.CreatorID
+		}
+		_, err := tx.Exec("INSERT OR IGNORE INTO goroutines VALUES (?, ?, ?, ?, ?, ?, ?, ?)", snapshotID, g.ID,
+			g.Status, g.WaitSinceMin, g.LockedToThread, id, createdBy, creatorID)
+		if err != nil {
+			return added, err
+		}
+		for key, value := range g.Labels {
+			_, err := tx.Exec("INSERT OR IGNORE INTO labels VALUES (?, ?, ?, ?)", snapshotID, g.ID, key, value)
+			if err != nil {
+				return added, err
+			}
+		}
+	}
+	return added, nil
+}
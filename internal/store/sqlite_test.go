@@ -0,0 +1,118 @@
+package store
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// query returns the rows of the query as lines of columns separated by |, like the sqlite3 shell
+func query(t *testing.T, conn *sql.DB, q string) string {
+	rows, err := conn.Query(q)
+	assert.Nil(t, err)
+	defer rows.Close()
+	columns, err := rows.Columns()
+	assert.Nil(t, err)
+	var out strings.Builder
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		assert.Nil(t, rows.Scan(pointers...))
+		for i, v := range values {
+			if i > 0 {
+				out.WriteString("|")
+			}
+			out.WriteString(v.String)
+		}
+		out.WriteString("\n")
+	}
+	assert.Nil(t, rows.Err())
+	return out.String()
+}
+
+func TestWriteSnapshot(t *testing.T) {
+	frame := model.StackFrame{FuncName: "main.serve(0x1)", File: "/src/main.go", Line: 12}
+	snapshot := model.Snapshot{
+		Target: "app's host",
+		Time:   time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		Goroutines: []model.Goroutine{
+			{ID: 1, Status: "select", StackTrace: []model.StackFrame{frame}, Labels: map[string]string{"tenant": "acme"}},
+			{ID: 2, Status: "select", StackTrace: []model.StackFrame{frame}, CratedBy: &frame, CreatorID: 1,
+				LockedToThread: true},
+		},
+	}
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "history.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+	assert.Nil(t, db.Save(snapshot))
+	assert.Len(t, db.stacks, 1)
+	assert.Nil(t, db.Save(snapshot))
+
+	assert.Equal(t, "1|app's host|2024-01-02T15:04:05.000Z|2|0\n2|app's host|2024-01-02T15:04:05.000Z|2|0\n",
+		query(t, db.db, "SELECT * FROM snapshots"))
+	assert.Equal(t, "main.serve|1\n", query(t, db.db, "SELECT func, depth FROM stacks"), "stacks are written once")
+	assert.Equal(t, "0|main.serve|main|/src/main.go|12\n", query(t, db.db, "SELECT depth, func, package, file, line FROM frames"))
+	assert.Equal(t, "1|select|0||\n2|select|1|main.serve|1\n",
+		query(t, db.db, "SELECT id, status, locked, created_by, creator_id FROM goroutines WHERE snapshot_id = 2"))
+	assert.Equal(t, "1|tenant|acme\n", query(t, db.db, "SELECT goroutine_id, key, value FROM labels WHERE snapshot_id = 1"))
+}
+
+func TestSaveFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	db, err := OpenSQLite(path)
+	assert.Nil(t, err)
+	snapshot := model.Snapshot{Target: "app", Goroutines: []model.Goroutine{
+		{ID: 1, StackTrace: []model.StackFrame{{FuncName: "main.main()", File: "/src/main.go", Line: 10}}},
+	}}
+	_, err = db.db.Exec("DROP VIEW stack_counts; DROP TABLE goroutines")
+	assert.Nil(t, err)
+
+	assert.ErrorContains(t, db.Save(snapshot), "failed to write "+path)
+	assert.ErrorContains(t, db.Save(snapshot), "failed to write "+path, "every failed snapshot is reported")
+	assert.Empty(t, db.stacks, "the stacks of failed snapshots are not remembered")
+	assert.Equal(t, "0\n", query(t, db.db, "SELECT count(*) FROM stacks"), "failed snapshots are rolled back")
+
+	assert.Nil(t, db.Close())
+	assert.ErrorIs(t, db.Save(snapshot), errClosed)
+}
+
+func TestSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	routines, err := model.ParseStackFrame(strings.NewReader(`goroutine 1 [chan receive]:
+main.main()
+	/src/main.go:10 +0x1d
+
+goroutine 6 [select]:
+net/http.(*conn).serve(0xc000)
+	/go/src/net/http/server.go:2000 +0x1d
+created by net/http.(*Server).Serve in goroutine 1
+	/go/src/net/http/server.go:3000 +0x1d
+`))
+	assert.Nil(t, err)
+	start := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	for session := 0; session < 2; session++ {
+		db, err := OpenSQLite(path)
+		assert.Nil(t, err)
+		assert.Nil(t, db.Save(model.Snapshot{Target: "app", Time: start.Add(time.Duration(session) * time.Minute), Goroutines: routines}))
+		assert.Nil(t, db.Close())
+	}
+
+	conn, err := sql.Open("sqlite", path)
+	assert.Nil(t, err)
+	defer conn.Close()
+	assert.Equal(t, "2024-01-02T15:04:05.000Z|1\n2024-01-02T15:05:05.000Z|1\n", query(t, conn, `SELECT s.time, count(*)
+		FROM goroutines g JOIN snapshots s ON s.id = g.snapshot_id JOIN frames f ON f.stack_id = g.stack_id
+		WHERE f.package = 'net/http' GROUP BY s.id ORDER BY s.time`))
+	assert.Equal(t, "4|4\n", query(t, conn, "SELECT count(*), sum(goroutines) FROM stack_counts"))
+
+	_, err = OpenSQLite(filepath.Join(t.TempDir(), "missing", "history.db"))
+	assert.Error(t, err)
+}
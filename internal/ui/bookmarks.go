@@ -0,0 +1,198 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/becheran/roumon/internal/config"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+// maxBookmarks is the number of bookmarks which have a number key
+const maxBookmarks = 9
+
+// SetBookmarks sets the bookmarks which are recalled with the keys 1 to 9. Save is called with all bookmarks once
+// the user saved or deleted one and may be nil.
+func (ui *UI) SetBookmarks(bookmarks []config.Bookmark, save func(bookmarks []config.Bookmark) error) {
+	ui.bookmarks = bookmarks
+	ui.saveBookmarks = save
+}
+
+// bookmarkIndex returns the bookmark of the number key
+func (ui *UI) bookmarkIndex(keyID string) (int, bool) {
+	if len(keyID) != 1 {
+		return 0, false
+	}
+	index, err := strconv.Atoi(keyID)
+	if err != nil || index < 1 || index > min(maxBookmarks, len(ui.bookmarks)) {
+		return 0, false
+	}
+	return index - 1, true
+}
+
+// currentBookmark returns the filter and the sort of the list
+func (ui *UI) currentBookmark(name string) config.Bookmark {
+	b := config.Bookmark{Name: name, Sort: formatSort(ui.sortKey, ui.sortDesc)}
+	if ui.filtered {
+		b.Filter = ui.filter.Text
+	}
+	if ui.statusFilter.active() {
+		b.Status = ui.statusFilter.String()
+	}
+	return b
+}
+
+// addBookmark replaces the bookmark of the same name or appends the bookmark. Returns the index of the bookmark and
+// false if all number keys are taken.
+func addBookmark(bookmarks []config.Bookmark, b config.Bookmark) ([]config.Bookmark, int, bool) {
+	for i, existing := range bookmarks {
+		if existing.Name == b.Name {
+			bookmarks[i] = b
+			return bookmarks, i, true
+		}
+	}
+	if len(bookmarks) >= maxBookmarks {
+		return bookmarks, 0, false
+	}
+	return append(bookmarks, b), len(bookmarks), true
+}
+
+// recallBookmark filters and sorts the list like the bookmark
+func (ui *UI) recallBookmark(index int) {
+	b := ui.bookmarks[index]
+	if len(b.Sort) > 0 {
+		key, desc, err := parseSort(b.Sort)
+		if err != nil {
+			ui.notice = fmt.Sprintf("[Bookmark %s: %s](fg:error)", plain(b.Name), plain(err.Error()))
+			return
+		}
+		ui.sortKey, ui.sortDesc = key, desc
+	}
+	ui.searching = false
+	ui.filtered = len(b.Filter) > 0
+	ui.filter.Text = b.Filter
+	if !ui.filtered {
+		ui.filter.Text = ui.filterPlaceholder()
+	}
+	ui.statusFilter, _ = parseStatusSelection(b.Status)
+	ui.list.ScrollTop()
+	ui.updateList()
+	ui.notice = fmt.Sprintf("Bookmark %d %s", index+1, plain(b.Name))
+}
+
+// storeBookmarks saves the bookmarks in the config file
+func (ui *UI) storeBookmarks() {
+	if ui.saveBookmarks == nil {
+		return
+	}
+	if err := ui.saveBookmarks(ui.bookmarks); err != nil {
+		ui.notice = fmt.Sprintf("[Failed to save the bookmarks: %s](fg:error)", plain(err.Error()))
+	}
+}
+
+// bookmarkText describes the filter and the sort of the bookmark
+func bookmarkText(b config.Bookmark) string {
+	var parts []string
+	if len(b.Filter) > 0 {
+		parts = append(parts, b.Filter)
+	}
+	if len(b.Status) > 0 {
+		parts = append(parts, b.Status)
+	}
+	if len(b.Sort) > 0 {
+		parts = append(parts, "sort "+b.Sort)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// chooseBookmark shows the bookmarks until one is recalled or a key other than a bookmark number or command is
+// pressed
+func (ui *UI) chooseBookmark(pollEvents <-chan termui.Event) (terminate bool) {
+	ui.chooser.Title = "Bookmarks"
+	deleting := false
+	for {
+		text := ""
+		for i, b := range ui.bookmarks {
+			text += fmt.Sprintf("%d %s: %s\n", i+1, plain(b.Name), plain(bookmarkText(b)))
+		}
+		if len(ui.bookmarks) == 0 {
+			text = "No bookmarks\n"
+		}
+		if deleting {
+			text += "\nNumber: Delete bookmark\nOther key: Cancel"
+		} else {
+			text += "\nNumber: Recall bookmark\ns: Save filter and sort\nd: Delete bookmark\nOther key: Close"
+		}
+		ui.chooser.Text = text
+		ui.render(ui.grid, ui.summary, ui.infoBar, ui.legend, ui.statusBar, ui.chooser)
+
+		e := <-pollEvents
+		if ui.keys.isQuit(e.ID) {
+			return true
+		}
+		index, ok := ui.bookmarkIndex(e.ID)
+		switch {
+		case deleting && ok:
+			ui.bookmarks = append(ui.bookmarks[:index:index], ui.bookmarks[index+1:]...)
+			ui.storeBookmarks()
+			deleting = false
+		case deleting:
+			deleting = false
+		case ok:
+			ui.recallBookmark(index)
+			return false
+		case e.ID == "s":
+			return ui.saveBookmark(pollEvents)
+		case e.ID == "d" && len(ui.bookmarks) > 0:
+			deleting = true
+		default:
+			return false
+		}
+	}
+}
+
+// saveBookmark asks for the name of the bookmark and saves the filter and the sort of the list under the name.
+// The description of the bookmark is the name if no name is typed.
+func (ui *UI) saveBookmark(pollEvents <-chan termui.Event) (terminate bool) {
+	name := ""
+	for {
+		ui.chooser.Text = fmt.Sprintf("Name: %s_\n\n%s\n\nEnter: Save\nEscape: Cancel",
+			plain(name), plain(bookmarkText(ui.currentBookmark(name))))
+		ui.render(ui.grid, ui.summary, ui.infoBar, ui.legend, ui.statusBar, ui.chooser)
+
+		e := <-pollEvents
+		switch e.ID {
+		case "<C-c>":
+			return true
+		case "<Escape>":
+			return false
+		case "<Backspace>", "<C-<Backspace>>":
+			if runes := []rune(name); len(runes) > 0 {
+				name = string(runes[:len(runes)-1])
+			}
+		case "<Space>":
+			name += " "
+		case "<Enter>":
+			b := ui.currentBookmark(strings.TrimSpace(name))
+			if len(b.Name) == 0 {
+				b.Name = bookmarkText(b)
+			}
+			bookmarks, index, ok := addBookmark(ui.bookmarks, b)
+			if !ok {
+				ui.notice = fmt.Sprintf("[All %d bookmarks are taken. Delete one first](fg:warn)", maxBookmarks)
+				return false
+			}
+			ui.bookmarks = bookmarks
+			ui.notice = fmt.Sprintf("[Saved bookmark %d %s](fg:ok)", index+1, plain(b.Name))
+			ui.storeBookmarks()
+			return false
+		default:
+			if utf8.RuneCountInString(e.ID) == 1 {
+				name += e.ID
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/config"
+	"github.com/becheran/roumon/internal/model"
+	"github.com/gizak/termui/v3/widgets"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurrentBookmark(t *testing.T) {
+	ui := &UI{filter: widgets.NewParagraph(), sortKey: model.SortByWait, sortDesc: true}
+	ui.filter.Text = "TYPE TO FILTER"
+	assert.Equal(t, config.Bookmark{Name: "all", Sort: "-wait"}, ui.currentBookmark("all"))
+
+	ui.filtered, ui.filter.Text = true, `pgx\.\(\*Pool\)`
+	ui.statusFilter = statusSelection{only: 1 << 4}
+	ui.sortKey, ui.sortDesc = model.SortByID, false
+	b := ui.currentBookmark("db")
+	assert.Equal(t, config.Bookmark{Name: "db", Filter: `pgx\.\(\*Pool\)`, Status: "select", Sort: "id"}, b)
+	assert.Equal(t, `pgx\.\(\*Pool\), select, sort id`, bookmarkText(b))
+
+	key, desc, err := parseSort(b.Sort)
+	assert.NoError(t, err)
+	assert.Equal(t, model.SortByID, key)
+	assert.False(t, desc)
+}
+
+func TestAddBookmark(t *testing.T) {
+	var bookmarks []config.Bookmark
+	bookmarks, index, ok := addBookmark(bookmarks, config.Bookmark{Name: "db", Filter: "pgx"})
+	assert.True(t, ok)
+	assert.Equal(t, 0, index)
+	bookmarks, index, _ = addBookmark(bookmarks, config.Bookmark{Name: "grpc", Filter: "grpc"})
+	assert.Equal(t, 1, index)
+	bookmarks, index, _ = addBookmark(bookmarks, config.Bookmark{Name: "db", Filter: "sql"})
+	assert.Equal(t, 0, index, "the bookmark of the same name is replaced")
+	assert.Equal(t, "sql", bookmarks[0].Filter)
+	assert.Len(t, bookmarks, 2)
+
+	for len(bookmarks) < maxBookmarks {
+		bookmarks = append(bookmarks, config.Bookmark{Name: string(rune('a' + len(bookmarks)))})
+	}
+	_, _, ok = addBookmark(bookmarks, config.Bookmark{Name: "more"})
+	assert.False(t, ok)
+
+	ui := &UI{bookmarks: bookmarks[:2]}
+	index, ok = ui.bookmarkIndex("2")
+	assert.True(t, ok)
+	assert.Equal(t, 1, index)
+	_, ok = ui.bookmarkIndex("3")
+	assert.False(t, ok)
+	_, ok = ui.bookmarkIndex("0")
+	assert.False(t, ok)
+}
@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// churnMark tells how a goroutine changed since the previous snapshot of its target
+type churnMark int
+
+const (
+	churnNone    churnMark = iota
+	churnAdded             // Not part of the previous snapshot
+	churnRemoved           // Part of the previous snapshot only. Listed until the next snapshot
+	churnChanged           // Changed its status or started to wait again
+)
+
+var churnRoles = map[churnMark]string{
+	churnAdded:   "added",
+	churnRemoved: "removed",
+	churnChanged: "info",
+}
+
+var churnMarks = map[churnMark]string{
+	churnNone:    " ",
+	churnAdded:   "+",
+	churnRemoved: "-",
+	churnChanged: "~",
+}
+
+// churn of the goroutines of a target between its last two snapshots
+type churn struct {
+	marks   map[string]churnMark // Mark by fingerprint. Unchanged goroutines are not listed
+	removed []model.Goroutine
+}
+
+// newChurn compares the snapshot with the previous snapshot of the target. A goroutine changed if its status
+// changed or if it waits for a shorter time than before, which means it woke up and waits again.
+func newChurn(previous, snapshot model.Snapshot) churn {
+	c := churn{marks: make(map[string]churnMark)}
+	for _, entry := range model.Diff(previous.Goroutines, snapshot.Goroutines) {
+		switch entry.Change {
+		case model.Added:
+			c.marks[entry.Fingerprint()] = churnAdded
+		case model.Removed:
+			c.marks[entry.Fingerprint()] = churnRemoved
+			c.removed = append(c.removed, entry.Goroutine)
+		}
+	}
+	before := make(map[string]model.Goroutine, len(previous.Goroutines))
+	for _, g := range previous.Goroutines {
+		before[g.Fingerprint()] = g
+	}
+	for _, g := range snapshot.Goroutines {
+		if old, ok := before[g.Fingerprint()]; ok && (old.Status != g.Status || g.WaitSinceMin < old.WaitSinceMin) {
+			c.marks[g.Fingerprint()] = churnChanged
+		}
+	}
+	return c
+}
+
+// trackChurn compares the snapshot with the previous snapshot of its target. The first snapshot of a target is
+// not marked.
+func (ui *UI) trackChurn(snapshot model.Snapshot) {
+	previous, ok := ui.snapshots[snapshot.Target]
+	if !ok {
+		return
+	}
+	ui.churn[snapshot.Target] = newChurn(previous, snapshot)
+}
+
+// removedRoutines returns the goroutines which disappeared with the latest snapshots of the shown targets. They are
+// only listed while the latest snapshots are shown.
+func (ui *UI) removedRoutines() []model.Goroutine {
+	if ui.timeline.browsing() {
+		return nil
+	}
+	var removed []model.Goroutine
+	for _, target := range ui.targets {
+		if len(ui.tab) == 0 || target == ui.tab {
+			removed = append(removed, ui.churn[target].removed...)
+		}
+	}
+	return removed
+}
+
+// markChurn marks the row by the change of the goroutine since the previous snapshot of its target while the
+// latest snapshots are shown. The rows of pinned goroutines are colored as pinned and the rows of unchanged
+// goroutines which wait longer than the threshold as error. The other rows take the color of their label.
+func (ui *UI) markChurn(g model.Goroutine, row string) string {
+	mark := churnNone
+	if len(ui.churn) > 0 && !ui.timeline.browsing() {
+		mark = ui.churn[g.Target].marks[g.Fingerprint()]
+		row = churnMarks[mark] + " " + row
+	}
+	role := churnRoles[mark]
+	switch {
+	case ui.pinned(g):
+		role = "notice"
+	case len(role) == 0 && ui.stuck(g):
+		role = "error"
+	case len(role) == 0:
+		role = ui.labelRole(g.Labels)
+	}
+	if len(role) == 0 {
+		return row
+	}
+	return fmt.Sprintf("[%s](fg:%s)", row, role)
+}
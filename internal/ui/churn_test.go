@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChurn(t *testing.T) {
+	ui := &UI{
+		timeline:  newTimeline(),
+		targets:   []string{"a"},
+		snapshots: map[string]model.Snapshot{},
+		churn:     map[string]churn{},
+	}
+	first := model.Snapshot{Target: "a", Goroutines: []model.Goroutine{
+		{ID: 1, Status: "select", WaitSinceMin: 5, Target: "a"},
+		{ID: 2, Status: "chan receive", WaitSinceMin: 3, Target: "a"},
+		{ID: 3, Status: "running", Target: "a"},
+		{ID: 4, Status: "IO wait", WaitSinceMin: 2, Target: "a"},
+	}}
+	ui.trackChurn(first)
+	assert.Empty(t, ui.churn, "the first snapshot is not marked")
+	ui.snapshots["a"] = first
+
+	second := model.Snapshot{Target: "a", Goroutines: []model.Goroutine{
+		{ID: 1, Status: "select", WaitSinceMin: 6, Target: "a"},
+		{ID: 2, Status: "runnable", Target: "a"},
+		{ID: 4, Status: "IO wait", Target: "a"},
+		{ID: 5, Status: "running", Target: "a"},
+	}}
+	ui.trackChurn(second)
+	ui.snapshots["a"] = second
+	ui.mergeSnapshots()
+
+	var ids []int64
+	for _, g := range ui.origData {
+		ids = append(ids, g.ID)
+	}
+	assert.Equal(t, []int64{1, 2, 4, 5, 3}, ids, "the removed goroutine is listed last")
+	assert.Equal(t, "  1 ", ui.markChurn(ui.origData[0], "1 "))
+	assert.Equal(t, "[~ 2 ](fg:info)", ui.markChurn(ui.origData[1], "2 "))
+	assert.Equal(t, "[~ 4 ](fg:info)", ui.markChurn(ui.origData[2], "4 "), "the wait started again")
+	assert.Equal(t, "[+ 5 ](fg:added)", ui.markChurn(ui.origData[3], "5 "))
+	assert.Equal(t, "[- 3 ](fg:removed)", ui.markChurn(ui.origData[4], "3 "))
+}
@@ -0,0 +1,87 @@
+package ui
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// clipboardCommands of the platforms. The first one which is installed is used.
+var clipboardCommands = map[string][][]string{
+	"darwin":  {{"pbcopy"}},
+	"windows": {{"clip.exe"}},
+	"linux":   {{"wl-copy"}, {"xclip", "-selection", "clipboard"}, {"xsel", "--clipboard", "--input"}},
+}
+
+// copyToClipboard copies the text with the clipboard command of the platform. Over SSH or without a command the
+// terminal is asked to copy the text with the OSC 52 escape sequence, which also reaches the clipboard of the
+// local machine. Returns how the text was copied.
+func copyToClipboard(text string) (string, error) {
+	remote := len(os.Getenv("SSH_TTY")) > 0 || len(os.Getenv("SSH_CONNECTION")) > 0
+	if !remote {
+		for _, command := range clipboardCommands[runtime.GOOS] {
+			if command[0] == "wl-copy" && len(os.Getenv("WAYLAND_DISPLAY")) == 0 {
+				continue
+			}
+			if _, err := exec.LookPath(command[0]); err != nil {
+				continue
+			}
+			cmd := exec.Command(command[0], command[1:]...)
+			cmd.Stdin = strings.NewReader(text)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				return "", fmt.Errorf("%s failed: %s %s", command[0], err.Error(), strings.TrimSpace(string(out)))
+			}
+			return command[0], nil
+		}
+	}
+	if _, err := os.Stdout.WriteString(osc52(text, len(os.Getenv("TMUX")) > 0)); err != nil {
+		return "", err
+	}
+	return "terminal", nil
+}
+
+// osc52 returns the escape sequence which sets the clipboard of the terminal. tmux only passes the sequence on to
+// the terminal if it is wrapped and allow-passthrough is set.
+func osc52(text string, tmux bool) string {
+	sequence := fmt.Sprintf("\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	if tmux {
+		return fmt.Sprintf("\x1bPtmux;%s\x1b\\", strings.ReplaceAll(sequence, "\x1b", "\x1b\x1b"))
+	}
+	return sequence
+}
+
+// copyGoroutines copies the goroutines as a goroutine dump and shows the result in the status bar
+func (ui *UI) copyGoroutines(routines []model.Goroutine, what string) {
+	if len(routines) == 0 {
+		ui.notice = "[Nothing to copy](fg:warn)"
+		return
+	}
+	method, err := copyToClipboard(model.Dump(routines))
+	if err != nil {
+		ui.notice = fmt.Sprintf("[Failed to copy %s: %s](fg:error)", what, plain(err.Error()))
+		return
+	}
+	ui.notice = fmt.Sprintf("[Copied %s with %s](fg:ok)", what, method)
+}
+
+// selectedGoroutines returns the goroutine of the selected row. Groups and nodes of the creator tree return the
+// goroutine whose stack is shown in the details.
+func (ui *UI) selectedGoroutines() []model.Goroutine {
+	row := ui.list.SelectedRow
+	switch {
+	case (ui.view == viewStacks || ui.view == viewDiff) && row < len(ui.groups):
+		return ui.groups[row].Goroutines[:1]
+	case ui.view == viewCreators && row < len(ui.creators):
+		if node := ui.creators[row].node; len(node.Goroutines) > 0 {
+			return node.Goroutines[:1]
+		}
+	case ui.view == viewRoutines && row < len(ui.filteredData):
+		return ui.filteredData[row : row+1]
+	}
+	return nil
+}
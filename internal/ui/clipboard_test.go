@@ -0,0 +1,12 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOSC52(t *testing.T) {
+	assert.Equal(t, "\x1b]52;c;Z29yb3V0aW5lIDE=\a", osc52("goroutine 1", false))
+	assert.Equal(t, "\x1bPtmux;\x1b\x1b]52;c;Z29yb3V0aW5lIDE=\a\x1b\\", osc52("goroutine 1", true))
+}
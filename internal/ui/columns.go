@@ -0,0 +1,256 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// DefaultColumns are the columns of the goroutine list if none are configured
+const DefaultColumns = "target,id,status"
+
+// baseColumns can be toggled in the column chooser even if they are not configured
+var baseColumns = []string{"target", "id", "status", "wait", "func", "created", "locked"}
+
+// scrollStep is the number of characters the list scrolls horizontally by key
+const scrollStep = 8
+
+// trimMode tells which part of a value which is longer than the column is cut
+type trimMode string
+
+const (
+	trimEnd    trimMode = "end"    // Cut the end
+	trimMiddle trimMode = "middle" // Cut the middle and keep the package and the method of function names
+)
+
+// column of the goroutine list
+type column struct {
+	name    string // One of baseColumns or label:<key>
+	width   int    // Fixed width. Zero fits the value
+	trim    trimMode
+	visible bool
+}
+
+// defaultTrim cuts function names in the middle and all other values at the end
+func defaultTrim(name string) trimMode {
+	if name == "func" || name == "created" {
+		return trimMiddle
+	}
+	return trimEnd
+}
+
+// parseColumns parses a comma separated list of columns with an optional width and trim mode, for example
+// id,status:20,func:40,label:tenant:10:middle. Base columns which are not listed are added as hidden columns.
+func parseColumns(spec string) ([]column, error) {
+	var columns []column
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if len(field) == 0 {
+			continue
+		}
+		parts := strings.Split(field, ":")
+		col := column{name: parts[0], visible: true}
+		widthPart := 1
+		if col.name == "label" {
+			if len(parts) < 2 || len(parts[1]) == 0 {
+				return nil, fmt.Errorf("missing key of label column %s", field)
+			}
+			col.name = "label:" + parts[1]
+			widthPart = 2
+		} else if !isBaseColumn(col.name) {
+			return nil, fmt.Errorf("unknown column %s. Expected one of %s or label:<key>", col.name, strings.Join(baseColumns, ", "))
+		}
+		if len(parts) > widthPart+2 {
+			return nil, fmt.Errorf("invalid column %s", field)
+		}
+		if len(parts) > widthPart {
+			width, err := strconv.Atoi(parts[widthPart])
+			if err != nil || width < 0 {
+				return nil, fmt.Errorf("invalid width of column %s", field)
+			}
+			col.width = width
+		}
+		col.trim = defaultTrim(col.name)
+		if len(parts) > widthPart+1 {
+			col.trim = trimMode(parts[widthPart+1])
+			if col.trim != trimEnd && col.trim != trimMiddle {
+				return nil, fmt.Errorf("invalid trim of column %s. Expected end or middle", field)
+			}
+		}
+		columns = append(columns, col)
+	}
+	for _, name := range baseColumns {
+		if !containsColumn(columns, name) {
+			columns = append(columns, column{name: name, trim: defaultTrim(name)})
+		}
+	}
+	return columns, nil
+}
+
+// formatColumns returns the visible columns in the format of parseColumns
+func formatColumns(columns []column) string {
+	var fields []string
+	for _, col := range columns {
+		if !col.visible {
+			continue
+		}
+		field := col.name
+		switch {
+		case col.trim != defaultTrim(col.name):
+			field += fmt.Sprintf(":%d:%s", col.width, col.trim)
+		case col.width > 0:
+			field += fmt.Sprintf(":%d", col.width)
+		}
+		fields = append(fields, field)
+	}
+	return strings.Join(fields, ",")
+}
+
+// toggleColumn shows or hides the column with the number from 1 and saves the columns. Returns false if there is
+// no such column.
+func (ui *UI) toggleColumn(number int) bool {
+	if number < 1 || number > min(9, len(ui.columns)) {
+		return false
+	}
+	ui.columns[number-1].visible = !ui.columns[number-1].visible
+	if ui.saveColumns != nil {
+		if err := ui.saveColumns(formatColumns(ui.columns)); err != nil {
+			ui.notice = fmt.Sprintf("[Failed to save the columns: %s](fg:error)", plain(err.Error()))
+		}
+	}
+	return true
+}
+
+func isBaseColumn(name string) bool {
+	for _, base := range baseColumns {
+		if base == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsColumn(columns []column, name string) bool {
+	for _, col := range columns {
+		if col.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// value of the column for the goroutine. The func column shows the first frame which is not hidden.
+func (c column) value(g model.Goroutine, hide frameFilter) string {
+	switch c.name {
+	case "target":
+		return g.Target
+	case "id":
+		return fmt.Sprintf("%05d", g.ID)
+	case "status":
+		return g.Status
+	case "wait":
+		if g.WaitSinceMin > 0 {
+			return fmt.Sprintf("%dm", g.WaitSinceMin)
+		}
+		return ""
+	case "func":
+		return hide.topFunc(g)
+	case "created":
+		if g.CratedBy != nil {
+			return g.CratedBy.Func()
+		}
+		return ""
+	case "locked":
+		if g.LockedToThread {
+			return "locked"
+		}
+		return ""
+	}
+	if key, ok := strings.CutPrefix(c.name, "label:"); ok {
+		return g.Labels[key]
+	}
+	return ""
+}
+
+// fit cuts or pads the value to the width of the column
+func (c column) fit(text string) string {
+	if c.trim == trimMiddle {
+		return fitMiddle(text, c.width)
+	}
+	return fit(text, c.width)
+}
+
+// fitMiddle cuts the middle of the text or pads it to the width. Zero keeps the text. Of function names like
+// github.com/org/repo/vendor/example.com/pkg.(*Type[...]).Method the leading path elements are dropped first,
+// then the middle of the last one, which keeps the package and the method. Widths count terminal columns.
+func fitMiddle(text string, width int) string {
+	if width <= 0 || displayWidth(text) <= width {
+		return fit(text, width)
+	}
+	if i := strings.LastIndex(text, "/"); i >= 0 {
+		last := text[i+1:]
+		elements := strings.Split(text[:i], "/")
+		for len(elements) > 0 {
+			short := "…/" + strings.Join(elements, "/") + "/" + last
+			if displayWidth(short) <= width {
+				return fit(short, width)
+			}
+			elements = elements[1:]
+		}
+		if short := "…/" + last; displayWidth(short) <= width {
+			return fit(short, width)
+		}
+		text = last
+	}
+	if first, last := strings.Index(text, "."), strings.LastIndex(text, "."); first > 0 && last > first {
+		short := text[:first] + "…" + text[last:]
+		if displayWidth(short) <= width {
+			return fit(short, width)
+		}
+	}
+	if width < 3 {
+		return fit(text, width)
+	}
+	head := (width - 1) / 2
+	return pad(cutWidth(text, head)+"…"+tailWidth(text, width-1-head), width)
+}
+
+// scrollText drops the first columns of the text which are scrolled out of view. A wide character which is cut
+// by the view is replaced by a space.
+func scrollText(text string, offset int) string {
+	if offset <= 0 {
+		return text
+	}
+	dropped := cutWidth(text, offset)
+	rest := text[len(dropped):]
+	if over := offset - displayWidth(dropped); over > 0 && len(rest) > 0 {
+		_, size := utf8.DecodeRuneInString(rest)
+		return strings.Repeat(" ", displayWidth(rest[:size])-over) + rest[size:]
+	}
+	return rest
+}
+
+// fit cuts or pads the text to the width in terminal columns. Zero keeps the text.
+func fit(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+	if displayWidth(text) > width {
+		return pad(cutWidth(text, width-1)+"…", width)
+	}
+	return pad(text, width)
+}
+
+// scrollList scrolls the rows of the list horizontally until the end of the widest row is visible. The rows of
+// the creators view are not scrolled.
+func (ui *UI) scrollList(delta int) {
+	widest := 0
+	for _, row := range ui.list.Rows {
+		widest = max(widest, displayWidth(styleMarkup.ReplaceAllString(row, "$1")))
+	}
+	ui.scrollX = max(0, min(ui.scrollX+delta, ui.scrollX+widest-ui.list.Inner.Dx()))
+	ui.updateList()
+}
@@ -0,0 +1,136 @@
+package ui
+
+import (
+	"image"
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+func TestParseColumnTrim(t *testing.T) {
+	columns, err := parseColumns("id,status:12:middle,func:40,label:tenant:10:end")
+	assert.NoError(t, err)
+	assert.Equal(t, column{name: "status", width: 12, trim: trimMiddle, visible: true}, columns[1])
+	assert.Equal(t, column{name: "func", width: 40, trim: trimMiddle, visible: true}, columns[2])
+	assert.Equal(t, column{name: "label:tenant", width: 10, trim: trimEnd, visible: true}, columns[3])
+
+	_, err = parseColumns("func:40:start")
+	assert.Error(t, err)
+	_, err = parseColumns("func:40:end:x")
+	assert.Error(t, err)
+}
+
+func TestFitMiddle(t *testing.T) {
+	name := "github.com/org/repo/vendor/example.com/pkg.(*Type[go.shape.int]).Method"
+	assert.Equal(t, name, fitMiddle(name, 0))
+	assert.Equal(t, "…/example.com/pkg.(*Type[go.shape.int]).Method", fitMiddle(name, 46))
+	assert.Equal(t, "…/pkg.(*Type[go.shape.int]).Method  ", fitMiddle(name, 36))
+	assert.Equal(t, "pkg….Method", fitMiddle(name, 11))
+	assert.Equal(t, "pkg.…ethod", fitMiddle(name, 10))
+	assert.Equal(t, "ab…yz", fitMiddle("abcdefghijklmnopqrstuvwxyz", 5))
+	assert.Equal(t, "short ", fitMiddle("short", 6))
+}
+
+func TestScrollText(t *testing.T) {
+	assert.Equal(t, "éfg", scrollText("abcdéfg", 4))
+	assert.Equal(t, "", scrollText("abc", 8))
+	assert.Equal(t, "abc", scrollText("abc", 0))
+}
+
+func TestFitWide(t *testing.T) {
+	// Every CJK character takes two columns
+	assert.Equal(t, "服务… ", fit("服务器处理", 6))
+	assert.Equal(t, "服务器处理  ", fit("服务器处理", 12))
+	assert.Equal(t, 6, displayWidth(fit("服务器处理", 6)))
+	assert.Equal(t, "…/处理.(*队列).Get  ", fitMiddle("example.com/服务器/处理.(*队列).Get", 20))
+	assert.Equal(t, 7, displayWidth(fitMiddle("服务器处理服务器处理", 7)))
+	assert.Equal(t, " 务器", scrollText("服务器", 1))
+	assert.Equal(t, "务器", scrollText("服务器", 2))
+}
+
+func TestWrapCells(t *testing.T) {
+	rows := wrapCells(termui.ParseStyles("IDs: 1, 2, 3", termui.StyleClear), 7)
+	var texts []string
+	for _, row := range rows {
+		texts = append(texts, termui.CellsToString(row))
+	}
+	assert.Equal(t, []string{"IDs: 1,", "2, 3"}, texts)
+
+	rows = wrapCells(termui.ParseStyles("服务 器处", termui.StyleClear), 4)
+	assert.Equal(t, "服务", termui.CellsToString(rows[0]))
+	assert.Equal(t, "器处", termui.CellsToString(rows[1]))
+}
+
+func TestWideListDraw(t *testing.T) {
+	list := newWideList()
+	list.SetRect(0, 0, 9, 4)
+	list.Rows = []string{"服务器处理", "ab"}
+	buf := termui.NewBuffer(list.GetRect())
+	list.Draw(buf)
+	row := ""
+	for x := list.Inner.Min.X; x < list.Inner.Max.X; x++ {
+		row += string(buf.GetCell(image.Pt(x, list.Inner.Min.Y)).Rune)
+	}
+	assert.Equal(t, "服 务 器 …", row, "wide characters take two columns and are cut with an ellipsis")
+}
+
+func TestLockedColumn(t *testing.T) {
+	columns, err := parseColumns("id,locked")
+	assert.NoError(t, err)
+	assert.Equal(t, "locked", columns[1].value(model.Goroutine{LockedToThread: true}, frameFilter{}))
+	assert.Empty(t, columns[1].value(model.Goroutine{}, frameFilter{}))
+}
+
+func TestWideListHighlighted(t *testing.T) {
+	l := newWideList()
+	cells := termui.ParseStyles("[00001](fg:red) main.main", termui.StyleClear)
+	assert.Equal(t, make([]bool, 15), l.highlighted(cells))
+	l.highlight = []string{"mm"}
+	assert.Equal(t, []bool{5: false, 6: true, 11: true, 14: false}, l.highlighted(cells))
+}
+
+func TestParseColumns(t *testing.T) {
+	columns, err := parseColumns(" id, func:40 ,label:tenant")
+	assert.NoError(t, err)
+	var names []string
+	for _, col := range columns {
+		names = append(names, col.name)
+	}
+	assert.Equal(t, []string{"id", "func", "label:tenant", "target", "status", "wait", "created", "locked"}, names,
+		"base columns which are not listed are hidden at the end")
+	assert.Equal(t, 40, columns[1].width)
+	assert.True(t, columns[2].visible)
+	assert.False(t, columns[3].visible)
+
+	for _, spec := range []string{"name", "label", "label:", "id:-1", "id:x"} {
+		_, err := parseColumns(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestToggleColumn(t *testing.T) {
+	var saved []string
+	ui := &UI{saveColumns: func(spec string) error {
+		saved = append(saved, spec)
+		return nil
+	}}
+	ui.columns, _ = parseColumns(DefaultColumns)
+
+	assert.True(t, ui.toggleColumn(1))
+	assert.True(t, ui.toggleColumn(5))
+	assert.False(t, ui.toggleColumn(0))
+	assert.False(t, ui.toggleColumn(len(ui.columns)+1))
+	assert.Equal(t, []string{"id,status", "id,status,func"}, saved)
+	assert.False(t, ui.columns[0].visible)
+}
+
+func TestFormatColumns(t *testing.T) {
+	for _, spec := range []string{"id,func:40,label:tenant:10:middle", "status:12:middle,created:0:end", DefaultColumns} {
+		columns, err := parseColumns(spec)
+		assert.NoError(t, err)
+		assert.Equal(t, spec, formatColumns(columns))
+	}
+}
@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// compactRow shows the goroutine in one dense line instead of the columns: the ID, the status, the wait, the top
+// function and its creator, for example 4431 chan receive 16m pgxpool.(*Pool).Acquire → main.serve. Function
+// names drop their import path.
+func (ui *UI) compactRow(g model.Goroutine) string {
+	var parts []string
+	if len(ui.targets) > 1 {
+		parts = append(parts, g.Target)
+	}
+	parts = append(parts, strconv.FormatInt(g.ID, 10), g.Status)
+	if g.WaitSinceMin > 0 {
+		parts = append(parts, fmt.Sprintf("%dm", g.WaitSinceMin))
+	}
+	parts = append(parts, shortFunc(ui.hide.topFunc(g)))
+	if g.CratedBy != nil {
+		parts = append(parts, "→", shortFunc(g.CratedBy.Func()))
+	}
+	return plain(strings.Join(parts, " "))
+}
+
+// shortFunc drops the import path of the function, for example github.com/jackc/pgx/v5/pgxpool.(*Pool).Acquire
+// becomes pgxpool.(*Pool).Acquire
+func shortFunc(name string) string {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// toggleCompact switches between the columns and the compact rows. The compact mode hides the charts, so the list
+// and the details take the full height.
+func (ui *UI) toggleCompact() {
+	ui.compact = !ui.compact
+	ui.layout()
+	ui.updateList()
+}
@@ -0,0 +1,29 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompactRow(t *testing.T) {
+	g := model.Goroutine{
+		ID:           4431,
+		Status:       "chan receive",
+		WaitSinceMin: 16,
+		StackTrace: []model.StackFrame{
+			{FuncName: "github.com/jackc/pgx/v5/pgxpool.(*Pool).Acquire(0xc000010000)"},
+		},
+		CratedBy: &model.StackFrame{FuncName: "main.serve"},
+		Target:   "localhost:6060",
+	}
+	ui := &UI{targets: []string{"localhost:6060"}}
+	assert.Equal(t, "4431 chan receive 16m pgxpool.(*Pool).Acquire → main.serve", ui.compactRow(g))
+
+	ui.targets = append(ui.targets, "localhost:6061")
+	g.WaitSinceMin, g.CratedBy = 0, nil
+	assert.Equal(t, "localhost:6060 4431 chan receive pgxpool.(*Pool).Acquire", ui.compactRow(g))
+
+	assert.Equal(t, "main.main", shortFunc("main.main"))
+}
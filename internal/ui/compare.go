@@ -0,0 +1,99 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// changeRoles are the roles of the theme colors of the changes
+var changeRoles = map[model.Change]string{
+	model.Persisting: "persisting",
+	model.Added:      "added",
+	model.Removed:    "removed",
+}
+
+var changeMarks = map[model.Change]string{
+	model.Persisting: "=",
+	model.Added:      "+",
+	model.Removed:    "-",
+}
+
+// capture of the shown goroutines at one point in time
+type capture struct {
+	time       time.Time
+	goroutines []model.Goroutine
+}
+
+// comparison of two captures
+type comparison struct {
+	a, b    capture
+	entries []model.DiffEntry
+	changes map[string]model.Change // Change by fingerprint
+}
+
+// newCapture takes the shown goroutines. The time is the time of the newest shown snapshot.
+func (ui *UI) newCapture() capture {
+	c := capture{goroutines: ui.origData}
+	for _, snapshot := range ui.shownSnapshots() {
+		if snapshot.Time.After(c.time) {
+			c.time = snapshot.Time
+		}
+	}
+	return c
+}
+
+// nextCapture captures snapshot A, then snapshot B and shows the comparison, then returns to the live view
+func (ui *UI) nextCapture() {
+	switch {
+	case ui.comparison != nil:
+		ui.comparison = nil
+		ui.captured = nil
+	case ui.captured == nil:
+		a := ui.newCapture()
+		ui.captured = &a
+	default:
+		b := ui.newCapture()
+		c := &comparison{
+			a:       *ui.captured,
+			b:       b,
+			entries: model.Diff(ui.captured.goroutines, b.goroutines),
+			changes: make(map[string]model.Change),
+		}
+		for _, entry := range c.entries {
+			c.changes[entry.Fingerprint()] = entry.Change
+		}
+		ui.comparison = c
+	}
+	ui.mergeSnapshots()
+	ui.updateList()
+	ui.updateStatus()
+	ui.updateStatusBar()
+}
+
+// compareStatus shows the captured snapshot or the summary of the comparison
+func (ui *UI) compareStatus() string {
+	if ui.comparison != nil {
+		counts := make(map[model.Change]int)
+		for _, entry := range ui.comparison.entries {
+			counts[entry.Change]++
+		}
+		return fmt.Sprintf("[Compare A %s with B %s:](fg:info) [+%d](fg:added) [-%d](fg:removed) =%d [F11 to close](fg:info)",
+			ui.comparison.a.time.Format(time.TimeOnly), ui.comparison.b.time.Format(time.TimeOnly),
+			counts[model.Added], counts[model.Removed], counts[model.Persisting])
+	}
+	if ui.captured != nil {
+		return fmt.Sprintf("[Captured A %s. F11 to capture B and compare](fg:info)", ui.captured.time.Format(time.TimeOnly))
+	}
+	return ""
+}
+
+// markChange colors the row by the change of the goroutine if a comparison is shown
+func (ui *UI) markChange(g model.Goroutine, row string) string {
+	if ui.comparison == nil {
+		return row
+	}
+	change := ui.comparison.changes[g.Fingerprint()]
+	return fmt.Sprintf("[%s %s](fg:%s)", changeMarks[change], row, changeRoles[change])
+}
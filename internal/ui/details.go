@@ -0,0 +1,284 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// styleMarkup matches the style markup of termui, for example [text](fg:red,mod:bold)
+var styleMarkup = regexp.MustCompile(`\[([^\]]*)\]\(((?:fg|bg|mod):[a-z]+(?:,(?:fg|bg|mod):[a-z]+)*)\)`)
+
+// match of the find pattern in the details
+type match struct {
+	line       int
+	start, end int // Byte offsets in the line without style markup
+}
+
+// detailFrame is a stack frame which is shown in the details
+type detailFrame struct {
+	line  int // First of the two lines of the frame
+	frame model.StackFrame
+}
+
+// details of the selected goroutine with the state of the find and the selected frame
+type details struct {
+	key     string   // Fingerprint of the shown goroutine
+	lines   []string // Lines with style markup
+	plain   []string // Lines without style markup
+	offset  int      // First shown line
+	pattern string   // Find pattern. Empty if nothing is searched
+	matches []match
+	current int // Index of the current match
+	frames  []detailFrame
+	frame   int // Index of the selected frame
+}
+
+// showDetails sets the text of the details panel. The frames are the frames of the text in the order in which
+// they are shown. The current match and the selected frame are kept if the same goroutine is shown again.
+func (ui *UI) showDetails(key, text string, frames []model.StackFrame) {
+	d := &ui.detail
+	same := d.key == key
+	d.key = key
+	d.lines = strings.Split(text, "\n")
+	d.plain = make([]string, len(d.lines))
+	for i, line := range d.lines {
+		d.plain[i] = styleMarkup.ReplaceAllString(line, "$1")
+	}
+	d.frames = frameLines(d.plain, frames)
+	if !same {
+		d.offset = 0
+		d.current = 0
+		d.frame = 0
+	}
+	d.frame = min(d.frame, max(0, len(d.frames)-1))
+	ui.findMatches()
+	ui.renderDetails()
+}
+
+// frameLines finds the lines of the frames. Every frame is searched after the line of the previous one.
+func frameLines(plain []string, frames []model.StackFrame) []detailFrame {
+	result := make([]detailFrame, 0, len(frames))
+	line := 0
+	for _, frame := range frames {
+		name, _, _ := strings.Cut(styleMarkup.ReplaceAllString(styledFrame(frame), "$1"), "\n")
+		for ; line < len(plain); line++ {
+			if strings.HasPrefix(strings.TrimSpace(plain[line]), name) {
+				result = append(result, detailFrame{line: line, frame: frame})
+				line++
+				break
+			}
+		}
+	}
+	return result
+}
+
+// selectedFrame returns the selected frame of the details
+func (ui *UI) selectedFrame() (model.StackFrame, bool) {
+	d := &ui.detail
+	if d.frame >= len(d.frames) {
+		return model.StackFrame{}, false
+	}
+	return d.frames[d.frame].frame, true
+}
+
+// toggleFocus moves the keys for moving from the list to the frames of the details and back. Narrow terminals
+// show the pane which has the focus.
+func (ui *UI) toggleFocus() {
+	ui.focusDetails = !ui.focusDetails
+	if ui.size == layoutNarrow {
+		ui.layout()
+	}
+	ui.renderDetails()
+}
+
+// moveFrame applies the moving actions to the selected frame. Details without frames are scrolled by lines.
+// Returns false if the action does not move.
+func (ui *UI) moveFrame(a action) bool {
+	d := &ui.detail
+	pos, last, page := d.frame, len(d.frames)-1, ui.details.Inner.Dy()/2
+	if len(d.frames) == 0 {
+		pos, last, page = d.offset, len(d.lines)-1, ui.details.Inner.Dy()
+	}
+	switch a {
+	case actionDown:
+		pos++
+	case actionUp:
+		pos--
+	case actionPageDown:
+		pos += max(1, page)
+	case actionPageUp:
+		pos -= max(1, page)
+	case actionTop:
+		pos = 0
+		d.offset = 0
+	case actionBottom:
+		pos = last
+	default:
+		return false
+	}
+	pos = max(0, min(pos, last))
+	if len(d.frames) == 0 {
+		d.offset = pos
+	} else {
+		d.frame = pos
+	}
+	ui.renderDetails()
+	return true
+}
+
+// filterFrame filters the goroutines by the function of the selected frame
+func (ui *UI) filterFrame() {
+	frame, ok := ui.selectedFrame()
+	if !ok {
+		return
+	}
+	ui.searching = false
+	ui.filtered = true
+	ui.filter.Text = regexp.QuoteMeta(frame.Func())
+	ui.list.ScrollTop()
+	ui.updateList()
+}
+
+// findMatches finds all case insensitive matches of the pattern. The pattern is matched literally.
+func (ui *UI) findMatches() {
+	d := &ui.detail
+	d.matches = nil
+	if len(d.pattern) > 0 {
+		pattern := regexp.MustCompile("(?i)" + regexp.QuoteMeta(d.pattern))
+		for i, line := range d.plain {
+			for _, loc := range pattern.FindAllStringIndex(line, -1) {
+				d.matches = append(d.matches, match{line: i, start: loc[0], end: loc[1]})
+			}
+		}
+	}
+	if d.current >= len(d.matches) {
+		d.current = 0
+	}
+}
+
+// findNext moves to the next or previous match and scrolls it into view
+func (ui *UI) findNext(delta int) {
+	d := &ui.detail
+	if len(d.matches) == 0 {
+		return
+	}
+	d.current = (d.current + delta + len(d.matches)) % len(d.matches)
+	if ui.focusDetails {
+		// Select the frame of the match
+		for i, f := range d.frames {
+			if f.line <= d.matches[d.current].line {
+				d.frame = i
+			}
+		}
+	}
+	ui.renderDetails()
+}
+
+// renderDetails shows the lines from the offset and highlights the matches and the selected frame. The selected
+// frame is scrolled into view while the details have the focus, otherwise the current match.
+func (ui *UI) renderDetails() {
+	d := &ui.detail
+	visible := max(1, ui.details.Inner.Dy())
+	switch {
+	case ui.focusDetails && len(d.frames) > 0:
+		line := d.frames[d.frame].line
+		if line < d.offset {
+			d.offset = line
+		} else if line+2 > d.offset+visible {
+			d.offset = max(0, line+2-visible)
+		}
+	case len(d.matches) > 0:
+		line := d.matches[d.current].line
+		if line < d.offset || line >= d.offset+visible {
+			d.offset = max(0, line-visible/3)
+		}
+	}
+	d.offset = min(d.offset, max(0, len(d.lines)-1))
+	ui.details.BorderStyle.Fg = ui.theme.colors["border"]
+	if ui.focusDetails {
+		ui.details.BorderStyle.Fg = ui.theme.colors["accent"]
+	}
+
+	lines := make([]string, 0, len(d.lines)-d.offset)
+	for i := d.offset; i < len(d.lines); i++ {
+		lines = append(lines, ui.highlight(i))
+	}
+	ui.details.Text = strings.Join(lines, "\n")
+	ui.updateFindTitle()
+}
+
+// highlight the matches of the line and the lines of the selected frame. Highlighted lines lose their other styles.
+func (ui *UI) highlight(line int) string {
+	d := &ui.detail
+	if ui.focusDetails && len(d.frames) > 0 {
+		if start := d.frames[d.frame].line; line == start || line == start+1 {
+			return fmt.Sprintf("[%s](fg:selected,bg:selectedbg)", plain(d.plain[line]))
+		}
+	}
+	text := ""
+	pos := 0
+	for i, m := range d.matches {
+		if m.line != line {
+			continue
+		}
+		style := "fg:matchtext,bg:match"
+		if i == d.current {
+			style = "fg:matchtext,bg:currentmatch"
+		}
+		text += plain(d.plain[line][pos:m.start]) + fmt.Sprintf("[%s](%s)", plain(d.plain[line][m.start:m.end]), style)
+		pos = m.end
+	}
+	if pos == 0 {
+		return d.lines[line]
+	}
+	return text + plain(d.plain[line][pos:])
+}
+
+// updateFindTitle shows the selected frame, the pattern and the position of the current match in the title of the
+// details
+func (ui *UI) updateFindTitle() {
+	d := &ui.detail
+	title, _, _ := strings.Cut(ui.details.Title, " | ")
+	if ui.focusDetails && len(d.frames) > 0 {
+		title += fmt.Sprintf(" | Frame %d/%d", d.frame+1, len(d.frames))
+	}
+	switch {
+	case len(d.pattern) == 0 && !ui.finding:
+	case len(d.matches) == 0:
+		title += fmt.Sprintf(" | Find %q: no match", d.pattern)
+	default:
+		title += fmt.Sprintf(" | Find %q: %d/%d", d.pattern, d.current+1, len(d.matches))
+	}
+	ui.details.Title = title
+}
+
+// editFind applies the key to the find pattern. Returns false if the key does not edit the pattern.
+func (ui *UI) editFind(keyID string) bool {
+	d := &ui.detail
+	switch keyID {
+	case "<Backspace>", "<C-<Backspace>>":
+		if len(d.pattern) > 0 {
+			runes := []rune(d.pattern)
+			d.pattern = string(runes[:len(runes)-1])
+		}
+	case "<Enter>":
+		ui.finding = false
+	case "<Escape>":
+		ui.finding = false
+		d.pattern = ""
+	case "<Space>":
+		d.pattern += " "
+	default:
+		if len([]rune(keyID)) != 1 {
+			return false
+		}
+		d.pattern += keyID
+	}
+	d.current = 0
+	ui.findMatches()
+	ui.renderDetails()
+	return true
+}
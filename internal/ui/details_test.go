@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrameLines(t *testing.T) {
+	g := model.Goroutine{
+		ID:       7,
+		Status:   "chan receive",
+		CratedBy: &model.StackFrame{FuncName: "main.main", File: "/src/main.go", Line: 10},
+		StackTrace: []model.StackFrame{
+			{FuncName: "runtime.gopark(0x0?)", File: "/usr/local/go/src/runtime/proc.go", Line: 398},
+			{FuncName: "main.worker(...)", File: "/src/main.go", Line: 20, Inlined: true},
+			{FuncName: "main.worker(0xc000010000)", File: "/src/main.go", Line: 20},
+		},
+	}
+	ui := &UI{}
+	text := ui.goroutineDetails(g)
+	plainLines := strings.Split(styleMarkup.ReplaceAllString(text, "$1"), "\n")
+
+	frames := frameLines(plainLines, ui.stackFrames(g))
+	assert.Len(t, frames, 4)
+	for i, f := range frames {
+		assert.Equal(t, ui.stackFrames(g)[i], f.frame)
+		assert.Contains(t, plainLines[f.line+1], f.frame.File)
+	}
+	assert.Equal(t, "  main.main", plainLines[frames[0].line])
+	assert.Equal(t, "  main.worker(...)", plainLines[frames[2].line])
+	assert.True(t, strings.HasSuffix(plainLines[frames[2].line+1], " inlined"))
+	assert.Less(t, frames[2].line, frames[3].line)
+}
+
+func newFindUI(lines ...string) *UI {
+	ui := &UI{details: newWideParagraph()}
+	ui.theme, _ = newTheme(DefaultTheme, nil, false)
+	ui.details.SetRect(0, 0, 80, 20)
+	ui.showDetails("key", strings.Join(lines, "\n"), nil)
+	return ui
+}
+
+func TestFindMatches(t *testing.T) {
+	ui := newFindUI("[main.Work](fg:function) in main.go", "Straße İstanbul work", "(*Pool).Acquire")
+	ui.detail.pattern = "WORK"
+	ui.findMatches()
+	assert.Equal(t, []match{{line: 0, start: 5, end: 9}, {line: 1, start: 18, end: 22}}, ui.detail.matches,
+		"offsets are taken from the lines without markup and lines with other scripts match")
+
+	ui.detail.pattern = "stra"
+	ui.findMatches()
+	assert.Equal(t, []match{{line: 1, start: 0, end: 4}}, ui.detail.matches)
+
+	ui.detail.pattern = "(*pool)."
+	ui.findMatches()
+	assert.Equal(t, []match{{line: 2, start: 0, end: 8}}, ui.detail.matches, "the pattern is matched literally")
+
+	ui.detail.pattern = "a"
+	ui.findMatches()
+	assert.Len(t, ui.detail.matches, 5)
+}
+
+func TestFindNext(t *testing.T) {
+	ui := newFindUI("a b", "b", "a")
+	for _, key := range []string{"A", "<Space>", "<Backspace>", "<Backspace>", "b"} {
+		assert.True(t, ui.editFind(key))
+	}
+	assert.Equal(t, "b", ui.detail.pattern)
+	assert.Len(t, ui.detail.matches, 2)
+	assert.Equal(t, 0, ui.detail.current)
+
+	ui.findNext(1)
+	assert.Equal(t, 1, ui.detail.current)
+	ui.findNext(1)
+	assert.Equal(t, 0, ui.detail.current, "next wraps around to the first match")
+	ui.findNext(-1)
+	assert.Equal(t, 1, ui.detail.current, "previous wraps around to the last match")
+	assert.Contains(t, ui.details.Title, `Find "b": 2/2`)
+
+	assert.False(t, ui.editFind("<Down>"))
+	assert.True(t, ui.editFind("<Escape>"))
+	assert.Empty(t, ui.detail.pattern)
+	assert.Empty(t, ui.detail.matches)
+}
+
+func TestHighlight(t *testing.T) {
+	ui := newFindUI("[main.work](fg:function) [in](mod:bold) work[1]", "nothing")
+	ui.detail.pattern = "work"
+	ui.findMatches()
+	ui.detail.current = 1
+	assert.Equal(t, "main.[work](fg:matchtext,bg:match) in [work](fg:matchtext,bg:currentmatch)(1)", ui.highlight(0),
+		"highlighted lines lose their other styles and brackets are replaced")
+	assert.Equal(t, "nothing", ui.highlight(1))
+}
@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// diffOrder is the order of the changes in the diff view
+var diffOrder = []churnMark{churnAdded, churnChanged, churnRemoved}
+
+// diffGroups groups the goroutines which appeared, changed or disappeared since the previous snapshot of their
+// target by stack. The marks are the change of every group.
+func (ui *UI) diffGroups(routines []model.Goroutine) ([]model.StackGroup, []churnMark) {
+	if ui.timeline.browsing() {
+		return nil, nil
+	}
+	byMark := make(map[churnMark][]model.Goroutine)
+	for _, g := range routines {
+		if mark := ui.churn[g.Target].marks[g.Fingerprint()]; mark != churnNone {
+			byMark[mark] = append(byMark[mark], g)
+		}
+	}
+	var groups []model.StackGroup
+	var marks []churnMark
+	for _, mark := range diffOrder {
+		for _, group := range model.GroupByStack(byMark[mark]) {
+			groups = append(groups, group)
+			marks = append(marks, mark)
+		}
+	}
+	return groups, marks
+}
+
+// diffRow shows the change, the size of the group, the status and where the goroutines wait
+func diffRow(group model.StackGroup, mark churnMark, scrollX int) string {
+	return fmt.Sprintf("[%s %s](fg:%s)", churnMarks[mark], scrollText(groupRow(group), scrollX), churnRoles[mark])
+}
+
+// diffTitle counts the goroutines of every change
+func (ui *UI) diffTitle() string {
+	if ui.timeline.browsing() {
+		return "Diff of the latest snapshots only"
+	}
+	counts := make(map[churnMark]int)
+	for i, group := range ui.groups {
+		counts[ui.diffMarks[i]] += len(group.Goroutines)
+	}
+	return fmt.Sprintf("Diff +%s ~%s -%s routines", formatCount(counts[churnAdded]), formatCount(counts[churnChanged]),
+		formatCount(counts[churnRemoved]))
+}
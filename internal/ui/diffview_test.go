@@ -0,0 +1,40 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffGroups(t *testing.T) {
+	stack := func(name string) []model.StackFrame {
+		return []model.StackFrame{{FuncName: name + "()", File: "/src/main.go"}}
+	}
+	ui := &UI{
+		timeline:  newTimeline(),
+		targets:   []string{"a"},
+		snapshots: map[string]model.Snapshot{},
+		churn:     map[string]churn{},
+	}
+	ui.snapshots["a"] = model.Snapshot{Target: "a", Goroutines: []model.Goroutine{
+		{ID: 1, Status: "select", Target: "a", StackTrace: stack("main.worker")},
+		{ID: 2, Status: "select", Target: "a", StackTrace: stack("main.worker")},
+		{ID: 3, Status: "running", Target: "a", StackTrace: stack("main.main")},
+	}}
+	next := model.Snapshot{Target: "a", Goroutines: []model.Goroutine{
+		{ID: 3, Status: "runnable", Target: "a", StackTrace: stack("main.main")},
+		{ID: 4, Status: "IO wait", Target: "a", StackTrace: stack("main.serve")},
+		{ID: 5, Status: "IO wait", Target: "a", StackTrace: stack("main.serve")},
+	}}
+	ui.trackChurn(next)
+	ui.snapshots["a"] = next
+	ui.mergeSnapshots()
+
+	ui.groups, ui.diffMarks = ui.diffGroups(ui.origData)
+	assert.Equal(t, []churnMark{churnAdded, churnChanged, churnRemoved}, ui.diffMarks)
+	assert.Len(t, ui.groups[0].Goroutines, 2)
+	assert.Len(t, ui.groups[2].Goroutines, 2)
+	assert.Equal(t, "[+ 2 × IO wait in main.serve ](fg:added)", diffRow(ui.groups[0], ui.diffMarks[0], 0))
+	assert.Equal(t, "Diff +2 ~1 -2 routines", ui.diffTitle())
+}
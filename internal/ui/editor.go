@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+// defaultEditorArgs open the file at the line in vi, vim, nvim, emacs, nano and most other terminal editors
+const defaultEditorArgs = "+{line} {file}"
+
+// editorArgs of the editors which do not understand +line
+var editorArgs = map[string]string{
+	"code":   "--goto {file}:{line}",
+	"codium": "--goto {file}:{line}",
+	"subl":   "{file}:{line}",
+	"goland": "--line {line} {file}",
+	"idea":   "--line {line} {file}",
+}
+
+// editor opens the files of the frames
+type editor struct {
+	command string            // Command with the placeholders {file} and {line}. Empty for $VISUAL or $EDITOR
+	paths   map[string]string // Local path by build path prefix
+}
+
+// SetEditor configures the command which opens the selected frame. The command may contain the placeholders
+// {file} and {line}, otherwise the arguments are chosen for the editor. Defaults to $VISUAL, $EDITOR or vi. The
+// paths map the path prefixes of the build to the prefixes of the local checkout, for example /build/src to
+// /home/me/src.
+func (ui *UI) SetEditor(command string, paths map[string]string) {
+	ui.editor = editor{command: command, paths: paths}
+}
+
+// args returns the command line which opens the file at the line
+func (e editor) args(file string, line int32) []string {
+	command := e.command
+	for _, env := range []string{os.Getenv("VISUAL"), os.Getenv("EDITOR"), "vi"} {
+		if len(command) == 0 {
+			command = env
+		}
+	}
+	if !strings.Contains(command, "{file}") {
+		args, ok := editorArgs[filepath.Base(strings.Fields(command)[0])]
+		if !ok {
+			args = defaultEditorArgs
+		}
+		command += " " + args
+	}
+	replacer := strings.NewReplacer("{file}", file, "{line}", strconv.Itoa(int(line)))
+	fields := strings.Fields(command)
+	for i, field := range fields {
+		fields[i] = replacer.Replace(field)
+	}
+	return fields
+}
+
+// localPath replaces the longest build path prefix of the file with the local prefix
+func (e editor) localPath(file string) string {
+	match := ""
+	for prefix := range e.paths {
+		if strings.HasPrefix(file, prefix) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	if len(match) == 0 {
+		return file
+	}
+	return e.paths[match] + strings.TrimPrefix(file, match)
+}
+
+// openFrame suspends the UI and opens the selected frame in the editor. The UI is restored once the editor exits.
+func (ui *UI) openFrame() {
+	frame, ok := ui.selectedFrame()
+	if !ok {
+		ui.notice = "[No frame selected](fg:warn)"
+		return
+	}
+	if frame.Autogenerated {
+		ui.notice = "[Autogenerated frames have no source file](fg:warn)"
+		return
+	}
+	file := ui.editor.localPath(frame.File)
+	if _, err := os.Stat(file); err != nil {
+		ui.notice = fmt.Sprintf("[%s not found. Map the build path to the checkout with -path-map](fg:error)", plain(file))
+		return
+	}
+	args := ui.editor.args(file, frame.Line)
+
+	termui.Close()
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if initErr := termui.Init(); initErr != nil {
+		ui.notice = fmt.Sprintf("[Failed to restore the UI: %s](fg:error)", plain(initErr.Error()))
+		return
+	}
+	ui.theme.setOutputMode()
+	ui.resize(termui.TerminalDimensions())
+	if err != nil {
+		ui.notice = fmt.Sprintf("[%s failed: %s](fg:error)", plain(args[0]), plain(err.Error()))
+	}
+}
@@ -0,0 +1,29 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditorArgs(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "emacs -nw")
+	assert.Equal(t, []string{"emacs", "-nw", "+42", "/src/main.go"}, editor{}.args("/src/main.go", 42))
+
+	t.Setenv("VISUAL", "/usr/bin/code")
+	assert.Equal(t, []string{"/usr/bin/code", "--goto", "/src/main.go:42"}, editor{}.args("/src/main.go", 42))
+
+	e := editor{command: "hx {file}:{line}"}
+	assert.Equal(t, []string{"hx", "/src/main.go:42"}, e.args("/src/main.go", 42))
+}
+
+func TestEditorLocalPath(t *testing.T) {
+	e := editor{paths: map[string]string{
+		"/build":          "/home/me",
+		"/build/src/repo": "/home/me/repo",
+	}}
+	assert.Equal(t, "/home/me/repo/main.go", e.localPath("/build/src/repo/main.go"))
+	assert.Equal(t, "/home/me/other/main.go", e.localPath("/build/other/main.go"))
+	assert.Equal(t, "/usr/local/go/src/runtime/proc.go", e.localPath("/usr/local/go/src/runtime/proc.go"))
+}
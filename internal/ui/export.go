@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+// exportFormat of the file which the listed goroutines are exported to
+type exportFormat struct {
+	name        string
+	ext         string
+	description string
+	write       func(w io.Writer, routines []model.Goroutine) error
+}
+
+var exportFormats = []exportFormat{
+	{name: "text", ext: "txt", description: "Goroutine dump, loaded again with -file", write: writeDump},
+	{name: "json", ext: "json", description: "Stacks with function, file and line", write: model.WriteJSON},
+	{name: "csv", ext: "csv", description: "One row per goroutine with its top frame", write: model.WriteCSV},
+	{name: "pprof", ext: "pb.gz", description: "Goroutine profile for go tool pprof and speedscope", write: model.WritePprof},
+	{name: "folded", ext: "folded", description: "Folded stacks for flamegraph.pl and inferno", write: model.WriteFolded},
+	{name: "markdown", ext: "md", description: "Summary for an issue or incident document", write: model.WriteMarkdown},
+	{name: "speedscope", ext: "speedscope.json", description: "Stacks for the web UI of speedscope", write: model.WriteSpeedscope},
+	{name: "dot", ext: "dot", description: "Graph of the spawn sites for Graphviz", write: model.WriteDOT},
+}
+
+// diffExportFormats of the comparison of snapshot A with B. The diff covers all captured goroutines, so the
+// listed goroutines are ignored.
+func (c *comparison) diffExportFormats() []exportFormat {
+	diff := model.CompareSnapshots(c.a.goroutines, c.b.goroutines)
+	write := func(writeDiff func(io.Writer, model.SnapshotDiff) error) func(io.Writer, []model.Goroutine) error {
+		return func(w io.Writer, _ []model.Goroutine) error { return writeDiff(w, diff) }
+	}
+	return []exportFormat{
+		{name: "text", ext: "diff.txt", description: "Added, removed and changed goroutines and stacks", write: write(model.WriteDiffText)},
+		{name: "json", ext: "diff.json", description: "Diff with the goroutines and their frames", write: write(model.WriteDiffJSON)},
+		{name: "markdown", ext: "diff.md", description: "Diff for an issue or deploy review", write: write(model.WriteDiffMarkdown)},
+	}
+}
+
+// exportFormats offers the formats of the diff while a comparison is shown
+func (ui *UI) exportFormats() []exportFormat {
+	if ui.comparison != nil {
+		return ui.comparison.diffExportFormats()
+	}
+	return exportFormats
+}
+
+func writeDump(w io.Writer, routines []model.Goroutine) error {
+	_, err := io.WriteString(w, model.Dump(routines))
+	return err
+}
+
+// exportFile writes the goroutines to a new file in the directory which is named after the time, for example
+// roumon-20240102-150405.json. Returns the path of the file.
+func exportFile(dir string, format exportFormat, routines []model.Goroutine, now time.Time) (string, error) {
+	path := filepath.Join(dir, fmt.Sprintf("roumon-%s.%s", now.Format("20060102-150405"), format.ext))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	if err := format.write(file, routines); err != nil {
+		file.Close()
+		return "", err
+	}
+	return path, file.Close()
+}
+
+// exportList writes the listed goroutines in the order of the list, or the diff of a shown comparison, to a file in
+// the working directory
+func (ui *UI) exportList(format exportFormat) {
+	if ui.comparison != nil {
+		path, err := exportFile("", format, nil, time.Now())
+		if err != nil {
+			ui.notice = fmt.Sprintf("[Failed to export: %s](fg:error)", plain(err.Error()))
+			return
+		}
+		ui.notice = fmt.Sprintf("[Exported the diff of A and B to %s](fg:ok)", plain(path))
+		return
+	}
+	if len(ui.filteredData) == 0 {
+		ui.notice = "[Nothing to export](fg:warn)"
+		return
+	}
+	path, err := exportFile("", format, ui.filteredData, time.Now())
+	if err != nil {
+		ui.notice = fmt.Sprintf("[Failed to export: %s](fg:error)", plain(err.Error()))
+		return
+	}
+	ui.notice = fmt.Sprintf("[Exported %s goroutines to %s](fg:ok)", formatCount(len(ui.filteredData)), plain(path))
+}
+
+// exportFormatName exports the listed goroutines, or the diff of a shown comparison, in the format with the name
+func (ui *UI) exportFormatName(name string) error {
+	formats := ui.exportFormats()
+	names := make([]string, len(formats))
+	for i, format := range formats {
+		if strings.EqualFold(format.name, name) {
+			ui.exportList(format)
+			return nil
+		}
+		names[i] = format.name
+	}
+	return fmt.Errorf("unknown format %s. Expected %s", name, strings.Join(names, ", "))
+}
+
+// chooseExport asks for the format and exports the listed goroutines. Any other key cancels the export.
+func (ui *UI) chooseExport(pollEvents <-chan termui.Event) (terminate bool) {
+	ui.chooser.Title = "Export"
+	formats := ui.exportFormats()
+	text := fmt.Sprintf("Export %s goroutines to the working directory as\n\n", formatCount(len(ui.filteredData)))
+	if ui.comparison != nil {
+		text = "Export the diff of A and B to the working directory as\n\n"
+	}
+	for i, format := range formats {
+		text += fmt.Sprintf("%d %s: %s\n", i+1, format.name, format.description)
+	}
+	ui.chooser.Text = text + "\nNumber: Export\nOther key: Cancel"
+	ui.render(ui.grid, ui.summary, ui.infoBar, ui.legend, ui.statusBar, ui.chooser)
+
+	e := <-pollEvents
+	if ui.keys.isQuit(e.ID) {
+		return true
+	}
+	if index, err := strconv.Atoi(e.ID); err == nil && index >= 1 && index <= len(formats) {
+		ui.exportList(formats[index-1])
+	}
+	return false
+}
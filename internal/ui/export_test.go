@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportFile(t *testing.T) {
+	dir := t.TempDir()
+	routines := []model.Goroutine{{ID: 7, Status: "select", StackTrace: []model.StackFrame{{FuncName: "main.main()"}}}}
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	path, err := exportFile(dir, exportFormats[0], routines, now)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "roumon-20240102-150405.txt"), path)
+	content, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, model.Dump(routines), string(content))
+
+	_, err = exportFile(dir, exportFormats[0], routines, now)
+	assert.Error(t, err, "an existing file is not overwritten")
+
+	path, err = exportFile(dir, exportFormats[2], routines, now)
+	assert.Nil(t, err)
+	assert.Equal(t, ".csv", filepath.Ext(path))
+
+	ui := &UI{}
+	assert.ErrorContains(t, ui.exportFormatName("xml"), "Expected text, json, csv")
+
+	ui.comparison = &comparison{a: capture{goroutines: routines}}
+	formats := ui.exportFormats()
+	assert.Equal(t, "markdown", formats[2].name)
+	path, err = exportFile(dir, formats[2], routines, now)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "roumon-20240102-150405.diff.md"), path)
+	content, err = os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), "0 added, 1 removed")
+	assert.ErrorContains(t, ui.exportFormatName("csv"), "Expected text, json, markdown")
+}
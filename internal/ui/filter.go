@@ -0,0 +1,725 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// frameScopes are the prefixes of the filter which match only the functions of the frames of the scope, for
+// example top:semacquire. createdby: is the long form of created:.
+var frameScopes = map[string]model.FrameScope{
+	"func:":      model.AnyFrame,
+	"top:":       model.TopFrame,
+	"created:":   model.CreatorFrame,
+	"createdby:": model.CreatorFrame,
+}
+
+// Keywords of the filter which combine its terms. Terms without a keyword between them are combined with AND.
+const (
+	keywordAnd = "AND"
+	keywordOr  = "OR"
+	keywordNot = "NOT"
+)
+
+// waitSpaces are spaces around the comparison of the wait time, which are dropped to keep it one word
+var waitSpaces = regexp.MustCompile(`\bwait\s*(>=|<=|>|<)\s*`)
+
+// waitTerm is a comparison of the wait time, for example wait>5m
+var waitTerm = regexp.MustCompile(`^wait(>=|<=|>|<)(.*)$`)
+
+// fieldTerm compares a single field of the goroutine with = or != and matches it with ~ or !~, for example
+// status=select or stack~"pgx"
+var fieldTerm = regexp.MustCompile(`^(id|status|stack|label|target|locked)(!=|!~|=|~)(.*)$`)
+
+// filterMode modifies how the expressions of the filter match. By default they match anywhere ignoring the case,
+// which lets short terms such as db match inside other words.
+type filterMode struct {
+	matchCase bool // Expressions and values are case sensitive
+	wholeWord bool // Expressions match only at word boundaries
+	fuzzy     bool // Words match the functions and statuses which contain their letters in order, like fzf
+}
+
+// filterQuery is the compiled text of the filter
+type filterQuery struct {
+	text string
+	mode filterMode
+	root filterNode // Tree of the terms. Nil if the text has none and all goroutines match
+	err  string     // First error of the text. Empty if it is valid
+}
+
+// fuzzyPatterns returns the fuzzy patterns of the node which are not negated, which are highlighted in the list
+func fuzzyPatterns(node filterNode) []string {
+	var children []filterNode
+	switch n := node.(type) {
+	case patternTerm:
+		if len(n.fuzzy) > 0 {
+			return []string{n.fuzzy}
+		}
+	case allNodes:
+		children = n
+	case anyNodes:
+		children = n
+	}
+	var patterns []string
+	for _, child := range children {
+		patterns = append(patterns, fuzzyPatterns(child)...)
+	}
+	return patterns
+}
+
+// filterNode of the tree which the text of the filter is parsed into
+type filterNode interface {
+	matches(g model.Goroutine, targets int) bool
+}
+
+// allNodes match if all of their nodes match
+type allNodes []filterNode
+
+func (n allNodes) matches(g model.Goroutine, targets int) bool {
+	for _, node := range n {
+		if !node.matches(g, targets) {
+			return false
+		}
+	}
+	return true
+}
+
+// anyNodes match if one of their nodes matches
+type anyNodes []filterNode
+
+func (n anyNodes) matches(g model.Goroutine, targets int) bool {
+	for _, node := range n {
+		if node.matches(g, targets) {
+			return true
+		}
+	}
+	return false
+}
+
+// notNode matches if its node does not match
+type notNode struct {
+	node filterNode
+}
+
+func (n notNode) matches(g model.Goroutine, targets int) bool {
+	return !n.node.matches(g, targets)
+}
+
+// patternTerm matches a regular expression against the ID, status, frames, creator, labels and target or, if
+// scoped, only against the functions of the frames of the scope. Fuzzy patterns match the functions and the status.
+type patternTerm struct {
+	re      *regexp.Regexp
+	literal bool // The text is not a valid regular expression and is matched literally
+	fuzzy   string
+	scoped  bool
+	scope   model.FrameScope
+}
+
+func (t patternTerm) matches(g model.Goroutine, targets int) bool {
+	if len(t.fuzzy) > 0 {
+		return t.matchesFuzzy(g)
+	}
+	re := t.re
+	if t.scoped {
+		return g.FuncMatches(re, t.scope)
+	}
+	matchID := re.MatchString(strconv.FormatInt(g.ID, 10))
+	matchStatus := re.MatchString(g.Status)
+	matchCreatedBy := g.CratedBy != nil && g.CratedBy.Matches(re)
+	matchStackTrace := model.StackMatches(g.StackTrace, re)
+	matchLockedToThread := g.LockedToThread && re.MatchString("locked to thread")
+	matchLabels := labelsMatch(g.Labels, re)
+	matchTarget := targets > 1 && re.MatchString(g.Target)
+	return matchStatus || matchID || matchCreatedBy || matchStackTrace || matchLockedToThread || matchLabels || matchTarget
+}
+
+// matchesFuzzy returns true if the function of a frame of the scope contains the letters of the pattern in order.
+// Without a scope the creator and the status are matched as well.
+func (t patternTerm) matchesFuzzy(g model.Goroutine) bool {
+	matches := func(text string) bool {
+		_, ok := fuzzyScore(t.fuzzy, text)
+		return ok
+	}
+	switch {
+	case t.scoped && t.scope == model.TopFrame:
+		return len(g.StackTrace) > 0 && matches(g.StackTrace[0].Func())
+	case t.scoped && t.scope == model.CreatorFrame:
+		return g.CratedBy != nil && matches(g.CratedBy.Func())
+	case !t.scoped && (matches(g.Status) || g.CratedBy != nil && matches(g.CratedBy.Func())):
+		return true
+	}
+	return slices.ContainsFunc(g.StackTrace, func(f model.StackFrame) bool { return matches(f.Func()) })
+}
+
+// packageTerm matches the goroutines with a frame in the package
+type packageTerm string
+
+func (t packageTerm) matches(g model.Goroutine, _ int) bool {
+	return slices.ContainsFunc(g.StackTrace, func(f model.StackFrame) bool { return f.InPackage(string(t)) })
+}
+
+// fileTerm matches the goroutines with a frame in a file whose path matches the expression
+type fileTerm struct {
+	re *regexp.Regexp
+}
+
+func (t fileTerm) matches(g model.Goroutine, _ int) bool {
+	return slices.ContainsFunc(g.StackTrace, func(f model.StackFrame) bool { return t.re.MatchString(f.File) })
+}
+
+// globPattern matches the text of a file: term which contains a wildcard but no other syntax of regular
+// expressions. Such text is matched as glob, all other text as expression.
+var globPattern = regexp.MustCompile(`^[^\\()|+^$\{\}]*[*?][^\\()|+^$\{\}]*$`)
+
+// globRegexp converts the glob to a regular expression which matches a path ending with the glob. * and ? match
+// across directories, so */vendor/* matches all files below a vendor directory.
+func globRegexp(glob string) string {
+	var pattern strings.Builder
+	pattern.WriteString("(^|/)")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			pattern.WriteString(".*")
+		case '?':
+			pattern.WriteString(".")
+		default:
+			pattern.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	pattern.WriteString("$")
+	return pattern.String()
+}
+
+// labelTerm matches the goroutines with the label key and, if given, the value of the label
+type labelTerm struct {
+	key, value string
+	anyValue   bool // Every value of the key matches
+	matchCase  bool // The value is case sensitive
+}
+
+func (t labelTerm) matches(g model.Goroutine, _ int) bool {
+	value, ok := g.Labels[t.key]
+	switch {
+	case !ok:
+		return false
+	case t.anyValue:
+		return true
+	case t.matchCase:
+		return value == t.value
+	}
+	return strings.EqualFold(value, t.value)
+}
+
+// idRange is a range of goroutine IDs including both ends
+type idRange struct {
+	from, to int64
+}
+
+// idTerm matches the goroutines whose ID is in one of the ranges
+type idTerm []idRange
+
+func (t idTerm) matches(g model.Goroutine, _ int) bool {
+	return slices.ContainsFunc(t, func(r idRange) bool { return g.ID >= r.from && g.ID <= r.to })
+}
+
+// parseIDs parses a comma separated list of IDs and ranges of IDs, for example 17,100-200
+func parseIDs(text string) (idTerm, error) {
+	var t idTerm
+	for _, part := range strings.Split(text, ",") {
+		if len(part) == 0 {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		if !isRange {
+			to = from
+		}
+		first, errFrom := strconv.ParseInt(from, 10, 64)
+		last, errTo := strconv.ParseInt(to, 10, 64)
+		if errFrom != nil || errTo != nil || last < first {
+			return nil, fmt.Errorf("invalid id %s", part)
+		}
+		t = append(t, idRange{from: first, to: last})
+	}
+	return t, nil
+}
+
+// fieldComparison matches the expression against one field of the goroutine
+type fieldComparison struct {
+	field  string // id, status, stack, label, target or locked
+	re     *regexp.Regexp
+	negate bool // The goroutines which do not match are listed
+}
+
+func (c fieldComparison) matches(g model.Goroutine, _ int) bool {
+	var match bool
+	switch c.field {
+	case "id":
+		match = c.re.MatchString(strconv.FormatInt(g.ID, 10))
+	case "status":
+		match = c.re.MatchString(g.Status)
+	case "stack":
+		match = model.StackMatches(g.StackTrace, c.re) || g.FuncMatches(c.re, model.AnyFrame)
+	case "label":
+		match = labelsMatch(g.Labels, c.re)
+	case "target":
+		match = c.re.MatchString(g.Target)
+	case "locked":
+		match = c.re.MatchString(strconv.FormatBool(g.LockedToThread))
+	}
+	return match != c.negate
+}
+
+// waitCondition compares the wait time of a goroutine with a limit
+type waitCondition struct {
+	op    string // >, >=, < or <=
+	limit time.Duration
+}
+
+// matches returns true if the wait time of the goroutine meets the condition. The runtime reports the wait time in
+// whole minutes.
+func (c waitCondition) matches(g model.Goroutine, _ int) bool {
+	wait := time.Duration(g.WaitSinceMin) * time.Minute
+	switch c.op {
+	case ">":
+		return wait > c.limit
+	case ">=":
+		return wait >= c.limit
+	case "<":
+		return wait < c.limit
+	case "<=":
+		return wait <= c.limit
+	}
+	return true
+}
+
+// parseWaitLimit parses a duration such as 90s or 1h30m or a number of minutes
+func parseWaitLimit(text string) (time.Duration, error) {
+	if minutes, err := strconv.Atoi(text); err == nil {
+		return time.Duration(minutes) * time.Minute, nil
+	}
+	limit, err := time.ParseDuration(text)
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("invalid wait %s", text)
+	}
+	return limit, nil
+}
+
+// compileFilter parses the text into a tree of terms combined with AND, OR, NOT and parentheses, for example
+// status=select AND wait>10m AND stack~"pgx" AND NOT pkg:runtime. Words which start with ! or - are negated like
+// with NOT. Words next to each other which are no terms of their own are joined into one regular expression, so
+// expressions with spaces need no quotes. The filter is parsed while it is typed, so a missing ) is added, a
+// dangling keyword is skipped and the first error is kept to show it in the title.
+func compileFilter(text string, mode filterMode) filterQuery {
+	p := filterParser{tokens: filterTokens(waitSpaces.ReplaceAllString(text, "wait$1")), mode: mode}
+	var nodes []filterNode
+	for {
+		if node := p.parseOr(); node != nil {
+			nodes = append(nodes, node)
+		}
+		if p.pos >= len(p.tokens) {
+			break
+		}
+		p.fail("unexpected )")
+		p.pos++
+	}
+	return filterQuery{text: text, mode: mode, root: combine(nodes, false), err: p.err}
+}
+
+// matches returns true if the goroutine matches the query
+func (q filterQuery) matches(g model.Goroutine, targets int) bool {
+	return q.root == nil || q.root.matches(g, targets)
+}
+
+// filterTokens splits the text at the spaces outside of double quotes. A ! or - in front of a word and parentheses
+// which are not balanced within a word become tokens of their own, so (status=select and redis) group while
+// (redis|grpc) stays an expression. A lone ! or -, which is typed before a negated word, is skipped.
+func filterTokens(text string) []string {
+	var words []string
+	var word strings.Builder
+	quoted := false
+	for _, r := range text {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			word.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+		default:
+			word.WriteRune(r)
+		}
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+
+	var tokens []string
+	for _, word := range words {
+		for len(word) > 0 {
+			if word == "!" || word == "-" {
+				word = ""
+			} else if word[0] == '!' || word[0] == '-' {
+				tokens, word = append(tokens, keywordNot), word[1:]
+			} else if word[0] == '(' && parenBalance(word) > 0 {
+				tokens, word = append(tokens, "("), word[1:]
+			} else {
+				break
+			}
+		}
+		closing := 0
+		for strings.HasSuffix(word, ")") && !strings.HasSuffix(word, `\)`) && parenBalance(word) < 0 {
+			word, closing = word[:len(word)-1], closing+1
+		}
+		if len(word) > 0 {
+			tokens = append(tokens, word)
+		}
+		for ; closing > 0; closing-- {
+			tokens = append(tokens, ")")
+		}
+	}
+	return tokens
+}
+
+// parenBalance returns the number of opening minus the number of closing parentheses which are neither escaped
+// nor quoted
+func parenBalance(word string) int {
+	balance, quoted, escaped := 0, false, false
+	for _, r := range word {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == '(' && !quoted:
+			balance++
+		case r == ')' && !quoted:
+			balance--
+		}
+	}
+	return balance
+}
+
+// unquote removes the double quotes around the text. A missing closing quote is accepted while it is typed.
+func unquote(text string) string {
+	if rest, ok := strings.CutPrefix(text, `"`); ok {
+		return strings.TrimSuffix(rest, `"`)
+	}
+	return text
+}
+
+// filterParser parses the tokens of the filter by recursive descent. OR binds weaker than AND and AND weaker than
+// NOT.
+type filterParser struct {
+	tokens []string
+	pos    int
+	mode   filterMode
+	err    string // First error
+}
+
+func (p *filterParser) fail(err string) {
+	if len(p.err) == 0 {
+		p.err = err
+	}
+}
+
+func (p *filterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// combine returns the single node or the nodes combined with AND or OR. Nil if there is no node.
+func combine(nodes []filterNode, or bool) filterNode {
+	switch {
+	case len(nodes) == 0:
+		return nil
+	case len(nodes) == 1:
+		return nodes[0]
+	case or:
+		return anyNodes(nodes)
+	}
+	return allNodes(nodes)
+}
+
+// parseOr parses the terms combined with OR until a ) or the end
+func (p *filterParser) parseOr() filterNode {
+	var nodes []filterNode
+	for {
+		if node := p.parseAnd(); node != nil {
+			nodes = append(nodes, node)
+		}
+		if p.peek() != keywordOr {
+			return combine(nodes, true)
+		}
+		p.pos++
+	}
+}
+
+// parseAnd parses the terms combined with AND or without a keyword until an OR, a ) or the end
+func (p *filterParser) parseAnd() filterNode {
+	var nodes []filterNode
+	for p.pos < len(p.tokens) && p.peek() != keywordOr && p.peek() != ")" {
+		if p.peek() == keywordAnd {
+			p.pos++
+			continue
+		}
+		if node := p.parseNot(false); node != nil {
+			nodes = append(nodes, node)
+		}
+	}
+	return combine(nodes, false)
+}
+
+// parseNot parses a negated term, a group in parentheses or a term. The words of a regular expression are joined
+// unless the term is negated, which negates only the next word.
+func (p *filterParser) parseNot(negated bool) filterNode {
+	token := p.peek()
+	switch token {
+	case "", keywordOr, keywordAnd, ")":
+		return nil
+	case keywordNot:
+		p.pos++
+		if node := p.parseNot(true); node != nil {
+			return notNode{node: node}
+		}
+		return nil
+	case "(":
+		p.pos++
+		node := p.parseOr()
+		if p.peek() == ")" {
+			p.pos++
+		}
+		return node
+	}
+	p.pos++
+	if negated || !isPattern(token) || p.mode.fuzzy {
+		return p.parseTerm(token)
+	}
+	words := []string{unquote(token)}
+	for p.pos < len(p.tokens) && isPattern(p.peek()) && !isScoped(p.peek()) {
+		words = append(words, unquote(p.peek()))
+		p.pos++
+	}
+	return p.parseTerm(strings.Join(words, " "))
+}
+
+// isPattern returns true if the token is a regular expression, which is joined with the next words
+func isPattern(token string) bool {
+	switch token {
+	case keywordAnd, keywordOr, keywordNot, "(", ")":
+		return false
+	}
+	for _, prefix := range []string{"pkg:", "id:", "label:", "file:"} {
+		if strings.HasPrefix(token, prefix) {
+			return false
+		}
+	}
+	return !waitTerm.MatchString(token) && !fieldTerm.MatchString(token)
+}
+
+// isScoped returns true if the token starts with a prefix of frameScopes
+func isScoped(token string) bool {
+	for prefix := range frameScopes {
+		if strings.HasPrefix(token, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTerm parses a comparison of the wait time, a package, IDs, a label, a file, a comparison of a field or a regular expression,
+// which is restricted to the functions of the frames of its scope by a prefix of frameScopes. Terms with an error
+// are skipped.
+func (p *filterParser) parseTerm(text string) filterNode {
+	if m := waitTerm.FindStringSubmatch(text); m != nil {
+		limit, err := parseWaitLimit(m[2])
+		if err != nil {
+			p.fail(err.Error())
+			return nil
+		}
+		return waitCondition{op: m[1], limit: limit}
+	}
+	if pkg, ok := strings.CutPrefix(text, "pkg:"); ok {
+		if len(pkg) == 0 {
+			return nil
+		}
+		return packageTerm(pkg)
+	}
+	if ids, ok := strings.CutPrefix(text, "id:"); ok {
+		t, err := parseIDs(ids)
+		if err != nil {
+			p.fail(err.Error())
+			return nil
+		}
+		if len(t) == 0 {
+			return nil
+		}
+		return t
+	}
+	if label, ok := strings.CutPrefix(text, "label:"); ok {
+		key, value, hasValue := strings.Cut(unquote(label), "=")
+		if len(key) == 0 {
+			return nil
+		}
+		return labelTerm{key: key, value: unquote(value), anyValue: !hasValue, matchCase: p.mode.matchCase}
+	}
+	if file, ok := strings.CutPrefix(text, "file:"); ok {
+		file = unquote(file)
+		if len(file) == 0 {
+			return nil
+		}
+		if globPattern.MatchString(file) && !strings.Contains(file, ".*") {
+			return fileTerm{re: regexp.MustCompile(p.flags() + globRegexp(file))}
+		}
+		re, _ := p.compilePattern(file)
+		return fileTerm{re: re}
+	}
+	if m := fieldTerm.FindStringSubmatch(text); m != nil {
+		value := unquote(m[3])
+		if len(value) == 0 {
+			return nil
+		}
+		c := fieldComparison{field: m[1], negate: strings.HasPrefix(m[2], "!")}
+		if strings.HasSuffix(m[2], "=") {
+			c.re = regexp.MustCompile(p.flags() + "^" + regexp.QuoteMeta(value) + "$")
+		} else {
+			c.re, _ = p.compilePattern(value)
+		}
+		return c
+	}
+	t := patternTerm{}
+	for prefix, scope := range frameScopes {
+		if rest, ok := strings.CutPrefix(text, prefix); ok {
+			text, t.scoped, t.scope = rest, true, scope
+		}
+	}
+	if p.mode.fuzzy {
+		t.fuzzy = unquote(text)
+		if len(t.fuzzy) == 0 {
+			return nil
+		}
+		return t
+	}
+	t.re, t.literal = p.compilePattern(text)
+	return t
+}
+
+// flags returns the flags of the regular expressions of the mode
+func (p *filterParser) flags() string {
+	if p.mode.matchCase {
+		return ""
+	}
+	return "(?i)"
+}
+
+// compilePattern compiles the text as regular expression of the mode. Text which is not a valid regular expression,
+// for example while a group is typed, is matched literally.
+func (p *filterParser) compilePattern(text string) (re *regexp.Regexp, literal bool) {
+	word := func(pattern string) string {
+		if p.mode.wholeWord {
+			return `\b(?:` + pattern + `)\b`
+		}
+		return pattern
+	}
+	re, err := regexp.Compile(p.flags() + word(text))
+	if err == nil {
+		return re, false
+	}
+	var syntaxErr *syntax.Error
+	if errors.As(err, &syntaxErr) {
+		p.fail("literal: " + string(syntaxErr.Code))
+	} else {
+		p.fail("literal: " + err.Error())
+	}
+	return regexp.MustCompile(p.flags() + word(regexp.QuoteMeta(text))), true
+}
+
+// filterRoutines returns the goroutines which match the filter. The query is compiled again once the text changed.
+// The status counter of the summary filters in addition.
+func (ui *UI) filterRoutines() []model.Goroutine {
+	if (!ui.filtered || len(ui.filter.Text) == 0) && !ui.statusFilter.active() {
+		return ui.origData
+	}
+	routines := make([]model.Goroutine, 0)
+	if !ui.filtered || len(ui.filter.Text) == 0 {
+		for _, g := range ui.origData {
+			if ui.statusFilter.matches(counterOf(g.Status)) {
+				routines = append(routines, g)
+			}
+		}
+		return routines
+	}
+	if ui.query.text != ui.filter.Text || ui.query.mode != ui.filterMode {
+		ui.query = compileFilter(ui.filter.Text, ui.filterMode)
+	}
+	for _, g := range ui.origData {
+		if !ui.statusFilter.matches(counterOf(g.Status)) {
+			continue
+		}
+		if ui.query.matches(g, len(ui.targets)) {
+			routines = append(routines, g)
+		}
+	}
+	return routines
+}
+
+func labelsMatch(labels map[string]string, re *regexp.Regexp) bool {
+	for key, value := range labels {
+		if re.MatchString(fmt.Sprintf("%s=%s", key, value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateFilterTitle shows the number of matching goroutines while a filter is set and the modes which are on
+func (ui *UI) updateFilterTitle() {
+	var parts []string
+	set := ui.filtered && len(ui.filter.Text) > 0
+	if set {
+		parts = append(parts, formatCount(len(ui.filteredData)))
+	}
+	if ui.filterMode.matchCase {
+		parts = append(parts, "case")
+	}
+	if ui.filterMode.wholeWord {
+		parts = append(parts, "word")
+	}
+	if ui.filterMode.fuzzy {
+		parts = append(parts, "fuzzy")
+	}
+	if set && len(ui.query.err) > 0 {
+		parts = append(parts, ui.query.err)
+	}
+	title := "Filter"
+	if len(parts) > 0 {
+		title = fmt.Sprintf("Filter (%s)", strings.Join(parts, ", "))
+	}
+	if ui.searching {
+		title += " Enter/Esc"
+	}
+	ui.filter.Title = title
+}
+
+// clearFilter lists all goroutines again
+func (ui *UI) clearFilter() {
+	ui.searching = false
+	ui.filtered = false
+	ui.statusFilter = statusSelection{}
+	ui.filter.Text = ui.filterPlaceholder()
+	ui.updateList()
+}
@@ -0,0 +1,180 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/gizak/termui/v3/widgets"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileFilter(t *testing.T) {
+	q := compileFilter("redis|grpc", filterMode{})
+	assert.False(t, q.root.(patternTerm).literal)
+	assert.True(t, q.root.(patternTerm).re.MatchString("github.com/GRPC/grpc-go"))
+
+	q = compileFilter("(*Pool", filterMode{})
+	assert.True(t, q.root.(patternTerm).literal)
+	assert.Equal(t, "literal: missing argument to repetition operator", q.err)
+	assert.True(t, q.root.(patternTerm).re.MatchString("redis.(*pool).Get"))
+
+	q = compileFilter("top:semacquire$", filterMode{})
+	assert.True(t, q.root.(patternTerm).scoped)
+	assert.Equal(t, model.TopFrame, q.root.(patternTerm).scope)
+	assert.True(t, q.root.(patternTerm).re.MatchString("sync.runtime_Semacquire"))
+
+	q = compileFilter("wait >= 1h30m top:acquire", filterMode{})
+	assert.Equal(t, waitCondition{op: ">=", limit: 90 * time.Minute}, q.root.(allNodes)[0])
+	assert.True(t, q.root.(allNodes)[1].(patternTerm).scoped)
+	assert.Equal(t, "(?i)acquire", q.root.(allNodes)[1].(patternTerm).re.String())
+
+	q = compileFilter("wait>5", filterMode{})
+	assert.Equal(t, waitCondition{op: ">", limit: 5 * time.Minute}, q.root, "a number counts minutes")
+	assert.Empty(t, q.err)
+
+	q = compileFilter("redis !(*conn -grpc", filterMode{})
+	assert.Equal(t, "(?i)redis", q.root.(allNodes)[0].(patternTerm).re.String())
+	assert.Len(t, q.root.(allNodes), 2)
+	assert.Equal(t, "literal: missing argument to repetition operator", q.err, "errors of negated terms are shown")
+
+	q = compileFilter("wait>5x", filterMode{})
+	assert.Equal(t, "invalid wait 5x", q.err)
+	assert.Nil(t, q.root)
+
+	q = compileFilter(`status=select AND wait>10m AND stack~"pgx pool" AND NOT pkg:runtime`, filterMode{})
+	assert.Empty(t, q.err)
+	assert.Len(t, q.root.(allNodes), 4)
+	assert.Equal(t, "(?i)^select$", q.root.(allNodes)[0].(fieldComparison).re.String())
+	assert.Equal(t, "(?i)pgx pool", q.root.(allNodes)[2].(fieldComparison).re.String())
+	assert.Equal(t, notNode{node: packageTerm("runtime")}, q.root.(allNodes)[3])
+
+	q = compileFilter("a OR b c", filterMode{})
+	assert.Len(t, q.root.(anyNodes), 2, "AND binds stronger than OR")
+
+	q = compileFilter("a) OR", filterMode{})
+	assert.Equal(t, "unexpected )", q.err)
+
+	q = compileFilter("db status=Select", filterMode{matchCase: true, wholeWord: true})
+	assert.Equal(t, `\b(?:db)\b`, q.root.(allNodes)[0].(patternTerm).re.String())
+	assert.Equal(t, `^Select$`, q.root.(allNodes)[1].(fieldComparison).re.String())
+
+	q = compileFilter("db", filterMode{wholeWord: true})
+	assert.True(t, q.root.(patternTerm).re.MatchString("pool.(*DB).Conn"))
+	assert.False(t, q.root.(patternTerm).re.MatchString("pool.(*DBPool).Conn"))
+}
+
+func TestFilterTokens(t *testing.T) {
+	assert.Equal(t, []string{"(", "status=select", "OR", "(redis|grpc)", ")"},
+		filterTokens("(status=select OR (redis|grpc))"))
+	assert.Equal(t, []string{"NOT", "(", "a", "b", ")"}, filterTokens("!(a b)"))
+	assert.Equal(t, []string{`stack~"a (b"`, "c"}, filterTokens(`stack~"a (b" c`))
+	assert.Equal(t, []string{`\(\*Pool`, `\*Pool\)`}, filterTokens(`\(\*Pool \*Pool\)`))
+	assert.Empty(t, filterTokens(" ! "))
+}
+
+func TestFilterRoutines(t *testing.T) {
+	ui := &UI{filter: widgets.NewParagraph(), filtered: true}
+	ui.origData = []model.Goroutine{
+		{ID: 1, Status: "running", StackTrace: []model.StackFrame{{FuncName: "main.main()", File: "/src/main.go", Line: 12}}},
+		{ID: 2, Status: "IO wait", StackTrace: []model.StackFrame{{FuncName: "net.(*conn).Read()", File: "/go/net/net.go", Line: 180}}},
+		{ID: 3, Status: "select", Labels: map[string]string{"tenant": "acme"}},
+	}
+	ids := func(text string) []int64 {
+		ui.filter.Text = text
+		var result []int64
+		for _, g := range ui.filterRoutines() {
+			result = append(result, g.ID)
+		}
+		return result
+	}
+	ui.origData[2].CratedBy = &model.StackFrame{FuncName: "main.main", File: "/src/main.go", Line: 20}
+	assert.Equal(t, []int64{1, 2}, ids(`\.go:1\d+`))
+	assert.Equal(t, []int64{1, 3}, ids("main"))
+	assert.Equal(t, []int64{1}, ids("func:^main\\."))
+	assert.Equal(t, []int64{2}, ids("top:read$"))
+	assert.Equal(t, []int64{3}, ids("created:main"))
+	assert.Equal(t, []int64{3}, ids("createdby:^main\\.main$"))
+	assert.Empty(t, ids("createdby:main\\.go"), "only the function of the creator matches")
+	assert.Empty(t, ids("func:main\\.go"), "scoped filters match only the functions")
+	assert.Equal(t, []int64{2, 3}, ids("^io wait$|tenant=a"))
+	assert.Equal(t, []int64{2}, ids("(*conn"))
+	assert.Equal(t, []int64{1, 2, 3}, ids(""))
+
+	ui.origData[1].WaitSinceMin, ui.origData[2].WaitSinceMin = 5, 12
+	assert.Equal(t, []int64{3}, ids("wait>5m"))
+	assert.Equal(t, []int64{2, 3}, ids("wait>=5m"))
+	assert.Equal(t, []int64{1, 2}, ids("wait<10m"))
+	assert.Equal(t, []int64{2}, ids("wait>1m conn"))
+
+	assert.Equal(t, []int64{2}, ids("pkg:net"))
+	assert.Equal(t, []int64{1}, ids("pkg:main main"))
+	assert.Empty(t, ids("pkg:main conn"))
+	assert.Empty(t, ids("pkg:ne"), "packages match as a whole")
+	assert.Equal(t, []int64{2}, ids("wait>=5m pkg:net top:read"))
+
+	assert.Equal(t, []int64{1, 3}, ids("!net/"))
+	assert.Equal(t, []int64{3}, ids("-pkg:main main"))
+	assert.Equal(t, []int64{1}, ids("main -tenant= !wait>10m"))
+	assert.Equal(t, []int64{1, 2, 3}, ids("!"), "a lone ! waits for the term")
+	assert.Equal(t, []int64{2, 3}, ids("^io wait$|tenant=a"), "words of one expression are joined again")
+
+	assert.Equal(t, []int64{3}, ids("status=select"))
+	assert.Equal(t, []int64{2, 3}, ids(`status="io wait" OR label~tenant`))
+	assert.Equal(t, []int64{1, 3}, ids(`status!="IO wait"`))
+	assert.Equal(t, []int64{2}, ids("status!=IO wait"), "the value ends at the space")
+	assert.Equal(t, []int64{1}, ids("id=1"))
+	assert.Equal(t, []int64{2}, ids("stack=net.(*conn).Read"))
+	assert.Equal(t, []int64{1, 2}, ids(`stack~"\.go:1"`))
+	assert.Equal(t, []int64{3}, ids("wait>10m AND NOT stack~main OR id=4"))
+	assert.Equal(t, []int64{1, 3}, ids("(status=running OR wait>10m) AND NOT pkg:net"))
+	assert.Equal(t, []int64{2}, ids("NOT (status=running OR status=select"), "a missing ) is added")
+	assert.Equal(t, []int64{1, 2, 3}, ids("AND OR NOT"), "dangling keywords are skipped")
+
+	ui.filterMode = filterMode{matchCase: true}
+	assert.Empty(t, ids("MAIN"))
+	assert.Equal(t, []int64{2}, ids("IO"))
+	ui.filterMode = filterMode{wholeWord: true}
+	assert.Equal(t, []int64{1, 3}, ids("main"))
+	assert.Empty(t, ids("mai"))
+	assert.Equal(t, []int64{2}, ids("conn"), "punctuation bounds words")
+	ui.filterMode = filterMode{fuzzy: true}
+	assert.Equal(t, []int64{2}, ids("nconrd"))
+	assert.Equal(t, []int64{1, 3}, ids("mnmn"), "the creator matches")
+	assert.Equal(t, []int64{2}, ids("ncr iowt"), "every word matches on its own")
+	assert.Equal(t, []int64{3}, ids("created:mnmn"))
+	assert.Equal(t, []int64{1, 3}, ids("!nconrd"))
+	assert.Equal(t, []string{"ncr", "iowt"}, fuzzyPatterns(compileFilter("ncr OR iowt !main", ui.filterMode).root))
+	ui.filterMode = filterMode{}
+
+	ui.origData[0].LockedToThread = true
+	assert.Equal(t, []int64{1}, ids("locked=true"))
+	assert.Equal(t, []int64{2, 3}, ids("locked=false"))
+
+	assert.Equal(t, []int64{2}, ids("id:2"))
+	assert.Equal(t, []int64{1, 3}, ids("id:1,3"))
+
+	ui.origData[0].Labels = map[string]string{"tenant": "globex", "region": "eu"}
+	assert.Equal(t, []int64{3}, ids("label:tenant=acme"))
+	assert.Equal(t, []int64{3}, ids("label:tenant=ACME"), "values ignore the case")
+	assert.Equal(t, []int64{1, 3}, ids("label:tenant"))
+	assert.Equal(t, []int64{1}, ids("label:region"))
+	assert.Empty(t, ids("label:tenant=acm"), "values match as a whole")
+	assert.Equal(t, []int64{1, 2}, ids("!label:tenant=acme"))
+	assert.Equal(t, []int64{3}, ids(`label:"tenant=acme"`))
+	ui.origData[2].Labels["tenant"] = "acme corp"
+	assert.Equal(t, []int64{3}, ids(`label:tenant="acme corp"`))
+	ui.origData[2].Labels["tenant"] = "acme"
+	ui.origData[0].Labels = nil
+	assert.Equal(t, []int64{2}, ids("file:/go/*"))
+	assert.Equal(t, []int64{1}, ids("file:src/*.go"), "globs match the end of the path")
+	assert.Empty(t, ids("file:net/*.g"))
+	assert.Equal(t, []int64{1}, ids(`file:main\.go$`), "the creator is no frame of the stack")
+	assert.Equal(t, []int64{1, 3}, ids("!file:*/net/*"))
+	assert.Equal(t, []int64{2, 3}, ids("id:2-5"))
+	assert.Equal(t, []int64{1, 3}, ids("id:1,3-3, main"))
+	assert.Equal(t, []int64{1, 3}, ids("!id:2"))
+	assert.Equal(t, []int64{1, 2, 3}, ids("id:5-2"), "invalid ranges are skipped")
+	assert.Equal(t, "invalid id 5-2", ui.query.err)
+	assert.Equal(t, []int64{3}, ids(`NOT locked=true status!="IO wait"`))
+}
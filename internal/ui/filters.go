@@ -0,0 +1,129 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/becheran/roumon/internal/config"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+// SetFilters sets the named filters of the config file which are picked in the UI
+func (ui *UI) SetFilters(filters []config.Filter) {
+	ui.filters = filters
+}
+
+// namedFilter returns the filter with the name. Names are not case sensitive.
+func (ui *UI) namedFilter(name string) (config.Filter, bool) {
+	for _, f := range ui.filters {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+	}
+	return config.Filter{}, false
+}
+
+// SetFilter filters the list by the named filter or by the text if no filter has the name. An empty text clears
+// the filter.
+func (ui *UI) SetFilter(nameOrText string) error {
+	if f, ok := ui.namedFilter(nameOrText); ok {
+		nameOrText = f.Filter
+	}
+	return ui.setFilter(nameOrText)
+}
+
+// matchFilters returns the filters whose name matches the input, best match first
+func matchFilters(filters []config.Filter, input string) []config.Filter {
+	type match struct {
+		config.Filter
+		score int
+	}
+	var matches []match
+	for _, f := range filters {
+		if score, ok := fuzzyScore(strings.TrimSpace(input), f.Name); ok {
+			matches = append(matches, match{Filter: f, score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	result := make([]config.Filter, len(matches))
+	for i, m := range matches {
+		result[i] = m.Filter
+	}
+	return result
+}
+
+// pickFilter lists the named filters which match the typed name until one is applied or Escape is pressed
+func (ui *UI) pickFilter(pollEvents <-chan termui.Event) (terminate bool) {
+	if len(ui.filters) == 0 {
+		ui.notice = "[No named filters in the config file](fg:warn)"
+		return false
+	}
+	input, selected := "", 0
+	for {
+		matches := matchFilters(ui.filters, input)
+		selected = max(0, min(selected, len(matches)-1))
+		ui.drawFilters(input, matches, selected)
+
+		e := <-pollEvents
+		switch e.Type {
+		case termui.MouseEvent:
+			continue
+		case termui.ResizeEvent:
+			ui.resize(termui.TerminalDimensions())
+			continue
+		}
+		switch e.ID {
+		case "<C-c>":
+			return true
+		case "<Escape>":
+			return false
+		case "<Down>", "<C-n>":
+			selected++
+		case "<Up>", "<C-p>":
+			selected--
+		case "<Backspace>", "<C-<Backspace>>":
+			if runes := []rune(input); len(runes) > 0 {
+				input = string(runes[:len(runes)-1])
+			}
+		case "<Space>":
+			input += " "
+		case "<Enter>":
+			if len(matches) > 0 {
+				f := matches[selected]
+				_ = ui.setFilter(f.Filter)
+				ui.notice = fmt.Sprintf("Filter %s", plain(f.Name))
+			}
+			return false
+		default:
+			if utf8.RuneCountInString(e.ID) == 1 {
+				input += e.ID
+				selected = 0
+			}
+		}
+	}
+}
+
+// drawFilters draws the typed name above the matching filters. The rows scroll with the selected filter.
+func (ui *UI) drawFilters(input string, matches []config.Filter, selected int) {
+	offset := max(0, selected-maxPaletteRows+1)
+	shown := matches[offset:min(len(matches), offset+maxPaletteRows)]
+	nameWidth := 0
+	for _, f := range shown {
+		nameWidth = max(nameWidth, displayWidth(f.Name))
+	}
+	lines := []string{fmt.Sprintf("Name: %s_", plain(input)), ""}
+	for i, f := range shown {
+		row := plain(pad(f.Name, nameWidth) + "  " + f.Filter)
+		if offset+i == selected {
+			row = fmt.Sprintf("[%s](fg:selected,bg:selectedbg)", row)
+		}
+		lines = append(lines, row)
+	}
+	if len(matches) == 0 {
+		lines = append(lines, "No matching filter")
+	}
+	ui.drawPicker(fmt.Sprintf("Filters %d. Enter applies, Escape closes", len(matches)), lines)
+}
@@ -0,0 +1,30 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchFilters(t *testing.T) {
+	filters := []config.Filter{
+		{Name: "idle baseline", Filter: "!net/http"},
+		{Name: "stuck db", Filter: "wait>5m pgx"},
+		{Name: "own code", Filter: "pkg:github.com/myorg/"},
+	}
+	assert.Len(t, matchFilters(filters, ""), 3)
+	matches := matchFilters(filters, "stuck")
+	assert.Len(t, matches, 1)
+	assert.Equal(t, "stuck db", matches[0].Name)
+	assert.Empty(t, matchFilters(filters, "xyz"))
+}
+
+func TestNamedFilter(t *testing.T) {
+	ui := &UI{filters: []config.Filter{{Name: "Sleepers", Filter: "^sleep$"}}}
+	f, ok := ui.namedFilter("sleepers")
+	assert.True(t, ok, "names are not case sensitive")
+	assert.Equal(t, "^sleep$", f.Filter)
+	_, ok = ui.namedFilter("sleep")
+	assert.False(t, ok)
+}
@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// styledFrame renders the package, receiver, function, file and line of the frame in distinct colors. Frames of
+// the runtime and the standard library are dimmed.
+func styledFrame(frame model.StackFrame) string {
+	pos := ""
+	if frame.Position != nil {
+		pos = fmt.Sprintf(" +0x%x", *frame.Position)
+	}
+	location := fmt.Sprintf("file://%s", frame.File)
+	if frame.Autogenerated {
+		location = frame.File
+	}
+
+	if frame.Stdlib() {
+		return fmt.Sprintf("[%s](fg:stdlib)\n   [%s:%d%s](fg:stdlib)", plain(frame.FuncName), plain(location), frame.Line, pos)
+	}
+
+	parts := frame.Parts()
+	name := ""
+	if len(parts.Package) > 0 {
+		name += fmt.Sprintf("[%s.](fg:package)", plain(parts.Package))
+	}
+	if len(parts.Receiver) > 0 {
+		name += fmt.Sprintf("[%s.](fg:receiver)", plain(parts.Receiver))
+	}
+	name += fmt.Sprintf("[%s](fg:function,mod:bold)%s", plain(parts.Name), plain(parts.Args))
+	separator := "#"
+	if frame.Autogenerated {
+		separator = ":"
+	}
+	return fmt.Sprintf("%s\n   [%s](fg:file)%s[%d](fg:line)%s", name, plain(location), separator, frame.Line, pos)
+}
+
+// frameFilter hides the frames of the runtime, the standard library and the configured packages
+type frameFilter struct {
+	enabled  bool
+	packages []string // Import paths which are hidden in addition to the standard library, including subpackages
+}
+
+// SetHiddenPackages hides the frames of the packages in addition to the runtime and the standard library while
+// frames are hidden, for example google.golang.org/grpc
+func (ui *UI) SetHiddenPackages(packages []string) {
+	ui.hide.packages = packages
+	ui.updateList()
+}
+
+// toggleHidden shows or hides the frames of the runtime, the standard library and the configured packages
+func (ui *UI) toggleHidden() {
+	ui.hide.enabled = !ui.hide.enabled
+	ui.updateList()
+}
+
+// hidden returns true if the frame is hidden
+func (f frameFilter) hidden(frame model.StackFrame) bool {
+	if !f.enabled {
+		return false
+	}
+	if frame.Stdlib() {
+		return true
+	}
+	pkg := frame.Parts().Package
+	for _, p := range f.packages {
+		if pkg == p || strings.HasPrefix(pkg, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// shown returns the frames which are not hidden. All frames are shown if all of them would be hidden.
+func (f frameFilter) shown(frames []model.StackFrame) []model.StackFrame {
+	if !f.enabled {
+		return frames
+	}
+	var shown []model.StackFrame
+	for _, frame := range frames {
+		if !f.hidden(frame) {
+			shown = append(shown, frame)
+		}
+	}
+	if len(shown) == 0 {
+		return frames
+	}
+	return shown
+}
+
+// topFunc returns the function of the first frame which is shown
+func (f frameFilter) topFunc(g model.Goroutine) string {
+	if frames := f.shown(g.StackTrace); len(frames) > 0 {
+		return frames[0].Func()
+	}
+	return ""
+}
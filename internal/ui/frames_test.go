@@ -0,0 +1,31 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHideFrames(t *testing.T) {
+	g := model.Goroutine{StackTrace: []model.StackFrame{
+		{FuncName: "runtime.gopark(0x0?)", File: "/go/src/runtime/proc.go", Line: 398},
+		{FuncName: "internal/poll.(*FD).Read(0xc0000)", File: "/go/src/internal/poll/fd_unix.go", Line: 164},
+		{FuncName: "google.golang.org/grpc/internal/transport.(*Stream).Read()", File: "/mod/transport.go", Line: 10},
+		{FuncName: "main.handle()", File: "/src/main.go", Line: 20},
+	}}
+	ui := &UI{}
+	assert.Equal(t, "runtime.gopark", ui.hide.topFunc(g))
+	assert.Len(t, ui.stackFrames(g), 4)
+
+	ui.hide = frameFilter{enabled: true, packages: []string{"google.golang.org/grpc"}}
+	assert.Equal(t, "main.handle", ui.hide.topFunc(g))
+	assert.Equal(t, g.StackTrace[3:], ui.stackFrames(g))
+	lines := strings.Split(styleMarkup.ReplaceAllString(ui.traceText(g.StackTrace), "$1"), "\n")
+	assert.Equal(t, "  … 3 hidden", lines[0])
+	assert.Equal(t, "  main.handle()", lines[1])
+
+	stdlib := model.Goroutine{StackTrace: g.StackTrace[:2]}
+	assert.Equal(t, stdlib.StackTrace, ui.stackFrames(stdlib), "frames are shown if all of them would be hidden")
+}
@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// maxGroupIDs limits the IDs which are listed in the details of a group
+const maxGroupIDs = 20
+
+// groupRow shows the size of the group, the status and where the goroutines wait
+func groupRow(group model.StackGroup) string {
+	g := group.Goroutines[0]
+	return fmt.Sprintf("%s × %s in %s ", formatCount(len(group.Goroutines)), plain(g.Status), plain(group.Func()))
+}
+
+// groupDetails describes the goroutines of the group with the range of their wait times and their shared stack
+func (ui *UI) groupDetails(group model.StackGroup) string {
+	routines := group.Goroutines
+	minWait, maxWait := routines[0].WaitSinceMin, routines[0].WaitSinceMin
+	var targets []string
+	ids := make([]string, 0, min(len(routines), maxGroupIDs))
+	for _, g := range routines {
+		minWait = min(minWait, g.WaitSinceMin)
+		maxWait = max(maxWait, g.WaitSinceMin)
+		if !slices.Contains(targets, g.Target) {
+			targets = append(targets, g.Target)
+		}
+		if len(ids) < maxGroupIDs {
+			ids = append(ids, strconv.FormatInt(g.ID, 10))
+		}
+	}
+	if len(routines) > maxGroupIDs {
+		ids = append(ids, fmt.Sprintf("and %s more", formatCount(len(routines)-maxGroupIDs)))
+	}
+	wait := fmt.Sprintf("%d min", minWait)
+	if minWait != maxWait {
+		wait = fmt.Sprintf("%d-%d min", minWait, maxWait)
+	}
+	target := ""
+	if len(ui.targets) > 1 {
+		target = fmt.Sprintf("Targets: [%s](mod:bold)\n\n", plain(strings.Join(targets, ", ")))
+	}
+	return fmt.Sprintf("%sGoroutines: [%s](mod:bold)\n\nIDs: %s\n\nStatus: [%s](mod:bold)\n\nWait Since: [%s](mod:bold)\n\n%s",
+		target,
+		formatCount(len(routines)),
+		strings.Join(ids, ", "),
+		routines[0].Status,
+		wait,
+		ui.stackDetails(routines[0]))
+}
+
+// formatCount separates the thousands of the count with a comma, for example 4,812
+func formatCount(count int) string {
+	digits := strconv.Itoa(count)
+	var text strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			text.WriteByte(',')
+		}
+		text.WriteRune(digit)
+	}
+	return text.String()
+}
@@ -0,0 +1,28 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatCount(t *testing.T) {
+	assert.Equal(t, "0", formatCount(0))
+	assert.Equal(t, "812", formatCount(812))
+	assert.Equal(t, "4,812", formatCount(4812))
+	assert.Equal(t, "1,234,567", formatCount(1234567))
+}
+
+func TestGroupRow(t *testing.T) {
+	routines := goroutines("chan receive", "chan receive")
+	for i := range routines {
+		routines[i].StackTrace = []model.StackFrame{
+			{FuncName: "runtime.gopark(0x0?)", File: "/usr/local/go/src/runtime/proc.go", Line: 398},
+			{FuncName: "github.com/redis/redis.(*Pool).Get(0xc000010000)", File: "/go/pkg/redis/pool.go", Line: 120},
+		}
+	}
+	groups := model.GroupByStack(routines)
+	assert.Len(t, groups, 1)
+	assert.Equal(t, "2 × chan receive in github.com/redis/redis.(*Pool).Get ", groupRow(groups[0]))
+}
@@ -0,0 +1,109 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+// filterHelp explains what the filter and the find match
+var filterHelp = []string{
+	"The filter is a case insensitive regular expression, for example redis|grpc or",
+	"server\\.go:1\\d\\d. It lists the goroutines whose ID, status, functions, files and",
+	"lines, creator, labels as key=value, target or \"locked to thread\" match. Text which",
+	"is no valid expression is matched literally and the title shows the error. The",
+	"prefixes func:, top: and created: or createdby: match only the functions of all",
+	"frames, of the top frame or of the creator, for example top:semacquire or",
+	"createdby:pool\\.New. wait>5m, wait>=1h or wait<2 (minutes) lists only the goroutines",
+	"which wait that long, for example wait>5m pgxpool. pkg:github.com/myorg/ lists only",
+	"the goroutines with a frame in the package or below it, id:17, id:100-200 or",
+	"id:17,100-200 the goroutines of the IDs and label:tenant=acme or label:tenant the",
+	"goroutines with the label. file:*/vendor/* or file:internal/worker/*.go lists the",
+	"goroutines with a frame in a matching file. Words starting with ! or - exclude what",
+	"they match, for example redis !pkg:net/http -top:gopark. Terms are combined with AND,",
+	"OR, NOT and parentheses, for example (status=select OR wait>10m) AND NOT pkg:runtime.",
+	"status, id, stack, label, target and locked compare one field with = and != or match",
+	"it with ~ and !~, for example stack~\"pgx pool\" or locked=true. Escape clears the",
+	"filter.",
+	"The find highlights the text in the details of the selected row.",
+}
+
+// helpLines arranges the keys of all actions in as many columns as fit into the width, followed by the filter
+// syntax
+func (ui *UI) helpLines(width int) []string {
+	entries := ui.keys.help()
+	columnWidth := 0
+	for _, entry := range entries {
+		columnWidth = max(columnWidth, displayWidth(entry)+3)
+	}
+	columns := max(1, min(3, width/columnWidth))
+	rows := (len(entries) + columns - 1) / columns
+
+	lines := []string{"[Keys](mod:bold)", ""}
+	for row := 0; row < rows; row++ {
+		line := ""
+		for col := 0; col < columns; col++ {
+			if i := col*rows + row; i < len(entries) {
+				line += fit(entries[i], columnWidth)
+			}
+		}
+		lines = append(lines, plain(strings.TrimRight(line, " ")))
+	}
+	lines = append(lines, "", "[Filter](mod:bold)", "")
+	for _, line := range filterHelp {
+		lines = append(lines, plain(line))
+	}
+	return lines
+}
+
+// showHelp shows the help until a key other than a moving key is pressed. The moving keys scroll the help if it
+// is taller than the terminal.
+func (ui *UI) showHelp(pollEvents <-chan termui.Event) (terminate bool) {
+	offset := 0
+	for {
+		width, height := termui.TerminalDimensions()
+		lines := ui.helpLines(width - 10)
+		textWidth := 0
+		for _, line := range lines {
+			textWidth = max(textWidth, displayWidth(styleMarkup.ReplaceAllString(line, "$1")))
+		}
+		visible := max(1, min(len(lines), height-8))
+		offset = max(0, min(offset, len(lines)-visible))
+		boxWidth, boxHeight := min(width, textWidth+6), visible+4
+		left, top := max(0, (width-boxWidth)/2), max(0, (height-boxHeight)/2)
+		ui.help.SetRect(left, top, left+boxWidth, top+boxHeight)
+		ui.help.Title = "Help. Any other key to close"
+		if visible < len(lines) {
+			ui.help.Title = fmt.Sprintf("Help %d-%d/%d. Scroll with %s/%s, any other key to close",
+				offset+1, offset+visible, len(lines), ui.keys.key(actionDown), ui.keys.key(actionUp))
+		}
+		ui.help.Text = strings.Join(lines[offset:offset+visible], "\n")
+		ui.render(ui.grid, ui.summary, ui.infoBar, ui.legend, ui.statusBar, ui.help)
+
+		e := <-pollEvents
+		switch e.Type {
+		case termui.MouseEvent:
+			continue
+		case termui.ResizeEvent:
+			ui.resize(termui.TerminalDimensions())
+			continue
+		}
+		if ui.keys.isQuit(e.ID) {
+			return true
+		}
+		a, _, _ := ui.keys.lookup(e.ID)
+		switch a {
+		case actionDown:
+			offset++
+		case actionUp:
+			offset--
+		case actionPageDown:
+			offset += visible
+		case actionPageUp:
+			offset -= visible
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHelpLines(t *testing.T) {
+	ui := &UI{}
+	ui.keys, _ = newKeymap("default", nil)
+	entries := ui.keys.help()
+	assert.Contains(t, entries, "F1/?: Help")
+
+	narrow := ui.helpLines(60)
+	wide := ui.helpLines(200)
+	assert.Less(t, len(wide), len(narrow))
+	assert.Equal(t, "[Keys](mod:bold)", wide[0])
+	assert.True(t, strings.HasPrefix(wide[2], "Down: Select next"))
+	assert.Contains(t, wide, "[Filter](mod:bold)")
+	for _, line := range narrow[2 : 2+len(entries)] {
+		assert.NotContains(t, line, "   ")
+	}
+}
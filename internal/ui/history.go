@@ -0,0 +1,117 @@
+package ui
+
+import (
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// maxHistorySamples limits the memory of the history. Neighboring samples are merged once the limit is reached.
+const maxHistorySamples = 1 << 15
+
+// sample of the goroutine counts of all targets
+type sample struct {
+	total   int
+	classes map[model.WaitClass]int
+}
+
+// history of the goroutine counts of the whole session
+type history struct {
+	samples  []sample
+	start    time.Time
+	min, max int
+	sum      float64
+	count    int
+}
+
+// add counts the goroutines by wait class
+func (h *history) add(now time.Time, goroutines []model.Goroutine) {
+	s := sample{total: len(goroutines), classes: make(map[model.WaitClass]int)}
+	for _, g := range goroutines {
+		s.classes[model.ClassifyWaitReason(g.Status)]++
+	}
+	if h.count == 0 {
+		h.start = now
+		h.min = s.total
+	}
+	h.min = min(h.min, s.total)
+	h.max = max(h.max, s.total)
+	h.sum += float64(s.total)
+	h.count++
+
+	if len(h.samples) == maxHistorySamples {
+		for i := 0; i < len(h.samples)/2; i++ {
+			h.samples[i] = peak(h.samples[2*i], h.samples[2*i+1])
+		}
+		h.samples = h.samples[:len(h.samples)/2]
+	}
+	h.samples = append(h.samples, s)
+}
+
+// avg of the total goroutine count of all samples
+func (h *history) avg() float64 {
+	if h.count == 0 {
+		return 0
+	}
+	return h.sum / float64(h.count)
+}
+
+// series returns the total and the counts of the wait classes which occurred in the session with at most the
+// number of points. Samples are merged by their maximum so that spikes stay visible. Every series has at least
+// two points, which the plot requires.
+func (h *history) series(points int) (total []float64, classes []model.WaitClass, counts [][]float64) {
+	buckets := h.buckets(max(2, points))
+	for _, class := range model.WaitClasses {
+		for _, b := range buckets {
+			if b.classes[class] > 0 {
+				classes = append(classes, class)
+				break
+			}
+		}
+	}
+	total = make([]float64, len(buckets))
+	counts = make([][]float64, len(classes))
+	for i := range classes {
+		counts[i] = make([]float64, len(buckets))
+	}
+	for i, b := range buckets {
+		total[i] = float64(b.total)
+		for j, class := range classes {
+			counts[j][i] = float64(b.classes[class])
+		}
+	}
+	return total, classes, counts
+}
+
+func (h *history) buckets(points int) []sample {
+	switch len(h.samples) {
+	case 0:
+		return []sample{{}, {}}
+	case 1:
+		return []sample{h.samples[0], h.samples[0]}
+	}
+	if len(h.samples) <= points {
+		return h.samples
+	}
+	buckets := make([]sample, points)
+	for i := range buckets {
+		start, end := i*len(h.samples)/points, (i+1)*len(h.samples)/points
+		buckets[i] = h.samples[start]
+		for _, s := range h.samples[start+1 : end] {
+			buckets[i] = peak(buckets[i], s)
+		}
+	}
+	return buckets
+}
+
+// peak merges two samples by their maximum counts
+func peak(a, b sample) sample {
+	merged := sample{total: max(a.total, b.total), classes: make(map[model.WaitClass]int)}
+	for class, count := range a.classes {
+		merged.classes[class] = count
+	}
+	for class, count := range b.classes {
+		merged.classes[class] = max(merged.classes[class], count)
+	}
+	return merged
+}
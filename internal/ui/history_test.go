@@ -0,0 +1,52 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func goroutines(statuses ...string) []model.Goroutine {
+	routines := make([]model.Goroutine, len(statuses))
+	for i, status := range statuses {
+		routines[i] = model.Goroutine{ID: int64(i + 1), Status: status}
+	}
+	return routines
+}
+
+func TestHistorySeries(t *testing.T) {
+	var h history
+	total, classes, counts := h.series(10)
+	assert.Equal(t, []float64{0, 0}, total)
+	assert.Empty(t, classes)
+	assert.Empty(t, counts)
+
+	start := time.Now()
+	h.add(start, goroutines("running", "chan receive"))
+	h.add(start.Add(time.Second), goroutines("running", "chan receive", "chan receive", "IO wait"))
+	h.add(start.Add(2*time.Second), goroutines("running"))
+
+	total, classes, counts = h.series(10)
+	assert.Equal(t, []float64{2, 4, 1}, total)
+	assert.Equal(t, []model.WaitClass{model.WaitClassRunning, model.WaitClassNetwork, model.WaitClassChannel}, classes)
+	assert.Equal(t, [][]float64{{1, 1, 1}, {0, 1, 0}, {1, 2, 0}}, counts)
+	assert.Equal(t, 1, h.min)
+	assert.Equal(t, 4, h.max)
+	assert.InDelta(t, 7.0/3, h.avg(), 0.001)
+
+	// Spikes are kept when samples are merged
+	total, _, counts = h.series(2)
+	assert.Equal(t, []float64{2, 4}, total)
+	assert.Equal(t, []float64{1, 2}, counts[2])
+}
+
+func TestHistoryMergesSamples(t *testing.T) {
+	var h history
+	for i := 0; i < maxHistorySamples+1; i++ {
+		h.add(time.Now(), goroutines("running"))
+	}
+	assert.Equal(t, maxHistorySamples/2+1, len(h.samples))
+	assert.Equal(t, maxHistorySamples+1, h.count)
+}
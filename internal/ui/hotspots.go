@@ -0,0 +1,87 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// hotspotBarWidth is the width of the bar of the function with the most goroutines
+const hotspotBarWidth = 10
+
+// barEighths draw the remainder of a bar in eighths of a character
+var barEighths = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+// hotspot is a function and the number of goroutines which are inside of it
+type hotspot struct {
+	function string
+	count    int
+}
+
+// countHotspots counts the goroutines by their top function, most goroutines first. Functions with equal counts
+// are sorted by name.
+func countHotspots(routines []model.Goroutine, hide frameFilter) []hotspot {
+	counts := make(map[string]int)
+	for _, g := range routines {
+		counts[hide.topFunc(g)]++
+	}
+	hotspots := make([]hotspot, 0, len(counts))
+	for function, count := range counts {
+		hotspots = append(hotspots, hotspot{function: function, count: count})
+	}
+	sort.Slice(hotspots, func(i, j int) bool {
+		if hotspots[i].count != hotspots[j].count {
+			return hotspots[i].count > hotspots[j].count
+		}
+		return hotspots[i].function < hotspots[j].function
+	})
+	return hotspots
+}
+
+// hotspotBar draws the count relative to the maximum with eighths of characters. The bar is colored by the share of
+// all goroutines: error from half of them, warn from a fifth.
+func hotspotBar(count, maxCount, total int) string {
+	eighths := (count*hotspotBarWidth*8 + maxCount - 1) / maxCount
+	bar := strings.Repeat("█", eighths/8) + barEighths[eighths%8]
+	role := "ok"
+	switch {
+	case count*2 >= total:
+		role = "error"
+	case count*5 >= total:
+		role = "warn"
+	}
+	return fmt.Sprintf("[%s](fg:%s)%s", bar, role, strings.Repeat(" ", hotspotBarWidth-len([]rune(bar))))
+}
+
+// toggleHotspots shows or hides the panel of the functions with the most goroutines
+func (ui *UI) toggleHotspots() {
+	ui.showHotspots = !ui.showHotspots
+	ui.layout()
+	ui.updateHotspots()
+}
+
+// updateHotspots lists the functions with the most listed goroutines, as many as fit into the panel
+func (ui *UI) updateHotspots() {
+	if !ui.showHotspots {
+		return
+	}
+	hotspots := countHotspots(ui.filteredData, ui.hide)
+	ui.hotspots.Title = fmt.Sprintf("Top functions of %s", formatCount(len(ui.filteredData)))
+	rows := ui.hotspots.Inner.Dy()
+	if rows <= 0 {
+		rows = len(hotspots)
+	}
+	var lines []string
+	for _, h := range hotspots[:min(rows, len(hotspots))] {
+		count := formatCount(h.count)
+		function := h.function
+		if width := ui.hotspots.Inner.Dx() - hotspotBarWidth - len(count) - 2; width > 0 {
+			function = fitMiddle(function, width)
+		}
+		lines = append(lines, fmt.Sprintf("%s %s %s", hotspotBar(h.count, hotspots[0].count, len(ui.filteredData)),
+			count, plain(function)))
+	}
+	ui.hotspots.Text = strings.Join(lines, "\n")
+}
@@ -0,0 +1,24 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountHotspots(t *testing.T) {
+	routine := func(function string) model.Goroutine {
+		return model.Goroutine{StackTrace: []model.StackFrame{{FuncName: function + "()"}}}
+	}
+	routines := []model.Goroutine{routine("b.wait"), routine("a.run"), routine("b.wait"), routine("c.read"),
+		routine("c.read"), routine("b.wait")}
+	assert.Equal(t, []hotspot{{"b.wait", 3}, {"c.read", 2}, {"a.run", 1}}, countHotspots(routines, frameFilter{}))
+}
+
+func TestHotspotBar(t *testing.T) {
+	assert.Equal(t, "[██████████](fg:error)", hotspotBar(50, 50, 100))
+	assert.Equal(t, "[██████████](fg:warn)", hotspotBar(20, 20, 100))
+	assert.Equal(t, "[█▏](fg:ok)        ", hotspotBar(11, 100, 1000))
+	assert.Equal(t, "[▏](fg:ok)         ", hotspotBar(1, 1000, 1000), "the smallest count is visible")
+}
@@ -0,0 +1,410 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// action which is bound to a key sequence
+type action string
+
+const (
+	actionQuit          action = "quit"
+	actionHelp          action = "help"
+	actionPause         action = "pause"
+	actionSort          action = "sort"
+	actionSortDirection action = "sort-direction"
+	actionPrevious      action = "previous-snapshot"
+	actionNext          action = "next-snapshot"
+	actionRetry         action = "retry"
+	actionRefresh       action = "refresh"
+	actionSlower        action = "slower"
+	actionFaster        action = "faster"
+	actionCompare       action = "compare"
+	actionGroup         action = "group"
+	actionCreators      action = "creators"
+	actionDiff          action = "diff"
+	actionExpand        action = "expand"
+	actionCollapse      action = "collapse"
+	actionFocus         action = "focus"
+	actionFilterFrame   action = "filter-frame"
+	actionHideFrames    action = "hide-frames"
+	actionStatusFilter  action = "status-filter"
+	actionOnlyStatus    action = "only-status"
+	actionHideStatus    action = "hide-status"
+	actionPin           action = "pin"
+	actionCopy          action = "copy"
+	actionCopyAll       action = "copy-all"
+	actionExport        action = "export"
+	actionOpen          action = "open"
+	actionGrowList      action = "grow-list"
+	actionShrinkList    action = "shrink-list"
+	actionVertical      action = "vertical"
+	actionCompact       action = "compact"
+	actionHotspots      action = "top-functions"
+	actionColumns       action = "columns"
+	actionColorBy       action = "color-by"
+	actionBookmarks     action = "bookmarks"
+	actionFilters       action = "filters"
+	actionPalette       action = "palette"
+	actionLog           action = "log"
+	actionDown          action = "down"
+	actionUp            action = "up"
+	actionPageDown      action = "page-down"
+	actionPageUp        action = "page-up"
+	actionTop           action = "top"
+	actionBottom        action = "bottom"
+	actionSearch        action = "search"
+	actionMatchCase     action = "match-case"
+	actionMatchWord     action = "match-word"
+	actionMatchFuzzy    action = "match-fuzzy"
+	actionFind          action = "find"
+	actionFindNext      action = "find-next"
+	actionFindPrevious  action = "find-previous"
+)
+
+// actions in the order of the help
+var actions = []action{
+	actionDown, actionUp, actionPageDown, actionPageUp, actionTop, actionBottom, actionExpand, actionCollapse,
+	actionFocus, actionHideFrames, actionFilterFrame, actionStatusFilter, actionOnlyStatus, actionHideStatus,
+	actionBookmarks, actionFilters, actionPin, actionOpen, actionCopy, actionCopyAll, actionExport, actionSearch,
+	actionMatchCase, actionMatchWord, actionMatchFuzzy, actionFind, actionFindNext, actionFindPrevious, actionPause,
+	actionSort, actionSortDirection, actionPrevious, actionNext, actionRetry, actionRefresh, actionSlower,
+	actionFaster, actionCompare, actionGroup, actionCreators, actionDiff, actionColumns, actionColorBy,
+	actionGrowList, actionShrinkList, actionVertical, actionCompact, actionHotspots, actionPalette, actionLog,
+	actionHelp, actionQuit,
+}
+
+var actionDescriptions = map[action]string{
+	actionQuit:          "Quit",
+	actionHelp:          "Help",
+	actionPause:         "Pause/resume refresh",
+	actionSort:          "Change sort column",
+	actionSortDirection: "Toggle sort direction",
+	actionPrevious:      "Previous snapshot",
+	actionNext:          "Next snapshot",
+	actionRetry:         "Retry failed targets",
+	actionRefresh:       "Fetch now",
+	actionSlower:        "Increase interval",
+	actionFaster:        "Decrease interval",
+	actionCompare:       "Capture A, capture B and compare",
+	actionGroup:         "Group identical stacks",
+	actionCreators:      "Tree of creators",
+	actionDiff:          "Changes since previous snapshot",
+	actionExpand:        "Expand creator/scroll right",
+	actionCollapse:      "Collapse creator/scroll left",
+	actionFocus:         "Move between list and frames",
+	actionFilterFrame:   "Filter by selected frame",
+	actionHideFrames:    "Hide/show stdlib frames",
+	actionStatusFilter:  "Filter by next status",
+	actionOnlyStatus:    "List only/also status of selected",
+	actionHideStatus:    "Hide/show status of selected",
+	actionPin:           "Pin/unpin selection",
+	actionOpen:          "Open selected frame in editor",
+	actionCopy:          "Copy stack of selection",
+	actionCopyAll:       "Copy stacks of list",
+	actionExport:        "Export list to file",
+	actionColumns:       "Choose columns",
+	actionColorBy:       "Color rows by next label",
+	actionBookmarks:     "Save/recall filter bookmarks",
+	actionFilters:       "Pick a named filter",
+	actionPalette:       "Command palette",
+	actionLog:           "Show log messages",
+	actionGrowList:      "Grow list",
+	actionShrinkList:    "Shrink list",
+	actionVertical:      "List above/left of details",
+	actionCompact:       "Compact rows without charts",
+	actionHotspots:      "Show/hide top functions",
+	actionDown:          "Select next",
+	actionUp:            "Select previous",
+	actionPageDown:      "Page down",
+	actionPageUp:        "Page up",
+	actionTop:           "First",
+	actionBottom:        "Last",
+	actionSearch:        "Filter",
+	actionMatchCase:     "Case sensitive filter on/off",
+	actionMatchWord:     "Whole word filter on/off",
+	actionMatchFuzzy:    "Fuzzy filter on/off",
+	actionFind:          "Find in details",
+	actionFindNext:      "Next match",
+	actionFindPrevious:  "Previous match",
+}
+
+// quitKey quits in every keymap, so that roumon can be left whatever keys are configured
+const quitKey = "<C-c>"
+
+// functionKeys are bound in all presets. The keys of a sequence are separated by a space.
+var functionKeys = map[string]action{
+	"<C-c>":       actionQuit,
+	"<F10>":       actionQuit,
+	"<F1>":        actionHelp,
+	"?":           actionHelp,
+	"<F2>":        actionPause,
+	"<F3>":        actionSort,
+	"<F4>":        actionSortDirection,
+	"<F5>":        actionPrevious,
+	"<F6>":        actionNext,
+	"<F7>":        actionRetry,
+	"<F8>":        actionSlower,
+	"<F9>":        actionFaster,
+	"<F11>":       actionCompare,
+	"<F12>":       actionColumns,
+	"<Insert>":    actionBookmarks,
+	"<C-<Space>>": actionPalette,
+	"<Down>":      actionDown,
+	"<Up>":        actionUp,
+	"<PageDown>":  actionPageDown,
+	"<PageUp>":    actionPageUp,
+	"<Home>":      actionTop,
+	"<End>":       actionBottom,
+	"<C-f>":       actionFind,
+	"<C-g>":       actionFindNext,
+	"<C-r>":       actionFindPrevious,
+	"<C-t>":       actionGroup,
+	"<C-o>":       actionCreators,
+	"<C-d>":       actionDiff,
+	"<Right>":     actionExpand,
+	"<Left>":      actionCollapse,
+	"<Tab>":       actionFocus,
+	"<C-w>":       actionFilterFrame,
+	"<C-q>":       actionHideFrames,
+	"<C-b>":       actionStatusFilter,
+	"<C-5>":       actionOnlyStatus,
+	"<C-7>":       actionHideStatus,
+	"<C-6>":       actionFilters,
+	"<C-x> c":     actionMatchCase,
+	"<C-x> w":     actionMatchWord,
+	"<C-x> z":     actionMatchFuzzy,
+	"<C-z>":       actionPin,
+	"<C-y>":       actionCopy,
+	"<C-a>":       actionCopyAll,
+	"<C-s>":       actionExport,
+	"<C-e>":       actionOpen,
+	"<C-k>":       actionGrowList,
+	"<C-j>":       actionShrinkList,
+	"<C-l>":       actionVertical,
+	"<C-v>":       actionCompact,
+	"<C-u>":       actionColorBy,
+	"<C-p>":       actionHotspots,
+	"<C-n>":       actionRefresh,
+	"<C-4>":       actionLog,
+}
+
+// presets of additional keys. Printable keys only edit the filter after the search key in the vim and emacs
+// presets.
+var presets = map[string]map[string]action{
+	"default": {},
+	"vim": {
+		"q":     actionQuit,
+		"p":     actionPause,
+		"s":     actionSort,
+		"S":     actionSortDirection,
+		"[":     actionPrevious,
+		"]":     actionNext,
+		"r":     actionRetry,
+		"R":     actionRefresh,
+		"-":     actionSlower,
+		"+":     actionFaster,
+		"c":     actionCompare,
+		"t":     actionGroup,
+		"o":     actionCreators,
+		"d":     actionDiff,
+		"l":     actionExpand,
+		"h":     actionCollapse,
+		"f":     actionFilterFrame,
+		"H":     actionHideFrames,
+		"b":     actionStatusFilter,
+		"i":     actionOnlyStatus,
+		"x":     actionHideStatus,
+		"m":     actionPin,
+		"y":     actionCopy,
+		"Y":     actionCopyAll,
+		"w":     actionExport,
+		"e":     actionOpen,
+		">":     actionGrowList,
+		"<":     actionShrinkList,
+		"L":     actionVertical,
+		"v":     actionCompact,
+		"F":     actionHotspots,
+		"C":     actionColumns,
+		"K":     actionColorBy,
+		"'":     actionBookmarks,
+		"\"":    actionFilters,
+		":":     actionPalette,
+		"M":     actionLog,
+		"j":     actionDown,
+		"k":     actionUp,
+		"<C-d>": actionPageDown,
+		"<C-u>": actionPageUp,
+		"g g":   actionTop,
+		"G":     actionBottom,
+		"/":     actionSearch,
+		"n":     actionFindNext,
+		"N":     actionFindPrevious,
+	},
+	"emacs": {
+		"<C-x> <C-c>": actionQuit,
+		"<C-n>":       actionDown,
+		"<C-p>":       actionUp,
+		"<C-v>":       actionPageDown,
+		"<M-v>":       actionPageUp,
+		"<M-<>":       actionTop,
+		"<M->>":       actionBottom,
+		"<C-s>":       actionSearch,
+		"<C-x> r":     actionBookmarks,
+		"<C-x> n":     actionFilters,
+		"<M-x>":       actionPalette,
+		"<C-x> <C-s>": actionExport,
+		"<C-x> 1":     actionCompact,
+		"<C-x> f":     actionHotspots,
+		"<C-x> g":     actionRefresh,
+		"<C-x> l":     actionLog,
+		"<C-x> s":     actionOnlyStatus,
+		"<C-x> h":     actionHideStatus,
+	},
+}
+
+// keymap binds key sequences to actions
+type keymap struct {
+	bindings     map[string]action // Action by key sequence
+	typeToFilter bool              // Printable keys edit the filter without pressing the search key first
+}
+
+// newKeymap creates the keymap of the preset. The overrides replace all keys of an action with the given key
+// sequence, for example "quit": "<C-x> <C-c>". ctrl-c quits regardless of the overrides.
+func newKeymap(preset string, overrides map[string]string) (*keymap, error) {
+	if len(preset) == 0 {
+		preset = "default"
+	}
+	keys, ok := presets[preset]
+	if !ok {
+		return nil, fmt.Errorf("unknown keymap %s. Expected default, vim or emacs", preset)
+	}
+	k := &keymap{
+		bindings:     make(map[string]action),
+		typeToFilter: preset == "default",
+	}
+	for sequence, a := range functionKeys {
+		k.bindings[sequence] = a
+	}
+	for sequence, a := range keys {
+		k.bindings[sequence] = a
+	}
+	for name, sequence := range overrides {
+		a := action(name)
+		if _, ok := actionDescriptions[a]; !ok {
+			return nil, fmt.Errorf("unknown action %s in keys", name)
+		}
+		sequence = strings.Join(strings.Fields(sequence), " ")
+		if len(sequence) == 0 {
+			return nil, fmt.Errorf("missing key of action %s", name)
+		}
+		if sequence == quitKey || strings.HasPrefix(sequence, quitKey+" ") {
+			return nil, fmt.Errorf("key %s of action %s is reserved to quit", quitKey, name)
+		}
+		for s, bound := range k.bindings {
+			if bound == a && s != quitKey {
+				delete(k.bindings, s)
+			}
+		}
+		k.bindings[sequence] = a
+	}
+	return k, nil
+}
+
+// lookup returns the action of the key sequence. Prefix is true if the sequence is the start of a longer one.
+func (k *keymap) lookup(sequence string) (a action, found, prefix bool) {
+	if a, ok := k.bindings[sequence]; ok {
+		return a, true, false
+	}
+	for s := range k.bindings {
+		if strings.HasPrefix(s, sequence+" ") {
+			return "", false, true
+		}
+	}
+	return "", false, false
+}
+
+// isQuit returns true if the single key quits
+func (k *keymap) isQuit(key string) bool {
+	return key == quitKey || k.bindings[key] == actionQuit
+}
+
+// keys returns the sequences which are bound to the action in display form, for example F10 or ctrl-c. Keys of
+// the preset come first, followed by the function keys and the remaining keys which are bound in all presets.
+func (k *keymap) keys(a action) []string {
+	var sequences []string
+	for sequence, bound := range k.bindings {
+		if bound == a {
+			sequences = append(sequences, sequence)
+		}
+	}
+	rank := func(sequence string) int {
+		switch {
+		case functionKeys[sequence] != a:
+			return 0
+		case strings.HasPrefix(sequence, "<F"):
+			return 1
+		}
+		return 2
+	}
+	sort.Slice(sequences, func(i, j int) bool {
+		ri, rj := rank(sequences[i]), rank(sequences[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return sequences[i] < sequences[j]
+	})
+	keys := make([]string, len(sequences))
+	for i, sequence := range sequences {
+		keys[i] = displayKey(sequence)
+	}
+	return keys
+}
+
+// key returns the first key of the action in display form
+func (k *keymap) key(a action) string {
+	if keys := k.keys(a); len(keys) > 0 {
+		return keys[0]
+	}
+	return ""
+}
+
+// help lists all actions with their keys, for example F1/?: Help
+func (k *keymap) help() []string {
+	var entries []string
+	for _, a := range actions {
+		if keys := k.keys(a); len(keys) > 0 {
+			entries = append(entries, fmt.Sprintf("%s: %s", strings.Join(keys, "/"), actionDescriptions[a]))
+		}
+	}
+	if k.typeToFilter {
+		entries = append(entries, "Text input: Filter results", "Enter/Escape: Apply/clear find")
+	} else {
+		entries = append(entries, "Enter/Escape: Apply/clear filter or find")
+	}
+	return entries
+}
+
+func displayKey(sequence string) string {
+	var keys []string
+	for _, key := range strings.Fields(sequence) {
+		if len(key) > 2 && strings.HasPrefix(key, "<") && strings.HasSuffix(key, ">") {
+			key = key[1 : len(key)-1]
+			modifier := ""
+			if name, ok := strings.CutPrefix(key, "C-"); ok {
+				modifier, key = "ctrl-", name
+			} else if name, ok := strings.CutPrefix(key, "M-"); ok {
+				modifier, key = "alt-", name
+			}
+			// Named keys with a modifier such as <C-<Space>>
+			if len(key) > 2 && strings.HasPrefix(key, "<") && strings.HasSuffix(key, ">") {
+				key = key[1 : len(key)-1]
+			}
+			key = modifier + key
+		}
+		keys = append(keys, key)
+	}
+	return strings.Join(keys, " ")
+}
@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeymapSequences(t *testing.T) {
+	k, err := newKeymap("vim", nil)
+	assert.Nil(t, err)
+	assert.False(t, k.typeToFilter)
+
+	_, found, prefix := k.lookup("g")
+	assert.False(t, found)
+	assert.True(t, prefix)
+	a, found, _ := k.lookup("g g")
+	assert.True(t, found)
+	assert.Equal(t, actionTop, a)
+	a, _, _ = k.lookup("<F10>")
+	assert.Equal(t, actionQuit, a)
+	assert.Equal(t, "q", k.key(actionQuit))
+}
+
+func TestKeymapOverrides(t *testing.T) {
+	k, err := newKeymap("", map[string]string{"quit": "<C-x>  <C-c>"})
+	assert.Nil(t, err)
+	assert.True(t, k.typeToFilter)
+	_, found, _ := k.lookup("<F10>")
+	assert.False(t, found)
+	a, found, _ := k.lookup("<C-x> <C-c>")
+	assert.True(t, found)
+	assert.Equal(t, actionQuit, a)
+	assert.Equal(t, []string{"ctrl-x ctrl-c", "ctrl-c"}, k.keys(actionQuit))
+	assert.Equal(t, "F1", k.key(actionHelp))
+
+	_, err = newKeymap("nano", nil)
+	assert.NotNil(t, err)
+	_, err = newKeymap("vim", map[string]string{"explode": "x"})
+	assert.NotNil(t, err)
+}
+
+func TestDisplayKey(t *testing.T) {
+	assert.Equal(t, "ctrl-Space", displayKey("<C-<Space>>"))
+	assert.Equal(t, "alt-<", displayKey("<M-<>"))
+	assert.Equal(t, "ctrl-x r", displayKey("<C-x> r"))
+	assert.Equal(t, "Insert", displayKey("<Insert>"))
+}
+
+func TestKeymapQuitKey(t *testing.T) {
+	k, err := newKeymap("emacs", map[string]string{"quit": "q", "help": "<F10>"})
+	assert.Nil(t, err)
+	a, found, _ := k.lookup("<C-c>")
+	assert.True(t, found, "ctrl-c quits although quit is bound to another key")
+	assert.Equal(t, actionQuit, a)
+	assert.True(t, k.isQuit("<C-c>"))
+
+	_, err = newKeymap("", map[string]string{"search": "<C-c>"})
+	assert.NotNil(t, err)
+	_, err = newKeymap("", map[string]string{"search": "<C-c> s"})
+	assert.NotNil(t, err)
+}
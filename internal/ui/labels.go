@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+// labelRoles color the rows by the value of the label. Values share a role if there are more values than roles.
+var labelRoles = []string{"label1", "label2", "label3", "label4", "label5", "label6"}
+
+// SetColorBy colors the rows by the value of the label with the key and shows the label as column. An empty key
+// disables the coloring.
+func (ui *UI) SetColorBy(key string) {
+	ui.colorBy = key
+	if len(key) > 0 {
+		ui.showLabelColumn(key)
+	}
+	ui.updateList()
+}
+
+// showLabelColumn shows the column of the label and adds it if it is not configured
+func (ui *UI) showLabelColumn(key string) {
+	name := "label:" + key
+	for i, col := range ui.columns {
+		if col.name == name {
+			ui.columns[i].visible = true
+			return
+		}
+	}
+	ui.columns = append(ui.columns, column{name: name, trim: defaultTrim(name), visible: true})
+}
+
+// labelKeys returns the sorted keys of the labels of the listed goroutines
+func (ui *UI) labelKeys() []string {
+	seen := make(map[string]bool)
+	for _, g := range ui.origData {
+		for key := range g.Labels {
+			seen[key] = true
+		}
+	}
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// nextColorBy colors the rows by the next label key of the listed goroutines and disables the coloring after the
+// last key
+func (ui *UI) nextColorBy() {
+	keys := ui.labelKeys()
+	if len(keys) == 0 {
+		ui.notice = "[No goroutine has labels](fg:warn)"
+		return
+	}
+	next := nextKey(keys, ui.colorBy)
+	ui.SetColorBy(next)
+	if len(next) == 0 {
+		ui.notice = "Rows are not colored by label"
+		return
+	}
+	ui.notice = fmt.Sprintf("Rows are colored by label %s", plain(next))
+}
+
+// nextKey returns the first key after the current one in the sorted keys. Empty after the last key.
+func nextKey(keys []string, current string) string {
+	if len(current) == 0 {
+		return keys[0]
+	}
+	i := sort.SearchStrings(keys, current)
+	if i < len(keys) && keys[i] == current {
+		i++
+	}
+	if i < len(keys) {
+		return keys[i]
+	}
+	return ""
+}
+
+// labelRole returns the role of the value of the label which colors the rows. Empty if the goroutine has no
+// such label or no label colors the rows.
+func (ui *UI) labelRole(labels map[string]string) string {
+	if len(ui.colorBy) == 0 {
+		return ""
+	}
+	value, ok := labels[ui.colorBy]
+	if !ok {
+		return ""
+	}
+	h := fnv.New32a()
+	h.Write([]byte(value))
+	return labelRoles[h.Sum32()%uint32(len(labelRoles))]
+}
@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelKeys(t *testing.T) {
+	ui := &UI{origData: []model.Goroutine{
+		{ID: 1, Labels: map[string]string{"tenant": "a", "handler": "/api"}},
+		{ID: 2},
+		{ID: 3, Labels: map[string]string{"tenant": "b"}},
+	}}
+	keys := ui.labelKeys()
+	assert.Equal(t, []string{"handler", "tenant"}, keys)
+	assert.Equal(t, "handler", nextKey(keys, ""))
+	assert.Equal(t, "tenant", nextKey(keys, "handler"))
+	assert.Equal(t, "", nextKey(keys, "tenant"))
+	assert.Equal(t, "tenant", nextKey(keys, "region"), "a key which disappeared continues with the next one")
+}
+
+func TestLabelRole(t *testing.T) {
+	ui := &UI{}
+	assert.Empty(t, ui.labelRole(map[string]string{"tenant": "a"}))
+
+	ui.colorBy = "tenant"
+	role := ui.labelRole(map[string]string{"tenant": "a"})
+	assert.Contains(t, labelRoles, role)
+	assert.Equal(t, role, ui.labelRole(map[string]string{"tenant": "a", "handler": "/api"}), "the color only depends on the value")
+	assert.Empty(t, ui.labelRole(map[string]string{"handler": "/api"}))
+
+	ui.columns, _ = parseColumns("id,label:tenant:10")
+	ui.columns[1].visible = false
+	ui.showLabelColumn("tenant")
+	assert.True(t, ui.columns[1].visible)
+	ui.showLabelColumn("handler")
+	assert.Equal(t, "label:handler", ui.columns[len(ui.columns)-1].name)
+	assert.True(t, ui.columns[len(ui.columns)-1].visible)
+}
@@ -0,0 +1,61 @@
+package ui
+
+import "github.com/becheran/roumon/internal/model"
+
+// asciiRunes replace the symbols of the UI and of termui in the linear mode. Screen readers skip or spell out
+// symbols and monochrome terminals without Unicode fonts show them as boxes.
+var asciiRunes = map[rune]rune{
+	'…': '.',
+	'↑': '^',
+	'↓': 'v',
+	'▲': '^',
+	'▼': 'v',
+	'→': '>',
+	'≥': '>',
+	'×': 'x',
+	'▸': '+',
+	'▾': '-',
+	'●': '*',
+	'•': '*',
+	'█': '#',
+	'▉': '#',
+	'▊': '#',
+	'▋': '#',
+	'▌': '#',
+	'▍': '#',
+	'▎': '#',
+	'▏': '#',
+}
+
+// asciiRune returns the ASCII replacement of the symbol or the rune itself
+func asciiRune(r rune) rune {
+	if ascii, ok := asciiRunes[r]; ok {
+		return ascii
+	}
+	return r
+}
+
+// SetLinear shows one pane at a time without borders and charts, so a screen reader reads the screen from top to
+// bottom. The selected row is marked with > and the goroutines which wait at least the threshold with !, because
+// both are only colored otherwise. Tab switches between the list and the details.
+func (ui *UI) SetLinear() {
+	ui.linear = true
+	for _, block := range ui.blocks() {
+		block.Border = false
+	}
+	ui.list.marker = true
+	ui.size = layoutNarrow
+	ui.layout()
+	ui.updateList()
+}
+
+// markStuck marks the rows of the goroutines which wait at least the threshold in the linear mode
+func (ui *UI) markStuck(g model.Goroutine, row string) string {
+	if !ui.linear || ui.waitThreshold <= 0 {
+		return row
+	}
+	if ui.stuck(g) {
+		return "! " + row
+	}
+	return "  " + row
+}
@@ -0,0 +1,50 @@
+package ui
+
+import (
+	"image"
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+func TestAsciiRune(t *testing.T) {
+	assert.Equal(t, '.', asciiRune('…'))
+	assert.Equal(t, '*', asciiRune('●'))
+	assert.Equal(t, 'a', asciiRune('a'))
+	assert.Equal(t, '服', asciiRune('服'))
+}
+
+func TestMarkStuck(t *testing.T) {
+	ui := &UI{waitThreshold: DefaultWaitThreshold}
+	stuck, fresh := model.Goroutine{ID: 1, WaitSinceMin: 12}, model.Goroutine{ID: 2, WaitSinceMin: 1}
+	assert.Equal(t, "1 ", ui.markStuck(stuck, "1 "), "only the linear mode marks the rows")
+
+	ui.linear = true
+	assert.Equal(t, "! 1 ", ui.markStuck(stuck, "1 "))
+	assert.Equal(t, "  2 ", ui.markStuck(fresh, "2 "))
+
+	ui.waitThreshold = 0
+	assert.Equal(t, "1 ", ui.markStuck(stuck, "1 "))
+}
+
+func TestWideListMarker(t *testing.T) {
+	list := newWideList()
+	list.marker = true
+	list.SetRect(0, 0, 8, 4)
+	list.Rows = []string{"ab", "cd"}
+	list.SelectedRow = 1
+	buf := termui.NewBuffer(list.GetRect())
+	list.Draw(buf)
+	var rows []string
+	for y := list.Inner.Min.Y; y < list.Inner.Min.Y+2; y++ {
+		row := ""
+		for x := list.Inner.Min.X; x < list.Inner.Max.X; x++ {
+			row += string(buf.GetCell(image.Pt(x, y)).Rune)
+		}
+		rows = append(rows, row)
+	}
+	assert.Equal(t, []string{"  ab  ", "> cd  "}, rows)
+}
@@ -0,0 +1,148 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+// maxLogLines is the number of lines which the log panel keeps
+const maxLogLines = 1000
+
+// logTimeLayout is the date and time which the log package writes before every message by default
+const logTimeLayout = "2006/01/02 15:04:05 "
+
+// logLine of the log panel
+type logLine struct {
+	time time.Time
+	text string
+}
+
+// LogBuffer keeps the latest lines of the log for the log panel. Pass it to log.SetOutput to show the log inside
+// the UI instead of writing it to the terminal, which would break the screen. Safe for concurrent use.
+type LogBuffer struct {
+	mu    sync.Mutex
+	lines []logLine
+	now   func() time.Time
+}
+
+// NewLogBuffer creates an empty log buffer
+func NewLogBuffer() *LogBuffer {
+	return &LogBuffer{now: time.Now}
+}
+
+// Write adds every line of the message with the time of the write. The date and time which the log package
+// writes before the message are dropped.
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	text := strings.TrimRight(string(p), "\n")
+	if len(text) > len(logTimeLayout) {
+		if _, err := time.ParseInLocation(logTimeLayout, text[:len(logTimeLayout)], time.Local); err == nil {
+			text = text[len(logTimeLayout):]
+		}
+	}
+	now := b.now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, line := range strings.Split(text, "\n") {
+		b.lines = append(b.lines, logLine{time: now, text: line})
+	}
+	if over := len(b.lines) - maxLogLines; over > 0 {
+		b.lines = append(b.lines[:0:0], b.lines[over:]...)
+	}
+	return len(p), nil
+}
+
+// snapshot returns a copy of the lines, oldest first
+func (b *LogBuffer) snapshot() []logLine {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]logLine(nil), b.lines...)
+}
+
+// SetLog shows the lines of the buffer in the log panel
+func (ui *UI) SetLog(logs *LogBuffer) {
+	ui.logs = logs
+}
+
+// logLines shows the time of every line and cuts the lines to the width
+func logLines(lines []logLine, width int) []string {
+	result := make([]string, len(lines))
+	for i, line := range lines {
+		text := line.text
+		if width > 9 {
+			text = strings.TrimRight(fit(text, width-9), " ")
+		}
+		result[i] = fmt.Sprintf("[%s](fg:stdlib) %s", line.time.Format(time.TimeOnly), plain(text))
+	}
+	return result
+}
+
+// showLog shows the log in a panel below the charts until a key other than a moving key is pressed. The panel
+// follows new lines while it is scrolled to the end.
+func (ui *UI) showLog(pollEvents <-chan termui.Event) (terminate bool) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	offset, follow := 0, true
+	for {
+		width, height := termui.TerminalDimensions()
+		top := height / 3
+		visible := max(1, height-3-top-4)
+		var lines []string
+		if ui.logs != nil {
+			lines = logLines(ui.logs.snapshot(), width-6)
+		}
+		last := max(0, len(lines)-visible)
+		if follow {
+			offset = last
+		}
+		offset = max(0, min(offset, last))
+		follow = offset == last
+		ui.help.SetRect(0, top, width, height-3)
+		ui.help.Title = fmt.Sprintf("Log %d lines. Scroll with %s/%s, any other key to close",
+			len(lines), ui.keys.key(actionDown), ui.keys.key(actionUp))
+		ui.help.Text = "No messages"
+		if len(lines) > 0 {
+			ui.help.Text = strings.Join(lines[offset:min(len(lines), offset+visible)], "\n")
+		}
+		ui.render(ui.grid, ui.summary, ui.infoBar, ui.legend, ui.statusBar, ui.help)
+
+		var e termui.Event
+		select {
+		case e = <-pollEvents:
+		case <-ticker.C:
+			continue
+		}
+		switch e.Type {
+		case termui.MouseEvent:
+			continue
+		case termui.ResizeEvent:
+			ui.resize(termui.TerminalDimensions())
+			continue
+		}
+		if ui.keys.isQuit(e.ID) {
+			return true
+		}
+		a, _, _ := ui.keys.lookup(e.ID)
+		switch a {
+		case actionDown:
+			offset++
+		case actionUp:
+			offset--
+			follow = false
+		case actionPageDown:
+			offset += visible
+		case actionPageUp:
+			offset -= visible
+			follow = false
+		case actionTop:
+			offset, follow = 0, false
+		case actionBottom:
+			follow = true
+		default:
+			return false
+		}
+	}
+}
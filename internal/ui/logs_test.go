@@ -0,0 +1,34 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogBuffer(t *testing.T) {
+	logs := NewLogBuffer()
+	at := time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local)
+	logs.now = func() time.Time { return at }
+	logger := log.New(logs, "", log.LstdFlags)
+	logger.Printf("localhost:6060: failed to list go routines")
+	fmt.Fprint(logs, "first\nsecond\n")
+
+	lines := logs.snapshot()
+	assert.Equal(t, []logLine{
+		{time: at, text: "localhost:6060: failed to list go routines"},
+		{time: at, text: "first"},
+		{time: at, text: "second"},
+	}, lines)
+	assert.Equal(t, []string{"[15:04:05](fg:stdlib) localhost:6060: failed t…"}, logLines(lines[:1], 34))
+
+	for i := 0; i < maxLogLines; i++ {
+		logger.Printf("line %d", i)
+	}
+	lines = logs.snapshot()
+	assert.Len(t, lines, maxLogLines)
+	assert.Equal(t, "line 0", lines[0].text)
+}
@@ -0,0 +1,367 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+// maxPaletteRows is the number of matching commands which the palette lists at once
+const maxPaletteRows = 12
+
+// command of the palette. Commands without run or without an argument perform the action.
+type command struct {
+	name        string
+	arg         string // Argument such as <duration>. Empty if the command takes none
+	description string
+	action      action
+	run         func(ui *UI, arg string) error
+}
+
+// argCommands take an argument which no key can pass
+var argCommands = []command{
+	{name: "sort", arg: "<key>", description: "Sort by none, id, status, wait, function or depth. - for descending",
+		action: actionSort, run: (*UI).sortBy},
+	{name: "filter", arg: "<regexp|name>", description: "Filter the list or apply a named filter. Empty clears it",
+		run: (*UI).SetFilter},
+	{name: "status", arg: "<counters>", description: "List only the counters, -counter hides one. Empty lists all",
+		run: (*UI).filterStatusName},
+	{name: "target", arg: "<number|name>", description: "Switch to the tab of the target. Empty for all",
+		run: (*UI).switchTarget},
+	{name: "interval", arg: "<duration>", description: "Set the fetch interval, for example 5s", run: (*UI).setInterval},
+	{name: "snapshot", arg: "<number|-n>", description: "Show a snapshot of the timeline. Empty for the latest",
+		run: (*UI).showSnapshot},
+	{name: "bookmark", arg: "<number|name>", description: "Recall the bookmark", run: (*UI).recallBookmarkName},
+	{name: "export", arg: "<text|json|csv>", description: "Export the list to a file. Empty asks for the format",
+		action: actionExport, run: (*UI).exportFormatName},
+}
+
+// paletteCommands returns the commands with an argument followed by the other actions in the order of the help
+func paletteCommands() []command {
+	commands := append([]command(nil), argCommands...)
+	for _, a := range actions {
+		if !slices.ContainsFunc(argCommands, func(c command) bool { return c.action == a }) {
+			commands = append(commands, command{name: string(a), description: actionDescriptions[a], action: a})
+		}
+	}
+	return commands
+}
+
+// fuzzyScore matches the letters of the pattern in order in the text ignoring the case. Consecutive letters and
+// letters at the start of a word score higher. Returns false if the text misses a letter.
+func fuzzyScore(pattern, text string) (int, bool) {
+	score, positions := fuzzyMatch([]rune(strings.ToLower(pattern)), lowerRunes(text), 0)
+	return score, positions != nil || len(pattern) == 0
+}
+
+// fuzzyMatch matches the runes of the pattern in order in the text from the index on, each at its first
+// occurrence. Returns the score of fuzzyScore and the indexes of the matched runes. Nil if the text misses a rune.
+func fuzzyMatch(pattern, text []rune, from int) (int, []int) {
+	var positions []int
+	score, last, i := 0, from-1, from
+	for _, p := range pattern {
+		for i < len(text) && text[i] != p {
+			i++
+		}
+		if i == len(text) {
+			return 0, nil
+		}
+		switch {
+		case i == last+1:
+			score += 3
+		case !unicode.IsLetter(text[i-1]):
+			score += 2
+		default:
+			score++
+		}
+		positions = append(positions, i)
+		last = i
+		i++
+	}
+	return score, positions
+}
+
+// fuzzyPositions returns the indexes of the runes of the text which the pattern matches in the alignment with the
+// highest score, like fzf highlights them. Nil if the text misses a letter.
+func fuzzyPositions(pattern string, text []rune) []int {
+	lower, p := lowerRunes(string(text)), []rune(strings.ToLower(pattern))
+	if len(p) == 0 {
+		return nil
+	}
+	best, bestScore := []int(nil), 0
+	for start, r := range lower {
+		if r != p[0] {
+			continue
+		}
+		score, positions := fuzzyMatch(p, lower, start)
+		if positions == nil {
+			break
+		}
+		if best == nil || score > bestScore {
+			best, bestScore = positions, score
+		}
+	}
+	return best
+}
+
+// lowerRunes returns the runes of the text in lower case. Unlike strings.ToLower the number of runes stays the same.
+func lowerRunes(text string) []rune {
+	runes := []rune(text)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// matchCommands returns the commands whose name matches the first word of the input, best match first, and the
+// argument after the first word. Only commands which take an argument match once an argument is typed.
+func matchCommands(commands []command, input string) ([]command, string) {
+	input = strings.TrimLeft(input, " ")
+	name, arg, hasArg := strings.Cut(input, " ")
+	arg = strings.TrimSpace(arg)
+	type match struct {
+		command
+		score int
+	}
+	var matches []match
+	for _, c := range commands {
+		if hasArg && len(c.arg) == 0 {
+			continue
+		}
+		if score, ok := fuzzyScore(name, c.name); ok {
+			matches = append(matches, match{command: c, score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return len(matches[i].name) < len(matches[j].name)
+	})
+	result := make([]command, len(matches))
+	for i, m := range matches {
+		result[i] = m.command
+	}
+	return result, arg
+}
+
+// showPalette lists the commands which match the typed text until a command is run or Escape is pressed
+func (ui *UI) showPalette(pollEvents <-chan termui.Event) (terminate bool) {
+	commands := paletteCommands()
+	input, selected := "", 0
+	for {
+		matches, arg := matchCommands(commands, input)
+		selected = max(0, min(selected, len(matches)-1))
+		ui.drawPalette(input, matches, selected)
+
+		e := <-pollEvents
+		switch e.Type {
+		case termui.MouseEvent:
+			continue
+		case termui.ResizeEvent:
+			ui.resize(termui.TerminalDimensions())
+			continue
+		}
+		switch e.ID {
+		case "<C-c>":
+			return true
+		case "<Escape>":
+			return false
+		case "<Down>", "<C-n>":
+			selected++
+		case "<Up>", "<C-p>":
+			selected--
+		case "<Tab>":
+			if len(matches) > 0 {
+				input = matches[selected].name
+				if len(matches[selected].arg) > 0 {
+					input += " "
+				}
+			}
+		case "<Backspace>", "<C-<Backspace>>":
+			if runes := []rune(input); len(runes) > 0 {
+				input = string(runes[:len(runes)-1])
+			}
+		case "<Space>":
+			input += " "
+		case "<Enter>":
+			if len(matches) == 0 {
+				ui.notice = fmt.Sprintf("[Unknown command %s](fg:error)", plain(input))
+				ui.updateStatusBar()
+				return false
+			}
+			return ui.runCommand(matches[selected], arg, pollEvents)
+		default:
+			if utf8.RuneCountInString(e.ID) == 1 {
+				input += e.ID
+				selected = 0
+			}
+		}
+	}
+}
+
+// drawPalette draws the typed text above the matching commands. The rows scroll with the selected command.
+func (ui *UI) drawPalette(input string, matches []command, selected int) {
+	offset := max(0, selected-maxPaletteRows+1)
+	shown := matches[offset:min(len(matches), offset+maxPaletteRows)]
+	nameWidth := 0
+	for _, c := range shown {
+		nameWidth = max(nameWidth, utf8.RuneCountInString(c.name+" "+c.arg))
+	}
+	lines := []string{fmt.Sprintf(": %s_", plain(input)), ""}
+	for i, c := range shown {
+		row := fmt.Sprintf("%-*s  %s", nameWidth, strings.TrimSpace(c.name+" "+c.arg), c.description)
+		if key := ui.keys.key(c.action); len(key) > 0 {
+			row += " (" + key + ")"
+		}
+		row = plain(row)
+		if offset+i == selected {
+			row = fmt.Sprintf("[%s](fg:selected,bg:selectedbg)", row)
+		}
+		lines = append(lines, row)
+	}
+	if len(matches) == 0 {
+		lines = append(lines, "No matching command")
+	}
+	ui.drawPicker(fmt.Sprintf("Commands %d. Tab completes, Enter runs, Escape closes", len(matches)), lines)
+}
+
+// drawPicker draws the lines in a box in the upper middle of the terminal, which is as wide as the widest line
+func (ui *UI) drawPicker(title string, lines []string) {
+	width, height := termui.TerminalDimensions()
+	textWidth := 0
+	for _, line := range lines {
+		textWidth = max(textWidth, displayWidth(styleMarkup.ReplaceAllString(line, "$1")))
+	}
+	boxWidth, boxHeight := min(width, textWidth+6), min(height, maxPaletteRows+6)
+	left, top := max(0, (width-boxWidth)/2), max(0, (height-boxHeight)/4)
+	ui.help.SetRect(left, top, left+boxWidth, top+boxHeight)
+	ui.help.Title = title
+	ui.help.Text = strings.Join(lines, "\n")
+	ui.render(ui.grid, ui.summary, ui.infoBar, ui.legend, ui.statusBar, ui.help)
+}
+
+// runCommand runs the command with the argument. Errors are shown in the status bar.
+func (ui *UI) runCommand(c command, arg string, pollEvents <-chan termui.Event) (terminate bool) {
+	if c.run == nil || len(arg) == 0 && len(c.action) > 0 {
+		terminate = ui.runAction(c.action, pollEvents)
+	} else if err := c.run(ui, arg); err != nil {
+		ui.notice = fmt.Sprintf("[%s: %s](fg:error)", c.name, plain(err.Error()))
+	}
+	ui.updateStatusBar()
+	return terminate
+}
+
+// sortBy sorts the list like -sort, for example by -wait
+func (ui *UI) sortBy(spec string) error {
+	key, desc, err := parseSort(spec)
+	if err != nil {
+		return err
+	}
+	ui.sortKey, ui.sortDesc = key, desc
+	ui.updateList()
+	return nil
+}
+
+// setFilter filters the list by the text. An empty text clears the filter.
+func (ui *UI) setFilter(text string) error {
+	if len(text) == 0 {
+		ui.clearFilter()
+		return nil
+	}
+	ui.searching = false
+	ui.filtered = true
+	ui.filter.Text = text
+	ui.list.ScrollTop()
+	ui.updateList()
+	return nil
+}
+
+// filterStatusName lists the goroutines of the comma separated counters, for example select,chan receive or
+// -IO wait. An empty argument lists all goroutines.
+func (ui *UI) filterStatusName(spec string) error {
+	selection, err := parseStatusSelection(spec)
+	if err != nil {
+		return err
+	}
+	ui.filterStatus(selection)
+	return nil
+}
+
+// switchTarget switches to the tab of the target with the number or the first target which contains the name
+func (ui *UI) switchTarget(arg string) error {
+	if len(ui.targets) < 2 {
+		return errors.New("only one target is monitored")
+	}
+	if len(arg) == 0 || strings.EqualFold(arg, "all") {
+		ui.switchTab(0)
+		return nil
+	}
+	if index, err := strconv.Atoi(arg); err == nil && index >= 0 && index <= len(ui.targets) {
+		ui.switchTab(index)
+		return nil
+	}
+	for i, target := range ui.targets {
+		if strings.Contains(target, arg) {
+			ui.switchTab(i + 1)
+			return nil
+		}
+	}
+	return fmt.Errorf("no target matches %s", arg)
+}
+
+// setInterval sets the time between two fetches
+func (ui *UI) setInterval(arg string) error {
+	if ui.controller == nil {
+		return errors.New("the source is not polled")
+	}
+	interval, err := time.ParseDuration(arg)
+	if err != nil || interval <= 0 {
+		return fmt.Errorf("invalid interval %s. Expected a duration such as 5s", arg)
+	}
+	ui.controller.SetInterval(interval)
+	return nil
+}
+
+// showSnapshot shows the snapshot with the number in the timeline, counted from 1 for the oldest snapshot. A
+// negative number moves back from the shown snapshot and an empty argument shows the latest snapshots.
+func (ui *UI) showSnapshot(arg string) error {
+	count := len(ui.timeline.snapshots)
+	if len(arg) == 0 || arg == "latest" {
+		ui.browse(count)
+		return nil
+	}
+	number, err := strconv.Atoi(arg)
+	switch {
+	case err != nil || number == 0 || number > count:
+		return fmt.Errorf("invalid snapshot %s. Expected 1 to %d, a negative number or latest", arg, count)
+	case number < 0:
+		ui.browse(number)
+	default:
+		ui.browse(number - 1 - ui.timeline.position())
+	}
+	return nil
+}
+
+// recallBookmarkName recalls the bookmark with the number or the name
+func (ui *UI) recallBookmarkName(arg string) error {
+	if index, ok := ui.bookmarkIndex(arg); ok {
+		ui.recallBookmark(index)
+		return nil
+	}
+	for i, b := range ui.bookmarks {
+		if strings.EqualFold(b.Name, arg) {
+			ui.recallBookmark(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("no bookmark %s", arg)
+}
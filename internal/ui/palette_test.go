@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	_, ok := fuzzyScore("srt", "sort")
+	assert.True(t, ok)
+	_, ok = fuzzyScore("tros", "sort")
+	assert.False(t, ok)
+	consecutive, _ := fuzzyScore("sort", "sort-direction")
+	scattered, _ := fuzzyScore("sort", "status-filter-other")
+	assert.Greater(t, consecutive, scattered)
+	wordStart, _ := fuzzyScore("sd", "sort-direction")
+	inWord, _ := fuzzyScore("sd", "sxxd")
+	assert.Greater(t, wordStart, inWord)
+}
+
+func TestFuzzyPositions(t *testing.T) {
+	assert.Equal(t, []int{0, 1, 2}, fuzzyPositions("abc", []rune("abcabc")))
+	assert.Equal(t, []int{6, 7, 8}, fuzzyPositions("Acq", []rune("a c q acquire")), "consecutive letters win")
+	assert.Equal(t, []int{4, 20}, fuzzyPositions("pa", []rune("sel pgxpool.(*Pool).Acquire")))
+	assert.Nil(t, fuzzyPositions("xyz", []rune("acquire")))
+	assert.Nil(t, fuzzyPositions("", []rune("acquire")))
+}
+
+func TestMatchCommands(t *testing.T) {
+	commands := paletteCommands()
+	names := func(matches []command) []string {
+		var result []string
+		for _, c := range matches {
+			result = append(result, c.name)
+		}
+		return result
+	}
+
+	matches, arg := matchCommands(commands, "sort")
+	assert.Empty(t, arg)
+	assert.Equal(t, []string{"sort", "sort-direction"}, names(matches)[:2], "the shorter name wins a tie")
+	assert.Equal(t, actionSort, matches[0].action, "sort without an argument changes the sort column")
+
+	matches, arg = matchCommands(commands, "sor -wait")
+	assert.Equal(t, "-wait", arg)
+	assert.Equal(t, "sort", matches[0].name)
+	for _, c := range matches {
+		assert.NotEmpty(t, c.arg, "only commands with an argument match once one is typed")
+	}
+
+	matches, _ = matchCommands(commands, "ivl")
+	assert.Equal(t, "interval", matches[0].name)
+
+	matches, arg = matchCommands(commands, "filter redis pool ")
+	assert.Equal(t, "filter", matches[0].name)
+	assert.Equal(t, "redis pool", arg)
+
+	matches, _ = matchCommands(commands, "")
+	assert.Len(t, matches, len(commands))
+}
+
+func TestPaletteArguments(t *testing.T) {
+	ui := &UI{targets: []string{"a"}, timeline: newTimeline()}
+	assert.Error(t, ui.switchTarget("1"))
+	assert.Error(t, ui.setInterval("5s"), "a file is not polled")
+	assert.Error(t, ui.showSnapshot("3"))
+	assert.Error(t, ui.recallBookmarkName("db"))
+	assert.ErrorContains(t, ui.filterStatusName("sleeping"), "Expected running, runnable")
+}
@@ -0,0 +1,133 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/becheran/roumon/internal/config"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+const (
+	defaultListShare         = 1.0 / 6 // Share of the list left of the details
+	defaultVerticalListShare = 0.4     // Share of the list above the details
+	paneStep                 = 0.05    // Change of the share by key
+	minPaneShare             = 0.1
+	maxPaneShare             = 0.9
+)
+
+// layoutSize is the breakpoint of the terminal width which decides how the widgets are arranged
+type layoutSize int
+
+const (
+	layoutNormal layoutSize = iota // Charts above the list and the details
+	layoutNarrow                   // One pane at a time. The focus key switches between the list and the details
+	layoutWide                     // List, details and charts side by side
+)
+
+const (
+	narrowWidth = 100 // Terminals which are narrower show one pane at a time
+	wideWidth   = 200 // Terminals which are at least as wide place the charts right of the panes
+	shortHeight = 24  // Terminals which are lower hide the charts
+)
+
+// sizeOf returns the breakpoint of the terminal width
+func sizeOf(width int) layoutSize {
+	switch {
+	case width < narrowWidth:
+		return layoutNarrow
+	case width >= wideWidth:
+		return layoutWide
+	}
+	return layoutNormal
+}
+
+// SetLayout arranges the list and the details. Save is called with the layout once the user changed it and may
+// be nil.
+func (ui *UI) SetLayout(layout config.Layout, save func(layout config.Layout) error) {
+	ui.panes = layout
+	ui.savePanes = save
+	if ui.panes.List != 0 {
+		ui.panes.List = ui.clampShare(ui.panes.List)
+	}
+	ui.layout()
+}
+
+// listShare returns the share of the list in the space of list and details
+func (ui *UI) listShare() float64 {
+	switch {
+	case ui.panes.List != 0:
+		return ui.panes.List
+	case ui.panes.Vertical:
+		return defaultVerticalListShare
+	}
+	return defaultListShare
+}
+
+// clampShare keeps both panes visible and rounds the share to keep the config file readable
+func (ui *UI) clampShare(share float64) float64 {
+	return math.Round(max(minPaneShare, min(maxPaneShare, share))*100) / 100
+}
+
+// resizeList grows or shrinks the list by the delta and shrinks or grows the details
+func (ui *UI) resizeList(delta float64) {
+	ui.panes.List = ui.clampShare(ui.listShare() + delta)
+	ui.layout()
+	ui.saveLayout()
+}
+
+// toggleVertical places the list above or left of the details with the default size
+func (ui *UI) toggleVertical() {
+	ui.panes.Vertical = !ui.panes.Vertical
+	ui.panes.List = 0
+	ui.layout()
+	ui.saveLayout()
+}
+
+func (ui *UI) saveLayout() {
+	if ui.savePanes == nil {
+		return
+	}
+	if err := ui.savePanes(ui.panes); err != nil {
+		ui.notice = fmt.Sprintf("[Failed to save the layout: %s](fg:error)", plain(err.Error()))
+	}
+}
+
+// handleMouse drags the border between the list and the details. Returns true if the panes changed.
+func (ui *UI) handleMouse(id string, mouse termui.Mouse) bool {
+	switch id {
+	case "<MouseLeft>":
+		if !ui.dragging {
+			ui.dragging = ui.onPaneBorder(mouse.X, mouse.Y)
+			return false
+		}
+		list, details := ui.list.Rectangle, ui.details.Rectangle
+		share := float64(mouse.X-list.Min.X+1) / float64(max(1, details.Max.X-list.Min.X))
+		if ui.panes.Vertical {
+			share = float64(mouse.Y-list.Min.Y+1) / float64(max(1, details.Max.Y-list.Min.Y))
+		}
+		ui.panes.List = ui.clampShare(share)
+		ui.layout()
+		return true
+	case "<MouseRelease>":
+		if ui.dragging {
+			ui.dragging = false
+			ui.saveLayout()
+			return true
+		}
+	}
+	return false
+}
+
+// onPaneBorder returns true if the position is on the border between the list and the details
+func (ui *UI) onPaneBorder(x, y int) bool {
+	if ui.size == layoutNarrow {
+		return false
+	}
+	details := ui.details.Rectangle
+	if ui.panes.Vertical {
+		return (y == details.Min.Y || y == details.Min.Y-1) && x >= details.Min.X && x < details.Max.X
+	}
+	return (x == details.Min.X || x == details.Min.X-1) && y >= details.Min.Y && y < details.Max.Y
+}
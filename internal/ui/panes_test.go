@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"image"
+	"testing"
+
+	"github.com/becheran/roumon/internal/config"
+	"github.com/gizak/termui/v3/widgets"
+	"github.com/stretchr/testify/assert"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+func TestDragPanes(t *testing.T) {
+	var saved []config.Layout
+	ui := &UI{list: newWideList(), details: newWideParagraph(), grid: termui.NewGrid()}
+	ui.SetLayout(config.Layout{}, func(layout config.Layout) error {
+		saved = append(saved, layout)
+		return nil
+	})
+	ui.list.Rectangle = image.Rect(0, 12, 25, 40)
+	ui.details.Rectangle = image.Rect(25, 12, 150, 40)
+
+	assert.False(t, ui.handleMouse("<MouseLeft>", termui.Mouse{X: 50, Y: 20}))
+	assert.False(t, ui.handleMouse("<MouseRelease>", termui.Mouse{X: 50, Y: 20}))
+	assert.Empty(t, saved)
+
+	assert.False(t, ui.handleMouse("<MouseLeft>", termui.Mouse{X: 25, Y: 20}))
+	assert.True(t, ui.handleMouse("<MouseLeft>", termui.Mouse{X: 59, Y: 20, Drag: true}))
+	assert.True(t, ui.handleMouse("<MouseRelease>", termui.Mouse{X: 59, Y: 20}))
+	assert.Equal(t, []config.Layout{{List: 0.4}}, saved)
+
+	ui.toggleVertical()
+	assert.Equal(t, defaultVerticalListShare, ui.listShare())
+	ui.resizeList(paneStep)
+	ui.resizeList(10)
+	assert.Equal(t, config.Layout{Vertical: true, List: maxPaneShare}, saved[len(saved)-1])
+}
+
+func TestResponsiveLayout(t *testing.T) {
+	assert.Equal(t, layoutNarrow, sizeOf(80))
+	assert.Equal(t, layoutNormal, sizeOf(narrowWidth))
+	assert.Equal(t, layoutWide, sizeOf(wideWidth))
+
+	ui := &UI{filter: widgets.NewParagraph(), list: newWideList(), details: newWideParagraph(),
+		routineHist: newHistoryPlot(), barchart: widgets.NewBarChart(), barchartLegend: widgets.NewParagraph(),
+		grid: termui.NewGrid()}
+	// gridItem returns the placed widget or nil
+	gridItem := func(entry interface{}) *termui.GridItem {
+		for _, item := range ui.grid.Items {
+			if item.Entry == entry {
+				return item
+			}
+		}
+		return nil
+	}
+
+	ui.size = layoutNarrow
+	ui.layout()
+	assert.NotNil(t, gridItem(ui.list))
+	assert.Nil(t, gridItem(ui.details))
+	assert.Nil(t, gridItem(ui.routineHist))
+	ui.focusDetails = true
+	ui.layout()
+	assert.Nil(t, gridItem(ui.list))
+	assert.Equal(t, 1.0, gridItem(ui.details).HeightRatio)
+	assert.False(t, ui.onPaneBorder(ui.details.Min.X, ui.details.Min.Y))
+	ui.focusDetails = false
+
+	ui.size = layoutWide
+	ui.layout()
+	assert.InDelta(t, 0.7, gridItem(ui.routineHist).XRatio, 0.001, "history right of the panes")
+	assert.Equal(t, 0.0, gridItem(ui.details).YRatio)
+
+	ui.size, ui.short = layoutNormal, true
+	ui.layout()
+	assert.Nil(t, gridItem(ui.routineHist), "too low for the charts")
+	ui.short = false
+	ui.layout()
+	assert.Equal(t, 0.0, gridItem(ui.routineHist).YRatio)
+	assert.InDelta(t, 0.3, gridItem(ui.details).YRatio, 0.001, "details below the charts")
+}
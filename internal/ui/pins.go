@@ -0,0 +1,108 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// pin tracks a goroutine across refreshes
+type pin struct {
+	id     int64
+	target string
+	status string // Status in the last snapshot of the target
+}
+
+// togglePin pins the selected goroutine or unpins it if it is pinned already
+func (ui *UI) togglePin() {
+	routines := ui.selectedGoroutines()
+	if len(routines) == 0 {
+		ui.notice = "[No goroutine selected](fg:warn)"
+		return
+	}
+	g := routines[0]
+	key := g.Fingerprint()
+	if _, ok := ui.pins[key]; ok {
+		delete(ui.pins, key)
+		ui.notice = fmt.Sprintf("Unpinned goroutine %d", g.ID)
+	} else {
+		ui.pins[key] = &pin{id: g.ID, target: g.Target, status: g.Status}
+		ui.notice = fmt.Sprintf("[Pinned goroutine %d](fg:ok)", g.ID)
+	}
+	ui.updateList()
+}
+
+// trackPins compares the pinned goroutines of the target with the snapshot. Pins of goroutines which are not part
+// of the snapshot anymore are removed. Returns the changes in the order of the goroutine IDs.
+func (ui *UI) trackPins(snapshot model.Snapshot) []string {
+	seen := make(map[string]model.Goroutine)
+	for _, g := range snapshot.Goroutines {
+		if _, ok := ui.pins[g.Fingerprint()]; ok {
+			seen[g.Fingerprint()] = g
+		}
+	}
+	var changed []*pin
+	var changes []string
+	for key, p := range ui.pins {
+		if p.target != snapshot.Target {
+			continue
+		}
+		g, ok := seen[key]
+		switch {
+		case !ok:
+			delete(ui.pins, key)
+			p.status = ""
+		case g.Status == p.status:
+			continue
+		default:
+			p.status = g.Status
+		}
+		changed = append(changed, p)
+	}
+	sort.Slice(changed, func(i, j int) bool { return changed[i].id < changed[j].id })
+	for _, p := range changed {
+		change := fmt.Sprintf("goroutine %d is %s", p.id, p.status)
+		if len(p.status) == 0 {
+			change = fmt.Sprintf("goroutine %d disappeared", p.id)
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// notifyPins flags pinned goroutines of the snapshot which disappeared or changed their status
+func (ui *UI) notifyPins(snapshot model.Snapshot) {
+	changes := ui.trackPins(snapshot)
+	if len(changes) == 0 {
+		return
+	}
+	log.Printf("Pinned %s", strings.Join(changes, ", "))
+	ui.notice = fmt.Sprintf("[Pinned %s](fg:warn)", strings.Join(changes, ", "))
+}
+
+// markPin marks the rows of the pinned goroutines while goroutines are pinned
+func (ui *UI) markPin(g model.Goroutine, row string) string {
+	if len(ui.pins) == 0 {
+		return row
+	}
+	if ui.pinned(g) {
+		return "● " + row
+	}
+	return "  " + row
+}
+
+func (ui *UI) pinned(g model.Goroutine) bool {
+	_, ok := ui.pins[g.Fingerprint()]
+	return ok
+}
+
+// pinStatus shows the number of pinned goroutines in the status bar
+func (ui *UI) pinStatus() string {
+	if len(ui.pins) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Pinned %d", len(ui.pins))
+}
@@ -0,0 +1,33 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackPins(t *testing.T) {
+	ui := &UI{pins: make(map[string]*pin), timeline: newTimeline()}
+	routines := []model.Goroutine{
+		{ID: 1, Status: "select", Target: "a"},
+		{ID: 2, Status: "chan receive", Target: "a"},
+		{ID: 3, Status: "running", Target: "a"},
+	}
+	for _, g := range routines {
+		ui.pins[g.Fingerprint()] = &pin{id: g.ID, target: g.Target, status: g.Status}
+	}
+	assert.Equal(t, "● 1 ", ui.markPin(routines[0], "1 "))
+	assert.Equal(t, "  4 ", ui.markPin(model.Goroutine{ID: 4, Target: "a"}, "4 "))
+	assert.Equal(t, "[● 1 ](fg:notice)", ui.markChurn(routines[0], "● 1 "))
+
+	snapshot := model.Snapshot{Target: "a", Goroutines: []model.Goroutine{
+		{ID: 3, Status: "running", Target: "a"},
+		{ID: 2, Status: "running", Target: "a"},
+	}}
+	assert.Equal(t, []string{"goroutine 1 disappeared", "goroutine 2 is running"}, ui.trackPins(snapshot))
+	assert.Len(t, ui.pins, 2)
+	assert.Empty(t, ui.trackPins(snapshot))
+	assert.Empty(t, ui.trackPins(model.Snapshot{Target: "b"}), "pins of other targets are kept")
+	assert.Equal(t, "Pinned 2", ui.pinStatus())
+}
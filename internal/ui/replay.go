@@ -0,0 +1,202 @@
+package ui
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+const (
+	// plotLeft is the width of the y axis of the plot of termui, left of which no points are drawn
+	plotLeft = 5
+	// maxReplayDelay is the longest wait for the next snapshot at normal speed, so gaps in a recording are skipped
+	maxReplayDelay = 5 * time.Second
+	// minReplayDelay keeps playing snapshots with the same time from redrawing the screen too often
+	minReplayDelay = 50 * time.Millisecond
+)
+
+// replaySpeeds relative to the time between the recorded snapshots
+var replaySpeeds = []float64{0.25, 0.5, 1, 2, 4, 8, 16, 32}
+
+// replay plays a recorded session back. The snapshots are stepped through in the timeline.
+type replay struct {
+	playing bool
+	speed   int              // Index of the speed in replaySpeeds
+	wake    <-chan time.Time // Fires when the next snapshot is due. Nil while paused
+}
+
+// SetReplay shows the first snapshot instead of the latest and plays the snapshots back in the time in which they
+// were recorded on the pause key. The interval keys change the speed and clicking into the history jumps to the
+// snapshot at that time.
+func (ui *UI) SetReplay() {
+	ui.replay = &replay{speed: 2}
+	ui.updateLegend()
+}
+
+// togglePlay starts or stops the replay. A replay which reached the end starts from the first snapshot.
+func (ui *UI) togglePlay() {
+	r := ui.replay
+	r.playing = !r.playing
+	if r.playing && ui.timeline.position() == len(ui.timeline.snapshots)-1 {
+		ui.timeline.cursor = 0
+		ui.showPosition()
+	}
+	ui.scheduleReplay()
+	ui.updateStatusBar()
+}
+
+// changeSpeed selects the next faster or slower speed of the replay
+func (ui *UI) changeSpeed(faster bool) {
+	r := ui.replay
+	if faster {
+		r.speed = min(r.speed+1, len(replaySpeeds)-1)
+	} else {
+		r.speed = max(r.speed-1, 0)
+	}
+	ui.scheduleReplay()
+	ui.updateStatusBar()
+}
+
+// scheduleReplay waits for the time between the shown and the next snapshot at the speed of the replay. The
+// replay pauses at the last snapshot.
+func (ui *UI) scheduleReplay() {
+	r := ui.replay
+	r.wake = nil
+	if !r.playing {
+		return
+	}
+	position := ui.timeline.position()
+	if position >= len(ui.timeline.snapshots)-1 {
+		r.playing = false
+		return
+	}
+	gap := ui.timeline.snapshots[position+1].Time.Sub(ui.timeline.snapshots[position].Time)
+	delay := time.Duration(float64(min(max(gap, 0), maxReplayDelay)) / replaySpeeds[r.speed])
+	r.wake = time.After(max(delay, minReplayDelay))
+}
+
+// replayWake returns the channel which fires when the next snapshot of the replay is due. Nil blocks forever.
+func (ui *UI) replayWake() <-chan time.Time {
+	if ui.replay == nil {
+		return nil
+	}
+	return ui.replay.wake
+}
+
+// replayStep shows the next snapshot of a playing replay
+func (ui *UI) replayStep() {
+	ui.timeline.step(1)
+	ui.keepCursor()
+	ui.showPosition()
+	ui.scheduleReplay()
+	ui.updateStatusBar()
+}
+
+// keepCursor keeps the cursor of a replay on the last snapshot, which the timeline would otherwise follow while
+// the snapshots are still loaded
+func (ui *UI) keepCursor() {
+	if ui.replay != nil && !ui.timeline.browsing() {
+		ui.timeline.freeze()
+	}
+}
+
+// showPosition shows the snapshots at the cursor of the timeline
+func (ui *UI) showPosition() {
+	ui.mergeSnapshots()
+	ui.updateList()
+	ui.updateStatus()
+	ui.updateHistory()
+}
+
+// replayStatus shows the state and the position of the replay
+func (ui *UI) replayStatus() string {
+	r := ui.replay
+	if len(ui.timeline.snapshots) == 0 {
+		return ""
+	}
+	state := "Paused"
+	if r.playing {
+		state = "Playing"
+	}
+	position := ui.timeline.position()
+	snapshot := ui.timeline.snapshots[position]
+	text := fmt.Sprintf("%s %sx, snapshot %d/%d", state, strconv.FormatFloat(replaySpeeds[r.speed], 'f', -1, 64),
+		position+1, len(ui.timeline.snapshots))
+	if len(snapshot.Source) > 0 {
+		text += " " + plain(filepath.Base(snapshot.Source))
+	}
+	return fmt.Sprintf("[%s %s](fg:info)", text, snapshot.Time.Format(time.DateTime))
+}
+
+// scrub shows the snapshot below a click into the history of a replay. Returns true if the click hit the history.
+func (ui *UI) scrub(id string, mouse termui.Mouse) bool {
+	if ui.replay == nil || id != "<MouseLeft>" || ui.dragging {
+		return false
+	}
+	index := ui.routineHist.index(mouse.X, mouse.Y, len(ui.timeline.snapshots))
+	if index < 0 {
+		return false
+	}
+	ui.timeline.cursor = index
+	ui.keepCursor()
+	ui.showPosition()
+	ui.scheduleReplay()
+	return true
+}
+
+// historyPlot is the plot of the history which marks the shown snapshot of a replay
+type historyPlot struct {
+	*widgets.Plot
+	marker      float64 // Position of the shown snapshot from 0 at the first to 1 at the last. Negative if unmarked
+	markerColor termui.Color
+}
+
+func newHistoryPlot() *historyPlot {
+	return &historyPlot{Plot: widgets.NewPlot(), marker: -1}
+}
+
+// drawArea returns the cells in which the plot draws its points
+func (p *historyPlot) drawArea() image.Rectangle {
+	return image.Rect(p.Inner.Min.X+plotLeft, p.Inner.Min.Y, p.Inner.Max.X, p.Inner.Max.Y-2)
+}
+
+// points returns the number of points of every line
+func (p *historyPlot) points() int {
+	if len(p.Data) == 0 {
+		return 0
+	}
+	return len(p.Data[0])
+}
+
+// index returns the index of the snapshot of the history below the cell, or -1 if the cell is outside of the plot.
+// The points are spread over the snapshots evenly, since every snapshot adds a sample to the history.
+func (p *historyPlot) index(x, y, snapshots int) int {
+	area := p.drawArea()
+	points := p.points()
+	if snapshots == 0 || points < 2 || !image.Pt(x, y).In(area) {
+		return -1
+	}
+	point := min((x-area.Min.X+p.HorizontalScale/2)/p.HorizontalScale, points-1)
+	return (point*(snapshots-1) + (points-1)/2) / (points - 1)
+}
+
+func (p *historyPlot) Draw(buf *termui.Buffer) {
+	p.Plot.Draw(buf)
+	points := p.points()
+	if p.marker < 0 || points < 2 {
+		return
+	}
+	area := p.drawArea()
+	x := area.Min.X + int(p.marker*float64(points-1)+0.5)*p.HorizontalScale
+	if x >= area.Max.X {
+		return
+	}
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		buf.SetCell(termui.NewCell(termui.VERTICAL_LINE, termui.NewStyle(p.markerColor)), image.Pt(x, y))
+	}
+}
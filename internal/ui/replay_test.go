@@ -0,0 +1,58 @@
+package ui
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryPlotIndex(t *testing.T) {
+	p := newHistoryPlot()
+	p.HorizontalScale = 2
+	p.SetRect(0, 0, 30, 10)
+	p.Data = [][]float64{{1, 2, 3, 4, 5}}
+	area := p.drawArea()
+	assert.Equal(t, image.Rect(6, 1, 29, 7), area)
+
+	assert.Equal(t, 0, p.index(area.Min.X, 3, 5))
+	assert.Equal(t, 1, p.index(area.Min.X+2, 3, 5))
+	assert.Equal(t, 2, p.index(area.Min.X+3, 3, 5), "nearest point")
+	assert.Equal(t, 4, p.index(area.Max.X-1, 3, 5), "right of the last point")
+	assert.Equal(t, -1, p.index(area.Min.X-1, 3, 5), "on the axis")
+	assert.Equal(t, -1, p.index(area.Min.X, area.Max.Y, 5), "on the labels")
+	assert.Equal(t, -1, p.index(area.Min.X, 3, 0), "nothing recorded")
+
+	assert.Equal(t, 50, p.index(area.Min.X+4, 3, 101), "merged samples are spread over the snapshots")
+	assert.Equal(t, 100, p.index(area.Min.X+8, 3, 101))
+}
+
+func TestReplay(t *testing.T) {
+	start := time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local)
+	ui := &UI{timeline: newTimeline(), replay: &replay{speed: 2}}
+	for i := 0; i < 3; i++ {
+		ui.timeline.add(model.Snapshot{Target: "a", Time: start.Add(time.Duration(i) * time.Minute)})
+		ui.keepCursor()
+	}
+	assert.Equal(t, 0, ui.timeline.position(), "a replay starts at the first snapshot")
+
+	ui.scheduleReplay()
+	assert.Nil(t, ui.replayWake(), "paused")
+	ui.replay.playing = true
+	ui.scheduleReplay()
+	assert.NotNil(t, ui.replayWake())
+
+	ui.timeline.step(2)
+	ui.keepCursor()
+	assert.True(t, ui.timeline.browsing(), "the cursor stays on the last snapshot")
+	assert.Equal(t, 2, ui.timeline.position())
+	ui.scheduleReplay()
+	assert.False(t, ui.replay.playing, "the replay stops at the last snapshot")
+	assert.Nil(t, ui.replayWake())
+
+	ui.replay.playing = true
+	ui.replay.speed = len(replaySpeeds) - 1
+	assert.Equal(t, "[Playing 32x, snapshot 3/3 2024-01-02 15:06:05](fg:info)", ui.replayStatus())
+}
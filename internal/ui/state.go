@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"log"
+
+	"github.com/becheran/roumon/internal/config"
+)
+
+// viewNames are the names of the views in the state file
+var viewNames = map[view]string{
+	viewStacks:   "stacks",
+	viewCreators: "creators",
+	viewDiff:     "diff",
+}
+
+// SetState restores the filter, the sort and the view of the last session. Empty fields keep the current ones.
+// Save is called with the state when the user quits and may be nil.
+func (ui *UI) SetState(state config.State, save func(state config.State) error) {
+	ui.saveState = save
+	if len(state.Sort) > 0 {
+		if err := ui.SetSort(state.Sort); err != nil {
+			log.Printf("Failed to restore the sort %s. Err: %s", state.Sort, err.Error())
+		}
+	}
+	if len(state.Status) > 0 {
+		if err := ui.filterStatusName(state.Status); err != nil {
+			log.Printf("Failed to restore the status filter %s. Err: %s", state.Status, err.Error())
+		}
+	}
+	if len(state.Filter) > 0 {
+		_ = ui.setFilter(state.Filter)
+	}
+	for v, name := range viewNames {
+		if name == state.View {
+			ui.toggleView(v)
+		}
+	}
+	if state.Compact != ui.compact {
+		ui.toggleCompact()
+	}
+}
+
+// currentState returns the filter, the sort and the view of the list
+func (ui *UI) currentState() config.State {
+	state := config.State{
+		Sort:    formatSort(ui.sortKey, ui.sortDesc),
+		View:    viewNames[ui.view],
+		Compact: ui.compact,
+	}
+	if ui.filtered {
+		state.Filter = ui.filter.Text
+	}
+	if ui.statusFilter.active() {
+		state.Status = ui.statusFilter.String()
+	}
+	return state
+}
+
+// storeState saves the state of the list for the next session
+func (ui *UI) storeState() {
+	if ui.saveState == nil {
+		return
+	}
+	if err := ui.saveState(ui.currentState()); err != nil {
+		log.Printf("Failed to save the state. Err: %s", err.Error())
+	}
+}
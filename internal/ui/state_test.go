@@ -0,0 +1,22 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/config"
+	"github.com/becheran/roumon/internal/model"
+	"github.com/gizak/termui/v3/widgets"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurrentState(t *testing.T) {
+	ui := &UI{filter: widgets.NewParagraph(), sortKey: model.SortByID, view: viewCreators, compact: true}
+	ui.filter.Text = "TYPE TO FILTER"
+	assert.Equal(t, config.State{Sort: "id", View: "creators", Compact: true}, ui.currentState(),
+		"the placeholder is no filter")
+
+	ui.filtered, ui.filter.Text = true, "pgxpool"
+	ui.statusFilter = ui.statusFilter.toggleHide(counterOf("IO wait"))
+	ui.view = viewRoutines
+	assert.Equal(t, config.State{Filter: "pgxpool", Status: "-IO wait", Sort: "id", Compact: true}, ui.currentState())
+}
@@ -0,0 +1,246 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/becheran/roumon/internal/model"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+// statusCounter counts the goroutines whose status matches
+type statusCounter struct {
+	name  string
+	role  string
+	match func(status string) bool
+}
+
+// statusCounters in the order of the summary. The last counter counts all statuses which no other counter matches.
+var statusCounters = []statusCounter{
+	{name: "running", role: "running", match: statusIs("running")},
+	{name: "runnable", role: "running", match: statusIs("runnable")},
+	{name: "IO wait", role: "network", match: statusIs("IO wait")},
+	{name: "chan receive", role: "channel", match: statusHasPrefix("chan receive")},
+	{name: "select", role: "channel", match: statusHasPrefix("select")},
+	{name: "syscall", role: "syscall", match: statusHasPrefix("syscall")},
+	{name: "GC", role: "idle", match: isGCStatus},
+	{name: "other", role: "user"},
+}
+
+func statusIs(name string) func(string) bool {
+	return func(status string) bool { return status == name }
+}
+
+func statusHasPrefix(prefix string) func(string) bool {
+	return func(status string) bool { return strings.HasPrefix(status, prefix) }
+}
+
+func isGCStatus(status string) bool {
+	return strings.HasPrefix(status, "GC ") || strings.Contains(status, "garbage collection") ||
+		strings.Contains(status, "gc (idle)") || status == "wait for GC cycle"
+}
+
+// counterOf returns the index of the counter of the status
+func counterOf(status string) int {
+	for i, counter := range statusCounters[:len(statusCounters)-1] {
+		if counter.match(status) {
+			return i
+		}
+	}
+	return len(statusCounters) - 1
+}
+
+// statusSelection lists the goroutines by their status counter. Every counter is a bit, for example 1<<2 for IO
+// wait.
+type statusSelection struct {
+	only uint // Counters whose goroutines are listed. 0 lists the goroutines of all counters which are not hidden
+	hide uint // Counters whose goroutines are not listed
+}
+
+// active returns true if the selection does not list all goroutines
+func (s statusSelection) active() bool {
+	return s.only != 0 || s.hide != 0
+}
+
+// matches returns true if the goroutines of the counter are listed
+func (s statusSelection) matches(counter int) bool {
+	bit := uint(1) << counter
+	return s.hide&bit == 0 && (s.only == 0 || s.only&bit != 0)
+}
+
+// toggleOnly adds the counter to the counters which are listed only or removes it again
+func (s statusSelection) toggleOnly(counter int) statusSelection {
+	bit := uint(1) << counter
+	s.hide &^= bit
+	s.only ^= bit
+	return s
+}
+
+// toggleHide hides the goroutines of the counter or lists them again
+func (s statusSelection) toggleHide(counter int) statusSelection {
+	bit := uint(1) << counter
+	s.only &^= bit
+	s.hide ^= bit
+	return s
+}
+
+// single returns the counter if only the goroutines of one counter are listed
+func (s statusSelection) single() (int, bool) {
+	for i := range statusCounters {
+		if s.only == 1<<i && s.hide == 0 {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// String lists the counters separated by commas. Hidden counters start with -, for example select,chan receive
+// or -IO wait.
+func (s statusSelection) String() string {
+	var names []string
+	for i, counter := range statusCounters {
+		switch {
+		case s.only&(1<<i) != 0:
+			names = append(names, counter.name)
+		case s.hide&(1<<i) != 0:
+			names = append(names, "-"+counter.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// parseStatusSelection parses the format of statusSelection.String. The names of the counters are not case
+// sensitive.
+func parseStatusSelection(spec string) (statusSelection, error) {
+	var s statusSelection
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			continue
+		}
+		hide := strings.HasPrefix(name, "-")
+		counter, ok := counterNamed(strings.TrimPrefix(name, "-"))
+		if !ok {
+			names := make([]string, len(statusCounters))
+			for i, counter := range statusCounters {
+				names[i] = counter.name
+			}
+			return s, fmt.Errorf("unknown counter %s. Expected %s", name, strings.Join(names, ", "))
+		}
+		if hide {
+			s.hide |= 1 << counter
+		} else {
+			s.only |= 1 << counter
+		}
+	}
+	return s, nil
+}
+
+// counterNamed returns the index of the counter with the name
+func counterNamed(name string) (int, bool) {
+	for i, counter := range statusCounters {
+		if strings.EqualFold(counter.name, name) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// countStatuses counts the goroutines of every counter
+func countStatuses(routines []model.Goroutine) []int {
+	counts := make([]int, len(statusCounters))
+	for _, g := range routines {
+		counts[counterOf(g.Status)]++
+	}
+	return counts
+}
+
+// updateSummary shows the counters of the listed goroutines before the filter. Counters without goroutines are
+// dimmed, the counters which are listed only are highlighted and hidden counters are dimmed and marked with -.
+func (ui *UI) updateSummary() {
+	counts := countStatuses(ui.origData)
+	ui.counterSpans = ui.counterSpans[:0]
+	text := ""
+	x := 0
+	for i, counter := range statusCounters {
+		label := fmt.Sprintf(" %s %s ", counter.name, formatCount(counts[i]))
+		style := "fg:" + counter.role
+		switch {
+		case ui.statusFilter.only&(1<<i) != 0:
+			style = "fg:selected,bg:selectedbg"
+		case ui.statusFilter.hide&(1<<i) != 0:
+			label = fmt.Sprintf(" -%s %s ", counter.name, formatCount(counts[i]))
+			style = "fg:stdlib"
+		case counts[i] == 0:
+			style = "fg:stdlib"
+		}
+		text += fmt.Sprintf("[%s](%s) ", label, style)
+		width := displayWidth(label)
+		ui.counterSpans = append(ui.counterSpans, [2]int{x, x + width})
+		x += width + 1
+	}
+	ui.summary.Text = text
+}
+
+// filterStatus lists the goroutines of the selected counters
+func (ui *UI) filterStatus(selection statusSelection) {
+	ui.statusFilter = selection
+	ui.updateList()
+}
+
+// nextStatusFilter lists only the goroutines of the next counter which counts goroutines and all goroutines after
+// the last one
+func (ui *UI) nextStatusFilter() {
+	counts := countStatuses(ui.origData)
+	first := 0
+	if counter, ok := ui.statusFilter.single(); ok {
+		first = counter + 1
+	} else if ui.statusFilter.active() {
+		first = len(statusCounters)
+	}
+	for counter := first; counter < len(statusCounters); counter++ {
+		if counts[counter] > 0 {
+			ui.filterStatus(statusSelection{}.toggleOnly(counter))
+			return
+		}
+	}
+	ui.filterStatus(statusSelection{})
+}
+
+// toggleSelectedStatus lists only the goroutines of the counter of the selected goroutine in addition to the ones
+// which are listed only, or hides them. The key toggles back.
+func (ui *UI) toggleSelectedStatus(hide bool) {
+	routines := ui.selectedGoroutines()
+	if len(routines) == 0 {
+		ui.notice = "[No goroutine selected](fg:warn)"
+		return
+	}
+	counter := counterOf(routines[0].Status)
+	if hide {
+		ui.filterStatus(ui.statusFilter.toggleHide(counter))
+	} else {
+		ui.filterStatus(ui.statusFilter.toggleOnly(counter))
+	}
+}
+
+// clickCounter lists only the goroutines of the counter which is left clicked in addition to the ones which are
+// listed only and hides the goroutines of the counter which is right clicked. A second click toggles back. Returns
+// true if a counter was clicked.
+func (ui *UI) clickCounter(id string, mouse termui.Mouse) bool {
+	rect := ui.summary.Inner
+	if id != "<MouseLeft>" && id != "<MouseRight>" || mouse.Drag || mouse.Y != rect.Min.Y {
+		return false
+	}
+	for i, span := range ui.counterSpans {
+		if mouse.X-rect.Min.X >= span[0] && mouse.X-rect.Min.X < span[1] {
+			if id == "<MouseRight>" {
+				ui.filterStatus(ui.statusFilter.toggleHide(i))
+			} else {
+				ui.filterStatus(ui.statusFilter.toggleOnly(i))
+			}
+			return true
+		}
+	}
+	return false
+}
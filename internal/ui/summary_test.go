@@ -0,0 +1,87 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/gizak/termui/v3/widgets"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterOf(t *testing.T) {
+	name := func(status string) string { return statusCounters[counterOf(status)].name }
+	assert.Equal(t, "running", name("running"))
+	assert.Equal(t, "chan receive", name("chan receive (nil chan)"))
+	assert.Equal(t, "select", name("select (no cases)"))
+	assert.Equal(t, "GC", name("GC worker (idle)"))
+	assert.Equal(t, "GC", name("force gc (idle)"))
+	assert.Equal(t, "other", name("chan send"))
+	assert.Equal(t, "other", name("sleep"))
+}
+
+func TestFilterByStatusCounter(t *testing.T) {
+	ui := &UI{filter: widgets.NewParagraph(), summary: widgets.NewParagraph()}
+	ui.origData = []model.Goroutine{
+		{ID: 1, Status: "running"},
+		{ID: 2, Status: "IO wait"},
+		{ID: 3, Status: "IO wait", StackTrace: []model.StackFrame{{FuncName: "net.(*conn).Read()"}}},
+		{ID: 4, Status: "semacquire"},
+	}
+	assert.Equal(t, []int{1, 0, 2, 0, 0, 0, 0, 1}, countStatuses(ui.origData))
+
+	ids := func() []int64 {
+		var result []int64
+		for _, g := range ui.filterRoutines() {
+			result = append(result, g.ID)
+		}
+		return result
+	}
+	ui.statusFilter = statusSelection{only: 1 << 2}
+	assert.Equal(t, []int64{2, 3}, ids())
+	ui.filtered, ui.filter.Text = true, "conn"
+	assert.Equal(t, []int64{3}, ids())
+	ui.statusFilter = statusSelection{only: 1 << 7}
+	assert.Empty(t, ids())
+
+	ui.updateSummary()
+	assert.Contains(t, ui.summary.Text, "[ IO wait 2 ](fg:network)")
+	assert.Contains(t, ui.summary.Text, "[ select 0 ](fg:stdlib)")
+	assert.Contains(t, ui.summary.Text, "[ other 1 ](fg:selected,bg:selectedbg)")
+	assert.Equal(t, [2]int{0, 11}, ui.counterSpans[0])
+	assert.Equal(t, [2]int{12, 24}, ui.counterSpans[1])
+
+	ui.filtered = false
+	ui.statusFilter = statusSelection{}.toggleOnly(0).toggleOnly(7)
+	assert.Equal(t, []int64{1, 4}, ids())
+	ui.statusFilter = statusSelection{}.toggleHide(2)
+	assert.Equal(t, []int64{1, 4}, ids())
+	ui.updateSummary()
+	assert.Contains(t, ui.summary.Text, "[ -IO wait 2 ](fg:stdlib)")
+	ui.statusFilter = ui.statusFilter.toggleOnly(2)
+	assert.Equal(t, []int64{2, 3}, ids(), "listing a hidden counter only shows it again")
+}
+
+func TestStatusSelection(t *testing.T) {
+	s, err := parseStatusSelection("select, Chan Receive")
+	assert.NoError(t, err)
+	assert.Equal(t, "chan receive,select", s.String())
+	_, ok := s.single()
+	assert.False(t, ok)
+
+	s, err = parseStatusSelection("-IO wait")
+	assert.NoError(t, err)
+	assert.Equal(t, statusSelection{hide: 1 << 2}, s)
+	assert.False(t, s.matches(2))
+	assert.True(t, s.matches(3))
+	assert.Equal(t, statusSelection{}, s.toggleHide(2))
+
+	s, err = parseStatusSelection("")
+	assert.NoError(t, err)
+	assert.False(t, s.active())
+	_, err = parseStatusSelection("select,sleeping")
+	assert.ErrorContains(t, err, "unknown counter sleeping")
+
+	counter, ok := statusSelection{}.toggleOnly(4).single()
+	assert.True(t, ok)
+	assert.Equal(t, 4, counter)
+}
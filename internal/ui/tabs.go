@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// tabState is the state of the list which every tab remembers
+type tabState struct {
+	filterText string
+	filtered   bool
+	status     statusSelection
+	sortKey    model.SortKey
+	sortDesc   bool
+	selected   int
+}
+
+// tabIndex returns the tab of the number key. 0 is the tab of all targets, 1 to 9 are the tabs of the targets in
+// order of their first snapshot.
+func (ui *UI) tabIndex(keyID string) (int, bool) {
+	if len(ui.targets) < 2 || len(keyID) != 1 {
+		return 0, false
+	}
+	index, err := strconv.Atoi(keyID)
+	if err != nil || index > len(ui.targets) {
+		return 0, false
+	}
+	return index, true
+}
+
+// switchTab shows the goroutines of the target of the tab, or of all targets for tab 0. The sort, the filter and
+// the selection of the previous tab are kept for the return to it.
+func (ui *UI) switchTab(index int) {
+	target := ""
+	if index > 0 {
+		target = ui.targets[index-1]
+	}
+	if target == ui.tab {
+		return
+	}
+	ui.tabs[ui.tab] = tabState{
+		filterText: ui.filter.Text,
+		filtered:   ui.filtered,
+		status:     ui.statusFilter,
+		sortKey:    ui.sortKey,
+		sortDesc:   ui.sortDesc,
+		selected:   ui.list.SelectedRow,
+	}
+	ui.tab = target
+	state, ok := ui.tabs[target]
+	if !ok {
+		state = ui.initialSort
+		state.filterText = ui.filterPlaceholder()
+	}
+	ui.filter.Text = state.filterText
+	ui.filtered = state.filtered
+	ui.statusFilter = state.status
+	ui.searching = false
+	ui.sortKey = state.sortKey
+	ui.sortDesc = state.sortDesc
+	ui.list.SelectedRow = state.selected
+	ui.mergeSnapshots()
+	ui.updateList()
+	ui.updateStatus()
+	ui.updateStatusBar()
+}
+
+// closeTab returns to the tab of all targets if the target of the shown tab is removed
+func (ui *UI) closeTab(target string) {
+	delete(ui.tabs, target)
+	if ui.tab == target {
+		ui.tab = ""
+		if state, ok := ui.tabs[""]; ok {
+			ui.filter.Text, ui.filtered, ui.statusFilter = state.filterText, state.filtered, state.status
+			ui.sortKey, ui.sortDesc = state.sortKey, state.sortDesc
+			ui.list.SelectedRow = state.selected
+		}
+	}
+}
+
+// tabBar lists the tabs which have a number key. The shown tab is highlighted.
+func (ui *UI) tabBar() string {
+	text := ""
+	for i := 0; i <= min(9, len(ui.targets)); i++ {
+		target, name := "", "All"
+		if i > 0 {
+			target = ui.targets[i-1]
+			name = plain(target)
+		}
+		label := fmt.Sprintf(" %d %s ", i, name)
+		if target == ui.tab {
+			label = fmt.Sprintf("[%s](fg:selected,bg:selectedbg)", label)
+		}
+		text += label
+	}
+	if len(ui.targets) > 9 {
+		text += fmt.Sprintf(" +%d", len(ui.targets)-9)
+	}
+	return text
+}
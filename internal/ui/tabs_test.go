@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTabIndex(t *testing.T) {
+	ui := &UI{targets: []string{"a"}}
+	_, ok := ui.tabIndex("1")
+	assert.False(t, ok, "a single target has no tabs")
+
+	ui.targets = []string{"a", "b"}
+	index, ok := ui.tabIndex("2")
+	assert.True(t, ok)
+	assert.Equal(t, 2, index)
+	index, ok = ui.tabIndex("0")
+	assert.True(t, ok)
+	assert.Equal(t, 0, index)
+	_, ok = ui.tabIndex("3")
+	assert.False(t, ok)
+	_, ok = ui.tabIndex("x")
+	assert.False(t, ok)
+}
+
+func TestMergeSnapshotsOfTab(t *testing.T) {
+	ui := &UI{
+		timeline:  newTimeline(),
+		targets:   []string{"a", "b"},
+		snapshots: map[string]model.Snapshot{},
+		tabs:      map[string]tabState{},
+	}
+	for _, target := range ui.targets {
+		snapshot := model.Snapshot{Goroutines: []model.Goroutine{{ID: 1}, {ID: 2}}}
+		snapshot.SetTarget(target)
+		ui.snapshots[target] = snapshot
+	}
+	ui.mergeSnapshots()
+	assert.Len(t, ui.origData, 4)
+
+	ui.tab = "b"
+	ui.mergeSnapshots()
+	assert.Len(t, ui.origData, 2)
+	for _, g := range ui.origData {
+		assert.Equal(t, "b", g.Target)
+	}
+	assert.Contains(t, ui.tabBar(), "[ 2 b ](fg:selected,bg:selectedbg)")
+
+	ui.closeTab("b")
+	assert.Empty(t, ui.tab)
+	assert.Contains(t, ui.tabBar(), "[ 0 All ](fg:selected,bg:selectedbg)")
+}
@@ -0,0 +1,396 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/becheran/roumon/internal/model"
+	termbox "github.com/nsf/termbox-go"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+// DefaultTheme is used if no theme is configured
+const DefaultTheme = "dark"
+
+// Tags of the theme colors. Untagged colors are drawn by termui itself, for example the white axes of the plot.
+const (
+	paletteColor termui.Color = 1 << 24 // Index of the 256 color palette in the lower bits
+	rgbColor     termui.Color = 1 << 25 // 24 bit color in the lower bits
+)
+
+// themes map the roles of the UI to colors. The roles are registered as color names of the style markup, for
+// example [Connected](fg:ok). A color is a name, an index of the 256 color palette, #rrggbb or default.
+var themes = map[string]map[string]string{
+	"dark": {
+		"text":         "white",
+		"accent":       "green",
+		"border":       "white",
+		"title":        "white",
+		"selected":     "white",
+		"selectedbg":   "green",
+		"bartext":      "black",
+		"ok":           "green",
+		"warn":         "yellow",
+		"error":        "red",
+		"info":         "cyan",
+		"notice":       "magenta",
+		"running":      "green",
+		"syscall":      "magenta",
+		"network":      "blue",
+		"channel":      "cyan",
+		"sync":         "yellow",
+		"user":         "white",
+		"idle":         "red",
+		"added":        "green",
+		"removed":      "red",
+		"persisting":   "white",
+		"package":      "blue",
+		"receiver":     "magenta",
+		"function":     "green",
+		"file":         "cyan",
+		"line":         "yellow",
+		"stdlib":       "gray",
+		"match":        "yellow",
+		"currentmatch": "cyan",
+		"matchtext":    "black",
+		"label1":       "cyan",
+		"label2":       "yellow",
+		"label3":       "magenta",
+		"label4":       "blue",
+		"label5":       "green",
+		"label6":       "208",
+	},
+	"light": {
+		"text":         "black",
+		"accent":       "22",
+		"border":       "240",
+		"title":        "black",
+		"selected":     "brightwhite",
+		"selectedbg":   "24",
+		"bartext":      "brightwhite",
+		"ok":           "28",
+		"warn":         "130",
+		"error":        "124",
+		"info":         "25",
+		"notice":       "90",
+		"running":      "28",
+		"syscall":      "90",
+		"network":      "19",
+		"channel":      "30",
+		"sync":         "130",
+		"user":         "240",
+		"idle":         "124",
+		"added":        "28",
+		"removed":      "124",
+		"persisting":   "black",
+		"package":      "19",
+		"receiver":     "90",
+		"function":     "28",
+		"file":         "30",
+		"line":         "130",
+		"stdlib":       "246",
+		"match":        "229",
+		"currentmatch": "153",
+		"matchtext":    "black",
+		"label1":       "25",
+		"label2":       "130",
+		"label3":       "90",
+		"label4":       "19",
+		"label5":       "28",
+		"label6":       "166",
+	},
+	"high-contrast": {
+		"text":         "brightwhite",
+		"accent":       "brightyellow",
+		"border":       "brightwhite",
+		"title":        "brightwhite",
+		"selected":     "black",
+		"selectedbg":   "brightyellow",
+		"bartext":      "black",
+		"ok":           "brightgreen",
+		"warn":         "brightyellow",
+		"error":        "brightred",
+		"info":         "brightcyan",
+		"notice":       "brightmagenta",
+		"running":      "brightgreen",
+		"syscall":      "brightmagenta",
+		"network":      "brightblue",
+		"channel":      "brightcyan",
+		"sync":         "brightyellow",
+		"user":         "brightwhite",
+		"idle":         "brightred",
+		"added":        "brightgreen",
+		"removed":      "brightred",
+		"persisting":   "brightwhite",
+		"package":      "brightcyan",
+		"receiver":     "brightmagenta",
+		"function":     "brightgreen",
+		"file":         "brightcyan",
+		"line":         "brightyellow",
+		"stdlib":       "250",
+		"match":        "brightyellow",
+		"currentmatch": "brightcyan",
+		"matchtext":    "black",
+		"label1":       "brightcyan",
+		"label2":       "brightyellow",
+		"label3":       "brightmagenta",
+		"label4":       "brightblue",
+		"label5":       "brightgreen",
+		"label6":       "208",
+	},
+}
+
+// waitClassRoles are the roles of the colors of the wait classes in the status bar chart
+var waitClassRoles = map[model.WaitClass]string{
+	model.WaitClassRunning:     "running",
+	model.WaitClassSyscall:     "syscall",
+	model.WaitClassNetwork:     "network",
+	model.WaitClassChannel:     "channel",
+	model.WaitClassSync:        "sync",
+	model.WaitClassUser:        "user",
+	model.WaitClassIdleRuntime: "idle",
+}
+
+// ansiNames of the first 16 colors of the palette
+var ansiNames = []string{
+	"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+	"gray", "brightred", "brightgreen", "brightyellow", "brightblue", "brightmagenta", "brightcyan", "brightwhite",
+}
+
+// ansiRGB approximates the first 16 colors of the palette which are defined by the terminal
+var ansiRGB = [16]uint32{
+	0x000000, 0x800000, 0x008000, 0x808000, 0x000080, 0x800080, 0x008080, 0xc0c0c0,
+	0x808080, 0xff0000, 0x00ff00, 0xffff00, 0x0000ff, 0xff00ff, 0x00ffff, 0xffffff,
+}
+
+// cubeLevels are the intensities of the 6x6x6 color cube of the palette
+var cubeLevels = [6]uint32{0, 95, 135, 175, 215, 255}
+
+// theme resolves the roles to colors
+type theme struct {
+	colors    map[string]termui.Color // Tagged color by role
+	truecolor bool                    // Draw 24 bit colors instead of the 256 color palette
+	mono      bool                    // Draw the default colors of the terminal and reverse cells with a background
+}
+
+// themeNames returns the names of the built-in themes
+func themeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newTheme creates the built-in theme. The colors replace the colors of single roles, for example "ok": "#00ff00".
+func newTheme(name string, colors map[string]string, truecolor bool) (*theme, error) {
+	if len(name) == 0 {
+		name = DefaultTheme
+	}
+	specs, ok := themes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme %s. Expected one of %s", name, strings.Join(themeNames(), ", "))
+	}
+	t := &theme{colors: make(map[string]termui.Color), truecolor: truecolor}
+	for role, spec := range specs {
+		t.colors[role], _ = parseColor(spec)
+	}
+	for role, spec := range colors {
+		if _, ok := specs[role]; !ok {
+			return nil, fmt.Errorf("unknown color role %s", role)
+		}
+		c, err := parseColor(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid color of %s. %s", role, err.Error())
+		}
+		t.colors[role] = c
+	}
+	return t, nil
+}
+
+// parseColor parses a color name, an index of the 256 color palette, #rrggbb or default
+func parseColor(spec string) (termui.Color, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	if spec == "default" {
+		return termui.ColorClear, nil
+	}
+	if spec == "grey" {
+		spec = "gray"
+	}
+	for i, name := range ansiNames {
+		if name == spec {
+			return paletteColor | termui.Color(i), nil
+		}
+	}
+	if hex, ok := strings.CutPrefix(spec, "#"); ok {
+		rgb, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || len(hex) != 6 {
+			return 0, fmt.Errorf("expected #rrggbb instead of %s", spec)
+		}
+		return rgbColor | termui.Color(rgb), nil
+	}
+	index, err := strconv.Atoi(spec)
+	if err != nil || index < 0 || index > 255 {
+		return 0, fmt.Errorf("expected a color name, 0-255 or #rrggbb instead of %s", spec)
+	}
+	return paletteColor | termui.Color(index), nil
+}
+
+// paletteRGB returns the 24 bit color of the palette index
+func paletteRGB(index int) uint32 {
+	switch {
+	case index < 16:
+		return ansiRGB[index]
+	case index < 232:
+		index -= 16
+		return cubeLevels[index/36]<<16 | cubeLevels[index/6%6]<<8 | cubeLevels[index%6]
+	}
+	gray := uint32(8 + 10*(index-232))
+	return gray<<16 | gray<<8 | gray
+}
+
+// nearestIndex returns the index of the color cube or the gray ramp of the palette which is closest to the 24 bit
+// color. The first 16 colors are skipped because they are defined by the terminal.
+func nearestIndex(rgb uint32) int {
+	best, bestDistance := 16, -1
+	for index := 16; index < 256; index++ {
+		if distance := colorDistance(rgb, paletteRGB(index)); bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = index, distance
+		}
+	}
+	return best
+}
+
+func colorDistance(a, b uint32) int {
+	distance := 0
+	for shift := 0; shift <= 16; shift += 8 {
+		d := int(a>>shift&0xff) - int(b>>shift&0xff)
+		distance += d * d
+	}
+	return distance
+}
+
+// attribute converts the color of a cell to the termbox attribute of the output mode. termui draws some parts
+// such as the axes of the plot and the scroll arrows of the list in white, which are shown in the text color.
+func (t *theme) attribute(c termui.Color, fg bool) termbox.Attribute {
+	if t.mono {
+		return termbox.ColorDefault
+	}
+	if fg && c == termui.ColorWhite {
+		c = t.colors["text"]
+	}
+	var rgb uint32
+	switch {
+	case c < 0:
+		return termbox.ColorDefault
+	case c&rgbColor != 0:
+		rgb = uint32(c &^ rgbColor)
+		if !t.truecolor {
+			return termbox.Attribute(nearestIndex(rgb) + 1)
+		}
+	default:
+		index := int(c &^ paletteColor)
+		if !t.truecolor {
+			return termbox.Attribute(index + 1)
+		}
+		rgb = paletteRGB(index)
+	}
+	return termbox.RGBToAttribute(uint8(rgb>>16), uint8(rgb>>8), uint8(rgb))
+}
+
+// setOutputMode switches termbox to 24 bit colors or the 256 color palette
+func (t *theme) setOutputMode() {
+	mode := termbox.Output256
+	if t.truecolor {
+		mode = termbox.OutputRGB
+	}
+	termbox.SetOutputMode(mode)
+}
+
+// SetTheme selects the built-in theme dark, light or high-contrast. The colors override the colors of single
+// roles. Truecolor draws 24 bit colors, otherwise #rrggbb is shown as the closest color of the 256 color palette.
+func (ui *UI) SetTheme(name string, colors map[string]string, truecolor bool) error {
+	t, err := newTheme(name, colors, truecolor)
+	if err != nil {
+		return err
+	}
+	ui.theme = t
+	t.setOutputMode()
+	ui.applyTheme()
+	ui.updateStatus()
+	ui.updateHistory()
+	return nil
+}
+
+// SetNoColor draws all text in the default colors of the terminal. The selected row, the matches of the find and
+// the bars are reversed instead of colored.
+func (ui *UI) SetNoColor() {
+	ui.theme.mono = true
+}
+
+// applyTheme registers the roles in the style markup and colors the widgets
+func (ui *UI) applyTheme() {
+	colors := ui.theme.colors
+	for role, c := range colors {
+		termui.StyleParserColorMap[role] = c
+	}
+
+	for _, block := range ui.blocks() {
+		block.BorderStyle.Fg = colors["border"]
+		block.TitleStyle.Fg = colors["title"]
+	}
+	ui.filter.TextStyle.Fg = colors["text"]
+	ui.filter.BorderStyle.Fg = colors["accent"]
+	ui.routineHist.AxesColor = colors["text"]
+	ui.list.TextStyle.Fg = colors["accent"]
+	ui.list.SelectedRowStyle.Fg = colors["selected"]
+	ui.list.SelectedRowStyle.Bg = colors["selectedbg"]
+	ui.details.TextStyle.Fg = colors["text"]
+	ui.barchart.NumStyles = []termui.Style{termui.NewStyle(colors["bartext"])}
+	ui.barchart.LabelStyles = []termui.Style{termui.NewStyle(colors["text"])}
+	ui.help.TextStyle.Fg = colors["accent"]
+	ui.legend.TextStyle.Fg = colors["accent"]
+	ui.statusBar.TextStyle.Fg = colors["text"]
+	ui.infoBar.TextStyle.Fg = colors["text"]
+	ui.stats.TextStyle.Fg = colors["text"]
+	ui.hotspots.TextStyle.Fg = colors["text"]
+	ui.chooser.TextStyle.Fg = colors["accent"]
+}
+
+// blocks returns the blocks of all widgets
+func (ui *UI) blocks() []*termui.Block {
+	return []*termui.Block{
+		&ui.list.Block, &ui.filter.Block, &ui.details.Block, &ui.routineHist.Block, &ui.barchart.Block,
+		&ui.barchartLegend.Block, &ui.legend.Block, &ui.statusBar.Block, &ui.infoBar.Block, &ui.summary.Block, &ui.help.Block, &ui.stats.Block,
+		&ui.hotspots.Block, &ui.chooser.Block,
+	}
+}
+
+// render draws the items like termui.Render but converts the colors with the theme. The linear mode replaces
+// symbols with ASCII characters.
+func (ui *UI) render(items ...termui.Drawable) {
+	for _, item := range items {
+		buf := termui.NewBuffer(item.GetRect())
+		item.Lock()
+		item.Draw(buf)
+		item.Unlock()
+		for point, cell := range buf.CellMap {
+			if point.In(buf.Rectangle) {
+				fg := ui.theme.attribute(cell.Style.Fg, true) | termbox.Attribute(cell.Style.Modifier)
+				if ui.theme.mono && cell.Style.Bg != termui.ColorClear {
+					fg |= termbox.AttrReverse
+				}
+				r := cell.Rune
+				if ui.linear {
+					r = asciiRune(r)
+				}
+				termbox.SetCell(point.X, point.Y, r, fg, ui.theme.attribute(cell.Style.Bg, false))
+			}
+		}
+	}
+	termbox.Flush()
+}
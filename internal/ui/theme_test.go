@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"testing"
+
+	termbox "github.com/nsf/termbox-go"
+	"github.com/stretchr/testify/assert"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+func TestParseColor(t *testing.T) {
+	c, err := parseColor("brightred")
+	assert.Nil(t, err)
+	assert.Equal(t, paletteColor|9, c)
+	c, err = parseColor("208")
+	assert.Nil(t, err)
+	assert.Equal(t, paletteColor|208, c)
+	c, err = parseColor("#FF8700")
+	assert.Nil(t, err)
+	assert.Equal(t, rgbColor|0xff8700, c)
+	c, err = parseColor("default")
+	assert.Nil(t, err)
+	assert.Equal(t, termui.ColorClear, c)
+
+	for _, invalid := range []string{"256", "#fff", "#gggggg", "purple"} {
+		_, err = parseColor(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestBuiltinThemes(t *testing.T) {
+	roles := themes[DefaultTheme]
+	for name, specs := range themes {
+		assert.Equal(t, len(roles), len(specs), name)
+		for role, spec := range specs {
+			_, err := parseColor(spec)
+			assert.Nil(t, err, "%s %s", name, role)
+			_, ok := roles[role]
+			assert.True(t, ok, "%s %s", name, role)
+		}
+	}
+}
+
+func TestThemeOverrides(t *testing.T) {
+	th, err := newTheme("light", map[string]string{"ok": "#00af00"}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, rgbColor|0x00af00, th.colors["ok"])
+
+	_, err = newTheme("solarized", nil, false)
+	assert.NotNil(t, err)
+	_, err = newTheme("", map[string]string{"unknown": "red"}, false)
+	assert.NotNil(t, err)
+	_, err = newTheme("", map[string]string{"ok": "nope"}, false)
+	assert.NotNil(t, err)
+}
+
+func TestThemeAttribute(t *testing.T) {
+	th, err := newTheme("light", map[string]string{"ok": "#00af00"}, false)
+	assert.Nil(t, err)
+	// The closest color of the palette is shown without truecolor
+	assert.Equal(t, termbox.Attribute(34+1), th.attribute(th.colors["ok"], true))
+	// White of termui is shown in the text color of the theme
+	assert.Equal(t, termbox.Attribute(0+1), th.attribute(termui.ColorWhite, true))
+	assert.Equal(t, termbox.ColorDefault, th.attribute(termui.ColorClear, false))
+
+	th.truecolor = true
+	assert.Equal(t, termbox.RGBToAttribute(0, 0xaf, 0), th.attribute(th.colors["ok"], true))
+	assert.Equal(t, termbox.RGBToAttribute(0xd7, 0xaf, 0x5f), th.attribute(paletteColor|179, false))
+	assert.Equal(t, termbox.RGBToAttribute(0x80, 0, 0), th.attribute(termui.ColorRed, true))
+}
+
+func TestThemeMono(t *testing.T) {
+	th, err := newTheme("dark", nil, true)
+	assert.Nil(t, err)
+	th.mono = true
+	assert.Equal(t, termbox.ColorDefault, th.attribute(th.colors["ok"], true))
+	assert.Equal(t, termbox.ColorDefault, th.attribute(th.colors["selectedbg"], false))
+}
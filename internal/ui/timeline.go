@@ -0,0 +1,127 @@
+package ui
+
+import "github.com/becheran/roumon/internal/model"
+
+const (
+	// maxTimeline is the number of snapshots kept to browse back in time
+	maxTimeline = 1000
+	// maxTimelineGoroutines is the number of goroutines of all kept snapshots. A goroutine with its stack takes
+	// about a kilobyte, so targets with many goroutines keep fewer snapshots.
+	maxTimelineGoroutines = 200_000
+)
+
+// timeline of the received snapshots of all targets
+type timeline struct {
+	snapshots  []model.Snapshot
+	cursor     int  // Index of the shown snapshot. Negative if the latest snapshots are shown
+	perTarget  int  // Number of snapshots kept of every target. Zero keeps maxTimeline snapshots of all targets
+	keepAll    bool // Keep every snapshot regardless of the limits
+	goroutines int  // Number of goroutines of the kept snapshots
+}
+
+func newTimeline() *timeline {
+	return &timeline{cursor: -1}
+}
+
+// add the snapshot and drop the oldest snapshots beyond the limits
+func (t *timeline) add(snapshot model.Snapshot) {
+	t.snapshots = append(t.snapshots, snapshot)
+	t.goroutines += len(snapshot.Goroutines)
+	if t.keepAll {
+		return
+	}
+	if t.perTarget <= 0 {
+		if len(t.snapshots) > maxTimeline {
+			t.evict("")
+		}
+	} else if t.count(snapshot.Target) > t.perTarget {
+		t.evict(snapshot.Target)
+	}
+	for t.goroutines > maxTimelineGoroutines && t.evict("") {
+	}
+}
+
+// count returns the number of snapshots of the target
+func (t *timeline) count(target string) int {
+	count := 0
+	for _, s := range t.snapshots {
+		if s.Target == target {
+			count++
+		}
+	}
+	return count
+}
+
+// evict removes the oldest snapshot of the target, or of any target if empty. The shown and the latest snapshot
+// are never removed, so a paused view keeps its snapshot. Returns false if no snapshot was removed.
+func (t *timeline) evict(target string) bool {
+	for i, s := range t.snapshots[:len(t.snapshots)-1] {
+		if i != t.cursor && (len(target) == 0 || s.Target == target) {
+			t.remove(i)
+			return true
+		}
+	}
+	return false
+}
+
+// remove the snapshot at the index, which must not be the shown snapshot, and keep the cursor on the shown snapshot
+func (t *timeline) remove(index int) {
+	t.goroutines -= len(t.snapshots[index].Goroutines)
+	t.snapshots = append(t.snapshots[:index], t.snapshots[index+1:]...)
+	if t.cursor > index {
+		t.cursor--
+	}
+}
+
+// browsing returns true if an older snapshot is shown
+func (t *timeline) browsing() bool {
+	return t.cursor >= 0
+}
+
+// freeze keeps showing the latest snapshot while new snapshots are added
+func (t *timeline) freeze() {
+	if len(t.snapshots) > 0 {
+		t.cursor = len(t.snapshots) - 1
+	}
+}
+
+// step moves the cursor by delta snapshots. Moving past the latest snapshot shows the latest snapshots again.
+func (t *timeline) step(delta int) {
+	if len(t.snapshots) == 0 {
+		return
+	}
+	cursor := t.position() + delta
+	switch {
+	case cursor >= len(t.snapshots)-1:
+		t.cursor = -1
+	case cursor < 0:
+		t.cursor = 0
+	default:
+		t.cursor = cursor
+	}
+}
+
+// position returns the index of the shown snapshot
+func (t *timeline) position() int {
+	if t.cursor < 0 {
+		return len(t.snapshots) - 1
+	}
+	return t.cursor
+}
+
+// view returns the snapshot of every target as it was at the cursor, ordered by first appearance
+func (t *timeline) view() []model.Snapshot {
+	var targets []string
+	latest := make(map[string]model.Snapshot)
+	for _, snapshot := range t.snapshots[:t.position()+1] {
+		if _, ok := latest[snapshot.Target]; !ok {
+			targets = append(targets, snapshot.Target)
+		}
+		latest[snapshot.Target] = snapshot
+	}
+	view := make([]model.Snapshot, len(targets))
+	for i, target := range targets {
+		view[i] = latest[target]
+	}
+	return view
+}
@@ -0,0 +1,91 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func snapshotOf(target string, goroutines int) model.Snapshot {
+	return model.Snapshot{Target: target, Goroutines: make([]model.Goroutine, goroutines)}
+}
+
+func TestTimelineLimit(t *testing.T) {
+	tl := newTimeline()
+	for i := 0; i < maxTimeline+10; i++ {
+		tl.add(snapshotOf("a", 1))
+	}
+	assert.Len(t, tl.snapshots, maxTimeline)
+	assert.Equal(t, maxTimeline, tl.goroutines)
+
+	tl = newTimeline()
+	large := maxTimelineGoroutines / 4
+	for i := 0; i < 10; i++ {
+		tl.add(snapshotOf("a", large))
+	}
+	assert.Len(t, tl.snapshots, 4, "large snapshots are limited by their goroutines")
+	assert.Equal(t, 4*large, tl.goroutines)
+
+	tl.add(snapshotOf("a", 2*maxTimelineGoroutines))
+	assert.Len(t, tl.snapshots, 1, "the latest snapshot is kept")
+}
+
+func TestTimelinePerTarget(t *testing.T) {
+	tl := newTimeline()
+	tl.perTarget = 2
+	for _, target := range []string{"a", "b", "a", "a", "b"} {
+		tl.add(snapshotOf(target, 1))
+	}
+	assert.Equal(t, 2, tl.count("a"))
+	assert.Equal(t, 2, tl.count("b"))
+	assert.Len(t, tl.snapshots, 4)
+}
+
+func TestKeepTimeline(t *testing.T) {
+	tl := newTimeline()
+	tl.keepAll = true
+	for i := 0; i < maxTimeline; i++ {
+		tl.add(snapshotOf("a", 1))
+	}
+	tl.add(snapshotOf("a", maxTimelineGoroutines))
+	assert.Len(t, tl.snapshots, maxTimeline+1)
+}
+
+func TestTimelineKeepsShown(t *testing.T) {
+	tl := newTimeline()
+	tl.perTarget = 2
+	tl.add(snapshotOf("a", 1))
+	tl.add(snapshotOf("a", 2))
+	tl.cursor = 0
+	for i := 0; i < 3; i++ {
+		tl.add(snapshotOf("a", 3+i))
+		assert.Equal(t, 0, tl.cursor)
+		assert.Len(t, tl.snapshots[tl.cursor].Goroutines, 1, "the paused snapshot is kept")
+	}
+	assert.Len(t, tl.snapshots, 2)
+	assert.Len(t, tl.snapshots[1].Goroutines, 5)
+
+	tl.cursor = 1
+	tl.add(snapshotOf("a", 6))
+	assert.Equal(t, 0, tl.cursor, "the cursor follows the shown snapshot")
+	assert.Len(t, tl.snapshots[tl.cursor].Goroutines, 5)
+}
+
+func TestTimelineStatus(t *testing.T) {
+	ui := &UI{timeline: newTimeline()}
+	ui.keys, _ = newKeymap("default", nil)
+	ui.timeline.add(snapshotOf("a", 1))
+	ui.timeline.add(snapshotOf("a", 1))
+	ui.timeline.freeze()
+	ui.frozen = true
+	ui.timeline.add(snapshotOf("a", 1))
+	assert.Equal(t, "[Paused, 1 snapshot behind](fg:warn). F2 to resume", ui.timelineStatus())
+	ui.timeline.add(snapshotOf("a", 1))
+	assert.Equal(t, "[Paused, 2 snapshots behind](fg:warn). F2 to resume", ui.timelineStatus())
+
+	ui.keys, _ = newKeymap("vim", nil)
+	assert.Equal(t, "[Paused, 2 snapshots behind](fg:warn). p to resume", ui.timelineStatus())
+	ui.keys, _ = newKeymap("default", map[string]string{"pause": "<C-x> p"})
+	assert.Equal(t, "[Paused, 2 snapshots behind](fg:warn). ctrl-x p to resume", ui.timelineStatus())
+}
@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// creatorRow is a visible node of the creator tree
+type creatorRow struct {
+	node  *model.CreatorNode
+	depth int
+}
+
+// creatorRows appends the nodes and the children of the expanded nodes to the rows
+func (ui *UI) creatorRows(nodes []*model.CreatorNode, depth int, rows []creatorRow) []creatorRow {
+	for _, node := range nodes {
+		rows = append(rows, creatorRow{node: node, depth: depth})
+		if ui.expanded[node.Key] {
+			rows = ui.creatorRows(node.Children, depth+1, rows)
+		}
+	}
+	return rows
+}
+
+// creatorRowText indents the node by its depth and shows the goroutines of its subtree
+func (ui *UI) creatorRowText(row creatorRow) string {
+	marker := " "
+	if len(row.node.Children) > 0 {
+		marker = "▸"
+		if ui.expanded[row.node.Key] {
+			marker = "▾"
+		}
+	}
+	name := "no creator"
+	if row.node.Site != nil {
+		name = plain(row.node.Site.Func())
+	}
+	return fmt.Sprintf("%s%s %s × %s ", strings.Repeat("  ", row.depth), marker, formatCount(row.node.Total), name)
+}
+
+// creatorDetails shows the site, the statuses of the goroutines which were created there and the stack of the
+// first one
+func (ui *UI) creatorDetails(node *model.CreatorNode) string {
+	text := "Goroutines without creator, such as the main goroutine\n\n"
+	if node.Site != nil {
+		text = fmt.Sprintf("Created by:\n  %s\n\n", styledFrame(*node.Site))
+	}
+	text += fmt.Sprintf("Goroutines: [%s](mod:bold) created here, [%s](mod:bold) including descendants\n\n",
+		formatCount(len(node.Goroutines)), formatCount(node.Total))
+	if len(node.Goroutines) == 0 {
+		return text + "All goroutines created here have exited\n"
+	}
+
+	counts := make(map[string]int)
+	for _, g := range node.Goroutines {
+		counts[g.Status]++
+	}
+	statuses := make([]string, 0, len(counts))
+	for status := range counts {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if counts[statuses[i]] != counts[statuses[j]] {
+			return counts[statuses[i]] > counts[statuses[j]]
+		}
+		return statuses[i] < statuses[j]
+	})
+	text += "Statuses:\n"
+	for _, status := range statuses {
+		text += fmt.Sprintf("  %s: [%s](mod:bold)\n", plain(status), formatCount(counts[status]))
+	}
+	g := node.Goroutines[0]
+	return text + fmt.Sprintf("\nTrace of goroutine %d:\n%s", g.ID, ui.traceText(g.StackTrace))
+}
+
+// creatorFrames returns the shown frames in the order of creatorDetails
+func (ui *UI) creatorFrames(node *model.CreatorNode) []model.StackFrame {
+	var frames []model.StackFrame
+	if node.Site != nil {
+		frames = append(frames, *node.Site)
+	}
+	if len(node.Goroutines) > 0 {
+		frames = append(frames, ui.hide.shown(node.Goroutines[0].StackTrace)...)
+	}
+	return frames
+}
+
+// expandCreator shows or hides the children of the selected node of the creator tree. Collapsing a node whose
+// children are hidden selects its parent.
+func (ui *UI) expandCreator(expand bool) {
+	if ui.view != viewCreators || ui.list.SelectedRow >= len(ui.creators) {
+		return
+	}
+	row := ui.creators[ui.list.SelectedRow]
+	switch {
+	case expand:
+		if len(row.node.Children) > 0 {
+			ui.expanded[row.node.Key] = true
+		}
+	case ui.expanded[row.node.Key]:
+		delete(ui.expanded, row.node.Key)
+	default:
+		for i := ui.list.SelectedRow - 1; i >= 0; i-- {
+			if ui.creators[i].depth < row.depth {
+				ui.list.SelectedRow = i
+				break
+			}
+		}
+	}
+	ui.updateList()
+}
+
+// firstGoroutine returns the first goroutine of the subtree. Every leaf of the tree has goroutines.
+func firstGoroutine(node *model.CreatorNode) model.Goroutine {
+	for len(node.Goroutines) == 0 {
+		node = node.Children[0]
+	}
+	return node.Goroutines[0]
+}
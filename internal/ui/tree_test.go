@@ -0,0 +1,32 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreatorRows(t *testing.T) {
+	spawn := &model.StackFrame{FuncName: "main.main", File: "/src/main.go", Line: 10}
+	serve := &model.StackFrame{FuncName: "main.(*server).serve", File: "/src/server.go", Line: 20}
+	routines := []model.Goroutine{
+		{ID: 1, Status: "running"},
+		{ID: 2, Status: "IO wait", CratedBy: spawn, CreatorID: 1},
+		{ID: 3, Status: "IO wait", CratedBy: serve, CreatorID: 2},
+		{ID: 4, Status: "IO wait", CratedBy: serve, CreatorID: 2},
+	}
+	ui := &UI{expanded: make(map[string]bool)}
+	roots := model.CreatorTree(routines)
+
+	rows := ui.creatorRows(roots, 0, nil)
+	assert.Len(t, rows, 2)
+	assert.Equal(t, "▸ 3 × main.main ", ui.creatorRowText(rows[0]))
+	assert.Equal(t, "  1 × no creator ", ui.creatorRowText(rows[1]))
+
+	ui.expanded[roots[0].Key] = true
+	rows = ui.creatorRows(roots, 0, nil)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, "▾ 3 × main.main ", ui.creatorRowText(rows[0]))
+	assert.Equal(t, "    2 × main.(*server).serve ", ui.creatorRowText(rows[1]))
+}
@@ -3,95 +3,173 @@ package ui
 import (
 	"fmt"
 	"log"
+	"math"
+	"path/filepath"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/becheran/roumon/internal/config"
 	"github.com/becheran/roumon/internal/model"
 	"github.com/gizak/termui/v3/widgets"
 
 	termui "github.com/gizak/termui/v3"
 )
 
+const padding = 1
+
+// intervals which can be selected from the UI
+var intervals = []time.Duration{
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	5 * time.Minute,
+}
+
+// view of the goroutines in the list
+type view int
+
 const (
-	padding         = 1
-	keepRoutineHist = 100
+	viewRoutines view = iota // One row for every goroutine
+	viewStacks               // One row for every group of goroutines with identical stacks
+	viewCreators             // Tree of the sites which created the goroutines
+	viewDiff                 // One row for every group of goroutines which changed since the previous snapshot
 )
 
+// Controller of the data source which is shown in the UI
+type Controller interface {
+	Interval() time.Duration
+	SetInterval(interval time.Duration)
+	Retry()
+	Refresh()
+}
+
 // UI contains all user interface elements
 type UI struct {
-	list           *widgets.List
+	list           *wideList
 	filter         *widgets.Paragraph
-	details        *widgets.Paragraph
-	routineHist    *widgets.Plot
+	details        *wideParagraph
+	routineHist    *historyPlot
 	barchart       *widgets.BarChart
 	barchartLegend *widgets.Paragraph
-	paused         *widgets.Paragraph
 	legend         *widgets.Paragraph
+	statusBar      *widgets.Paragraph
+	infoBar        *widgets.Paragraph
+	summary        *widgets.Paragraph
 	help           *widgets.Paragraph
+	stats          *widgets.Paragraph
+	hotspots       *widgets.Paragraph
+	chooser        *widgets.Paragraph
 
 	grid          *termui.Grid
+	showStats     bool
+	showHotspots  bool
+	controller    Controller
 	filtered      bool
+	statusFilter  statusSelection // Status counters whose goroutines are listed
+	counterSpans  [][2]int        // Columns of the status counters in the summary
+	query         filterQuery     // Compiled filter text
+	filterMode    filterMode      // How the expressions of the filter match
 	origData      []model.Goroutine
+	targets       []string // Targets in order of their first snapshot
+	tab           string   // Target of the shown tab. Empty for the tab of all targets
+	tabs          map[string]tabState
+	pins          map[string]*pin  // Pinned goroutines by fingerprint
+	churn         map[string]churn // Changes between the last two snapshots by target
+	snapshots     map[string]model.Snapshot
+	timeline      *timeline
+	fetchStatus   map[string]model.FetchStatus
+	health        map[string]model.FetchStatus // Last health probe by target
 	filteredData  []model.Goroutine
-	minGoRoutines int
-	maxGoRoutines int
-	avgGoRoutines float64
+	view          view
+	groups        []model.StackGroup // Rows of the list in the stacks and the diff view
+	diffMarks     []churnMark        // Change of the groups in the diff view
+	creators      []creatorRow       // Rows of the list in the creators view
+	expanded      map[string]bool    // Keys of the expanded nodes of the creator tree
+	scrollX       int                // Characters of the rows which are scrolled out of view on the left
+	frozen        bool               // Paused on the snapshot which was shown when the user paused
+	replay        *replay            // Plays a recorded session back. Nil if the snapshots are live
+	captured      *capture           // Snapshot A of a comparison. Nil if nothing is captured
+	comparison    *comparison        // Shown instead of the snapshots. Nil if nothing is compared
+	sortKey       model.SortKey
+	sortDesc      bool
+	initialSort   tabState      // Sort of a tab which is shown for the first time
+	waitThreshold time.Duration // Goroutines which wait longer are highlighted. 0 disables the highlighting
+	columns       []column
+	keys          *keymap
+	theme         *theme
+	editor        editor
+	panes         config.Layout
+	savePanes     func(layout config.Layout) error
+	saveColumns   func(spec string) error
+	bookmarks     []config.Bookmark // Filters and sorts recalled with the keys 1 to 9
+	filters       []config.Filter   // Named filters of the config file
+	saveBookmarks func(bookmarks []config.Bookmark) error
+	saveState     func(state config.State) error
+	logs          *LogBuffer  // Lines of the log panel. Nil if the log is not kept
+	dragging      bool        // The border between the list and the details is dragged with the mouse
+	pendingKeys   string      // Keys of an incomplete key sequence
+	searching     bool        // Keys edit the filter until Enter or Escape is pressed
+	finding       bool        // Keys edit the find pattern of the details until Enter or Escape is pressed
+	focusDetails  bool        // Moving keys select the frames of the details instead of the rows of the list
+	compact       bool        // Rows show the goroutines in one dense line instead of the columns. The charts are hidden
+	size          layoutSize  // Breakpoint of the terminal width
+	short         bool        // The terminal is too low for the charts
+	linear        bool        // One pane at a time without borders, charts and symbols for screen readers
+	colorBy       string      // Key of the label whose value colors the rows. Empty if the rows are not colored
+	hide          frameFilter // Frames of the runtime, the standard library and configured packages which are hidden
+	notice        string      // Result of the last action in the status bar. Cleared by the next key
+	detail        details
+	history       history
 }
 
-// NewUI creates a new console user interface
-func NewUI() *UI {
+// NewUI creates a new console user interface. The controller is optional.
+func NewUI(controller Controller) *UI {
 	if err := termui.Init(); err != nil {
 		log.Fatalf("Failed to initialize termui: %v", err)
 	}
 
 	filter := widgets.NewParagraph()
-	filter.Text = "TYPE TO FILTER"
-	filter.TextStyle.Fg = termui.ColorWhite
-	filter.BorderStyle.Fg = termui.ColorGreen
 	filter.Title = "Filter"
 	filter.PaddingTop = padding
 	filter.PaddingRight = padding
 	filter.PaddingLeft = padding
 	filter.PaddingBottom = padding
 
-	plot := widgets.NewPlot()
-	plot.Data = make([][]float64, 1)
-	plot.Data[0] = make([]float64, 2, keepRoutineHist)
-	plot.AxesColor = termui.ColorWhite
-	plot.LineColors[0] = termui.ColorGreen
+	plot := newHistoryPlot()
 	plot.HorizontalScale = 2
 	plot.PaddingTop = padding
 	plot.PaddingRight = padding
 	plot.PaddingLeft = padding
 	plot.PaddingBottom = padding
 
-	routineList := widgets.NewList()
+	routineList := newWideList()
 	routineList.PaddingTop = padding
 	routineList.PaddingRight = padding
 	routineList.PaddingLeft = padding
 	routineList.PaddingBottom = padding
 	routineList.Rows = []string{}
-	routineList.TextStyle.Fg = termui.ColorGreen
-	routineList.SelectedRowStyle.Fg = termui.ColorWhite
-	routineList.SelectedRowStyle.Bg = termui.ColorGreen
 
-	details := widgets.NewParagraph()
+	details := newWideParagraph()
 	details.PaddingTop = padding
 	details.PaddingRight = padding
 	details.PaddingLeft = padding
 	details.PaddingBottom = padding
 	details.Title = "Details"
-	details.TextStyle = termui.NewStyle(termui.ColorWhite)
 	details.SetRect(0, 0, 60, 10)
 
 	barchart := widgets.NewBarChart()
 	barchart.Title = "Status"
 	barchart.BarWidth = 3
 	barchart.BarGap = 1
-	barchart.BarColors = []termui.Color{termui.ColorGreen}
-	barchart.NumStyles = []termui.Style{termui.NewStyle(termui.ColorBlack)}
-	barchart.LabelStyles = []termui.Style{termui.NewStyle(termui.ColorWhite)}
 	barchart.PaddingTop = padding
 	barchart.PaddingRight = padding
 	barchart.PaddingLeft = padding
@@ -107,26 +185,42 @@ func NewUI() *UI {
 	barchartLabel.Text = ""
 
 	help := widgets.NewParagraph()
-	help.TextStyle.Fg = termui.ColorGreen
-	help.Text = "Help\n\nArrows up/down: Select from list\nText input: Filter results\nF10: Quit\nF2: Pause\n\nPress any key to continue"
-	help.PaddingBottom = 2
+	help.PaddingBottom = 1
 	help.PaddingLeft = 2
 	help.PaddingRight = 2
-	help.PaddingTop = 2
-
-	paused := widgets.NewParagraph()
-	paused.TextStyle.Fg = termui.ColorGreen
-	paused.Text = "Paused. Press any key to continue"
-	paused.PaddingBottom = 2
-	paused.PaddingLeft = 2
-	paused.PaddingRight = 2
-	paused.PaddingTop = 2
+	help.PaddingTop = 1
 
 	legend := widgets.NewParagraph()
-	legend.Text = "F1 Help | F2 Pause | F10 Quit"
-	legend.TextStyle.Fg = termui.ColorGreen
 	legend.Border = false
 
+	statusBar := widgets.NewParagraph()
+	statusBar.Border = false
+
+	infoBar := widgets.NewParagraph()
+	infoBar.Border = false
+
+	summary := widgets.NewParagraph()
+	summary.Border = false
+
+	stats := widgets.NewParagraph()
+	stats.Title = "Stats"
+	stats.PaddingTop = padding
+	stats.PaddingRight = padding
+	stats.PaddingLeft = padding
+	stats.PaddingBottom = padding
+
+	hotspots := widgets.NewParagraph()
+	hotspots.PaddingRight = padding
+	hotspots.PaddingLeft = padding
+	hotspots.WrapText = false
+
+	chooser := widgets.NewParagraph()
+	chooser.Title = "Columns"
+	chooser.PaddingBottom = 1
+	chooser.PaddingLeft = 2
+	chooser.PaddingRight = 2
+	chooser.PaddingTop = 1
+
 	grid := termui.NewGrid()
 
 	ui := UI{
@@ -137,34 +231,599 @@ func NewUI() *UI {
 		barchart:       barchart,
 		barchartLegend: barchartLabel,
 		help:           help,
-		paused:         paused,
 		legend:         legend,
+		statusBar:      statusBar,
+		infoBar:        infoBar,
+		summary:        summary,
+		stats:          stats,
+		hotspots:       hotspots,
+		chooser:        chooser,
 		grid:           grid,
+		controller:     controller,
+		snapshots:      make(map[string]model.Snapshot),
+		timeline:       newTimeline(),
+		fetchStatus:    make(map[string]model.FetchStatus),
+		health:         make(map[string]model.FetchStatus),
+		expanded:       make(map[string]bool),
+		tabs:           make(map[string]tabState),
+		pins:           make(map[string]*pin),
+		churn:          make(map[string]churn),
 	}
 
-	grid.Set(
-		termui.NewRow(3.0/10,
-			termui.NewCol(3.0/10,
-				termui.NewCol(5.0/8, ui.barchart),
-				termui.NewCol(3.0/8, ui.barchartLegend)),
-			termui.NewCol(7.0/10, ui.routineHist),
-		),
-		termui.NewRow(7.0/10,
-			termui.NewCol(1.0/6,
-				termui.NewRow(1.5/10, ui.filter),
-				termui.NewRow(8.5/10, ui.list)),
-			termui.NewCol(5.0/6, ui.details),
-		),
+	ui.theme, _ = newTheme(DefaultTheme, nil, false)
+	ui.applyTheme()
+	ui.columns, _ = parseColumns(DefaultColumns)
+	ui.waitThreshold = DefaultWaitThreshold
+	_ = ui.SetSort(DefaultSort)
+	ui.keys, _ = newKeymap("default", nil)
+	ui.updateKeys()
+	ui.layout()
+	ui.updateHistory()
+	ui.updateLegend()
+	ui.updateStatusBar()
+
+	return &ui
+}
+
+// SetKeymap selects the keybindings of the preset default, vim or emacs. The keys map an action to a key sequence
+// and override the keys of the preset.
+func (ui *UI) SetKeymap(preset string, keys map[string]string) error {
+	k, err := newKeymap(preset, keys)
+	if err != nil {
+		return err
+	}
+	ui.keys = k
+	ui.updateKeys()
+	return nil
+}
+
+// updateKeys shows the keys of the keymap in the legend and the filter
+func (ui *UI) updateKeys() {
+	if !ui.filtered {
+		ui.filter.Text = ui.filterPlaceholder()
+	}
+	ui.updateLegend()
+}
+
+// SetColumns configures the columns of the goroutine list. See DefaultColumns for the format, which is used if the
+// spec is empty. Save is called with the visible columns once the user toggled one and may be nil.
+func (ui *UI) SetColumns(spec string, save func(spec string) error) error {
+	if len(spec) == 0 {
+		spec = DefaultColumns
+	}
+	columns, err := parseColumns(spec)
+	if err != nil {
+		return err
+	}
+	ui.columns = columns
+	ui.saveColumns = save
+	ui.updateList()
+	return nil
+}
+
+// SetHistory keeps the number of snapshots of every target to browse back in time instead of the latest
+// snapshots of all targets. Targets with many goroutines keep fewer snapshots.
+func (ui *UI) SetHistory(perTarget int) {
+	ui.timeline.perTarget = perTarget
+}
+
+// layout places the widgets in the grid. The stats panel is only shown once a target serves expvars. The list is
+// placed left of or above the details. Narrow terminals show the list or the details and wide terminals place the
+// charts right of them.
+func (ui *UI) layout() {
+	charts := !ui.compact && !ui.short
+	// Set appends to the items of the grid
+	ui.grid.Items = nil
+	switch {
+	case ui.size == layoutNarrow && ui.focusDetails:
+		ui.grid.Set(termui.NewRow(1, ui.details))
+	case ui.size == layoutNarrow:
+		ui.grid.Set(termui.NewRow(1.5/10, ui.filter), termui.NewRow(8.5/10, ui.list))
+	case ui.size == layoutWide && charts:
+		ui.grid.Set(termui.NewRow(1,
+			termui.NewCol(7.0/10, ui.panesRow(1)),
+			termui.NewCol(3.0/10, ui.chartRows()...)))
+	case charts:
+		ui.grid.Set(termui.NewRow(3.0/10, ui.chartColumns()...), ui.panesRow(7.0/10))
+	default:
+		ui.grid.Set(ui.panesRow(1))
+	}
+}
+
+// panesRow places the list left of or above the details in a row of the height
+func (ui *UI) panesRow(height float64) termui.GridItem {
+	share := ui.listShare()
+	if ui.panes.Vertical {
+		return termui.NewRow(height,
+			termui.NewCol(1,
+				termui.NewRow(share,
+					termui.NewCol(1.0/5, ui.filter),
+					termui.NewCol(4.0/5, ui.list)),
+				termui.NewRow(1-share, ui.details)),
+		)
+	}
+	return termui.NewRow(height,
+		termui.NewCol(share,
+			termui.NewRow(1.5/10, ui.filter),
+			termui.NewRow(8.5/10, ui.list)),
+		termui.NewCol(1-share, ui.details),
 	)
+}
 
-	ui.updatePlotTitle()
+// chartColumns places the status chart, the history and the optional panels next to each other above the panes
+func (ui *UI) chartColumns() []interface{} {
+	status := termui.NewCol(3.0/10,
+		termui.NewCol(5.0/8, ui.barchart),
+		termui.NewCol(3.0/8, ui.barchartLegend))
+	history := 7.0 / 10
+	if ui.showHotspots {
+		history -= 3.0 / 10
+	}
+	if ui.showStats {
+		history -= 2.0 / 10
+	}
+	columns := []interface{}{status, termui.NewCol(history, ui.routineHist)}
+	if ui.showHotspots {
+		columns = append(columns, termui.NewCol(3.0/10, ui.hotspots))
+	}
+	if ui.showStats {
+		columns = append(columns, termui.NewCol(2.0/10, ui.stats))
+	}
+	return columns
+}
 
-	return &ui
+// chartRows places the history, the status chart and the optional panels below each other right of the panes
+func (ui *UI) chartRows() []interface{} {
+	total := 7.0
+	if ui.showHotspots {
+		total += 3
+	}
+	if ui.showStats {
+		total += 2
+	}
+	rows := []interface{}{
+		termui.NewRow(4/total, ui.routineHist),
+		termui.NewRow(3/total,
+			termui.NewCol(5.0/8, ui.barchart),
+			termui.NewCol(3.0/8, ui.barchartLegend)),
+	}
+	if ui.showHotspots {
+		rows = append(rows, termui.NewRow(3/total, ui.hotspots))
+	}
+	if ui.showStats {
+		rows = append(rows, termui.NewRow(2/total, ui.stats))
+	}
+	return rows
+}
+
+// updateStats shows the expvars of the target of the selected goroutine
+func (ui *UI) updateStats() {
+	target := ui.selectedTarget()
+	var snapshot model.Snapshot
+	for _, s := range ui.shownSnapshots() {
+		if s.Vars != nil && (snapshot.Vars == nil || s.Target == target) {
+			snapshot = s
+		}
+	}
+	if snapshot.Vars == nil {
+		ui.stats.Text = ""
+		return
+	}
+	if !ui.showStats {
+		ui.showStats = true
+		ui.layout()
+	}
+	ui.stats.Title = "Stats"
+	if len(ui.targets) > 1 {
+		ui.stats.Title = fmt.Sprintf("Stats - %s", snapshot.Target)
+	}
+
+	vars := snapshot.Vars
+	text := ""
+	if heap, ok := vars["memstats.HeapInuse"]; ok {
+		text += fmt.Sprintf("Heap in use: [%s](mod:bold)\n", formatBytes(int64(heap)))
+	}
+	if sys, ok := vars["memstats.Sys"]; ok {
+		text += fmt.Sprintf("Sys: [%s](mod:bold)\n", formatBytes(int64(sys)))
+	}
+	if gc, ok := vars["memstats.NumGC"]; ok {
+		text += fmt.Sprintf("GC count: [%d](mod:bold)\n", int64(gc))
+	}
+	if pause, ok := vars["memstats.PauseTotalNs"]; ok {
+		text += fmt.Sprintf("GC pause: [%s](mod:bold)\n", time.Duration(pause).Round(time.Microsecond))
+	}
+
+	// Custom vars of the program
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		if !strings.HasPrefix(name, "memstats.") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if len(names) > 0 && len(text) > 0 {
+		text += "\n"
+	}
+	for _, name := range names {
+		text += fmt.Sprintf("%s: [%s](mod:bold)\n", plain(name), strconv.FormatFloat(vars[name], 'f', -1, 64))
+	}
+	ui.stats.Text = text
+}
+
+func (ui *UI) updateLegend() {
+	k := ui.keys
+	middle := fmt.Sprintf("%s/%s Timeline", k.key(actionPrevious), k.key(actionNext))
+	if ui.controller != nil {
+		middle = fmt.Sprintf("%s/%s Interval", k.key(actionSlower), k.key(actionFaster))
+	}
+	if ui.replay != nil {
+		ui.legend.Text = fmt.Sprintf("%s Help | %s Play | %s/%s Step | %s/%s Speed | %s Quit", k.key(actionHelp),
+			k.key(actionPause), k.key(actionPrevious), k.key(actionNext), k.key(actionSlower), k.key(actionFaster),
+			k.key(actionQuit))
+		return
+	}
+	ui.legend.Text = fmt.Sprintf("%s Help | %s Pause | %s | %s Quit", k.key(actionHelp), k.key(actionPause), middle, k.key(actionQuit))
+}
+
+func (ui *UI) updateStatusBar() {
+	var parts []string
+	for _, part := range []string{ui.notice, ui.compareStatus(), ui.timelineStatus(), ui.fetchStatusText(), ui.healthText(), ui.pinStatus(), ui.profileSummary()} {
+		if len(part) > 0 {
+			parts = append(parts, part)
+		}
+	}
+	ui.statusBar.Text = strings.Join(parts, " | ")
+	ui.updateInfoBar()
+}
+
+// updateInfoBar shows the targets, the time and latency of the last fetch, the goroutine counts, the sort and
+// the filter of the list and the number of goroutines which could not be parsed
+func (ui *UI) updateInfoBar() {
+	var parts []string
+	switch len(ui.targets) {
+	case 0:
+		parts = append(parts, "No target")
+	case 1:
+		parts = append(parts, fmt.Sprintf("Target %s", plain(ui.targets[0])))
+	default:
+		parts = append(parts, ui.tabBar())
+	}
+
+	var last time.Time
+	skipped := 0
+	for _, snapshot := range ui.snapshots {
+		if snapshot.Time.After(last) {
+			last = snapshot.Time
+		}
+		skipped += snapshot.Skipped
+	}
+	var latency time.Duration
+	for _, status := range ui.fetchStatus {
+		latency = max(latency, status.Latency)
+	}
+	switch {
+	case last.IsZero():
+		parts = append(parts, "Waiting for first snapshot")
+	case latency > 0:
+		parts = append(parts, fmt.Sprintf("Last fetch %s in %s", last.Format(time.TimeOnly), latency.Round(time.Millisecond)))
+	default:
+		parts = append(parts, fmt.Sprintf("Last snapshot %s", last.Format(time.TimeOnly)))
+	}
+
+	count := fmt.Sprintf("%s goroutines", formatCount(len(ui.origData)))
+	if len(ui.filteredData) != len(ui.origData) {
+		count = fmt.Sprintf("%s of %s goroutines", formatCount(len(ui.filteredData)), formatCount(len(ui.origData)))
+	}
+	parts = append(parts, count)
+	if stuck := ui.stuckStatus(); len(stuck) > 0 {
+		parts = append(parts, stuck)
+	}
+	if ui.sortKey != model.SortNone {
+		direction := "↑"
+		if ui.sortDesc {
+			direction = "↓"
+		}
+		parts = append(parts, fmt.Sprintf("Sort %s %s", ui.sortKey, direction))
+	}
+	if ui.statusFilter.active() {
+		parts = append(parts, fmt.Sprintf("Status %s", ui.statusFilter))
+	}
+	if len(ui.colorBy) > 0 {
+		parts = append(parts, fmt.Sprintf("Color %s", plain(ui.colorBy)))
+	}
+	if ui.filtered && len(ui.filter.Text) > 0 {
+		parts = append(parts, fmt.Sprintf("Filter %q", plain(ui.filter.Text)))
+	}
+	if skipped > 0 {
+		parts = append(parts, fmt.Sprintf("[%d parse errors](fg:warn)", skipped))
+	}
+	ui.infoBar.Text = strings.Join(parts, " | ")
+}
+
+// profileSummary shows the totals of the heap, block and mutex profiles of all shown targets
+func (ui *UI) profileSummary() string {
+	var heap, block, mutex int64
+	var hasHeap, hasBlock, hasMutex bool
+	for _, snapshot := range ui.shownSnapshots() {
+		for _, p := range snapshot.Profiles {
+			switch p.Name {
+			case "heap":
+				heap += p.Total("inuse_space")
+				hasHeap = true
+			case "block":
+				block += p.Total("delay")
+				hasBlock = true
+			case "mutex":
+				mutex += p.Total("delay")
+				hasMutex = true
+			}
+		}
+	}
+	var parts []string
+	if hasHeap {
+		parts = append(parts, fmt.Sprintf("Heap %s", formatBytes(heap)))
+	}
+	if hasBlock {
+		parts = append(parts, fmt.Sprintf("Block %s", time.Duration(block).Round(time.Millisecond)))
+	}
+	if hasMutex {
+		parts = append(parts, fmt.Sprintf("Mutex %s", time.Duration(mutex).Round(time.Millisecond)))
+	}
+	return strings.Join(parts, " | ")
+}
+
+func formatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%dB", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+// timelineStatus shows the position in the timeline while browsing and if nothing is polled
+func (ui *UI) timelineStatus() string {
+	if ui.replay != nil {
+		return ui.replayStatus()
+	}
+	if !ui.frozen && (len(ui.timeline.snapshots) < 2 || (ui.controller != nil && !ui.timeline.browsing())) {
+		return ""
+	}
+	position := ui.timeline.position()
+	snapshot := ui.timeline.snapshots[position]
+	text := fmt.Sprintf("Snapshot %d/%d", position+1, len(ui.timeline.snapshots))
+	if len(snapshot.Source) > 0 {
+		text += " " + plain(filepath.Base(snapshot.Source))
+	}
+	text += " " + snapshot.Time.Format(time.DateTime)
+	if ui.frozen {
+		behind := len(ui.timeline.snapshots) - 1 - position
+		if behind == 1 {
+			return "[Paused, 1 snapshot behind](fg:warn)" + ui.keyHint(actionPause, "resume")
+		}
+		return fmt.Sprintf("[Paused, %d snapshots behind](fg:warn)%s", behind, ui.keyHint(actionPause, "resume"))
+	}
+	if ui.timeline.browsing() && ui.controller != nil {
+		return fmt.Sprintf("[%s](fg:info)%s", text, ui.keyHint(actionNext, "return"))
+	}
+	return fmt.Sprintf("[%s](fg:info)", text)
+}
+
+// keyHint returns a hint to hit the key of the action, for example ". F2 to resume", or nothing if no key is bound
+// to the action. The hint is placed outside of styled text, in which keys such as ] would end the style.
+func (ui *UI) keyHint(a action, what string) string {
+	key := ui.keys.key(a)
+	if len(key) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(". %s to %s", key, what)
+}
+
+func (ui *UI) fetchStatusText() string {
+	if ui.controller == nil {
+		return ui.pushStatusText()
+	}
+	configured := ui.controller.Interval()
+	text := fmt.Sprintf("Interval %s", configured)
+	if len(ui.fetchStatus) == 0 {
+		return text
+	}
+
+	var backoff time.Duration
+	var connected, retrying, gaveUp []model.FetchStatus
+	disconnected := 0
+	for _, status := range ui.fetchStatus {
+		switch status.State {
+		case model.Connected:
+			backoff = max(backoff, status.Interval)
+			connected = append(connected, status)
+		case model.Retrying:
+			retrying = append(retrying, status)
+		case model.GaveUp:
+			gaveUp = append(gaveUp, status)
+		case model.Disconnected:
+			disconnected++
+		}
+	}
+	if backoff > configured {
+		text += fmt.Sprintf(" [(backoff %s)](fg:warn)", backoff)
+	}
+	text += " | "
+
+	if len(ui.fetchStatus) == 1 {
+		switch {
+		case len(retrying) == 1:
+			status := retrying[0]
+			role := "warn"
+			if status.Timeout {
+				role = "notice"
+			}
+			text += fmt.Sprintf("[Retrying in %s (failure %d): %s](fg:%s)",
+				status.Interval.Round(100*time.Millisecond), status.Failures, plain(status.Err.Error()), role)
+		case len(gaveUp) == 1:
+			status := gaveUp[0]
+			text += fmt.Sprintf("[Gave up after %d failures: %s](fg:error)%s", status.Failures, plain(status.Err.Error()),
+				ui.keyHint(actionRetry, "retry"))
+		default:
+			text += "[Connected](fg:ok)" + ui.countdown(connected)
+		}
+		return text
+	}
+
+	text += fmt.Sprintf("[%d/%d connected](fg:ok)", len(connected), len(ui.fetchStatus)) + ui.countdown(connected)
+	if len(retrying) > 0 {
+		text += fmt.Sprintf(" [%d retrying](fg:warn)", len(retrying))
+	}
+	timedOut := 0
+	for _, status := range append(retrying, gaveUp...) {
+		if status.Timeout {
+			timedOut++
+		}
+	}
+	if timedOut > 0 {
+		text += fmt.Sprintf(" [(%d timed out)](fg:notice)", timedOut)
+	}
+	if len(gaveUp) > 0 {
+		text += fmt.Sprintf(" [%d gave up](fg:error)%s", len(gaveUp), ui.keyHint(actionRetry, "retry"))
+	}
+	if disconnected > 0 {
+		text += fmt.Sprintf(" [%d disconnected](fg:warn)", disconnected)
+	}
+	return text
+}
+
+// countdown shows the time until the next of the connected targets is fetched. The health text shows the time
+// instead if the targets are probed.
+func (ui *UI) countdown(connected []model.FetchStatus) string {
+	if len(ui.health) > 0 || len(connected) == 0 {
+		return ""
+	}
+	next := time.Duration(math.MaxInt64)
+	for _, status := range connected {
+		next = min(next, time.Until(status.Time.Add(status.Latency+status.Interval)))
+	}
+	if next = next.Round(time.Second); next < time.Second {
+		return ", fetching"
+	}
+	return fmt.Sprintf(", next in %s", next)
+}
+
+// healthText shows if the targets answer their health probes and when the next profile is fetched
+func (ui *UI) healthText() string {
+	if len(ui.health) == 0 {
+		return ""
+	}
+	if len(ui.health) == 1 {
+		for target, probe := range ui.health {
+			if probe.Err != nil {
+				return fmt.Sprintf("[Down since %s: %s](fg:error)", probe.Since.Format(time.TimeOnly), plain(probe.Err.Error()))
+			}
+			text := "[Up](fg:ok)"
+			if fetch, ok := ui.fetchStatus[target]; ok {
+				next := time.Until(fetch.Time.Add(fetch.Latency + fetch.Interval)).Round(time.Second)
+				if next > 0 {
+					text += fmt.Sprintf(", next profile in %s", next)
+				}
+			}
+			return text
+		}
+	}
+	up := 0
+	for _, probe := range ui.health {
+		if probe.Err == nil {
+			up++
+		}
+	}
+	text := fmt.Sprintf("[%d/%d up](fg:ok)", up, len(ui.health))
+	if down := len(ui.health) - up; down > 0 {
+		text += fmt.Sprintf(" [%d down](fg:error)", down)
+	}
+	return text
 }
 
-func (ui *UI) updatePlotTitle() {
-	ui.routineHist.Title = fmt.Sprintf("History # goroutines (Min: %d Avg: %0.2f Max: %d)",
-		ui.minGoRoutines, ui.avgGoRoutines, ui.maxGoRoutines)
+// pushStatusText shows how many of the programs which pushed their snapshots are still connected
+func (ui *UI) pushStatusText() string {
+	if len(ui.fetchStatus) == 0 {
+		return ""
+	}
+	connected := 0
+	for _, status := range ui.fetchStatus {
+		if status.State == model.Connected {
+			connected++
+		}
+	}
+	text := fmt.Sprintf("[%d/%d connected](fg:ok)", connected, len(ui.fetchStatus))
+	if disconnected := len(ui.fetchStatus) - connected; disconnected > 0 {
+		text += fmt.Sprintf(" [%d disconnected](fg:warn)", disconnected)
+	}
+	return text
+}
+
+// plain replaces the brackets of text which would otherwise be parsed as style
+func plain(text string) string {
+	return strings.NewReplacer("[", "(", "]", ")").Replace(text)
+}
+
+// changeInterval selects the next shorter or longer interval
+func (ui *UI) changeInterval(longer bool) {
+	if ui.controller == nil {
+		return
+	}
+	current := ui.controller.Interval()
+	next := current
+	if longer {
+		next = intervals[len(intervals)-1]
+		for _, interval := range intervals {
+			if interval > current {
+				next = interval
+				break
+			}
+		}
+	} else {
+		next = intervals[0]
+		for i := len(intervals) - 1; i >= 0; i-- {
+			if intervals[i] < current {
+				next = intervals[i]
+				break
+			}
+		}
+	}
+	ui.controller.SetInterval(next)
+	ui.updateStatusBar()
+}
+
+// updateHistory plots the goroutine counts of the session in the text color and the counts of every wait class in
+// the color of the class
+func (ui *UI) updateHistory() {
+	// History data size cannot be limited in termui. The samples are merged to fit the width
+	total, classes, counts := ui.history.series((ui.routineHist.Dx() - 10) >> 1)
+	ui.routineHist.Data = append([][]float64{total}, counts...)
+	colors := []termui.Color{ui.theme.colors["text"]}
+	for _, class := range classes {
+		colors = append(colors, ui.theme.colors[waitClassRoles[class]])
+	}
+	ui.routineHist.LineColors = colors
+	ui.routineHist.Title = fmt.Sprintf("History # goroutines by wait class (Min: %d Avg: %0.2f Max: %d)",
+		ui.history.min, ui.history.avg(), ui.history.max)
+	if ui.history.count > 0 {
+		span := time.Since(ui.history.start)
+		if ui.replay != nil {
+			span = ui.timeline.snapshots[len(ui.timeline.snapshots)-1].Time.Sub(ui.history.start)
+		}
+		ui.routineHist.Title += fmt.Sprintf(" over %s", span.Round(time.Second))
+	}
+	ui.routineHist.marker = -1
+	if count := len(ui.timeline.snapshots); ui.replay != nil && count > 0 {
+		ui.routineHist.marker = 0
+		if count > 1 {
+			ui.routineHist.marker = float64(ui.timeline.position()) / float64(count-1)
+		}
+		ui.routineHist.markerColor = ui.theme.colors["info"]
+	}
 }
 
 func (ui *UI) updateStatus() {
@@ -180,83 +839,389 @@ func (ui *UI) updateStatus() {
 	}
 	sort.Strings(types)
 	data := make([]float64, len(types))
+	colors := make([]termui.Color, len(types))
 	labels := make([]string, len(types))
 	label := ""
 	uniqueID := 1
 	for idx, t := range types {
 		data[idx] = typeCount[t]
+		role := waitClassRoles[model.ClassifyWaitReason(t)]
+		colors[idx] = ui.theme.colors[role]
 		newLabel := t[:3]
 		if slices.Contains(labels, newLabel) {
 			newLabel = fmt.Sprintf("%s%d", t[:2], uniqueID)
 			uniqueID++
 		}
 		labels[idx] = newLabel
-		label = fmt.Sprintf("%s%s: %s\n", label, newLabel, t)
+		label = fmt.Sprintf("%s[%s](fg:%s): %s\n", label, newLabel, role, t)
+	}
+	if len(colors) == 0 {
+		colors = []termui.Color{ui.theme.colors["accent"]}
 	}
 	ui.barchart.Data = data
+	ui.barchart.BarColors = colors
 	ui.barchart.Labels = labels
 	ui.barchartLegend.Text = label
 }
 
 func (ui *UI) updateList() {
-	if ui.filter.Text == "" || !ui.filtered {
-		ui.filteredData = ui.origData
-	} else {
-		ui.filteredData = make([]model.Goroutine, 0)
-		for _, d := range ui.origData {
-			filterText := strings.ToLower(ui.filter.Text)
-			matchID := strings.Contains(strings.ToLower(fmt.Sprintf("%d", d.ID)), filterText)
-			matchStatus := strings.Contains(strings.ToLower(d.Status), filterText)
-			matchCreatedBy := d.CratedBy != nil && strings.Contains(strings.ToLower(d.CratedBy.String()), filterText)
-			matchStackTrace := model.StackContains(d.StackTrace, filterText)
-			matchLockedToThread := d.LockedToThread && strings.Contains("locked to thread", filterText)
-			if matchStatus || matchID || matchCreatedBy || matchStackTrace || matchLockedToThread {
-				ui.filteredData = append(ui.filteredData, d)
-			}
-		}
+	ui.filteredData = ui.filterRoutines()
+	ui.list.highlight = nil
+	if ui.filtered && ui.filterMode.fuzzy {
+		ui.list.highlight = fuzzyPatterns(ui.query.root)
+	}
+	ui.updateFilterTitle()
+
+	if ui.sortKey != model.SortNone {
+		ui.filteredData = slices.Clone(ui.filteredData)
+		model.Sort(ui.filteredData, ui.sortKey, ui.sortDesc)
 	}
 
 	// Update list
-	ui.list.Rows = make([]string, len(ui.filteredData))
-	for i := 0; i < len(ui.filteredData); i++ {
-		ui.list.Rows[i] = fmt.Sprintf("%05d %s ", ui.filteredData[i].ID, ui.filteredData[i].Status)
+	ui.groups, ui.diffMarks, ui.creators = nil, nil, nil
+	switch ui.view {
+	case viewDiff:
+		ui.groups, ui.diffMarks = ui.diffGroups(ui.filteredData)
+		ui.list.Rows = make([]string, len(ui.groups))
+		for i, group := range ui.groups {
+			ui.list.Rows[i] = diffRow(group, ui.diffMarks[i], ui.scrollX)
+		}
+	case viewStacks:
+		ui.groups = model.GroupByStack(ui.filteredData)
+		ui.list.Rows = make([]string, len(ui.groups))
+		for i, group := range ui.groups {
+			ui.list.Rows[i] = scrollText(groupRow(group), ui.scrollX)
+		}
+	case viewCreators:
+		ui.creators = ui.creatorRows(model.CreatorTree(ui.filteredData), 0, nil)
+		ui.list.Rows = make([]string, len(ui.creators))
+		for i, row := range ui.creators {
+			ui.list.Rows[i] = ui.creatorRowText(row)
+		}
+	default:
+		ui.list.Rows = make([]string, len(ui.filteredData))
+		for i := 0; i < len(ui.filteredData); i++ {
+			ui.list.Rows[i] = ui.row(ui.filteredData[i])
+		}
+	}
+
+	skipped := ""
+	skippedCount := 0
+	for _, snapshot := range ui.shownSnapshots() {
+		skippedCount += snapshot.Skipped
+	}
+	if skippedCount > 0 {
+		skipped = fmt.Sprintf(", %d skipped", skippedCount)
 	}
 
-	if len(ui.filteredData) == 0 {
+	sorted := ""
+	if ui.sortKey != model.SortNone {
+		direction := "↑"
+		if ui.sortDesc {
+			direction = "↓"
+		}
+		sorted = fmt.Sprintf(" by %s %s", ui.sortKey, direction)
+	}
+	if ui.scrollX > 0 && ui.view != viewCreators {
+		sorted += fmt.Sprintf(" from column %d", ui.scrollX+1)
+	}
+
+	ui.updateInfoBar()
+	ui.updateSummary()
+	ui.updateHotspots()
+
+	title := "Routines"
+	switch ui.view {
+	case viewStacks:
+		title = fmt.Sprintf("Stacks of %s routines", formatCount(len(ui.filteredData)))
+	case viewCreators:
+		title = fmt.Sprintf("Creators of %s routines", formatCount(len(ui.filteredData)))
+	case viewDiff:
+		title = ui.diffTitle()
+	}
+	if len(ui.list.Rows) == 0 {
 		ui.list.SelectedRow = 0
-		ui.details.Text = ""
-		ui.list.Title = "Routines (0/0)"
+		ui.showDetails("", "", nil)
+		ui.list.Title = fmt.Sprintf("%s (0/0%s)%s", title, skipped, sorted)
+		ui.updateStats()
 		return
 	}
 
-	if ui.list.SelectedRow >= len(ui.filteredData) {
-		ui.list.SelectedRow = len(ui.filteredData) - 1
+	if ui.list.SelectedRow >= len(ui.list.Rows) {
+		ui.list.SelectedRow = len(ui.list.Rows) - 1
 	} else if ui.list.SelectedRow < 0 {
 		ui.list.SelectedRow = 0
 	}
 
-	selectedData := ui.filteredData[ui.list.SelectedRow]
-	trace := ""
-	for _, t := range selectedData.StackTrace {
-		trace += fmt.Sprintf("  %s\n", t.String())
-	}
-	createdBy := ""
-	if selectedData.CratedBy != nil {
-		createdBy = fmt.Sprintf("Created by:\n  %s\n\n", selectedData.CratedBy.String())
+	ui.updateDetailsTitle(ui.selectedTarget())
+	switch ui.view {
+	case viewStacks:
+		group := ui.groups[ui.list.SelectedRow]
+		ui.showDetails(group.Key, ui.groupDetails(group), ui.stackFrames(group.Goroutines[0]))
+	case viewDiff:
+		group, mark := ui.groups[ui.list.SelectedRow], ui.diffMarks[ui.list.SelectedRow]
+		ui.showDetails(churnMarks[mark]+group.Key, ui.groupDetails(group), ui.stackFrames(group.Goroutines[0]))
+	case viewCreators:
+		node := ui.creators[ui.list.SelectedRow].node
+		ui.showDetails(node.Key, ui.creatorDetails(node), ui.creatorFrames(node))
+	default:
+		selected := ui.filteredData[ui.list.SelectedRow]
+		ui.showDetails(selected.Fingerprint(), ui.goroutineDetails(selected), ui.stackFrames(selected))
 	}
+
+	ui.list.Title = fmt.Sprintf("%s (%d/%d%s)%s", title, ui.list.SelectedRow+1, len(ui.list.Rows), skipped, sorted)
+	ui.updateStats()
+}
+
+// goroutineDetails describes the goroutine with its labels, creator and stack
+func (ui *UI) goroutineDetails(selectedData model.Goroutine) string {
 	lockedToThread := ""
 	if selectedData.LockedToThread {
 		lockedToThread = " [locked to thread](mod:bold)"
 	}
-	ui.details.Text = fmt.Sprintf("ID: [%d](mod:bold)\n\nStatus: [%s](mod:bold)\n\nWait Since: [%d min](mod:bold)%s\n\n%sTrace:\n%s",
+	labels := ""
+	if len(selectedData.Labels) > 0 {
+		keys := make([]string, 0, len(selectedData.Labels))
+		for key := range selectedData.Labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			labels += fmt.Sprintf("  %s=[%s](mod:bold)\n", key, selectedData.Labels[key])
+		}
+		labels = fmt.Sprintf("Labels:\n%s\n", labels)
+	}
+	target := ""
+	if len(ui.targets) > 1 {
+		target = fmt.Sprintf("Target: [%s](mod:bold)\n\n", selectedData.Target)
+	}
+	return fmt.Sprintf("%sID: [%d](mod:bold)\n\nStatus: [%s](mod:bold)\n\nWait Since: [%d min](mod:bold)%s\n\n%s%s",
+		target,
 		selectedData.ID,
 		selectedData.Status,
 		selectedData.WaitSinceMin,
 		lockedToThread,
-		createdBy,
-		trace)
+		labels,
+		ui.stackDetails(selectedData))
+}
+
+// stackDetails shows the creator and the stack of the goroutine
+func (ui *UI) stackDetails(g model.Goroutine) string {
+	createdBy := ""
+	if g.CratedBy != nil {
+		createdBy = fmt.Sprintf("Created by:\n  %s\n\n", styledFrame(*g.CratedBy))
+	}
+	return fmt.Sprintf("%sTrace:\n%s", createdBy, ui.traceText(g.StackTrace))
+}
+
+// stackFrames returns the shown frames in the order of stackDetails
+func (ui *UI) stackFrames(g model.Goroutine) []model.StackFrame {
+	if g.CratedBy == nil {
+		return ui.hide.shown(g.StackTrace)
+	}
+	return append([]model.StackFrame{*g.CratedBy}, ui.hide.shown(g.StackTrace)...)
+}
+
+// traceText shows one frame after another. Hidden frames are replaced by the number of frames which are hidden.
+func (ui *UI) traceText(frames []model.StackFrame) string {
+	trace := ""
+	shown := ui.hide.shown(frames)
+	hidden := 0
+	for _, t := range frames {
+		if len(shown) < len(frames) && ui.hide.hidden(t) {
+			hidden++
+			continue
+		}
+		if hidden > 0 {
+			trace += fmt.Sprintf("  [… %d hidden](fg:stdlib)\n", hidden)
+			hidden = 0
+		}
+		trace += fmt.Sprintf("  %s%s\n", styledFrame(t), frameMarks(t))
+	}
+	if hidden > 0 {
+		trace += fmt.Sprintf("  [… %d hidden](fg:stdlib)\n", hidden)
+	}
+	return trace
+}
+
+// selectedTarget returns the target of the selected row. Empty if nothing is selected.
+func (ui *UI) selectedTarget() string {
+	row := ui.list.SelectedRow
+	switch {
+	case (ui.view == viewStacks || ui.view == viewDiff) && row < len(ui.groups):
+		return ui.groups[row].Goroutines[0].Target
+	case ui.view == viewCreators && row < len(ui.creators):
+		return firstGoroutine(ui.creators[row].node).Target
+	case ui.view == viewRoutines && row < len(ui.filteredData):
+		return ui.filteredData[row].Target
+	}
+	return ""
+}
+
+// row renders the visible columns of the goroutine. The target is only shown if there are multiple targets.
+func (ui *UI) row(g model.Goroutine) string {
+	var fields []string
+	for _, col := range ui.columns {
+		if !col.visible || (col.name == "target" && len(ui.targets) < 2) {
+			continue
+		}
+		fields = append(fields, col.fit(plain(col.value(g, ui.hide))))
+	}
+	if ui.compact {
+		fields = []string{ui.compactRow(g)}
+	}
+	text := ui.markStuck(g, ui.markPin(g, scrollText(strings.Join(fields, " ")+" ", ui.scrollX)))
+	if ui.comparison != nil {
+		return ui.markChange(g, text)
+	}
+	return ui.markChurn(g, text)
+}
+
+// chooseColumns shows the column chooser until a key other than a column number is pressed
+func (ui *UI) chooseColumns(pollEvents <-chan termui.Event) (terminate bool) {
+	ui.chooser.Title = "Columns"
+	for {
+		text := ""
+		for i, col := range ui.columns {
+			if i >= 9 {
+				break
+			}
+			mark := " "
+			if col.visible {
+				mark = "x"
+			}
+			text += fmt.Sprintf("%d [%s] %s\n", i+1, mark, col.name)
+		}
+		ui.chooser.Text = text + "\nNumber: Toggle column\nOther key: Close"
+		ui.render(ui.grid, ui.summary, ui.infoBar, ui.legend, ui.statusBar, ui.chooser)
+
+		e := <-pollEvents
+		if ui.keys.isQuit(e.ID) {
+			return true
+		}
+		number, err := strconv.Atoi(e.ID)
+		if err != nil || !ui.toggleColumn(number) {
+			return false
+		}
+		ui.updateList()
+	}
+}
+
+func (ui *UI) updateDetailsTitle(target string) {
+	ui.details.Title = "Details"
+	for _, snapshot := range ui.shownSnapshots() {
+		if build := snapshot.Build; snapshot.Target == target && build != nil {
+			ui.details.Title = fmt.Sprintf("Details - %s %s (%s)", build.Main.Path, build.Main.Version, build.GoVersion)
+		}
+	}
+}
+
+func frameMarks(frame model.StackFrame) string {
+	switch {
+	case frame.Inlined:
+		return " [inlined](mod:bold)"
+	case frame.Autogenerated:
+		return " [autogenerated](mod:bold)"
+	}
+	return ""
+}
+
+// KeepTimeline keeps every snapshot to browse back in time, for sources such as files which are held in memory
+// anyway. By default only the latest snapshots up to a limit of goroutines are kept.
+func (ui *UI) KeepTimeline() {
+	ui.timeline.keepAll = true
+}
+
+// shownSnapshots returns the latest snapshot of every target or the older snapshots selected in the timeline
+func (ui *UI) shownSnapshots() []model.Snapshot {
+	if ui.timeline.browsing() {
+		return ui.timeline.view()
+	}
+	snapshots := make([]model.Snapshot, len(ui.targets))
+	for i, target := range ui.targets {
+		snapshots[i] = ui.snapshots[target]
+	}
+	return snapshots
+}
+
+// mergeSnapshots of all targets into the displayed data. A comparison is shown instead if there is one. Only the
+// goroutines of the target of the shown tab are kept.
+func (ui *UI) mergeSnapshots() {
+	if ui.comparison != nil {
+		ui.origData = make([]model.Goroutine, 0, len(ui.comparison.entries))
+		for _, entry := range ui.comparison.entries {
+			if len(ui.tab) == 0 || entry.Goroutine.Target == ui.tab {
+				ui.origData = append(ui.origData, entry.Goroutine)
+			}
+		}
+		return
+	}
+	snapshots := ui.shownSnapshots()
+	if len(ui.tab) > 0 {
+		var shown []model.Snapshot
+		for _, s := range snapshots {
+			if s.Target == ui.tab {
+				shown = append(shown, s)
+			}
+		}
+		snapshots = shown
+	}
+	ui.origData = append(model.Merge(snapshots), ui.removedRoutines()...)
+}
+
+// togglePause freezes the shown snapshots while new snapshots are still received, or shows the latest snapshots
+// again
+func (ui *UI) togglePause() {
+	if ui.timeline.browsing() {
+		ui.timeline.step(len(ui.timeline.snapshots))
+	} else {
+		ui.timeline.freeze()
+	}
+	ui.frozen = ui.timeline.browsing()
+	ui.mergeSnapshots()
+	ui.updateList()
+	ui.updateStatus()
+	ui.updateStatusBar()
+}
 
-	ui.list.Title = fmt.Sprintf("Routines (%d/%d)", ui.list.SelectedRow+1, len(ui.list.Rows))
+// toggleView lists the rows of the view, or all goroutines if the view is shown already
+func (ui *UI) toggleView(v view) {
+	if ui.view == v {
+		v = viewRoutines
+	}
+	ui.view = v
+	ui.list.ScrollTop()
+	ui.updateList()
+}
+
+// browse moves through the timeline of snapshots
+func (ui *UI) browse(delta int) {
+	ui.timeline.step(delta)
+	ui.keepCursor()
+	ui.frozen = ui.frozen && ui.timeline.browsing()
+	ui.mergeSnapshots()
+	ui.updateList()
+	ui.updateStatus()
+	ui.updateStatusBar()
+	if ui.replay != nil {
+		ui.updateHistory()
+		ui.scheduleReplay()
+	}
+}
+
+func (ui *UI) removeTarget(target string) {
+	delete(ui.snapshots, target)
+	delete(ui.fetchStatus, target)
+	delete(ui.health, target)
+	ui.closeTab(target)
+	delete(ui.churn, target)
+	for key, p := range ui.pins {
+		if p.target == target {
+			delete(ui.pins, key)
+		}
+	}
+	ui.targets = slices.DeleteFunc(ui.targets, func(t string) bool { return t == target })
+	ui.mergeSnapshots()
 }
 
 // Stop UI and close all event listeners
@@ -266,28 +1231,56 @@ func (ui *UI) Stop() {
 
 func (ui *UI) resize(width, height int) {
 	log.Printf("Resize to: (%d,%d)", width, height)
-	ui.paused.SetRect(width/2.0-25, height/4.0-4, width/2.0+25, height/4.0+4)
-	ui.help.SetRect(width/2.0-20, height/4.0-10, width/2.0+20, height/4.0+10)
-	ui.legend.SetRect(width-35, height-4, width-1, height-1)
-	ui.grid.SetRect(0, 0, width, height)
+	ui.chooser.SetRect(max(0, width/2.0-20), max(0, height/4.0-8), min(width, width/2.0+20), min(height, height/4.0+10))
+	legendWidth := min(len(ui.legend.Text)+2, width/2)
+	ui.legend.SetRect(width-legendWidth, height-1, width, height)
+	ui.statusBar.SetRect(0, height-1, width-legendWidth, height)
+	ui.infoBar.SetRect(0, height-2, width, height-1)
+	ui.summary.SetRect(0, height-3, width, height-2)
+	ui.grid.SetRect(0, 0, width, height-3)
+	size := sizeOf(width)
+	if ui.linear {
+		size = layoutNarrow
+	}
+	if short := height < shortHeight; size != ui.size || short != ui.short {
+		ui.size, ui.short = size, short
+		ui.layout()
+	}
+	ui.updateHistory()
 }
 
 // Run UI in fullscreen mode
-func (ui *UI) Run(terminate chan<- error, routinesUpdate <-chan []model.Goroutine) {
+func (ui *UI) Run(terminate chan<- error, routinesUpdate <-chan model.Snapshot, statusUpdate <-chan model.FetchStatus) {
 	ui.updateList()
 
 	termWidth, termHeight := termui.TerminalDimensions()
 	ui.resize(termWidth, termHeight)
 
-	termui.Render(ui.grid, ui.legend)
+	ui.render(ui.grid, ui.summary, ui.infoBar, ui.legend, ui.statusBar)
+
+	// Counts down to the next fetch in the status bar
+	var tick <-chan time.Time
+	if ui.controller != nil {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
 
 	pollEvents := termui.PollEvents()
 	for {
 		select {
+		case <-tick:
+			ui.updateStatusBar()
+		case <-ui.replayWake():
+			ui.replayStep()
 		case evt := <-pollEvents:
 			switch evt.Type {
 			case termui.MouseEvent:
-				continue
+				mouse, ok := evt.Payload.(termui.Mouse)
+				if !ok || !(ui.clickCounter(evt.ID, mouse) || ui.scrub(evt.ID, mouse) || ui.handleMouse(evt.ID, mouse)) {
+					continue
+				}
+				ui.updateStatusBar()
 			case termui.ResizeEvent:
 				resized, ok := evt.Payload.(termui.Resize)
 				if !ok {
@@ -298,97 +1291,324 @@ func (ui *UI) Run(terminate chan<- error, routinesUpdate <-chan []model.Goroutin
 			case termui.KeyboardEvent:
 				terminateEvent := ui.handleKeyEvent(evt.ID, pollEvents)
 				if terminateEvent {
+					ui.storeState()
 					terminate <- nil
 					return
 				}
 			}
-		case routines := <-routinesUpdate:
-			// History data size cannot be limited in termui. This is a workaround
-			var keepRoutineHist = (ui.routineHist.Dx() - 10) >> 1
-			ui.origData = routines
-			if len(ui.routineHist.Data[0]) >= keepRoutineHist {
-				ui.routineHist.Data[0] = ui.routineHist.Data[0][1:]
+		case status := <-statusUpdate:
+			if status.Gone {
+				ui.removeTarget(status.Target)
+				ui.updateList()
+				ui.updateStatus()
+			} else if status.Probe {
+				ui.health[status.Target] = status
+			} else {
+				ui.fetchStatus[status.Target] = status
 			}
-			ui.routineHist.Data[0] = append(ui.routineHist.Data[0], float64(len(routines)))
-
-			if ui.minGoRoutines == 0 || len(routines) < ui.minGoRoutines {
-				ui.minGoRoutines = len(routines)
+			ui.updateStatusBar()
+		case snapshot := <-routinesUpdate:
+			if _, known := ui.snapshots[snapshot.Target]; !known {
+				ui.targets = append(ui.targets, snapshot.Target)
 			}
-			if len(routines) > ui.maxGoRoutines {
-				ui.maxGoRoutines = len(routines)
+			ui.trackChurn(snapshot)
+			ui.snapshots[snapshot.Target] = snapshot
+			ui.timeline.add(snapshot)
+			ui.keepCursor()
+			ui.notifyPins(snapshot)
+			ui.mergeSnapshots()
+			// The history shows the latest snapshots even if older ones are shown
+			var routines []model.Goroutine
+			for _, s := range ui.snapshots {
+				routines = append(routines, s.Goroutines...)
 			}
-			if ui.avgGoRoutines > 0 {
-				ui.avgGoRoutines = (ui.avgGoRoutines + float64(len(routines))) / 2.0
-			} else {
-				ui.avgGoRoutines = float64(len(routines))
+			now := time.Now()
+			if ui.replay != nil {
+				now = snapshot.Time
 			}
-			ui.updatePlotTitle()
+			ui.history.add(now, routines)
+			ui.updateHistory()
 			ui.updateList()
 			ui.updateStatus()
+			ui.updateStatusBar()
 		}
 
-		termui.Render(ui.grid, ui.legend)
+		ui.render(ui.grid, ui.summary, ui.infoBar, ui.legend, ui.statusBar)
 	}
 }
 
 func (ui *UI) handleKeyEvent(keyID string, pollEvents <-chan termui.Event) (terminate bool) {
-	switch keyID {
-	case "<C-c>", "<F10>":
+	if len(ui.notice) > 0 {
+		ui.notice = ""
+		ui.updateStatusBar()
+	}
+	if ui.finding && ui.editFind(keyID) {
+		return false
+	}
+	// A key after the start of a sequence such as ctrl-x c continues the sequence instead of editing the filter
+	if ui.searching && len(ui.pendingKeys) == 0 && ui.editFilter(keyID) {
+		return false
+	}
+	// Keys such as ? only edit the filter once the user started to type it
+	typing := ui.keys.typeToFilter && ui.filtered && len(ui.pendingKeys) == 0
+	if typing && utf8.RuneCountInString(keyID) == 1 && ui.editFilter(keyID) {
+		return false
+	}
+	if keyID == "<Escape>" && (ui.filtered || ui.statusFilter.active()) && len(ui.pendingKeys) == 0 {
+		ui.clearFilter()
+		return false
+	}
+	sequence := keyID
+	if len(ui.pendingKeys) > 0 {
+		sequence = ui.pendingKeys + " " + keyID
+	}
+	a, found, prefix := ui.keys.lookup(sequence)
+	if prefix {
+		ui.pendingKeys = sequence
+		return false
+	}
+	ui.pendingKeys = ""
+	if !found {
+		if index, ok := ui.tabIndex(keyID); ok {
+			ui.switchTab(index)
+		} else if index, ok := ui.bookmarkIndex(keyID); ok && len(ui.targets) < 2 {
+			// The number keys switch the tabs of several targets
+			ui.recallBookmark(index)
+			ui.updateStatusBar()
+		} else if ui.keys.typeToFilter {
+			ui.editFilter(keyID)
+		}
+		return false
+	}
+	if ui.focusDetails && ui.moveFrame(a) {
+		return false
+	}
+	return ui.runAction(a, pollEvents)
+}
+
+// runAction performs the action. Returns true if the application should terminate.
+func (ui *UI) runAction(a action, pollEvents <-chan termui.Event) (terminate bool) {
+	switch a {
+	case actionQuit:
 		return true
-	case "<F1>":
-		termui.Render(ui.grid, ui.legend, ui.help)
-		e := <-pollEvents
-		if e.ID == "<C-c>" || e.ID == "<F10>" {
+	case actionHelp:
+		if ui.showHelp(pollEvents) {
 			return true
 		}
-		termui.Render(ui.grid, ui.legend)
-	case "<F2>":
-		// Pause
-		termui.Render(ui.grid, ui.legend, ui.paused)
-		e := <-pollEvents
-		if e.ID == "<C-c>" || e.ID == "<F10>" {
+	case actionPause:
+		if ui.replay != nil {
+			ui.togglePlay()
+		} else {
+			ui.togglePause()
+		}
+	case actionSort:
+		ui.sortKey = model.SortKeys[(slices.Index(model.SortKeys, ui.sortKey)+1)%len(model.SortKeys)]
+		ui.updateList()
+	case actionSortDirection:
+		ui.sortDesc = !ui.sortDesc
+		ui.updateList()
+	case actionCompare:
+		ui.nextCapture()
+	case actionGroup:
+		ui.toggleView(viewStacks)
+	case actionCreators:
+		ui.toggleView(viewCreators)
+	case actionDiff:
+		ui.toggleView(viewDiff)
+	case actionExpand:
+		if ui.view == viewCreators {
+			ui.expandCreator(true)
+		} else {
+			ui.scrollList(scrollStep)
+		}
+	case actionCollapse:
+		if ui.view == viewCreators {
+			ui.expandCreator(false)
+		} else {
+			ui.scrollList(-scrollStep)
+		}
+	case actionFocus:
+		ui.toggleFocus()
+	case actionFilterFrame:
+		ui.filterFrame()
+	case actionHideFrames:
+		ui.toggleHidden()
+	case actionPin:
+		ui.togglePin()
+		ui.updateStatusBar()
+	case actionStatusFilter:
+		ui.nextStatusFilter()
+	case actionOnlyStatus:
+		ui.toggleSelectedStatus(false)
+		ui.updateStatusBar()
+	case actionHideStatus:
+		ui.toggleSelectedStatus(true)
+		ui.updateStatusBar()
+	case actionGrowList:
+		ui.resizeList(paneStep)
+		ui.updateStatusBar()
+	case actionShrinkList:
+		ui.resizeList(-paneStep)
+		ui.updateStatusBar()
+	case actionVertical:
+		ui.toggleVertical()
+		ui.updateStatusBar()
+	case actionCompact:
+		ui.toggleCompact()
+	case actionHotspots:
+		ui.toggleHotspots()
+	case actionColorBy:
+		ui.nextColorBy()
+		ui.updateStatusBar()
+	case actionOpen:
+		ui.openFrame()
+		ui.updateStatusBar()
+	case actionCopy:
+		routines := ui.selectedGoroutines()
+		what := "stack"
+		if len(routines) > 0 {
+			what = fmt.Sprintf("stack of goroutine %d", routines[0].ID)
+		}
+		ui.copyGoroutines(routines, what)
+		ui.updateStatusBar()
+	case actionCopyAll:
+		ui.copyGoroutines(ui.filteredData, fmt.Sprintf("%s goroutines", formatCount(len(ui.filteredData))))
+		ui.updateStatusBar()
+	case actionExport:
+		if ui.chooseExport(pollEvents) {
+			return true
+		}
+		ui.updateStatusBar()
+	case actionColumns:
+		if ui.chooseColumns(pollEvents) {
+			return true
+		}
+	case actionBookmarks:
+		if ui.chooseBookmark(pollEvents) {
 			return true
 		}
-		termui.Render(ui.grid, ui.legend)
-	case "<Down>":
+		ui.updateStatusBar()
+	case actionFilters:
+		if ui.pickFilter(pollEvents) {
+			return true
+		}
+		ui.updateStatusBar()
+	case actionPalette:
+		return ui.showPalette(pollEvents)
+	case actionLog:
+		return ui.showLog(pollEvents)
+	case actionPrevious:
+		ui.browse(-1)
+	case actionNext:
+		ui.browse(1)
+	case actionRetry:
+		if ui.controller != nil {
+			ui.controller.Retry()
+		}
+	case actionRefresh:
+		if ui.controller != nil {
+			ui.controller.Refresh()
+		}
+	case actionSlower:
+		if ui.replay != nil {
+			ui.changeSpeed(false)
+		} else {
+			ui.changeInterval(true)
+		}
+	case actionFaster:
+		if ui.replay != nil {
+			ui.changeSpeed(true)
+		} else {
+			ui.changeInterval(false)
+		}
+	case actionDown:
 		ui.list.ScrollDown()
 		ui.updateList()
-	case "<Up>":
+	case actionUp:
 		ui.list.ScrollUp()
 		ui.updateList()
-	case "<PageDown>":
+	case actionPageDown:
 		ui.list.ScrollPageDown()
 		ui.updateList()
-	case "<PageUp>":
+	case actionPageUp:
 		ui.list.ScrollPageUp()
 		ui.updateList()
-	case "<Home>":
+	case actionTop:
 		ui.list.ScrollTop()
 		ui.updateList()
-	case "<End>":
+	case actionBottom:
 		ui.list.ScrollBottom()
 		ui.updateList()
+	case actionFind:
+		ui.finding = true
+		ui.detail.pattern = ""
+		ui.findMatches()
+		ui.renderDetails()
+	case actionFindNext:
+		ui.findNext(1)
+	case actionFindPrevious:
+		ui.findNext(-1)
+	case actionMatchCase:
+		ui.filterMode.matchCase = !ui.filterMode.matchCase
+		ui.updateList()
+	case actionMatchWord:
+		ui.filterMode.wholeWord = !ui.filterMode.wholeWord
+		ui.updateList()
+	case actionMatchFuzzy:
+		ui.filterMode.fuzzy = !ui.filterMode.fuzzy
+		ui.updateList()
+	case actionSearch:
+		ui.searching = true
+		ui.filtered = true
+		ui.filter.Text = ""
+		ui.updateList()
+	}
+	return false
+}
+
+// editFilter applies the key to the filter text. Returns false if the key does not edit the filter.
+func (ui *UI) editFilter(keyID string) bool {
+	switch keyID {
 	case "<Backspace>", "<C-<Backspace>>":
-		if len(ui.filter.Text) > 0 {
-			ui.filter.Text = ui.filter.Text[:len(ui.filter.Text)-1]
+		if ui.filtered && len(ui.filter.Text) > 0 {
+			runes := []rune(ui.filter.Text)
+			ui.filter.Text = string(runes[:len(runes)-1])
 		}
-	case "<Space>":
-		if !ui.filtered {
-			ui.filter.Text = ""
+	case "<Enter>":
+		if !ui.searching {
+			return false
 		}
-		ui.filtered = true
-		ui.filter.Text += " "
-		ui.updateList()
+		ui.searching = false
+	case "<Escape>":
+		if !ui.searching && !ui.filtered {
+			return false
+		}
+		ui.clearFilter()
+		return true
+	case "<Space>":
+		ui.appendFilter(" ")
 	default:
-		// < sign
-		if keyID[0] != 0x3C {
-			if !ui.filtered {
-				ui.filter.Text = ""
-			}
-			ui.filtered = true
-			ui.filter.Text += keyID
+		if utf8.RuneCountInString(keyID) != 1 {
+			return false
 		}
-		ui.updateList()
+		ui.appendFilter(keyID)
 	}
-	return false
+	ui.updateList()
+	return true
+}
+
+func (ui *UI) appendFilter(text string) {
+	if !ui.filtered {
+		ui.filter.Text = ""
+	}
+	ui.filtered = true
+	ui.filter.Text += text
+}
+
+// filterPlaceholder is shown in the filter until the user types
+func (ui *UI) filterPlaceholder() string {
+	if ui.keys.typeToFilter {
+		return "TYPE TO FILTER"
+	}
+	return fmt.Sprintf("%s TO FILTER", strings.ToUpper(ui.keys.key(actionSearch)))
 }
@@ -0,0 +1,40 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type fixedInterval time.Duration
+
+func (i fixedInterval) Interval() time.Duration { return time.Duration(i) }
+func (fixedInterval) SetInterval(time.Duration) {}
+func (fixedInterval) Retry()                    {}
+func (fixedInterval) Refresh()                  {}
+
+func TestFetchStatusText(t *testing.T) {
+	ui := &UI{controller: fixedInterval(time.Second)}
+	ui.keys, _ = newKeymap("default", nil)
+	assert.Equal(t, "Interval 1s", ui.fetchStatusText())
+
+	ui.fetchStatus = map[string]model.FetchStatus{"a": {Target: "a", State: model.Connected, Interval: time.Second}}
+	assert.Equal(t, "Interval 1s | [Connected](fg:ok), fetching", ui.fetchStatusText())
+
+	failed := errors.New("connection refused")
+	ui.fetchStatus["a"] = model.FetchStatus{Target: "a", State: model.Retrying, Interval: 2 * time.Second, Failures: 1, Err: failed}
+	assert.Equal(t, "Interval 1s | [Retrying in 2s (failure 1): connection refused](fg:warn)", ui.fetchStatusText())
+
+	ui.fetchStatus["a"] = model.FetchStatus{Target: "a", State: model.GaveUp, Failures: 3, Err: failed}
+	assert.Equal(t, "Interval 1s | [Gave up after 3 failures: connection refused](fg:error). F7 to retry", ui.fetchStatusText())
+	ui.keys, _ = newKeymap("vim", nil)
+	assert.Equal(t, "Interval 1s | [Gave up after 3 failures: connection refused](fg:error). r to retry", ui.fetchStatusText())
+
+	ui.fetchStatus["b"] = model.FetchStatus{Target: "b", State: model.Connected, Interval: time.Second}
+	ui.fetchStatus["c"] = model.FetchStatus{Target: "c", State: model.Retrying, Interval: time.Second, Failures: 1, Err: failed}
+	assert.Equal(t, "Interval 1s | [1/3 connected](fg:ok), fetching [1 retrying](fg:warn) [1 gave up](fg:error). r to retry",
+		ui.fetchStatusText())
+}
@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+// DefaultSort lists the goroutines which wait the longest first
+const DefaultSort = "-wait"
+
+// DefaultWaitThreshold is the wait time after which a goroutine is highlighted
+const DefaultWaitThreshold = 10 * time.Minute
+
+// SetSort sorts the list by the key, for example id. A leading - sorts in descending order. The sort is the
+// initial sort of every tab.
+func (ui *UI) SetSort(spec string) error {
+	key, desc, err := parseSort(spec)
+	if err != nil {
+		return err
+	}
+	ui.initialSort = tabState{sortKey: key, sortDesc: desc}
+	ui.sortKey, ui.sortDesc = key, desc
+	ui.updateList()
+	return nil
+}
+
+// parseSort returns the key and the direction of a sort such as -wait
+func parseSort(spec string) (model.SortKey, bool, error) {
+	key, err := model.ParseSortKey(strings.TrimPrefix(spec, "-"))
+	return key, strings.HasPrefix(spec, "-"), err
+}
+
+// formatSort returns the sort in the format of parseSort
+func formatSort(key model.SortKey, desc bool) string {
+	if desc {
+		return "-" + key.String()
+	}
+	return key.String()
+}
+
+// SetWaitThreshold highlights the goroutines which wait at least the threshold. 0 disables the highlighting.
+func (ui *UI) SetWaitThreshold(threshold time.Duration) {
+	ui.waitThreshold = threshold
+	ui.updateList()
+}
+
+// stuck returns true if the goroutine waits at least the threshold. The runtime reports the wait in minutes.
+func (ui *UI) stuck(g model.Goroutine) bool {
+	return ui.waitThreshold > 0 && time.Duration(g.WaitSinceMin)*time.Minute >= ui.waitThreshold
+}
+
+// stuckStatus shows the number of listed goroutines which wait at least the threshold
+func (ui *UI) stuckStatus() string {
+	count := 0
+	for _, g := range ui.filteredData {
+		if ui.stuck(g) {
+			count++
+		}
+	}
+	if count == 0 {
+		return ""
+	}
+	return fmt.Sprintf("[%s waiting ≥ %s](fg:error)", formatCount(count), formatThreshold(ui.waitThreshold))
+}
+
+// formatThreshold drops the zero units of durations like 10m0s
+func formatThreshold(d time.Duration) string {
+	text := d.String()
+	if strings.HasSuffix(text, "m0s") {
+		text = strings.TrimSuffix(text, "0s")
+	}
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
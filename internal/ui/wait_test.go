@@ -0,0 +1,31 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStuck(t *testing.T) {
+	ui := &UI{timeline: newTimeline(), waitThreshold: DefaultWaitThreshold}
+	ui.filteredData = []model.Goroutine{
+		{ID: 1, Status: "select", WaitSinceMin: 12},
+		{ID: 2, Status: "chan receive", WaitSinceMin: 10},
+		{ID: 3, Status: "IO wait", WaitSinceMin: 9},
+	}
+	assert.Equal(t, "[2 waiting ≥ 10m](fg:error)", ui.stuckStatus())
+	assert.Equal(t, "[1 ](fg:error)", ui.markChurn(ui.filteredData[0], "1 "))
+	assert.Equal(t, "3 ", ui.markChurn(ui.filteredData[2], "3 "))
+
+	ui.waitThreshold = 0
+	assert.Empty(t, ui.stuckStatus())
+}
+
+func TestFormatThreshold(t *testing.T) {
+	assert.Equal(t, "10m", formatThreshold(10*time.Minute))
+	assert.Equal(t, "2h", formatThreshold(2*time.Hour))
+	assert.Equal(t, "1h30m", formatThreshold(90*time.Minute))
+	assert.Equal(t, "30s", formatThreshold(30*time.Second))
+}
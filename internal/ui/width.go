@@ -0,0 +1,172 @@
+package ui
+
+import (
+	"image"
+	"strings"
+
+	"github.com/gizak/termui/v3/widgets"
+	"github.com/mattn/go-runewidth"
+
+	termui "github.com/gizak/termui/v3"
+)
+
+// displayWidth returns the number of terminal columns of the text. Wide characters such as CJK take two columns
+// and combining marks none.
+func displayWidth(text string) int {
+	return runewidth.StringWidth(text)
+}
+
+// cutWidth returns the longest start of the text which fits into the width
+func cutWidth(text string, width int) string {
+	used := 0
+	for i, r := range text {
+		if used += runewidth.RuneWidth(r); used > width {
+			return text[:i]
+		}
+	}
+	return text
+}
+
+// tailWidth returns the longest end of the text which fits into the width
+func tailWidth(text string, width int) string {
+	runes := []rune(text)
+	used := 0
+	for i := len(runes) - 1; i >= 0; i-- {
+		if used += runewidth.RuneWidth(runes[i]); used > width {
+			return string(runes[i+1:])
+		}
+	}
+	return text
+}
+
+// pad fills the text with spaces up to the width
+func pad(text string, width int) string {
+	return text + strings.Repeat(" ", max(0, width-displayWidth(text)))
+}
+
+// wideParagraph is a paragraph which wraps and cuts its lines by their width in terminal columns. The paragraph
+// of termui wraps by the number of characters, which lets lines with wide characters run into the border.
+type wideParagraph struct {
+	*widgets.Paragraph
+}
+
+func newWideParagraph() *wideParagraph {
+	return &wideParagraph{Paragraph: widgets.NewParagraph()}
+}
+
+func (p *wideParagraph) Draw(buf *termui.Buffer) {
+	p.Block.Draw(buf)
+	y := 0
+	for _, line := range termui.SplitCells(termui.ParseStyles(p.Text, p.TextStyle), '\n') {
+		for _, row := range wrapCells(line, p.Inner.Dx()) {
+			if y >= p.Inner.Dy() {
+				return
+			}
+			for _, cx := range termui.BuildCellWithXArray(termui.TrimCells(row, p.Inner.Dx())) {
+				buf.SetCell(cx.Cell, image.Pt(cx.X, y).Add(p.Inner.Min))
+			}
+			y++
+		}
+	}
+}
+
+// wrapCells breaks the cells at the last space before the width like termui. Words which are wider than the width
+// are kept and cut when drawn.
+func wrapCells(cells []termui.Cell, width int) [][]termui.Cell {
+	var rows [][]termui.Cell
+	var row []termui.Cell
+	used, space := 0, -1 // Width of the row and index of its last space
+	for _, cell := range cells {
+		w := runewidth.RuneWidth(cell.Rune)
+		if used+w > width {
+			switch {
+			case cell.Rune == ' ':
+				rows, row, used, space = append(rows, row), nil, 0, -1
+				continue
+			case space >= 0:
+				rows = append(rows, row[:space])
+				row = append([]termui.Cell(nil), row[space+1:]...)
+				used, space = 0, -1
+				for _, c := range row {
+					used += runewidth.RuneWidth(c.Rune)
+				}
+			}
+		}
+		if cell.Rune == ' ' {
+			space = len(row)
+		}
+		row = append(row, cell)
+		used += w
+	}
+	return append(rows, row)
+}
+
+// wideList is a list which cuts its rows by their width in terminal columns. The list of termui cuts by the number
+// of characters, which lets rows with wide characters run into the border.
+type wideList struct {
+	*widgets.List
+	topRow    int      // First shown row
+	marker    bool     // Rows start with > if selected and with a space otherwise
+	highlight []string // Fuzzy patterns whose letters are highlighted in every row
+}
+
+func newWideList() *wideList {
+	return &wideList{List: widgets.NewList()}
+}
+
+// highlighted returns which of the cells the highlighted patterns match
+func (l *wideList) highlighted(cells []termui.Cell) []bool {
+	highlighted := make([]bool, len(cells))
+	if len(l.highlight) == 0 {
+		return highlighted
+	}
+	runes := make([]rune, len(cells))
+	for i, cell := range cells {
+		runes[i] = cell.Rune
+	}
+	for _, pattern := range l.highlight {
+		for _, i := range fuzzyPositions(pattern, runes) {
+			highlighted[i] = true
+		}
+	}
+	return highlighted
+}
+
+func (l *wideList) Draw(buf *termui.Buffer) {
+	l.Block.Draw(buf)
+	height := l.Inner.Dy()
+	if l.SelectedRow >= l.topRow+height {
+		l.topRow = l.SelectedRow - height + 1
+	} else if l.SelectedRow < l.topRow {
+		l.topRow = l.SelectedRow
+	}
+	for row := l.topRow; row < len(l.Rows) && row-l.topRow < height; row++ {
+		cells := termui.ParseStyles(l.Rows[row], l.TextStyle)
+		if l.marker {
+			marker := "  "
+			if row == l.SelectedRow {
+				marker = "> "
+			}
+			cells = append(termui.ParseStyles(marker, l.TextStyle), cells...)
+		}
+		cells = termui.TrimCells(cells, l.Inner.Dx())
+		highlighted := l.highlighted(cells)
+		for i, cx := range termui.BuildCellWithXArray(cells) {
+			cell := cx.Cell
+			if row == l.SelectedRow {
+				cell.Style = l.SelectedRowStyle
+			}
+			if highlighted[i] {
+				cell.Style = termui.NewStyle(termui.StyleParserColorMap["matchtext"], termui.StyleParserColorMap["match"])
+			}
+			buf.SetCell(cell, image.Pt(cx.X, row-l.topRow).Add(l.Inner.Min))
+		}
+	}
+	arrow := termui.NewStyle(termui.ColorWhite)
+	if l.topRow > 0 {
+		buf.SetCell(termui.NewCell(termui.UP_ARROW, arrow), image.Pt(l.Inner.Max.X-1, l.Inner.Min.Y))
+	}
+	if len(l.Rows) > l.topRow+height {
+		buf.SetCell(termui.NewCell(termui.DOWN_ARROW, arrow), image.Pt(l.Inner.Max.X-1, l.Inner.Max.Y-1))
+	}
+}
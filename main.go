@@ -1,66 +1,397 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/becheran/roumon/internal/api"
 	"github.com/becheran/roumon/internal/client"
+	"github.com/becheran/roumon/internal/collector"
+	"github.com/becheran/roumon/internal/discovery"
+	"github.com/becheran/roumon/internal/dump"
 	"github.com/becheran/roumon/internal/model"
+	"github.com/becheran/roumon/internal/otlp"
+	"github.com/becheran/roumon/internal/statsd"
+	"github.com/becheran/roumon/internal/store"
 	"github.com/becheran/roumon/internal/ui"
+	"github.com/becheran/roumon/roumongrpc"
+	"google.golang.org/grpc"
 )
 
+// discoveryInterval is the time between two lookups of the discovered targets
+const discoveryInterval = 10 * time.Second
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serve(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "top" {
+		top(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "report" {
+		htmlReport(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "record" {
+		record(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		diff(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		replay(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "bundle" {
+		createBundle(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		importBundle(os.Args[2:])
+		return
+	}
+
 	var host string
+	var targets stringList
+	var targetFile string
 	var dbgFile string
 	var port int
 	var versionFlag bool
+	var opts client.Options
+	var k8s discovery.Kubernetes
+	var consul discovery.Consul
+	var dns discovery.DNS
+	var grpcListen, grpcToken, apiListen string
+	var readStdin bool
+	var dumpFile, dumpDir, dirOrder, follow, recordDir, sqliteFile, otlpEndpoint, statsdAddr, statsdPrefix string
+	var pid int
+	var otlpHeaders, statsdTags []string
+	var dogStatsD bool
+	var pidTimeout time.Duration
+	var pidKill bool
 	flag.StringVar(&host, "host", "localhost", "The pprof server IP or hostname")
 	flag.IntVar(&port, "port", 6060, "The pprof server port")
+	flag.Var(&targets, "target", "The pprof server as URL or unix socket. For example unix:///var/run/app.sock:/debug/pprof/goroutine. Can be repeated. Overrides host and port")
+	flag.StringVar(&targetFile, "target-file", "", "File with one target per line. Lines starting with # are ignored")
+	flag.StringVar(&opts.CACert, "ca-cert", "", "Path to a PEM encoded CA certificate to verify the pprof server")
+	flag.StringVar(&opts.ClientCert, "client-cert", "", "Path to a PEM encoded client certificate for mTLS")
+	flag.StringVar(&opts.ClientKey, "client-key", "", "Path to the PEM encoded key of the client certificate")
+	flag.BoolVar(&opts.Insecure, "insecure", false, "Skip verification of the pprof server certificate")
+	flag.StringVar(&opts.BasicAuth, "basic-auth", "", "Basic auth credentials user:password. Env: ROUMON_BASIC_AUTH")
+	flag.StringVar(&opts.BearerToken, "bearer-token", "", "Bearer token for the pprof server. Env: ROUMON_BEARER_TOKEN")
+	flag.StringVar(&opts.TokenFile, "token-file", "", "File which contains the bearer token. Read before every request. Env: ROUMON_TOKEN_FILE")
+	flag.Var((*stringList)(&opts.Headers), "header", "Additional request header \"Name: value\". Can be repeated")
+	flag.StringVar(&opts.Proxy, "proxy", "", "Proxy URL, for example socks5://localhost:1080 or http://proxy:3128. Defaults to HTTP_PROXY and HTTPS_PROXY")
+	flag.StringVar(&opts.SSH, "ssh", "", "Fetch through the SSH server user@bastion[:port]")
+	flag.StringVar(&opts.SSHKey, "ssh-key", "", "Private key for the SSH server. Defaults to the SSH agent and the keys in ~/.ssh")
+	flag.StringVar(&opts.SSHKnownHosts, "ssh-known-hosts", "", "Known hosts file to verify the SSH server. Defaults to ~/.ssh/known_hosts")
+	flag.Var((*commaList)(&opts.Profiles), "profiles", "Comma separated profiles fetched alongside the goroutines: heap, block and mutex")
+	flag.BoolVar(&opts.Expvar, "expvar", false, "Fetch the expvars from /debug/vars alongside the goroutines and show them in a stats panel")
+	flag.DurationVar(&opts.FetchTimeout, "fetch-timeout", 10*time.Second, "Timeout of a single request to the pprof server. 0 disables the timeout")
+	flag.Float64Var(&opts.RateLimit, "rate-limit", 0, "Maximum requests per second to a single host. 0 is unlimited")
+	flag.IntVar(&opts.MaxConcurrent, "max-concurrent", 0, "Maximum requests in flight to all targets. 0 is unlimited")
+	flag.DurationVar(&opts.Interval, "interval", time.Second, "Time between two fetches of the goroutine profile")
+	flag.StringVar(&k8s.Selector, "k8s-selector", "", "Monitor all running pods which match the label selector, for example app=myservice")
+	flag.StringVar(&k8s.Namespace, "k8s-namespace", "", "Namespace of the pods. Defaults to the namespace of the kube context")
+	flag.StringVar(&k8s.Context, "k8s-context", "", "Kube context. Defaults to the current context")
+	flag.IntVar(&k8s.Port, "k8s-port", 6060, "The pprof port of the pods")
+	flag.StringVar(&consul.Service, "consul-service", "", "Monitor all healthy instances of the consul service")
+	flag.StringVar(&consul.Addr, "consul-addr", "", "Address of the consul agent. Defaults to CONSUL_HTTP_ADDR or http://127.0.0.1:8500. Env for the ACL token: CONSUL_HTTP_TOKEN")
+	flag.StringVar(&dns.Name, "dns-srv", "", "Monitor all targets of the DNS SRV record, for example _pprof._tcp.myservice.example.com")
+	flag.StringVar(&grpcListen, "grpc-listen", "", "Address to receive the snapshots pushed by monitored programs over gRPC, for example :7777")
+	flag.StringVar(&grpcToken, "grpc-token", "", "Bearer token which the pushing programs have to send. Env: ROUMON_GRPC_TOKEN")
+	flag.DurationVar(&opts.HealthInterval, "health-interval", 0, "Time between two health probes of the pprof index. 0 disables the probes")
+	flag.IntVar(&opts.MaxRetries, "max-retries", 10, "Consecutive failed fetches until roumon gives up on a target. 0 retries forever")
+	flag.BoolVar(&readStdin, "stdin", false, "Show the goroutine dumps read from stdin instead of polling a target")
+	flag.StringVar(&dumpFile, "file", "", "Show the goroutine dumps of the file instead of polling a target")
+	flag.StringVar(&dumpDir, "dir", "", "Show the goroutine dumps of all files in the directory as timeline")
+	flag.StringVar(&dirOrder, "dir-order", "name", "Order of the files in the directory: name or mtime")
+	flag.StringVar(&follow, "follow", "", "Show the goroutine dumps appended to the files matching the glob pattern, for example ./logs/*.log")
+	flag.IntVar(&pid, "pid", 0, "Send SIGQUIT to the local go process and show its goroutine dump. This terminates the process. Requires -pid-kill. Linux only")
+	flag.BoolVar(&pidKill, "pid-kill", false, "Confirm that -pid terminates the process after its dump")
+	flag.DurationVar(&pidTimeout, "pid-timeout", 5*time.Second, "Time to wait for the process to write its dump and exit after SIGQUIT")
+	flag.StringVar(&recordDir, "record", "", "Save every snapshot compressed to the directory, which -dir replays. Every target gets a subdirectory")
+	flag.StringVar(&sqliteFile, "sqlite", "", "Store every snapshot in the SQLite database file for SQL queries")
+	flag.StringVar(&otlpEndpoint, "otlp", "", "Push the goroutine counts of every snapshot as OpenTelemetry metrics to the OTLP/HTTP endpoint, for example http://localhost:4318")
+	flag.Var((*stringList)(&otlpHeaders), "otlp-header", "Additional header \"Name: value\" of the OTLP requests. Can be repeated. Env: OTEL_EXPORTER_OTLP_HEADERS")
+	flag.StringVar(&statsdAddr, "statsd", "", "Send the goroutine counts of every snapshot as gauges to the StatsD agent over UDP, for example localhost:8125")
+	flag.StringVar(&statsdPrefix, "statsd-prefix", "roumon", "Prefix of the StatsD metric names")
+	flag.BoolVar(&dogStatsD, "dogstatsd", false, "Send the target, status and function of the StatsD gauges as DogStatsD tags instead of parts of the names")
+	flag.Var((*stringList)(&statsdTags), "statsd-tag", "DogStatsD tag key:value added to every gauge, for example env:prod. Can be repeated")
+	flag.StringVar(&apiListen, "api-listen", "", "Address to serve the latest snapshots, stack groups and history as JSON at /snapshot, /groups and /history, for example localhost:7070")
+	uiOpts := registerUIFlags(flag.CommandLine)
 	flag.StringVar(&dbgFile, "debug", "", "Path to debug file")
 	flag.BoolVar(&versionFlag, "v", false, "Print version of roumon and exit")
 	flag.Parse()
 
-	version := "dev"
-	if info, ok := debug.ReadBuildInfo(); ok {
-		version = info.Main.Version
-	}
+	// Credentials should not be visible in the process list
+	authFromEnv(&opts)
+	envFallback(&grpcToken, "ROUMON_GRPC_TOKEN")
 
+	version := buildVersion()
 	if versionFlag {
 		fmt.Println(version)
 		return
 	}
 
-	if len(dbgFile) > 0 {
-		f, err := os.OpenFile(dbgFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	logs := ui.NewLogBuffer()
+	defer setupLog(dbgFile, logs)()
+	log.Printf("Start roumon (%s)", version)
+
+	cfg, err := uiOpts.loadConfig()
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(2)
+	}
+
+	if len(targetFile) > 0 {
+		fileTargets, err := readTargetFile(targetFile)
 		if err != nil {
-			log.Fatalf("error opening file: %v", err)
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+		targets = append(targets, fileTargets...)
+	}
+	var discoverers discovery.Multi
+	if len(k8s.Selector) > 0 {
+		discoverers = append(discoverers, &k8s)
+		defer k8s.Close()
+	}
+	if len(consul.Service) > 0 {
+		discoverers = append(discoverers, &consul)
+	}
+	if len(dns.Name) > 0 {
+		discoverers = append(discoverers, &dns)
+	}
+	offline := readStdin || len(dumpFile) > 0 || len(dumpDir) > 0 || len(follow) > 0 || pid > 0
+	if offline && (len(targets) > 0 || len(discoverers) > 0 || len(grpcListen) > 0) {
+		fmt.Println("stdin, file, dir, follow and pid cannot be combined with other targets")
+		os.Exit(2)
+	}
+	var snapshots []model.Snapshot
+	sources := 0
+	for _, set := range []bool{readStdin, len(dumpFile) > 0, len(dumpDir) > 0, len(follow) > 0, pid > 0} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		fmt.Println("only one of stdin, file, dir, follow and pid can be shown")
+		os.Exit(2)
+	}
+	if len(dumpFile) > 0 || len(dumpDir) > 0 || pid > 0 {
+		var err error
+		switch {
+		case pid > 0 && !pidKill:
+			err = fmt.Errorf("pid terminates process %d after its dump. Add -pid-kill to confirm", pid)
+		case pid > 0:
+			snapshots, err = dump.Attach(pid, pidTimeout)
+		case dirOrder != "name" && dirOrder != "mtime":
+			err = fmt.Errorf("dir-order must be name or mtime, but got: %s", dirOrder)
+		case len(dumpFile) > 0:
+			snapshots, err = dump.LoadFile(dumpFile)
+		default:
+			snapshots, err = dump.LoadDir(dumpDir, dirOrder == "mtime")
+		}
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+	}
+
+	var c *client.Pool
+	var controller ui.Controller
+	if !offline {
+		if len(targets) == 0 && len(discoverers) == 0 && len(grpcListen) == 0 {
+			targets = append(targets, net.JoinHostPort(host, strconv.Itoa(port)))
+		}
+		var err error
+		c, err = client.NewPool(targets, opts)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+		controller = c
+	}
+	var grpcListener net.Listener
+	if len(grpcListen) > 0 {
+		var err error
+		grpcListener, err = net.Listen("tcp", grpcListen)
+		if err != nil {
+			fmt.Printf("failed to listen for gRPC pushes. Err: %s\n", err.Error())
+			os.Exit(2)
+		}
+	}
+	var apiListener net.Listener
+	if len(apiListen) > 0 {
+		var err error
+		if apiListener, err = net.Listen("tcp", apiListen); err != nil {
+			fmt.Printf("failed to listen for API requests. Err: %s\n", err.Error())
+			os.Exit(2)
+		}
+		log.Printf("Serve the API on %s", apiListener.Addr())
+	}
+	var recorder *dump.Recorder
+	if len(recordDir) > 0 {
+		var err error
+		if recorder, err = dump.NewRecorder(recordDir); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+	}
+	var db *store.SQLite
+	if len(sqliteFile) > 0 {
+		var err error
+		if db, err = store.OpenSQLite(sqliteFile); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(2)
 		}
 		defer func() {
-			if err := f.Close(); err != nil {
-				log.Printf("error closing file: %v", err)
+			if err := db.Close(); err != nil {
+				log.Print(err.Error())
 			}
 		}()
-		log.SetOutput(f)
-	} else {
-		log.SetOutput(io.Discard)
 	}
+	var exporter *otlp.Exporter
+	if len(otlpEndpoint) > 0 {
+		var err error
+		if exporter, err = otlp.NewExporter(otlpEndpoint, otlpHeaders, opts.FetchTimeout, version); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+	}
+	var statsdClient *statsd.Client
+	if len(statsdAddr) > 0 {
+		var err error
+		if statsdClient, err = statsd.NewClient(statsdAddr, statsdPrefix, dogStatsD, statsdTags); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+		defer statsdClient.Close()
+	}
+	ui := ui.NewUI(controller)
+	if len(snapshots) > 0 {
+		// The snapshots of the files are in memory already
+		ui.KeepTimeline()
+	}
+	if err := uiOpts.configure(ui, cfg); err != nil {
+		ui.Stop()
+		fmt.Println(err.Error())
+		os.Exit(2)
+	}
+	ui.SetLog(logs)
 
-	log.Printf("Start roumon (%s)", version)
+	terminate := make(chan error)
 
-	c := client.NewClient(host, port)
-	ui := ui.NewUI()
+	routinesUpdate := make(chan model.Snapshot)
+	statusUpdate := make(chan model.FetchStatus)
+	shown := routinesUpdate
+	if recorder != nil {
+		recorded := make(chan model.Snapshot)
+		go recorder.Tee(shown, recorded)
+		shown = recorded
+	}
+	if db != nil {
+		stored := make(chan model.Snapshot)
+		go db.Tee(shown, stored)
+		shown = stored
+	}
+	if exporter != nil {
+		pushed := make(chan model.Snapshot)
+		go exporter.Tee(shown, pushed)
+		shown = pushed
+	}
+	if statsdClient != nil {
+		sent := make(chan model.Snapshot)
+		go statsdClient.Tee(shown, sent)
+		shown = sent
+	}
+	if apiListener != nil {
+		apiServer := api.NewServer()
+		served := make(chan model.Snapshot)
+		go apiServer.Tee(shown, served)
+		shown = served
+		go func() {
+			if err := http.Serve(apiListener, apiServer); err != nil {
+				terminate <- fmt.Errorf("API server failed. Err: %s", err.Error())
+			}
+		}()
+	}
+	go ui.Run(terminate, shown, statusUpdate)
+	switch {
+	case readStdin:
+		// Offline mode without polling
+		go func() {
+			if err := dump.Read(os.Stdin, "stdin", routinesUpdate); err != nil {
+				terminate <- fmt.Errorf("failed to read stdin. Err: %s", err.Error())
+			}
+		}()
+	case len(follow) > 0:
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			if err := dump.Follow(ctx, follow, opts.Interval, routinesUpdate); err != nil {
+				terminate <- err
+			}
+		}()
+	case offline:
+		go func() {
+			for _, snapshot := range snapshots {
+				routinesUpdate <- snapshot
+			}
+		}()
+	default:
+		go c.Run(terminate, routinesUpdate, statusUpdate)
+		defer c.Stop()
+	}
 
-	terminate := make(chan error)
+	if grpcListener != nil {
+		var serverOpts []grpc.ServerOption
+		if len(grpcToken) > 0 {
+			serverOpts = append(serverOpts, roumongrpc.TokenAuth(grpcToken))
+		}
+		server := grpc.NewServer(serverOpts...)
+		pushed := collector.New(routinesUpdate, statusUpdate)
+		roumongrpc.RegisterSnapshotServer(server, pushed)
+		log.Printf("Receive pushed snapshots on %s", grpcListener.Addr())
+		go func() {
+			if err := server.Serve(grpcListener); err != nil {
+				terminate <- fmt.Errorf("gRPC server failed. Err: %s", err.Error())
+			}
+		}()
+		defer server.Stop()
+		defer pushed.Stop()
+	}
 
-	routinesUpdate := make(chan []model.Goroutine)
-	go c.Run(terminate, routinesUpdate)
-	go ui.Run(terminate, routinesUpdate)
+	if len(discoverers) > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan struct{})
+		go func() {
+			discovery.Run(ctx, discoverers, discoveryInterval, c.Sync)
+			close(stopped)
+		}()
+		defer func() {
+			cancel()
+			<-stopped
+		}()
+	}
 
-	err := <-terminate
+	err = <-terminate
 	ui.Stop()
 
 	if err != nil {
@@ -70,3 +401,138 @@ func main() {
 
 	log.Print("Stopped")
 }
+
+// setupLog writes the log to the debug file and the log panel of the TUI. The log is discarded if neither is
+// given. The returned function closes the file.
+func setupLog(dbgFile string, panel *ui.LogBuffer) func() {
+	var writers []io.Writer
+	if panel != nil {
+		writers = append(writers, panel)
+	}
+	if len(dbgFile) == 0 {
+		// Discarded without writers
+		log.SetOutput(io.MultiWriter(writers...))
+		return func() {}
+	}
+	f, err := os.OpenFile(dbgFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		log.Fatalf("error opening file: %v", err)
+	}
+	log.SetOutput(io.MultiWriter(append(writers, f)...))
+	return func() {
+		if err := f.Close(); err != nil {
+			log.Printf("error closing file: %v", err)
+		}
+	}
+}
+
+// buildVersion returns the module version of roumon or dev if it was not built as module
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+// envFallback sets value to the environment variable if it was not set by a flag
+func envFallback(value *string, envVar string) {
+	if len(*value) == 0 {
+		*value = os.Getenv(envVar)
+	}
+}
+
+// authFromEnv reads the credentials from the environment if no auth flag is set.
+// An auth flag replaces all variables, otherwise a flag and an exported variable of another method would conflict
+func authFromEnv(opts *client.Options) {
+	if len(opts.BasicAuth) > 0 || len(opts.BearerToken) > 0 || len(opts.TokenFile) > 0 {
+		return
+	}
+	opts.BasicAuth = os.Getenv("ROUMON_BASIC_AUTH")
+	opts.BearerToken = os.Getenv("ROUMON_BEARER_TOKEN")
+	opts.TokenFile = os.Getenv("ROUMON_TOKEN_FILE")
+}
+
+// supportsTrueColor returns true if the terminal announces 24 bit colors
+func supportsTrueColor() bool {
+	colorTerm := os.Getenv("COLORTERM")
+	return colorTerm == "truecolor" || colorTerm == "24bit"
+}
+
+// stringList is a flag which can be repeated
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// commaList is a flag with comma separated values
+type commaList []string
+
+func (l *commaList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *commaList) Set(value string) error {
+	*l = nil
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// pathMap is a repeatable flag which maps a build path prefix to a local path prefix
+type pathMap map[string]string
+
+func (m pathMap) String() string {
+	var pairs []string
+	for build, local := range m {
+		pairs = append(pairs, build+"="+local)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+func (m pathMap) Set(value string) error {
+	build, local, ok := strings.Cut(value, "=")
+	if !ok || len(build) == 0 || len(local) == 0 {
+		return fmt.Errorf("expected build=local instead of %s", value)
+	}
+	m[build] = local
+	return nil
+}
+
+// merge returns the paths of the config file overridden by the paths of the flag
+func (m pathMap) merge(paths map[string]string) map[string]string {
+	merged := make(map[string]string, len(paths)+len(m))
+	for build, local := range paths {
+		merged[build] = local
+	}
+	for build, local := range m {
+		merged[build] = local
+	}
+	return merged
+}
+
+// readTargetFile returns all targets of the file
+func readTargetFile(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read target file. Err: %s", err.Error())
+	}
+	var targets []string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		targets = append(targets, line)
+	}
+	return targets, nil
+}
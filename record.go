@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/becheran/roumon/internal/client"
+	"github.com/becheran/roumon/internal/dump"
+	"github.com/becheran/roumon/internal/model"
+	"github.com/becheran/roumon/internal/otlp"
+	"github.com/becheran/roumon/internal/statsd"
+	"github.com/becheran/roumon/internal/store"
+)
+
+// record saves every fetched snapshot to a directory, which -dir replays, or to an SQLite database, or sends its
+// goroutine counts to an OTLP endpoint or a StatsD agent without running the TUI until roumon is interrupted.
+func record(args []string) {
+	flags := flag.NewFlagSet("roumon record", flag.ExitOnError)
+	var targets stringList
+	var opts client.Options
+	out := flags.String("out", "", "Directory of the recording. Every target gets a subdirectory")
+	sqliteFile := flags.String("sqlite", "", "Store every snapshot in the SQLite database file for SQL queries")
+	otlpEndpoint := flags.String("otlp", "", "Push the goroutine counts of every snapshot as OpenTelemetry metrics to the OTLP/HTTP endpoint, for example http://localhost:4318")
+	var otlpHeaders stringList
+	flags.Var(&otlpHeaders, "otlp-header", "Additional header \"Name: value\" of the OTLP requests. Can be repeated. Env: OTEL_EXPORTER_OTLP_HEADERS")
+	statsdAddr := flags.String("statsd", "", "Send the goroutine counts of every snapshot as gauges to the StatsD agent over UDP, for example localhost:8125")
+	statsdPrefix := flags.String("statsd-prefix", "roumon", "Prefix of the StatsD metric names")
+	dogStatsD := flags.Bool("dogstatsd", false, "Send the target, status and function of the StatsD gauges as DogStatsD tags instead of parts of the names")
+	var statsdTags stringList
+	flags.Var(&statsdTags, "statsd-tag", "DogStatsD tag key:value added to every gauge, for example env:prod. Can be repeated")
+	host := flags.String("host", "localhost", "The pprof server IP or hostname")
+	port := flags.Int("port", 6060, "The pprof server port")
+	flags.Var(&targets, "target", "The pprof server as URL or unix socket. Can be repeated. Overrides host and port")
+	flags.DurationVar(&opts.Interval, "interval", 2*time.Second, "Time between two snapshots")
+	flags.DurationVar(&opts.FetchTimeout, "fetch-timeout", 10*time.Second, "Timeout of a single request to the pprof server. 0 disables the timeout")
+	flags.IntVar(&opts.MaxRetries, "max-retries", 0, "Consecutive failed fetches until roumon gives up on a target. 0 retries forever")
+	flags.StringVar(&opts.BasicAuth, "basic-auth", "", "Basic auth credentials user:password. Env: ROUMON_BASIC_AUTH")
+	flags.StringVar(&opts.BearerToken, "bearer-token", "", "Bearer token for the pprof server. Env: ROUMON_BEARER_TOKEN")
+	flags.BoolVar(&opts.Insecure, "insecure", false, "Skip verification of the pprof server certificate")
+	flags.Var((*stringList)(&opts.Headers), "header", "Additional request header \"Name: value\". Can be repeated")
+	dbgFile := flags.String("debug", "", "Path to debug file")
+	_ = flags.Parse(args)
+	authFromEnv(&opts)
+
+	defer setupLog(*dbgFile, nil)()
+
+	if len(*out) == 0 && len(*sqliteFile) == 0 && len(*otlpEndpoint) == 0 && len(*statsdAddr) == 0 {
+		fmt.Println("record needs -out, -sqlite, -otlp or -statsd")
+		os.Exit(2)
+	}
+	var saves []func(model.Snapshot) (string, error)
+	if len(*out) > 0 {
+		recorder, err := dump.NewRecorder(*out)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+		saves = append(saves, recorder.Save)
+	}
+	if len(*sqliteFile) > 0 {
+		db, err := store.OpenSQLite(*sqliteFile)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+		defer func() {
+			if err := db.Close(); err != nil {
+				fmt.Println(err.Error())
+			}
+		}()
+		saves = append(saves, func(snapshot model.Snapshot) (string, error) { return *sqliteFile, db.Save(snapshot) })
+	}
+	if len(*otlpEndpoint) > 0 {
+		exporter, err := otlp.NewExporter(*otlpEndpoint, otlpHeaders, opts.FetchTimeout, buildVersion())
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+		saves = append(saves, func(snapshot model.Snapshot) (string, error) {
+			return exporter.Endpoint(), exporter.Push(context.Background(), snapshot)
+		})
+	}
+	if len(*statsdAddr) > 0 {
+		sender, err := statsd.NewClient(*statsdAddr, *statsdPrefix, *dogStatsD, statsdTags)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+		defer sender.Close()
+		saves = append(saves, func(snapshot model.Snapshot) (string, error) { return *statsdAddr, sender.Send(snapshot) })
+	}
+	if len(targets) == 0 {
+		targets = append(targets, net.JoinHostPort(*host, strconv.Itoa(*port)))
+	}
+	pool, err := client.NewPool(targets, opts)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(2)
+	}
+	terminate := make(chan error)
+	routinesUpdate := make(chan model.Snapshot)
+	pool.Run(terminate, routinesUpdate, nil)
+	defer pool.Stop()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	for {
+		select {
+		case err := <-terminate:
+			fmt.Println(err.Error())
+			log.Print(err.Error())
+			os.Exit(1)
+		case snapshot := <-routinesUpdate:
+			for _, save := range saves {
+				path, err := save(snapshot)
+				if err != nil {
+					fmt.Printf("%s: failed to record snapshot: %s\n", snapshot.Target, err.Error())
+					continue
+				}
+				fmt.Printf("%s %s %d goroutines %s\n", snapshot.Time.Format(time.TimeOnly), snapshot.Target,
+					len(snapshot.Goroutines), path)
+			}
+		case <-interrupt:
+			return
+		}
+	}
+}
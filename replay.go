@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/becheran/roumon/internal/bundle"
+	"github.com/becheran/roumon/internal/dump"
+	"github.com/becheran/roumon/internal/model"
+	"github.com/becheran/roumon/internal/ui"
+)
+
+// replay plays a session back which was recorded with -record or roumon record, or the recording of a bundle
+func replay(args []string) {
+	flags := flag.NewFlagSet("roumon replay", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: roumon replay [flags] <recording directory or bundle>")
+		flags.PrintDefaults()
+	}
+	uiOpts := registerUIFlags(flags)
+	dbgFile := flags.String("debug", "", "Path to debug file")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	cfg, err := uiOpts.loadConfig()
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(2)
+	}
+
+	dir := flags.Arg(0)
+	if strings.HasSuffix(dir, bundleExt) {
+		// A bundle is unpacked to a temporary directory for the time of the replay
+		tmp, err := os.MkdirTemp("", "roumon-replay-")
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		defer func() { _ = os.RemoveAll(tmp) }()
+		if _, err := extractBundle(dir, tmp); err != nil {
+			_ = os.RemoveAll(tmp)
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+		dir = filepath.Join(tmp, bundle.SessionDir)
+	}
+	snapshots, err := dump.LoadSession(dir)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(2)
+	}
+	if len(snapshots) == 0 {
+		fmt.Printf("no snapshots recorded in %s\n", flags.Arg(0))
+		os.Exit(2)
+	}
+
+	logs := ui.NewLogBuffer()
+	defer setupLog(*dbgFile, logs)()
+
+	ui := ui.NewUI(nil)
+	ui.KeepTimeline()
+	ui.SetReplay()
+	if err := uiOpts.configure(ui, cfg); err != nil {
+		ui.Stop()
+		fmt.Println(err.Error())
+		os.Exit(2)
+	}
+	ui.SetLog(logs)
+
+	terminate := make(chan error)
+	routinesUpdate := make(chan model.Snapshot)
+	go ui.Run(terminate, routinesUpdate, make(chan model.FetchStatus))
+	go func() {
+		for _, snapshot := range snapshots {
+			routinesUpdate <- snapshot
+		}
+	}()
+
+	err = <-terminate
+	ui.Stop()
+	if err != nil {
+		fmt.Println(err.Error())
+		log.Print(err.Error())
+	}
+	log.Print("Stopped")
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/becheran/roumon/internal/dump"
+	"github.com/becheran/roumon/internal/model"
+	"github.com/becheran/roumon/internal/report"
+)
+
+// htmlReport writes a saved dump or recording as standalone HTML page, which is shared with people who do not run
+// roumon themselves
+func htmlReport(args []string) {
+	flags := flag.NewFlagSet("roumon report", flag.ExitOnError)
+	htmlFile := flags.String("html", "", "Path of the HTML file to write. - writes to stdout")
+	dumpFile := flags.String("file", "", "Report the goroutine dumps of the file")
+	dumpDir := flags.String("dir", "", "Report the goroutine dumps of all files in the directory as recording")
+	dirOrder := flags.String("dir-order", "name", "Order of the files of -dir: name or mtime")
+	_ = flags.Parse(args)
+
+	if len(*htmlFile) == 0 || (len(*dumpFile) > 0) == (len(*dumpDir) > 0) {
+		fmt.Println("report needs -html and either -file or -dir")
+		os.Exit(2)
+	}
+	if *dirOrder != "name" && *dirOrder != "mtime" {
+		fmt.Printf("dir-order must be name or mtime, but got: %s\n", *dirOrder)
+		os.Exit(2)
+	}
+	var snapshots []model.Snapshot
+	var err error
+	if len(*dumpFile) > 0 {
+		snapshots, err = dump.LoadFile(*dumpFile)
+	} else {
+		snapshots, err = dump.LoadDir(*dumpDir, *dirOrder == "mtime")
+	}
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(2)
+	}
+
+	out := os.Stdout
+	if *htmlFile != "-" {
+		if out, err = os.Create(*htmlFile); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	}
+	if err = report.Write(out, snapshots, time.Now()); err == nil && out != os.Stdout {
+		err = out.Close()
+	}
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,46 @@
+package roumongrpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authorizationKey is the metadata key of the token
+const authorizationKey = "authorization"
+
+// TokenAuth is the server option which rejects all streams without the bearer token
+func TokenAuth(token string) grpc.ServerOption {
+	return grpc.StreamInterceptor(func(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		for _, value := range md.Get(authorizationKey) {
+			got, ok := strings.CutPrefix(value, "Bearer ")
+			if ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1 {
+				return handler(srv, stream)
+			}
+		}
+		return status.Error(codes.Unauthenticated, "invalid or missing bearer token")
+	})
+}
+
+// Token is the dial option which sends the bearer token with every stream. Use transport security to not send the
+// token in plain text.
+func Token(token string) grpc.DialOption {
+	return grpc.WithPerRPCCredentials(tokenCredentials(token))
+}
+
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{authorizationKey: "Bearer " + string(t)}, nil
+}
+
+func (tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
@@ -0,0 +1,149 @@
+// Package roumongrpc defines the gRPC service which lets monitored apps push goroutine snapshots to roumon.
+//
+// The app dials out to roumon and streams its snapshots, so roumon does not have to poll the app and the app
+// may run behind NAT. The messages are the JSON types of roumonhttp, encoded with the codec CodecName instead of
+// protobuf. Clients in other languages set the content type application/grpc+roumon-json and send JSON
+// messages. See doc/endpoint.md for the specification.
+//
+//	conn, err := grpc.NewClient("roumon.example.com:7777", grpc.WithTransportCredentials(insecure.NewCredentials()))
+//	stream, err := roumongrpc.NewSnapshotClient(conn).StreamSnapshots(ctx)
+//	err = stream.Send(&roumongrpc.Snapshot{Source: "api-1", Dump: dump})
+package roumongrpc
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/becheran/roumon/roumonhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+const (
+	// ServiceName is the full name of the gRPC service
+	ServiceName = "roumon.v1.Roumon"
+	// StreamSnapshotsMethod is the full method name of the client streaming call
+	StreamSnapshotsMethod = "/" + ServiceName + "/StreamSnapshots"
+	// CodecName is the content subtype of the messages
+	CodecName = "roumon-json"
+)
+
+func init() {
+	encoding.RegisterCodec(codec{})
+}
+
+// Snapshot is the message pushed by the app
+type Snapshot struct {
+	Source string          `json:"source"` // Name of the app instance. Defaults to the peer address
+	Dump   roumonhttp.Dump `json:"dump"`
+}
+
+// Ack is returned by roumon once the app closes the stream
+type Ack struct {
+	Received int `json:"received"` // Number of received snapshots
+}
+
+// SnapshotServer is the server API of the service
+type SnapshotServer interface {
+	StreamSnapshots(stream SnapshotStream) error
+}
+
+// SnapshotStream is the server side of a stream of snapshots
+type SnapshotStream interface {
+	Recv() (*Snapshot, error)
+	SendAndClose(ack *Ack) error
+	Context() context.Context
+}
+
+// RegisterSnapshotServer registers the service at the gRPC server
+func RegisterSnapshotServer(registrar grpc.ServiceRegistrar, srv SnapshotServer) {
+	registrar.RegisterService(&serviceDesc, srv)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*SnapshotServer)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamSnapshots",
+			Handler:       streamSnapshotsHandler,
+			ClientStreams: true,
+		},
+	},
+}
+
+func streamSnapshotsHandler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SnapshotServer).StreamSnapshots(&serverStream{stream})
+}
+
+type serverStream struct {
+	grpc.ServerStream
+}
+
+func (s *serverStream) Recv() (*Snapshot, error) {
+	snapshot := new(Snapshot)
+	if err := s.RecvMsg(snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func (s *serverStream) SendAndClose(ack *Ack) error {
+	return s.SendMsg(ack)
+}
+
+// SnapshotClient is the client API of the service
+type SnapshotClient struct {
+	conn grpc.ClientConnInterface
+}
+
+// NewSnapshotClient creates a client which uses the connection
+func NewSnapshotClient(conn grpc.ClientConnInterface) *SnapshotClient {
+	return &SnapshotClient{conn: conn}
+}
+
+// StreamSnapshots opens a stream to push snapshots
+func (c *SnapshotClient) StreamSnapshots(ctx context.Context, opts ...grpc.CallOption) (*SnapshotSender, error) {
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(CodecName)}, opts...)
+	stream, err := c.conn.NewStream(ctx, &serviceDesc.Streams[0], StreamSnapshotsMethod, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &SnapshotSender{stream}, nil
+}
+
+// SnapshotSender is the client side of a stream of snapshots
+type SnapshotSender struct {
+	stream grpc.ClientStream
+}
+
+// Send pushes a snapshot
+func (s *SnapshotSender) Send(snapshot *Snapshot) error {
+	return s.stream.SendMsg(snapshot)
+}
+
+// CloseAndRecv closes the stream and waits for the acknowledgment of roumon
+func (s *SnapshotSender) CloseAndRecv() (*Ack, error) {
+	if err := s.stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	ack := new(Ack)
+	if err := s.stream.RecvMsg(ack); err != nil {
+		return nil, err
+	}
+	return ack, nil
+}
+
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (codec) Name() string {
+	return CodecName
+}
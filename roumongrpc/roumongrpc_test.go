@@ -0,0 +1,97 @@
+package roumongrpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/becheran/roumon/roumongrpc"
+	"github.com/becheran/roumon/roumonhttp"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type server struct {
+	received chan *roumongrpc.Snapshot
+}
+
+func (s *server) StreamSnapshots(stream roumongrpc.SnapshotStream) error {
+	count := 0
+	for {
+		snapshot, err := stream.Recv()
+		if err != nil {
+			return stream.SendAndClose(&roumongrpc.Ack{Received: count})
+		}
+		count++
+		s.received <- snapshot
+	}
+}
+
+func TestStreamSnapshots(t *testing.T) {
+	listener := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer()
+	received := make(chan *roumongrpc.Snapshot, 2)
+	roumongrpc.RegisterSnapshotServer(srv, &server{received: received})
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	stream, err := roumongrpc.NewSnapshotClient(conn).StreamSnapshots(context.Background())
+	assert.Nil(t, err)
+	dump, err := roumonhttp.Collect()
+	assert.Nil(t, err)
+	assert.Nil(t, stream.Send(&roumongrpc.Snapshot{Source: "app", Dump: dump}))
+	assert.Nil(t, stream.Send(&roumongrpc.Snapshot{Source: "app", Dump: dump}))
+	ack, err := stream.CloseAndRecv()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, ack.Received)
+
+	snapshot := <-received
+	assert.Equal(t, "app", snapshot.Source)
+	assert.Equal(t, len(dump.Goroutines), len(snapshot.Dump.Goroutines))
+}
+
+func TestTokenAuth(t *testing.T) {
+	listener := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer(roumongrpc.TokenAuth("secret"))
+	received := make(chan *roumongrpc.Snapshot, 1)
+	roumongrpc.RegisterSnapshotServer(srv, &server{received: received})
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	defer srv.Stop()
+
+	push := func(token string) error {
+		conn, err := grpc.NewClient("passthrough:///bufnet",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			roumongrpc.Token(token))
+		assert.Nil(t, err)
+		defer conn.Close()
+		stream, err := roumongrpc.NewSnapshotClient(conn).StreamSnapshots(context.Background())
+		if err != nil {
+			return err
+		}
+		_ = stream.Send(&roumongrpc.Snapshot{Source: "app"})
+		_, err = stream.CloseAndRecv()
+		return err
+	}
+	assert.Equal(t, codes.Unauthenticated, status.Code(push("wrong")))
+	assert.Nil(t, push("secret"))
+	assert.Equal(t, "app", (<-received).Source)
+}
@@ -0,0 +1,180 @@
+// Package roumonhttp serves goroutine info in the format roumon understands best.
+//
+// Mounting the handler is optional. roumon falls back to the text dumps of net/http/pprof if the endpoint is
+// not available. When it is, roumon receives labels and build info in one call and the target does not have to
+// render the full text dump. See doc/endpoint.md for the specification.
+//
+//	mux := http.NewServeMux()
+//	mux.Handle(roumonhttp.Path, roumonhttp.Handler())
+//
+// Or wrap an existing handler:
+//
+//	http.ListenAndServe("localhost:6060", roumonhttp.Middleware(http.DefaultServeMux))
+package roumonhttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"runtime/pprof"
+	"strings"
+	"time"
+
+	"github.com/becheran/roumon/internal/model"
+)
+
+const (
+	// Path where roumon expects the endpoint
+	Path = "/debug/roumon"
+	// Version of the response format
+	Version = 1
+	// ContentType of the response
+	ContentType = "application/json"
+)
+
+// Dump is the response of the endpoint
+type Dump struct {
+	Version    int              `json:"version"`
+	Time       time.Time        `json:"time"`
+	Build      *debug.BuildInfo `json:"build,omitempty"`
+	Goroutines []Goroutine      `json:"goroutines"`
+}
+
+// Goroutine info of a single goroutine
+type Goroutine struct {
+	ID             int64             `json:"id"`
+	Status         string            `json:"status"`
+	WaitMinutes    int64             `json:"waitMinutes,omitempty"`
+	LockedToThread bool              `json:"lockedToThread,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Stack          []Frame           `json:"stack"`
+	CreatedBy      *Frame            `json:"createdBy,omitempty"`
+}
+
+// Frame is one stack frame
+type Frame struct {
+	Func   string `json:"func"`
+	File   string `json:"file"`
+	Line   int32  `json:"line"`
+	Offset *int   `json:"offset,omitempty"`
+}
+
+// Handler returns the handler which serves the goroutine info as JSON
+func Handler() http.Handler {
+	return http.HandlerFunc(serve)
+}
+
+// Middleware serves the endpoint at Path and forwards all other requests to next
+func Middleware(next http.Handler) http.Handler {
+	handler := Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == Path {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Collect creates the dump of all goroutines of the running program
+func Collect() (Dump, error) {
+	profile := pprof.Lookup("goroutine")
+
+	var groups bytes.Buffer
+	if err := profile.WriteTo(&groups, 1); err != nil {
+		return Dump{}, err
+	}
+	var stacks bytes.Buffer
+	if err := profile.WriteTo(&stacks, 2); err != nil {
+		return Dump{}, err
+	}
+
+	result, err := model.ParseDump(&stacks)
+	if err != nil {
+		return Dump{}, err
+	}
+	labelGroups, err := model.ParseLabelGroups(&groups)
+	if err != nil {
+		return Dump{}, err
+	}
+	model.AssignLabels(result.Goroutines, labelGroups)
+
+	dump := Dump{
+		Version:    Version,
+		Time:       time.Now(),
+		Goroutines: make([]Goroutine, len(result.Goroutines)),
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		dump.Build = info
+	}
+	for i, g := range result.Goroutines {
+		dump.Goroutines[i] = fromModel(g)
+	}
+	return dump, nil
+}
+
+func serve(w http.ResponseWriter, r *http.Request) {
+	dump, err := Collect()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Add("Vary", "Accept-Encoding")
+	var out io.Writer = w
+	if acceptsGzip(r) {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer func() {
+			if err := gz.Close(); err != nil {
+				log.Printf("roumonhttp: failed to write response. Err: %s", err.Error())
+			}
+		}()
+		out = gz
+	}
+	if err := json.NewEncoder(out).Encode(dump); err != nil {
+		log.Printf("roumonhttp: failed to write response. Err: %s", err.Error())
+	}
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(encoding), ";")
+		if strings.TrimSpace(name) == "gzip" && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+func fromModel(g model.Goroutine) Goroutine {
+	routine := Goroutine{
+		ID:             g.ID,
+		Status:         g.Status,
+		WaitMinutes:    g.WaitSinceMin,
+		LockedToThread: g.LockedToThread,
+		Labels:         g.Labels,
+		Stack:          make([]Frame, len(g.StackTrace)),
+	}
+	for i, f := range g.StackTrace {
+		routine.Stack[i] = fromModelFrame(f)
+	}
+	if g.CratedBy != nil {
+		createdBy := fromModelFrame(*g.CratedBy)
+		routine.CreatedBy = &createdBy
+	}
+	return routine
+}
+
+func fromModelFrame(f model.StackFrame) Frame {
+	return Frame{
+		Func:   f.FuncName,
+		File:   f.File,
+		Line:   f.Line,
+		Offset: f.Position,
+	}
+}
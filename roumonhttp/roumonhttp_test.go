@@ -0,0 +1,75 @@
+package roumonhttp_test
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/becheran/roumon/roumonhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlerServesLabels(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	started := make(chan struct{})
+	go pprof.Do(context.Background(), pprof.Labels("tenant", "acme"), func(context.Context) {
+		close(started)
+		<-block
+	})
+	<-started
+
+	server := httptest.NewServer(roumonhttp.Middleware(http.NotFoundHandler()))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + roumonhttp.Path)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, roumonhttp.ContentType, resp.Header.Get("Content-Type"))
+
+	var dump roumonhttp.Dump
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&dump))
+	assert.Equal(t, roumonhttp.Version, dump.Version)
+	assert.NotEmpty(t, dump.Goroutines)
+
+	found := false
+	for _, g := range dump.Goroutines {
+		if g.Labels["tenant"] == "acme" {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestMiddlewareForwards(t *testing.T) {
+	server := httptest.NewServer(roumonhttp.Middleware(http.NotFoundHandler()))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/other")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestHandlerCompresses(t *testing.T) {
+	server := httptest.NewServer(roumonhttp.Handler())
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	assert.Nil(t, err)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+
+	gz, err := gzip.NewReader(resp.Body)
+	assert.Nil(t, err)
+	var dump roumonhttp.Dump
+	assert.Nil(t, json.NewDecoder(gz).Decode(&dump))
+	assert.NotEmpty(t, dump.Goroutines)
+}
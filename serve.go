@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/becheran/roumon/internal/api"
+	"github.com/becheran/roumon/internal/collector"
+	"github.com/becheran/roumon/internal/metrics"
+	"github.com/becheran/roumon/internal/model"
+	"github.com/becheran/roumon/internal/ui"
+	"github.com/becheran/roumon/roumongrpc"
+	"google.golang.org/grpc"
+)
+
+// serve runs roumon as collector for the snapshots which many programs push with the agent. Programs which
+// disconnect are kept with their history.
+func serve(args []string) {
+	flags := flag.NewFlagSet("roumon serve", flag.ExitOnError)
+	listen := flags.String("listen", ":7777", "Address to receive the snapshots pushed by the monitored programs over gRPC")
+	token := flags.String("token", "", "Bearer token which the pushing programs have to send. Env: ROUMON_GRPC_TOKEN")
+	metricsListen := flags.String("metrics-listen", "", "Address to serve the goroutine counts of the latest snapshots to Prometheus at /metrics, for example :9090")
+	apiListen := flags.String("api-listen", "", "Address to serve the latest snapshots, stack groups and history as JSON at /snapshot, /groups and /history, for example localhost:7070")
+	history := flags.Int("history", 1000, "Number of snapshots kept of every program to browse back in time. Programs with many goroutines keep fewer")
+	uiOpts := registerUIFlags(flags)
+	dbgFile := flags.String("debug", "", "Path to debug file")
+	_ = flags.Parse(args)
+	envFallback(token, "ROUMON_GRPC_TOKEN")
+	cfg, err := uiOpts.loadConfig()
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(2)
+	}
+
+	logs := ui.NewLogBuffer()
+	defer setupLog(*dbgFile, logs)()
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Printf("failed to listen for gRPC pushes. Err: %s\n", err.Error())
+		os.Exit(2)
+	}
+	log.Printf("Start roumon collector on %s", listener.Addr())
+	var metricsListener net.Listener
+	if len(*metricsListen) > 0 {
+		if metricsListener, err = net.Listen("tcp", *metricsListen); err != nil {
+			fmt.Printf("failed to listen for metrics scrapes. Err: %s\n", err.Error())
+			os.Exit(2)
+		}
+		log.Printf("Serve metrics on %s/metrics", metricsListener.Addr())
+	}
+	var apiListener net.Listener
+	if len(*apiListen) > 0 {
+		if apiListener, err = net.Listen("tcp", *apiListen); err != nil {
+			fmt.Printf("failed to listen for API requests. Err: %s\n", err.Error())
+			os.Exit(2)
+		}
+		log.Printf("Serve the API on %s", apiListener.Addr())
+	}
+
+	routinesUpdate := make(chan model.Snapshot)
+	statusUpdate := make(chan model.FetchStatus)
+	c := collector.New(routinesUpdate, statusUpdate)
+	c.SetKeepDisconnected(true)
+	var serverOpts []grpc.ServerOption
+	if len(*token) > 0 {
+		serverOpts = append(serverOpts, roumongrpc.TokenAuth(*token))
+	}
+	server := grpc.NewServer(serverOpts...)
+	roumongrpc.RegisterSnapshotServer(server, c)
+
+	ui := ui.NewUI(nil)
+	ui.SetHistory(*history)
+	if err := uiOpts.configure(ui, cfg); err != nil {
+		ui.Stop()
+		fmt.Println(err.Error())
+		os.Exit(2)
+	}
+	ui.SetLog(logs)
+	terminate := make(chan error)
+	shown := routinesUpdate
+	if metricsListener != nil {
+		exporter := metrics.NewExporter()
+		scraped := make(chan model.Snapshot)
+		go exporter.Tee(routinesUpdate, scraped)
+		shown = scraped
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", exporter)
+		go func() {
+			if err := http.Serve(metricsListener, mux); err != nil {
+				terminate <- fmt.Errorf("metrics server failed. Err: %s", err.Error())
+			}
+		}()
+	}
+	if apiListener != nil {
+		apiServer := api.NewServer()
+		served := make(chan model.Snapshot)
+		go apiServer.Tee(shown, served)
+		shown = served
+		go func() {
+			if err := http.Serve(apiListener, apiServer); err != nil {
+				terminate <- fmt.Errorf("API server failed. Err: %s", err.Error())
+			}
+		}()
+	}
+	go ui.Run(terminate, shown, statusUpdate)
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			terminate <- fmt.Errorf("gRPC server failed. Err: %s", err.Error())
+		}
+	}()
+
+	err = <-terminate
+	ui.Stop()
+	c.Stop()
+	server.Stop()
+	if err != nil {
+		fmt.Println(err.Error())
+		log.Print(err.Error())
+	}
+	log.Print("Stopped")
+}
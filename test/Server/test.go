@@ -1,6 +1,7 @@
 package main
 
 import (
+	"expvar"
 	"log"
 	"math/rand"
 	"net/http"
@@ -9,6 +10,8 @@ import (
 	"time"
 )
 
+var spawned = expvar.NewInt("spawned")
+
 // Start a test server which can be used to monitor go routines
 func main() {
 	go func() {
@@ -17,6 +20,7 @@ func main() {
 	go func() {
 		for {
 			time.Sleep(1 * time.Second)
+			spawned.Add(1)
 			go func() {
 				min := 10
 				max := 5000
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/becheran/roumon/internal/client"
+	"github.com/becheran/roumon/internal/dump"
+	"github.com/becheran/roumon/internal/model"
+)
+
+// top prints the goroutines grouped by their stacks as a table to stdout instead of running the TUI. The table is
+// printed once or, with -watch, again after every interval until roumon is interrupted. Instead of the table
+// -output=json prints the parsed snapshots, -output=csv one row for every goroutine, -output=pprof a goroutine
+// profile, -output=folded the stacks for flame graphs, -output=speedscope the stacks for speedscope,
+// -output=markdown a summary for an incident document and -output=dot the graph of the spawn sites.
+// -format-template formats the snapshots with a Go template instead.
+func top(args []string) {
+	flags := flag.NewFlagSet("roumon top", flag.ExitOnError)
+	var targets stringList
+	var opts client.Options
+	host := flags.String("host", "localhost", "The pprof server IP or hostname")
+	port := flags.Int("port", 6060, "The pprof server port")
+	flags.Var(&targets, "target", "The pprof server as URL or unix socket. Can be repeated. Overrides host and port")
+	dumpFile := flags.String("file", "", "Print the goroutine dump of the file instead of fetching it")
+	once := flags.Bool("once", false, "Print the table once and exit. This is the default")
+	watch := flags.Bool("watch", false, "Print the table again after every interval until interrupted")
+	limit := flags.Int("n", 20, "Number of stacks to print, largest first. 0 prints all")
+	noHeaders := flags.Bool("no-headers", false, "Omit the header of the table and the CSV")
+	output := flags.String("output", "table", "Format of the output: table, json with all goroutines and frames of the snapshots, csv with one row per goroutine, pprof, folded stacks for flame graphs, speedscope, a markdown summary or a dot graph of the spawn sites")
+	formatTemplate := flags.String("format-template", "", "Path of a Go template which formats the snapshots instead of -output. See doc/template.md")
+	flags.DurationVar(&opts.Interval, "interval", 2*time.Second, "Time between two tables with -watch")
+	flags.DurationVar(&opts.FetchTimeout, "fetch-timeout", 10*time.Second, "Timeout of a single request to the pprof server. 0 disables the timeout")
+	flags.IntVar(&opts.MaxRetries, "max-retries", 3, "Consecutive failed fetches until roumon gives up on a target. 0 retries forever")
+	flags.StringVar(&opts.BasicAuth, "basic-auth", "", "Basic auth credentials user:password. Env: ROUMON_BASIC_AUTH")
+	flags.StringVar(&opts.BearerToken, "bearer-token", "", "Bearer token for the pprof server. Env: ROUMON_BEARER_TOKEN")
+	flags.BoolVar(&opts.Insecure, "insecure", false, "Skip verification of the pprof server certificate")
+	flags.Var((*stringList)(&opts.Headers), "header", "Additional request header \"Name: value\". Can be repeated")
+	dbgFile := flags.String("debug", "", "Path to debug file")
+	_ = flags.Parse(args)
+	authFromEnv(&opts)
+
+	defer setupLog(*dbgFile, nil)()
+
+	if *once && *watch {
+		fmt.Println("once and watch cannot be combined")
+		os.Exit(2)
+	}
+	if !slices.Contains([]string{"table", "json", "csv", "pprof", "folded", "speedscope", "markdown", "dot"}, *output) {
+		fmt.Printf("unknown output %q, expected table, json, csv, pprof, folded, speedscope, markdown or dot\n", *output)
+		os.Exit(2)
+	}
+	if *output == "pprof" && *watch {
+		fmt.Println("pprof cannot be combined with watch")
+		os.Exit(2)
+	}
+	var tmpl *template.Template
+	if len(*formatTemplate) > 0 {
+		if *output != "table" {
+			fmt.Println("output and format-template cannot be combined")
+			os.Exit(2)
+		}
+		var err error
+		if tmpl, err = model.ParseTemplate(*formatTemplate); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+	}
+	printed := false
+	printSnapshots := func(snapshots []model.Snapshot) {
+		// With -watch the header of the CSV is printed once and the rows of every refresh are appended
+		header := !*noHeaders && (*output != "csv" || !printed)
+		printed = true
+		err := writeOutput(os.Stdout, *output, tmpl, snapshots, *limit, header)
+		switch {
+		case err != nil && tmpl != nil:
+			// A template which fails for one snapshot fails for every snapshot
+			fmt.Println(err.Error())
+			os.Exit(1)
+		case err != nil:
+			log.Print(err.Error())
+		}
+	}
+	if len(*dumpFile) > 0 {
+		if *watch || len(targets) > 0 {
+			fmt.Println("file cannot be combined with watch or targets")
+			os.Exit(2)
+		}
+		snapshots, err := dump.LoadFile(*dumpFile)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+		// A file with several dumps is printed with the latest dump
+		latest := make(map[string]model.Snapshot)
+		for _, snapshot := range snapshots {
+			latest[snapshot.Target] = snapshot
+		}
+		printSnapshots(latestSnapshots(latest))
+		return
+	}
+
+	if len(targets) == 0 {
+		targets = append(targets, net.JoinHostPort(*host, strconv.Itoa(*port)))
+	}
+	pool, err := client.NewPool(targets, opts)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(2)
+	}
+	terminate := make(chan error)
+	routinesUpdate := make(chan model.Snapshot)
+	pool.Run(terminate, routinesUpdate, nil)
+	defer pool.Stop()
+
+	latest := make(map[string]model.Snapshot)
+	var tick <-chan time.Time
+	if *watch {
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	changed := false
+	for {
+		select {
+		case err := <-terminate:
+			fmt.Println(err.Error())
+			log.Print(err.Error())
+			os.Exit(1)
+		case snapshot := <-routinesUpdate:
+			latest[snapshot.Target] = snapshot
+			changed = true
+			if !*watch && len(latest) == len(targets) {
+				printSnapshots(latestSnapshots(latest))
+				return
+			}
+		case now := <-tick:
+			if !changed {
+				continue
+			}
+			changed = false
+			snapshots := latestSnapshots(latest)
+			if *output != "table" || tmpl != nil {
+				printSnapshots(snapshots)
+				continue
+			}
+			fmt.Printf("# %s %d goroutines\n", now.Format(time.TimeOnly), len(model.Merge(snapshots)))
+			printSnapshots(snapshots)
+			fmt.Println()
+		}
+	}
+}
+
+// writeOutput writes the snapshots in the format of -output, or with the template if it is not nil. The limit
+// applies to the table and the header to the table and the CSV.
+func writeOutput(w io.Writer, output string, tmpl *template.Template, snapshots []model.Snapshot, limit int, header bool) error {
+	switch {
+	case tmpl != nil:
+		return model.WriteTemplate(w, tmpl, snapshots, time.Now())
+	case output == "json":
+		return model.WriteSnapshotsJSON(w, snapshots)
+	case output == "csv" && header:
+		return model.WriteCSV(w, model.Merge(snapshots))
+	case output == "csv":
+		return model.WriteCSVRows(w, model.Merge(snapshots))
+	case output == "pprof":
+		return model.WritePprof(w, model.Merge(snapshots))
+	case output == "folded":
+		return model.WriteFolded(w, model.Merge(snapshots))
+	case output == "speedscope":
+		return model.WriteSpeedscope(w, model.Merge(snapshots))
+	case output == "markdown":
+		return model.WriteMarkdown(w, model.Merge(snapshots))
+	case output == "dot":
+		return model.WriteDOT(w, model.Merge(snapshots))
+	default:
+		return model.WriteTable(w, model.Merge(snapshots), limit, header)
+	}
+}
+
+// latestSnapshots returns the latest snapshots of all targets in the order of their names
+func latestSnapshots(latest map[string]model.Snapshot) []model.Snapshot {
+	snapshots := make([]model.Snapshot, 0, len(latest))
+	for _, snapshot := range latest {
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Target < snapshots[j].Target })
+	return snapshots
+}
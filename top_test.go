@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/becheran/roumon/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const topDump = `goroutine 1 [running]:
+main.main()
+	/src/main.go:10 +0x1d
+
+goroutine 7 [chan receive, 3 minutes]:
+main.worker()
+	/src/main.go:20 +0x2a
+created by main.main in goroutine 1
+	/src/main.go:12 +0x3b
+
+goroutine 8 [chan receive, 5 minutes]:
+main.worker()
+	/src/main.go:20 +0x2a
+created by main.main in goroutine 1
+	/src/main.go:12 +0x3b
+`
+
+func topSnapshots(t *testing.T) []model.Snapshot {
+	routines, err := model.ParseStackFrame(strings.NewReader(topDump))
+	assert.Nil(t, err)
+	snapshot := model.Snapshot{Goroutines: routines}
+	snapshot.SetTarget("localhost:6060")
+	return []model.Snapshot{snapshot}
+}
+
+func TestWriteOutputTable(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, writeOutput(&buf, "table", nil, topSnapshots(t), 1, true))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2, "the header and the largest stack")
+	assert.Equal(t, "COUNT", strings.Fields(lines[0])[0])
+	assert.Equal(t, []string{"2", "chan", "receive"}, strings.Fields(lines[1])[:3])
+
+	buf.Reset()
+	assert.Nil(t, writeOutput(&buf, "table", nil, topSnapshots(t), 0, false))
+	assert.NotContains(t, buf.String(), "COUNT")
+	assert.Len(t, strings.Split(strings.TrimSpace(buf.String()), "\n"), 2)
+}
+
+func TestWriteOutputCSV(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, writeOutput(&buf, "csv", nil, topSnapshots(t), 1, true))
+	assert.Nil(t, writeOutput(&buf, "csv", nil, topSnapshots(t), 1, false))
+	rows, err := csv.NewReader(&buf).ReadAll()
+	assert.Nil(t, err)
+	assert.Len(t, rows, 7, "one header and the goroutines of both refreshes without a limit")
+	assert.Equal(t, "target", rows[0][0])
+	for _, row := range rows[1:] {
+		assert.Equal(t, "localhost:6060", row[0])
+	}
+	assert.Equal(t, "8", rows[6][1])
+}
+
+func TestWriteOutputJSON(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, writeOutput(&buf, "json", nil, topSnapshots(t), 1, true))
+	var out struct {
+		Snapshots []struct {
+			Target     string
+			Goroutines []json.RawMessage
+		}
+	}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &out))
+	assert.Len(t, out.Snapshots, 1)
+	assert.Equal(t, "localhost:6060", out.Snapshots[0].Target)
+	assert.Len(t, out.Snapshots[0].Goroutines, 3, "the limit only applies to the table")
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/becheran/roumon/internal/config"
+	"github.com/becheran/roumon/internal/ui"
+)
+
+// uiFlags are the flags of the TUI which roumon, roumon serve and roumon replay share
+type uiFlags struct {
+	flags         *flag.FlagSet
+	configFile    string
+	stateFile     string
+	clean         bool
+	keymap        string
+	theme         string
+	trueColor     bool
+	noColor       bool
+	linear        bool
+	columns       string
+	sortSpec      string
+	filterSpec    string
+	colorBy       string
+	waitThreshold time.Duration
+	hide          []string
+	paths         pathMap
+}
+
+// registerUIFlags registers the flags of the TUI
+func registerUIFlags(flags *flag.FlagSet) *uiFlags {
+	f := &uiFlags{flags: flags, paths: make(pathMap)}
+	flags.StringVar(&f.configFile, "config", config.DefaultPath(), "Path to the config file")
+	flags.StringVar(&f.stateFile, "state", config.DefaultStatePath(), "Path to the file which keeps the filter, sort and view of the list from one session to the next")
+	flags.BoolVar(&f.clean, "clean", false, "Start without the filter, sort and view of the last session")
+	flags.StringVar(&f.keymap, "keymap", "", "Keybindings: default, vim or emacs. Overrides the keymap of the config file")
+	flags.StringVar(&f.theme, "theme", "", "Colors: dark, light or high-contrast. Overrides the theme of the config file")
+	flags.BoolVar(&f.trueColor, "truecolor", supportsTrueColor(), "Draw 24 bit colors. Defaults to true if COLORTERM is truecolor or 24bit")
+	flags.BoolVar(&f.noColor, "no-color", len(os.Getenv("NO_COLOR")) > 0, "Draw the default colors of the terminal only. Defaults to true if NO_COLOR is set")
+	flags.BoolVar(&f.linear, "linear", false, "Show one pane at a time without borders, charts and symbols, and mark the state of rows with text. For screen readers")
+	flags.StringVar(&f.columns, "columns", "", "Comma separated columns of the goroutine list with an optional width and trim, end or middle: target, id, status, wait, func, created, locked and label:<key>. For example id,func:40,label:tenant:10:middle. Defaults to "+ui.DefaultColumns+". Overrides the columns of the config file")
+	flags.StringVar(&f.sortSpec, "sort", ui.DefaultSort, "Sort of the list: none, id, status, wait, function or depth. A leading - sorts in descending order")
+	flags.StringVar(&f.filterSpec, "filter", "", "Initial filter of the list: the name of a filter of the config file or a filter expression")
+	flags.StringVar(&f.colorBy, "color-by", "", "Color the rows by the value of the label with the key, for example tenant, and show the label as column")
+	flags.DurationVar(&f.waitThreshold, "wait-threshold", ui.DefaultWaitThreshold, "Highlight the goroutines which wait at least this long. 0 disables the highlighting")
+	flags.Var((*commaList)(&f.hide), "hide", "Comma separated packages whose frames are hidden along with the runtime and the standard library, for example google.golang.org/grpc. Overrides the packages of the config file")
+	flags.Var(f.paths, "path-map", "Map a build path prefix to the local checkout to open frames in the editor, for example /build/src=/home/me/src. Can be repeated")
+	return f
+}
+
+// loadConfig loads the config file and overrides its settings with the flags
+func (f *uiFlags) loadConfig() (config.Config, error) {
+	cfg, err := config.Load(f.configFile)
+	if err != nil {
+		return cfg, err
+	}
+	if len(f.keymap) > 0 {
+		cfg.Keymap = f.keymap
+	}
+	if len(f.theme) > 0 {
+		cfg.Theme = f.theme
+	}
+	cfg.Paths = f.paths.merge(cfg.Paths)
+	if len(f.columns) > 0 {
+		cfg.Columns = f.columns
+	}
+	if len(f.hide) > 0 {
+		cfg.Hide = f.hide
+	}
+	return cfg, nil
+}
+
+// configure sets up the UI with the config and the flags. Changes of the layout, bookmarks and columns are saved
+// in the config file and the filter, sort and view of the list in the state file.
+func (f *uiFlags) configure(u *ui.UI, cfg config.Config) error {
+	if err := u.SetKeymap(cfg.Keymap, cfg.Keys); err != nil {
+		return err
+	}
+	if err := u.SetTheme(cfg.Theme, cfg.Colors, f.trueColor || cfg.TrueColor); err != nil {
+		return err
+	}
+	if f.noColor || cfg.NoColor {
+		u.SetNoColor()
+	}
+	if f.linear || cfg.Linear {
+		u.SetLinear()
+	}
+	u.SetEditor(cfg.Editor, cfg.Paths)
+	u.SetHiddenPackages(cfg.Hide)
+	u.SetLayout(cfg.Layout, func(layout config.Layout) error {
+		return config.Update(f.configFile, func(cfg *config.Config) { cfg.Layout = layout })
+	})
+	u.SetBookmarks(cfg.Bookmarks, func(bookmarks []config.Bookmark) error {
+		return config.Update(f.configFile, func(cfg *config.Config) { cfg.Bookmarks = bookmarks })
+	})
+	u.SetFilters(cfg.Filters)
+	err := u.SetColumns(cfg.Columns, func(spec string) error {
+		return config.Update(f.configFile, func(cfg *config.Config) { cfg.Columns = spec })
+	})
+	if err != nil {
+		return err
+	}
+	if err := u.SetSort(f.sortSpec); err != nil {
+		return err
+	}
+	u.SetWaitThreshold(f.waitThreshold)
+	u.SetColorBy(f.colorBy)
+	u.SetState(loadState(f.stateFile, f.clean, f.flags), func(state config.State) error {
+		return config.SaveState(f.stateFile, state)
+	})
+	if len(f.filterSpec) > 0 {
+		_ = u.SetFilter(f.filterSpec)
+	}
+	return nil
+}
+
+// loadState loads the state of the last session unless clean is set. The sort and the filter which are given by
+// flags replace the ones of the state.
+func loadState(path string, clean bool, flags *flag.FlagSet) config.State {
+	if clean {
+		return config.State{}
+	}
+	state, err := config.LoadState(path)
+	if err != nil {
+		log.Print(err.Error())
+		return config.State{}
+	}
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "sort":
+			state.Sort = ""
+		case "filter":
+			state.Filter, state.Status = "", ""
+		}
+	})
+	return state
+}